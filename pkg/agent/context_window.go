@@ -90,11 +90,13 @@ func TrimMessagesToTokenLimit(messages []Message, tools []OpenAITool, maxTokens
 
 	trimmed := trimToolResponses(messages, maxToolResponseTokens)
 	if estimateMessagesTokens(trimmed, tools) <= maxTokens {
+		contextTruncations.WithLabelValues("tool_responses").Inc()
 		return trimmed
 	}
 
 	trimmed = trimToolResponses(trimmed, aggressiveToolResponseTokens)
 	if estimateMessagesTokens(trimmed, tools) <= maxTokens {
+		contextTruncations.WithLabelValues("tool_responses_aggressive").Inc()
 		return trimmed
 	}
 
@@ -115,12 +117,14 @@ func TrimMessagesToTokenLimit(messages []Message, tools []OpenAITool, maxTokens
 		candidate := nonSystem[i:]
 		test := assembleWithTruncationNotice(systemMsg, candidate, i > 0)
 		if estimateMessagesTokens(test, tools) <= target {
+			contextTruncations.WithLabelValues("drop_messages").Inc()
 			return test
 		}
 	}
 
 	// Fallback: keep system prompt and only the last non-system message. This drops
 	// everything in between, so always mark the history as truncated.
+	contextTruncations.WithLabelValues("last_message_only").Inc()
 	tail := []Message{}
 	if len(nonSystem) > 0 {
 		tail = append(tail, nonSystem[len(nonSystem)-1])
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// errToolCallCompleted refuses requests a server makes on behalf of a tool
+// call that has already returned.
+var errToolCallCompleted = errors.New("the tool call has completed")
+
+// toolElicitor forwards the input requests a tool call's server makes to the
+// user as input_request events, and waits for the answer.
+type toolElicitor struct {
+	events *toolCallEvents
+	tc     ToolCall
+	req    LoopRequest
+	seq    atomic.Int64
+}
+
+func newToolElicitor(events *toolCallEvents, tc ToolCall, req LoopRequest) *toolElicitor {
+	return &toolElicitor{events: events, tc: tc, req: req}
+}
+
+// Elicit asks the user and returns their answer. Runs nobody is watching
+// can't ask, so their requests are cancelled, and requests arriving after
+// the tool call completed are refused.
+func (e *toolElicitor) Elicit(ctx context.Context, ereq mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	if e.req.RegisterInput == nil {
+		inputRequests.WithLabelValues("unattended").Inc()
+		return &mcp.ElicitationResult{Action: "cancel"}, nil
+	}
+	if e.events.ctx.Err() != nil {
+		inputRequests.WithLabelValues("canceled").Inc()
+		return nil, errToolCallCompleted
+	}
+	// The request arrives on the MCP session's context; stop with the call too.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(e.events.ctx, cancel)()
+
+	request := InputRequestEvent{
+		InputID:    fmt.Sprintf("%s-input-%d", e.tc.ID, e.seq.Add(1)),
+		ToolCallID: e.tc.ID,
+		ToolName:   e.tc.Function.Name,
+		ServerID:   ereq.ServerID,
+		Message:    ereq.Message,
+		Schema:     ereq.Schema,
+	}
+	wait, err := e.req.RegisterInput(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request input from the user: %w", err)
+	}
+	e.events.send(SSEEvent{Type: "input_request", Data: request})
+
+	resolved, err := wait(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			inputRequests.WithLabelValues("canceled").Inc()
+		} else {
+			inputRequests.WithLabelValues("error").Inc()
+		}
+		return nil, fmt.Errorf("waiting for user input: %w", err)
+	}
+	if resolved.ResolvedAt == "" {
+		resolved.ResolvedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	inputRequests.WithLabelValues(inputActionLabel(resolved.Action)).Inc()
+	e.events.send(SSEEvent{Type: "input_resolved", Data: resolved})
+
+	result := &mcp.ElicitationResult{Action: resolved.Action}
+	if resolved.Action == "accept" {
+		result.Content = resolved.Content
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"context"
+	"testing"
+)
+
+func elicitationRequest() mcp.ElicitationRequest {
+	return mcp.ElicitationRequest{
+		ServerID: "k8s",
+		ToolName: "k8s_restart",
+		Message:  "Which namespace?",
+		Schema:   map[string]interface{}{"type": "object"},
+	}
+}
+
+func TestToolElicitor_ForwardsRequestAndAnswer(t *testing.T) {
+	var asked []InputRequestEvent
+	req := LoopRequest{
+		RegisterInput: func(_ context.Context, r InputRequestEvent) (InputWaitFunc, error) {
+			asked = append(asked, r)
+			return func(context.Context) (InputResolvedEvent, error) {
+				return InputResolvedEvent{InputID: r.InputID, Action: "accept", Content: map[string]interface{}{"namespace": "payments"}}, nil
+			}, nil
+		},
+	}
+	loop := &AgentLoop{}
+	eventCh := make(chan SSEEvent, 4)
+	tc := ToolCall{ID: "call_1"}
+	tc.Function.Name = "k8s_restart"
+	elicitor := newToolElicitor(loop.newToolCallEvents(context.Background(), eventCh), tc, req)
+
+	result, err := elicitor.Elicit(context.Background(), elicitationRequest())
+	if err != nil {
+		t.Fatalf("Elicit: %v", err)
+	}
+	if result.Action != "accept" || result.Content["namespace"] != "payments" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(asked) != 1 || asked[0].InputID != "call_1-input-1" || asked[0].ServerID != "k8s" || asked[0].Message != "Which namespace?" {
+		t.Fatalf("unexpected input request: %+v", asked)
+	}
+	close(eventCh)
+	var types []string
+	for event := range eventCh {
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 || types[0] != "input_request" || types[1] != "input_resolved" {
+		t.Fatalf("expected input_request then input_resolved, got %v", types)
+	}
+}
+
+func TestToolElicitor_DropsContentUnlessAccepted(t *testing.T) {
+	req := LoopRequest{
+		RegisterInput: func(_ context.Context, r InputRequestEvent) (InputWaitFunc, error) {
+			return func(context.Context) (InputResolvedEvent, error) {
+				return InputResolvedEvent{InputID: r.InputID, Action: "decline", Content: map[string]interface{}{"namespace": "payments"}}, nil
+			}, nil
+		},
+	}
+	elicitor := newToolElicitor((&AgentLoop{}).newToolCallEvents(context.Background(), make(chan SSEEvent, 4)), ToolCall{ID: "call_1"}, req)
+
+	result, err := elicitor.Elicit(context.Background(), elicitationRequest())
+	if err != nil {
+		t.Fatalf("Elicit: %v", err)
+	}
+	if result.Action != "decline" || result.Content != nil {
+		t.Fatalf("expected a decline without content, got %+v", result)
+	}
+}
+
+func TestToolElicitor_CancelsUnattendedRuns(t *testing.T) {
+	elicitor := newToolElicitor((&AgentLoop{}).newToolCallEvents(context.Background(), nil), ToolCall{ID: "call_1"}, LoopRequest{})
+
+	result, err := elicitor.Elicit(context.Background(), elicitationRequest())
+	if err != nil || result.Action != "cancel" {
+		t.Fatalf("expected unattended requests to be cancelled, got %+v, %v", result, err)
+	}
+}
+
+func TestToolElicitor_StopsWithTheToolCall(t *testing.T) {
+	req := LoopRequest{
+		RegisterInput: func(_ context.Context, r InputRequestEvent) (InputWaitFunc, error) {
+			return func(ctx context.Context) (InputResolvedEvent, error) {
+				<-ctx.Done()
+				return InputResolvedEvent{}, ctx.Err()
+			}, nil
+		},
+	}
+	eventCh := make(chan SSEEvent, 4)
+	events := (&AgentLoop{}).newToolCallEvents(context.Background(), eventCh)
+	elicitor := newToolElicitor(events, ToolCall{ID: "call_1"}, req)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := elicitor.Elicit(context.Background(), elicitationRequest())
+		errCh <- err
+	}()
+	if event := <-eventCh; event.Type != "input_request" {
+		t.Fatalf("expected input_request, got %q", event.Type)
+	}
+
+	// The call returns while the user is still being asked.
+	events.stop()
+	if err := <-errCh; err == nil {
+		t.Fatal("expected the pending request to end with the call")
+	}
+	if _, err := elicitor.Elicit(context.Background(), elicitationRequest()); err != errToolCallCompleted {
+		t.Fatalf("expected requests after the call to be refused, got %v", err)
+	}
+	close(eventCh)
+	for event := range eventCh {
+		t.Errorf("unexpected event after the call completed: %q", event.Type)
+	}
+}
@@ -130,9 +130,24 @@ func NewLLMClient(logger log.Logger, httpClient *http.Client) *LLMClient {
 	return &LLMClient{httpClient: httpClient, logger: logger}
 }
 
-func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest, grafanaURL, authToken, orgID string) (result *ChatCompletionResponse, err error) {
+// ContentDeltaFunc receives assistant text from ChatCompletionStream as it is
+// parsed. It is called on the caller's goroutine, before the completion returns.
+type ContentDeltaFunc func(ContentDeltaEvent)
+
+func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest, grafanaURL, authToken, orgID string) (*ChatCompletionResponse, error) {
+	return c.ChatCompletionStream(ctx, req, grafanaURL, authToken, orgID, nil)
+}
+
+// ChatCompletionStream is ChatCompletion with onDelta invoked for each content
+// chunk as it arrives. If an interrupted stream is retried after text was
+// delivered, onDelta first receives a Reset so the partial text is discarded.
+// A nil onDelta behaves exactly like ChatCompletion.
+func (c *LLMClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest, grafanaURL, authToken, orgID string, onDelta ContentDeltaFunc) (result *ChatCompletionResponse, err error) {
 	ctx, span := tracing.DefaultTracer().Start(ctx, "llm_call")
+	start := time.Now()
+	model := modelLabel(req.Model)
 	defer func() {
+		llmRequestDuration.WithLabelValues(model, llmStatusLabel(ctx, err)).Observe(time.Since(start).Seconds())
 		if err != nil {
 			tracing.Error(span, err)
 		}
@@ -155,6 +170,15 @@ func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionReques
 		attribute.Int("llm.request_bytes", len(body)),
 	)
 
+	var onContent func(string)
+	streamed := false
+	if onDelta != nil {
+		onContent = func(delta string) {
+			streamed = true
+			onDelta(ContentDeltaEvent{Delta: delta})
+		}
+	}
+
 	for attempt := 1; attempt <= maxLLMAttempts; attempt++ {
 		httpReq, err := c.buildHTTPRequest(ctx, body, grafanaURL, authToken, orgID)
 		if err != nil {
@@ -178,6 +202,7 @@ func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionReques
 			}
 			if attempt < maxLLMAttempts {
 				c.logger.Warn("LLM request failed, retrying", "error", err, "attempt", attempt)
+				llmRetries.WithLabelValues(model, "network").Inc()
 				if !sleepWithContext(ctx, retryDelay(nil, attempt)) {
 					return nil, ctx.Err()
 				}
@@ -206,6 +231,7 @@ func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionReques
 				"requestBytes", llmErr.RequestBytes,
 				"attempt", attempt)
 			if llmErr.Retryable && attempt < maxLLMAttempts {
+				llmRetries.WithLabelValues(model, "http").Inc()
 				if !sleepWithContext(ctx, retryDelay(resp, attempt)) {
 					return nil, ctx.Err()
 				}
@@ -214,13 +240,18 @@ func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionReques
 			return nil, llmErr
 		}
 
-		result, err = parseStream(resp)
+		result, err = parseStream(resp, onContent)
 		resp.Body.Close()
 		if err != nil {
 			// An interrupted stream is transient — retry the whole request rather
 			// than surfacing a partial (poisoned) response.
 			if errors.Is(err, errIncompleteStream) && attempt < maxLLMAttempts {
 				c.logger.Warn("LLM stream incomplete, retrying", "attempt", attempt)
+				llmRetries.WithLabelValues(model, "stream").Inc()
+				if streamed {
+					streamed = false
+					onDelta(ContentDeltaEvent{Reset: true})
+				}
 				if !sleepWithContext(ctx, retryDelay(nil, attempt)) {
 					return nil, ctx.Err()
 				}
@@ -235,6 +266,7 @@ func (c *LLMClient) ChatCompletion(ctx context.Context, req ChatCompletionReques
 		span.SetAttributes(
 			attribute.Int("llm.prompt_tokens", result.Usage.PromptTokens),
 			attribute.Int("llm.completion_tokens", result.Usage.CompletionTokens),
+			attribute.Int("llm.cached_prompt_tokens", result.Usage.CachedTokens()),
 		)
 	}
 
@@ -332,7 +364,9 @@ func (c *LLMClient) buildHTTPRequest(ctx context.Context, body []byte, grafanaUR
 	return req, nil
 }
 
-func parseStream(resp *http.Response) (*ChatCompletionResponse, error) {
+// parseStream assembles a streamed completion. onContent, when non-nil, is
+// called with each non-empty content delta in arrival order.
+func parseStream(resp *http.Response, onContent func(string)) (*ChatCompletionResponse, error) {
 	var (
 		responseID   string
 		content      strings.Builder
@@ -370,6 +404,9 @@ func parseStream(resp *http.Response) (*ChatCompletionResponse, error) {
 				finishReason = *choice.FinishReason
 			}
 			content.WriteString(choice.Delta.Content)
+			if onContent != nil && choice.Delta.Content != "" {
+				onContent(choice.Delta.Content)
+			}
 			for _, tc := range choice.Delta.ToolCalls {
 				existing := toolCallMap[tc.Index]
 				if tc.ID != "" {
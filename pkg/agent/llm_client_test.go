@@ -306,6 +306,73 @@ func TestLLMClient_ChatCompletion_IncompleteStreamExhausted(t *testing.T) {
 	}
 }
 
+func TestLLMClient_ChatCompletionStream_DeliversContentDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeSSEChunks(w,
+			`{"id":"x","choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}`,
+			`{"id":"x","choices":[{"index":0,"delta":{"content":"Hel"},"finish_reason":null}]}`,
+			`{"id":"x","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":null}]}`,
+			`{"id":"x","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		)
+	}))
+	defer server.Close()
+
+	var deltas []ContentDeltaEvent
+	client := NewLLMClient(log.DefaultLogger, &http.Client{Timeout: llmTimeout})
+	resp, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	}, server.URL, "token", "1", func(d ContentDeltaEvent) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Hello" {
+		t.Fatalf("content = %q, want Hello", resp.Choices[0].Message.Content)
+	}
+	want := []ContentDeltaEvent{{Delta: "Hel"}, {Delta: "lo"}}
+	if fmt.Sprint(deltas) != fmt.Sprint(want) {
+		t.Fatalf("deltas = %+v, want %+v", deltas, want)
+	}
+}
+
+func TestLLMClient_ChatCompletionStream_ResetsDeltasOnRetry(t *testing.T) {
+	// The first attempt streams some text and is then cut off. The retry must
+	// retract that text before delivering the second attempt's deltas.
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if attempts == 1 {
+			fmt.Fprint(w, `data: {"id":"x","choices":[{"index":0,"delta":{"content":"partial"},"finish_reason":null}]}`+"\n\n")
+			return
+		}
+		writeSSEChunks(w,
+			`{"id":"x","choices":[{"index":0,"delta":{"content":"whole"},"finish_reason":null}]}`,
+			`{"id":"x","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		)
+	}))
+	defer server.Close()
+
+	var deltas []ContentDeltaEvent
+	client := NewLLMClient(log.DefaultLogger, &http.Client{Timeout: llmTimeout})
+	resp, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	}, server.URL, "token", "1", func(d ContentDeltaEvent) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "whole" {
+		t.Fatalf("content = %q, want whole", resp.Choices[0].Message.Content)
+	}
+	want := []ContentDeltaEvent{{Delta: "partial"}, {Reset: true}, {Delta: "whole"}}
+	if fmt.Sprint(deltas) != fmt.Sprint(want) {
+		t.Fatalf("deltas = %+v, want %+v", deltas, want)
+	}
+}
+
 func TestLLMClient_ChatCompletion_ErrorBody(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTooManyRequests)
@@ -396,3 +463,31 @@ func TestLLMClient_ChatCompletion_RetriesTransientStatus(t *testing.T) {
 		t.Fatalf("content = %q, want ok", resp.Choices[0].Message.Content)
 	}
 }
+
+func TestLLMStatusLabel(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{"ok", context.Background(), nil, "ok"},
+		{"canceled", canceled, errors.New("boom"), "canceled"},
+		{"server error", context.Background(), &LLMHTTPError{StatusCode: 503}, "http_5xx"},
+		{"client error", context.Background(), &LLMHTTPError{StatusCode: 400}, "http_4xx"},
+		{"incomplete stream", context.Background(), errIncompleteStream, "stream_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llmStatusLabel(tt.ctx, tt.err); got != tt.want {
+				t.Fatalf("llmStatusLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := modelLabel("gpt-whatever"); got != "other" {
+		t.Fatalf("modelLabel = %q, want other", got)
+	}
+}
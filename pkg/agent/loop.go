@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -35,6 +36,11 @@ const maxTruncationRetries = 1
 // the model reissues a smaller, complete call rather than repeating the cutoff.
 const truncatedToolCallNudge = "[SYSTEM: Your previous tool call was cut off before its arguments were complete, so it was discarded. Reissue it now as a single, complete, valid JSON tool call. If the arguments are large (for example a full dashboard), reduce their size or split the work into smaller steps.]"
 
+// ErrTokenBudgetExhausted is returned (wrapped) by a TokenBudgetChecker when
+// the caller has used up a token budget. The loop ends the run with an
+// ErrorEvent coded "token_budget_exhausted".
+var ErrTokenBudgetExhausted = errors.New("token budget exhausted")
+
 type AgentLoop struct {
 	llmClient *LLMClient
 	mcpProxy  *mcp.Proxy
@@ -72,14 +78,59 @@ type LoopRequest struct {
 	// before the loop runs. Used to hide graphiti write tools from user sessions.
 	ExcludeToolNames []string
 
+	// ToolCatalog pins the run to the tools listed when it started. When nil
+	// the loop lists tools itself.
+	ToolCatalog *mcp.ToolCatalog
+
 	// MCPServers carries per-server tool-selection settings, used to honor the
 	// user's Manage Tools choices inside the agent loop (not just at HTTP edges).
 	MCPServers []mcp.ServerConfig
 
+	// ToolPolicies are the admin-defined tool access rules applied on top of
+	// UserRole's defaults, both to the tools offered and to each call.
+	ToolPolicies []rbac.Rule
+	// UserTeams are the Grafana teams of the user the run is for, matched
+	// against the team conditions of ToolPolicies.
+	UserTeams []string
+	// UserTeamsUnresolved is set when the user's teams could not be looked
+	// up; team deny rules then match regardless of UserTeams.
+	UserTeamsUnresolved bool
+
+	// StreamContentDeltas emits content_delta events while the LLM is still
+	// generating, ahead of the assembled content event. Only interactive runs
+	// set it; background consumers just want the final answer.
+	StreamContentDeltas bool
+
 	ApprovalPolicy       string
 	MaxParallelToolCalls int
 	RegisterApproval     ApprovalRegistrar
 	CheckApprovalGrant   ApprovalGrantChecker
+
+	// RecordTokenUsage is called after every LLM response that reports usage;
+	// CheckTokenBudget before every LLM call after the first, so a budget that
+	// runs out mid-run stops the loop at the next iteration.
+	RecordTokenUsage TokenUsageRecorder
+	CheckTokenBudget TokenBudgetChecker
+
+	// RegisterInput forwards MCP servers' requests for user input to the
+	// user. When nil, nobody is watching the run and such requests are
+	// cancelled.
+	RegisterInput InputRegistrar
+
+	// SamplingTokenBudget caps the LLM tokens MCP servers may spend through
+	// sampling requests during the run. Zero refuses sampling.
+	SamplingTokenBudget int
+}
+
+// unavailableServersNote tells the model which data sources are down, so it
+// reports the gap instead of guessing why their tools are missing.
+func unavailableServersNote(servers []string) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	return "\n\n## Unavailable Data Sources\n\nThese MCP servers are unreachable and their tools are not available in this run: " +
+		strings.Join(servers, ", ") +
+		". If answering needs data from them, say so instead of guessing.\n"
 }
 
 func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSEEvent) {
@@ -96,12 +147,22 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 	completionBudget := completionTokenBudget(maxTokens)
 	promptBudget := maxTokens - completionBudget
 
-	mcpTools, err := a.mcpProxy.ListTools()
-	if err != nil {
-		a.logger.Error("Failed to list MCP tools, proceeding without tools", "error", err)
-		mcpTools = []mcp.Tool{}
+	catalog := req.ToolCatalog
+	if catalog != nil {
+		a.send(ctx, eventCh, SSEEvent{Type: "tool_catalog", Data: ToolCatalogEvent{Version: catalog.Version, ToolCount: len(catalog.Tools)}})
+	} else {
+		var err error
+		catalog, err = a.mcpProxy.ToolCatalog()
+		if err != nil {
+			a.logger.Error("Failed to list MCP tools, proceeding without tools", "error", err)
+			catalog = &mcp.ToolCatalog{Tools: []mcp.Tool{}}
+		}
+		// Pin the run to this catalog, so tool calls resolve against the
+		// tools it was offered even if a server's tools change mid-run.
+		req.ToolCatalog = catalog
 	}
-	mcpTools = rbac.FilterToolsByRole(mcpTools, req.UserRole)
+	mcpTools := catalog.Tools
+	mcpTools = rbac.FilterToolsByRole(mcpTools, req.toolSubject(), req.toolPolicy())
 	mcpTools = mcp.FilterToolsBySelection(mcpTools, req.MCPServers)
 	if len(req.ExcludeToolNames) > 0 {
 		excluded := make(map[string]bool, len(req.ExcludeToolNames))
@@ -118,7 +179,8 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 	}
 	openAITools := ConvertMCPToolsToOpenAI(mcpTools)
 
-	messages := BuildContextWindow(req.SystemPrompt, req.Messages, req.Summary, req.RecentMessageCount)
+	systemPrompt := req.SystemPrompt + unavailableServersNote(catalog.Unavailable)
+	messages := BuildContextWindow(systemPrompt, req.Messages, req.Summary, req.RecentMessageCount)
 
 	// Per-run state for transport-failure aggregation. We emit at most one
 	// mcp_unavailable event per run, once at least 2 distinct tools have hit
@@ -131,14 +193,29 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 
 	// Run-level usage/tool-call totals, surfaced on the "done" event so the
 	// caller can persist per-session stats (tokens, turns, tool calls).
-	usageByModel := make(map[string]ModelUsage)
+	usage := newRunUsage()
 	toolCallCount := 0
+	sampling := a.newRunSampling(ctx, req, usage)
 
 	for iteration := 0; iteration < maxIter; iteration++ {
 		if ctx.Err() != nil {
 			return
 		}
 
+		if iteration > 0 && req.CheckTokenBudget != nil {
+			if err := req.CheckTokenBudget(ctx); err != nil {
+				if errors.Is(err, ErrTokenBudgetExhausted) {
+					a.send(ctx, eventCh, SSEEvent{
+						Type: "error",
+						Data: ErrorEvent{Message: err.Error(), Code: "token_budget_exhausted"},
+					})
+					return
+				}
+				// Fail open: a budget store outage shouldn't take the agent down.
+				a.logger.Warn("Token budget check failed", "error", err)
+			}
+		}
+
 		messages = TrimMessagesToTokenLimit(messages, openAITools, promptBudget)
 
 		a.logger.Debug("Agent loop iteration",
@@ -169,7 +246,13 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 			Tools:     openAITools,
 			MaxTokens: completionBudget,
 		}
-		resp, effectiveModel, err := a.chatCompletionWithFallback(ctx, llmReq, req)
+		var deltas *contentDeltaBuffer
+		if req.StreamContentDeltas {
+			deltas = &contentDeltaBuffer{emit: func(d ContentDeltaEvent) {
+				a.send(ctx, eventCh, SSEEvent{Type: "content_delta", Data: d})
+			}}
+		}
+		resp, effectiveModel, err := a.chatCompletionWithFallback(ctx, llmReq, req, deltas.handler())
 		if err != nil {
 			if ctx.Err() != nil {
 				return
@@ -182,16 +265,20 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 		}
 
 		if resp.Usage != nil {
-			usage := usageByModel[effectiveModel]
-			usage.Model = effectiveModel
-			usage.PromptTokens += resp.Usage.PromptTokens
-			usage.CompletionTokens += resp.Usage.CompletionTokens
-			usage.TotalTokens += resp.Usage.TotalTokens
-			usageByModel[effectiveModel] = usage
+			recorded := usage.add(effectiveModel, resp.Usage)
+			if req.RecordTokenUsage != nil {
+				req.RecordTokenUsage(ctx, recorded)
+			}
 		}
 
 		msg := resp.Choices[0].Message
 
+		// Text that accompanies tool calls never reaches the user (see
+		// msg.Content below), so retract whatever was streamed of it.
+		if len(msg.ToolCalls) > 0 {
+			deltas.discard()
+		}
+
 		// Drop tool calls whose arguments are malformed JSON before they enter
 		// history. This happens when the model is cut off mid tool-call
 		// (finish_reason=length) or a stream is interrupted, leaving truncated
@@ -251,21 +338,24 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 					Data: ContentEvent{Content: msg.Content},
 				})
 			}
-			var promptTokens, completionTokens, totalTokens int64
+			usageByModel := usage.snapshot()
+			var promptTokens, completionTokens, totalTokens, cachedPromptTokens int64
 			for _, u := range usageByModel {
 				promptTokens += int64(u.PromptTokens)
 				completionTokens += int64(u.CompletionTokens)
 				totalTokens += int64(u.TotalTokens)
+				cachedPromptTokens += int64(u.CachedPromptTokens)
 			}
 			a.send(ctx, eventCh, SSEEvent{
 				Type: "done",
 				Data: DoneEvent{
-					TotalIterations:  iteration + 1,
-					PromptTokens:     promptTokens,
-					CompletionTokens: completionTokens,
-					TotalTokens:      totalTokens,
-					ToolCallCount:    toolCallCount,
-					UsageByModel:     usageByModel,
+					TotalIterations:    iteration + 1,
+					PromptTokens:       promptTokens,
+					CompletionTokens:   completionTokens,
+					TotalTokens:        totalTokens,
+					CachedPromptTokens: cachedPromptTokens,
+					ToolCallCount:      toolCallCount,
+					UsageByModel:       usageByModel,
 				},
 			})
 			return
@@ -274,40 +364,21 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 		msg.Content = ""
 		messages = append(messages, msg)
 
-		for _, tc := range msg.ToolCalls {
-			if ctx.Err() != nil {
-				return
-			}
-			toolCallCount++
-
-			a.send(ctx, eventCh, SSEEvent{
-				Type: "tool_call_start",
-				Data: ToolCallStartEvent{
-					ID:        tc.ID,
-					Name:      tc.Function.Name,
-					Arguments: tc.Function.Arguments,
-				},
-			})
+		toolCallCount += len(msg.ToolCalls)
+		outcomes := a.executeToolCalls(ctx, eventCh, msg.ToolCalls, req, sampling)
+		if ctx.Err() != nil {
+			return
+		}
 
-			toolContent, isError, errorKind := a.executeToolWithApproval(ctx, eventCh, tc, req)
-
-			a.send(ctx, eventCh, SSEEvent{
-				Type: "tool_call_result",
-				Data: ToolCallResultEvent{
-					ID:        tc.ID,
-					Name:      tc.Function.Name,
-					Content:   toolContent,
-					IsError:   isError,
-					ErrorKind: errorKind,
-				},
-			})
+		for i, tc := range msg.ToolCalls {
+			outcome := outcomes[i]
 
 			// LLM-facing content: when the failure is a transport outage, replace
 			// the raw error text with a directive so the model sees that result
 			// is UNAVAILABLE and must not fabricate around it. The SSE event
-			// above still carries the raw content so the user sees real errors.
-			llmContent := toolContent
-			if errorKind == "transport" {
+			// already carried the raw content so the user sees real errors.
+			llmContent := outcome.content
+			if outcome.errorKind == "transport" {
 				llmContent = fmt.Sprintf("[SYSTEM: MCP transport failure for tool '%s' after retries. Result is UNAVAILABLE — do not fabricate output. Either retry this tool once, or tell the user the data is currently unavailable.]", tc.Function.Name)
 				transportFailedTools[tc.Function.Name] = struct{}{}
 			}
@@ -316,31 +387,17 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 				ToolCallID: tc.ID,
 				Content:    llmContent,
 			})
-
-			if !mcpUnavailableEmitted && len(transportFailedTools) >= 2 {
-				mcpUnavailableEmitted = true
-				a.send(ctx, eventCh, SSEEvent{
-					Type: "mcp_unavailable",
-					Data: MCPUnavailableEvent{
-						Message: "MCP server unreachable — results may be incomplete. Please retry.",
-					},
-				})
-			}
-			if !isError {
-				a.send(ctx, eventCh, SSEEvent{
-					Type: "evidence",
-					Data: EvidenceEvent{
-						ID:       tc.ID,
-						Title:    evidenceTitle(tc.Function.Name),
-						Summary:  summarizeToolEvidence(toolContent),
-						Source:   "mcp",
-						ToolName: tc.Function.Name,
-						Query:    extractEvidenceQuery(tc.Function.Arguments),
-					},
-				})
-			}
 		}
 
+		if !mcpUnavailableEmitted && len(transportFailedTools) >= 2 {
+			mcpUnavailableEmitted = true
+			a.send(ctx, eventCh, SSEEvent{
+				Type: "mcp_unavailable",
+				Data: MCPUnavailableEvent{
+					Message: "MCP server unreachable — results may be incomplete. Please retry.",
+				},
+			})
+		}
 	}
 	a.send(ctx, eventCh, SSEEvent{
 		Type: "error",
@@ -348,13 +405,13 @@ func (a *AgentLoop) Run(ctx context.Context, req LoopRequest, eventCh chan<- SSE
 	})
 }
 
-func (a *AgentLoop) chatCompletionWithFallback(ctx context.Context, llmReq ChatCompletionRequest, req LoopRequest) (*ChatCompletionResponse, string, error) {
+func (a *AgentLoop) chatCompletionWithFallback(ctx context.Context, llmReq ChatCompletionRequest, req LoopRequest, onDelta ContentDeltaFunc) (*ChatCompletionResponse, string, error) {
 	effectiveModel := llmReq.Model
 	if effectiveModel == "" {
 		effectiveModel = "base"
 	}
 
-	resp, err := a.llmClient.ChatCompletion(ctx, llmReq, req.GrafanaURL, req.AuthToken, req.OrgID)
+	resp, err := a.llmClient.ChatCompletionStream(ctx, llmReq, req.GrafanaURL, req.AuthToken, req.OrgID, onDelta)
 	if err == nil {
 		return resp, effectiveModel, nil
 	}
@@ -372,7 +429,7 @@ func (a *AgentLoop) chatCompletionWithFallback(ctx context.Context, llmReq ChatC
 		"messageCount", llmErr.MessageCount,
 		"toolCount", llmErr.ToolCount)
 
-	resp, fallbackErr := a.llmClient.ChatCompletion(ctx, fallbackReq, req.GrafanaURL, req.AuthToken, req.OrgID)
+	resp, fallbackErr := a.llmClient.ChatCompletionStream(ctx, fallbackReq, req.GrafanaURL, req.AuthToken, req.OrgID, onDelta)
 	if fallbackErr != nil {
 		a.logger.Warn("LLM base fallback failed", "error", fallbackErr)
 		return nil, "base", fallbackErr
@@ -382,6 +439,63 @@ func (a *AgentLoop) chatCompletionWithFallback(ctx context.Context, llmReq ChatC
 	return resp, "base", nil
 }
 
+// contentDeltaFlushInterval bounds how often streamed text is forwarded as a
+// content_delta event. Providers send roughly one chunk per token; batching
+// keeps the run event log and SSE traffic proportional to time, not tokens.
+const contentDeltaFlushInterval = 100 * time.Millisecond
+
+// contentDeltaBuffer batches one LLM turn's streamed text into content_delta
+// events. A nil buffer is valid and does nothing, so call sites don't need to
+// check whether streaming is enabled.
+type contentDeltaBuffer struct {
+	emit      func(ContentDeltaEvent)
+	pending   strings.Builder
+	lastFlush time.Time
+	// emitted is set once any text has been sent, so a reset is only sent when
+	// there is something for the client to discard.
+	emitted bool
+}
+
+func (b *contentDeltaBuffer) handler() ContentDeltaFunc {
+	if b == nil {
+		return nil
+	}
+	return b.add
+}
+
+func (b *contentDeltaBuffer) add(d ContentDeltaEvent) {
+	if d.Reset {
+		b.discard()
+		return
+	}
+	b.pending.WriteString(d.Delta)
+	if time.Since(b.lastFlush) >= contentDeltaFlushInterval {
+		b.flush()
+	}
+}
+
+func (b *contentDeltaBuffer) flush() {
+	if b == nil || b.pending.Len() == 0 {
+		return
+	}
+	b.emit(ContentDeltaEvent{Delta: b.pending.String()})
+	b.pending.Reset()
+	b.lastFlush = time.Now()
+	b.emitted = true
+}
+
+// discard drops buffered text and retracts any that was already emitted.
+func (b *contentDeltaBuffer) discard() {
+	if b == nil {
+		return
+	}
+	b.pending.Reset()
+	if b.emitted {
+		b.emit(ContentDeltaEvent{Reset: true})
+		b.emitted = false
+	}
+}
+
 func llmErrorEvent(err error) ErrorEvent {
 	if errors.Is(err, errIncompleteStream) {
 		return ErrorEvent{
@@ -424,8 +538,16 @@ func completionTokenBudget(maxTotalTokens int) int {
 	return budget
 }
 
+func (req LoopRequest) toolPolicy() *rbac.Policy {
+	return &rbac.Policy{Rules: req.ToolPolicies, Servers: req.MCPServers}
+}
+
+func (req LoopRequest) toolSubject() rbac.Subject {
+	return rbac.Subject{Role: req.UserRole, Teams: req.UserTeams, TeamsUnresolved: req.UserTeamsUnresolved, OrgID: req.OrgID}
+}
+
 func (a *AgentLoop) executeTool(ctx context.Context, tc ToolCall, req LoopRequest) (content string, isError bool, errorKind string) {
-	_, span := tracing.DefaultTracer().Start(ctx, "mcp_tool_call",
+	ctx, span := tracing.DefaultTracer().Start(ctx, "mcp_tool_call",
 		trace.WithAttributes(attribute.String("mcp.tool_name", tc.Function.Name)))
 	defer func() {
 		span.SetAttributes(attribute.Bool("mcp.is_error", isError))
@@ -435,12 +557,12 @@ func (a *AgentLoop) executeTool(ctx context.Context, tc ToolCall, req LoopReques
 		span.End()
 	}()
 
-	tool, found := a.mcpProxy.FindToolByName(tc.Function.Name)
+	tool, found := req.ToolCatalog.FindTool(tc.Function.Name)
 	if !found {
 		return fmt.Sprintf("Unknown tool: %s", tc.Function.Name), true, "tool"
 	}
-	if !rbac.CanAccessTool(req.UserRole, tool) {
-		return fmt.Sprintf("Access denied: %s role cannot access tool %s", req.UserRole, tc.Function.Name), true, "tool"
+	if decision := req.toolPolicy().Decide(req.toolSubject(), tool); !decision.Allowed {
+		return fmt.Sprintf("Access denied to tool %s: %s", tc.Function.Name, decision.Reason), true, "tool"
 	}
 	if !mcp.IsToolEnabled(tc.Function.Name, req.MCPServers) {
 		return fmt.Sprintf("Tool %s is disabled in MCP server settings", tc.Function.Name), true, "tool"
@@ -451,12 +573,26 @@ func (a *AgentLoop) executeTool(ctx context.Context, tc ToolCall, req LoopReques
 		return fmt.Sprintf("Invalid tool arguments: %v", err), true, "tool"
 	}
 	mcp.EnsureScopedGraphitiArgs(tool, args, req.OrgID)
+	if err := mcp.ValidateArguments(tool.InputSchema, args); err != nil {
+		return fmt.Sprintf("Invalid arguments for %s: %v. Fix these arguments to match the tool's input schema and call it again.", tc.Function.Name, err), true, "tool"
+	}
 
-	result, err := a.mcpProxy.CallToolWithContext(tc.Function.Name, args, req.OrgID, req.OrgName, req.ScopeOrgID)
+	// Output is validated against the run's pinned definition of the tool.
+	callCtx := mcp.WithCatalogTool(ctx, tool)
+	result, err := a.mcpProxy.CallToolWithContext(callCtx, tc.Function.Name, args, req.OrgID, req.OrgName, req.ScopeOrgID)
 	if err != nil {
 		a.logger.Error("Tool call failed", "tool", tc.Function.Name, "error", err)
+		// The tool's own timeout fired (the run itself is still live), so tell
+		// the model plainly rather than surfacing a raw context error.
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Sprintf("Tool call timed out: %s did not respond within its configured timeout. Try a narrower query or time range.", tc.Function.Name), true, "tool"
+		}
+		var se *mcp.OutputSchemaError
+		if errors.As(err, &se) {
+			return fmt.Sprintf("Tool returned invalid output: %v", err), true, string(mcp.ErrKindSchema)
+		}
 		var te *mcp.TransportError
-		if errors.As(err, &te) {
+		if errors.As(err, &te) || errors.Is(err, mcp.ErrCircuitOpen) {
 			return fmt.Sprintf("Tool call error: %v", err), true, "transport"
 		}
 		return fmt.Sprintf("Tool call error: %v", err), true, "protocol"
@@ -477,8 +613,121 @@ func (a *AgentLoop) executeTool(ctx context.Context, tc ToolCall, req LoopReques
 	return text, false, ""
 }
 
+// toolCallOutcome is the result of one tool call, kept by index so the tool
+// messages sent back to the LLM follow the order of the assistant's calls.
+type toolCallOutcome struct {
+	content   string
+	isError   bool
+	errorKind string
+}
+
+// executeToolCalls runs one assistant turn's tool calls and returns their
+// outcomes in the same order as calls. Calls that don't need approval share a
+// worker pool bounded by MaxParallelToolCalls. A call that does is a barrier:
+// the pool is drained, the gated call runs on its own, and dispatch resumes
+// after it. The user is never asked to approve several tools at once, and a
+// read the model issued after a write still observes that write.
+// Calls skipped because ctx was cancelled have zero outcomes; callers must
+// check ctx before using them.
+func (a *AgentLoop) executeToolCalls(ctx context.Context, eventCh chan<- SSEEvent, calls []ToolCall, req LoopRequest, sampling *runSampling) []toolCallOutcome {
+	outcomes := make([]toolCallOutcome, len(calls))
+
+	limit := req.MaxParallelToolCalls
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+dispatch:
+	for i, tc := range calls {
+		if a.requiresApproval(tc, req) {
+			wg.Wait()
+			if ctx.Err() != nil {
+				break
+			}
+			outcomes[i] = a.runToolCall(ctx, eventCh, tc, req, sampling)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			outcomes[i] = a.runToolCall(ctx, eventCh, tc, req, sampling)
+		})
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// runToolCall executes a single tool call and emits its tool_call_start,
+// tool_call_result and (on success) evidence events. All three are sent from
+// the same goroutine so they stay paired even when calls run concurrently.
+// tool_call_progress events for the call, and approvals for sampling requests
+// and input requests its server makes, fall between start and result.
+func (a *AgentLoop) runToolCall(ctx context.Context, eventCh chan<- SSEEvent, tc ToolCall, req LoopRequest, sampling *runSampling) toolCallOutcome {
+	a.send(ctx, eventCh, SSEEvent{
+		Type: "tool_call_start",
+		Data: ToolCallStartEvent{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		},
+	})
+
+	events := a.newToolCallEvents(ctx, eventCh)
+	callCtx := mcp.WithProgress(events.ctx, newToolProgressReporter(events, tc).report)
+	callCtx = mcp.WithSampler(callCtx, sampling.forCall(events, tc))
+	callCtx = mcp.WithElicitor(callCtx, newToolElicitor(events, tc, req))
+	toolContent, isError, errorKind := a.executeToolWithApproval(callCtx, eventCh, tc, req)
+	events.stop()
+
+	a.send(ctx, eventCh, SSEEvent{
+		Type: "tool_call_result",
+		Data: ToolCallResultEvent{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Content:   toolContent,
+			IsError:   isError,
+			ErrorKind: errorKind,
+		},
+	})
+
+	if !isError {
+		a.send(ctx, eventCh, SSEEvent{
+			Type: "evidence",
+			Data: EvidenceEvent{
+				ID:       tc.ID,
+				Title:    evidenceTitle(tc.Function.Name),
+				Summary:  summarizeToolEvidence(toolContent),
+				Source:   "mcp",
+				ToolName: tc.Function.Name,
+				Query:    extractEvidenceQuery(tc.Function.Arguments),
+			},
+		})
+	}
+
+	return toolCallOutcome{content: toolContent, isError: isError, errorKind: errorKind}
+}
+
+// requiresApproval reports whether executeToolWithApproval may block on a
+// human decision for this call. Unknown tools never do; they fail fast.
+func (a *AgentLoop) requiresApproval(tc ToolCall, req LoopRequest) bool {
+	if !approvalPolicyEnabled(req.ApprovalPolicy) {
+		return false
+	}
+	tool, found := req.ToolCatalog.FindTool(tc.Function.Name)
+	if !found {
+		return false
+	}
+	return mcp.ClassifyToolRisk(tool, req.MCPServers).RequiresApproval
+}
+
 func (a *AgentLoop) executeToolWithApproval(ctx context.Context, eventCh chan<- SSEEvent, tc ToolCall, req LoopRequest) (content string, isError bool, errorKind string) {
-	tool, found := a.mcpProxy.FindToolByName(tc.Function.Name)
+	tool, found := req.ToolCatalog.FindTool(tc.Function.Name)
 	if !found {
 		return a.executeTool(ctx, tc, req)
 	}
@@ -489,18 +738,32 @@ func (a *AgentLoop) executeToolWithApproval(ctx context.Context, eventCh chan<-
 	}
 
 	approval := ApprovalRequestEvent{
-		ApprovalID: tc.ID,
-		ToolCallID: tc.ID,
-		ToolName:   tc.Function.Name,
-		Risk:       riskLabel(risk),
-		Reason:     risk.Reason,
-		Arguments:  tc.Function.Arguments,
+		ApprovalID:  tc.ID,
+		ToolCallID:  tc.ID,
+		ToolName:    tc.Function.Name,
+		Risk:        riskLabel(risk),
+		Reason:      risk.Reason,
+		Arguments:   tc.Function.Arguments,
+		ServerID:    tool.ServerID,
+		Annotations: tool.Annotations,
 	}
 
+	emit := func(event SSEEvent) { a.send(ctx, eventCh, event) }
+	if approved, refusal, kind := a.awaitApproval(ctx, emit, approval, "Tool "+tc.Function.Name, req); !approved {
+		return refusal, true, kind
+	}
+	return a.executeTool(ctx, tc, req)
+}
+
+// awaitApproval settles an approval request: by a grant saved for the
+// session, or by asking the user and waiting for the decision. When the
+// request isn't approved, refusal tells the model why in terms of subject
+// ("Tool x"), and is empty if the run was cancelled while waiting.
+func (a *AgentLoop) awaitApproval(ctx context.Context, emit func(SSEEvent), approval ApprovalRequestEvent, subject string, req LoopRequest) (approved bool, refusal string, errorKind string) {
 	if req.CheckApprovalGrant != nil {
 		granted, err := req.CheckApprovalGrant(ctx, approval)
 		if err != nil {
-			a.logger.Warn("Failed to check saved approval grant", "error", err, "tool", tc.Function.Name)
+			a.logger.Warn("Failed to check saved approval grant", "error", err, "tool", approval.ToolName)
 		} else if granted {
 			resolved := ApprovalResolvedEvent{
 				ApprovalID: approval.ApprovalID,
@@ -508,46 +771,56 @@ func (a *AgentLoop) executeToolWithApproval(ctx context.Context, eventCh chan<-
 				Comment:    "approved by saved tool grant",
 				ResolvedAt: time.Now().UTC().Format(time.RFC3339),
 			}
-			a.send(ctx, eventCh, SSEEvent{Type: "approval_request", Data: approval})
-			a.send(ctx, eventCh, SSEEvent{Type: "approval_resolved", Data: resolved})
-			return a.executeTool(ctx, tc, req)
+			approvalDecisions.WithLabelValues("granted", approval.Risk).Inc()
+			emit(SSEEvent{Type: "approval_request", Data: approval})
+			emit(SSEEvent{Type: "approval_resolved", Data: resolved})
+			return true, "", ""
 		}
 	}
 
 	if req.RegisterApproval == nil {
-		return fmt.Sprintf("Tool %s requires approval before execution: %s", tc.Function.Name, risk.Reason), true, "approval_required"
+		return false, fmt.Sprintf("%s requires approval before execution: %s", subject, approval.Reason), "approval_required"
 	}
 
 	waitApproval, err := req.RegisterApproval(ctx, approval)
 	if err != nil {
-		return fmt.Sprintf("Failed to prepare approval for tool %s: %v", tc.Function.Name, err), true, "approval_required"
+		return false, fmt.Sprintf("%s approval could not be requested: %v", subject, err), "approval_required"
 	}
 
-	a.send(ctx, eventCh, SSEEvent{
+	emit(SSEEvent{
 		Type: "approval_request",
 		Data: approval,
 	})
 
+	waitStart := time.Now()
 	resolved, err := waitApproval(ctx)
+	decision := approvalDecisionLabel(resolved.Decision)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		decision = "canceled"
+	case err != nil:
+		decision = "error"
+	}
+	approvalWait.WithLabelValues(decision).Observe(time.Since(waitStart).Seconds())
+	approvalDecisions.WithLabelValues(decision, approval.Risk).Inc()
 	if err != nil {
 		if ctx.Err() != nil {
-			return "", true, "approval_required"
+			return false, "", "approval_required"
 		}
-		return fmt.Sprintf("Tool %s approval failed: %v", tc.Function.Name, err), true, "approval_required"
+		return false, fmt.Sprintf("%s approval failed: %v", subject, err), "approval_required"
 	}
 	if resolved.ResolvedAt == "" {
 		resolved.ResolvedAt = time.Now().UTC().Format(time.RFC3339)
 	}
-	a.send(ctx, eventCh, SSEEvent{
+	emit(SSEEvent{
 		Type: "approval_resolved",
 		Data: resolved,
 	})
 
 	if resolved.Decision != "approved" {
-		return fmt.Sprintf("Tool %s was not approved by the user.", tc.Function.Name), true, "approval_denied"
+		return false, fmt.Sprintf("%s was not approved by the user.", subject), "approval_denied"
 	}
-
-	return a.executeTool(ctx, tc, req)
+	return true, "", ""
 }
 
 func approvalPolicyEnabled(policy string) bool {
@@ -594,19 +867,34 @@ func toolCallHasValidArgs(tc ToolCall) bool {
 	return args == "" || json.Valid([]byte(args))
 }
 
+// extractText returns the text the LLM sees for a tool result. Structured
+// content is rendered compactly, and text blocks that merely repeat it as
+// serialized JSON, as the MCP spec suggests servers do, are dropped.
 func extractText(result *mcp.CallToolResult) string {
+	structured := result.StructuredContent != nil
+	var normalized interface{}
+	if structured {
+		normalized = normalizeStructured(result.StructuredContent)
+	}
 	var out string
 	for _, block := range result.Content {
-		if block.Type == "text" {
+		if block.Type != "text" {
+			continue
+		}
+		if structured && isStructuredDuplicate(block.Text, normalized) {
+			continue
+		}
+		if out != "" {
+			out += "\n"
+		}
+		out += block.Text
+	}
+	if structured {
+		if rendered := renderStructuredContent(result.StructuredContent); rendered != "" {
 			if out != "" {
 				out += "\n"
 			}
-			out += block.Text
-		}
-	}
-	if out == "" && result.StructuredContent != nil {
-		if data, err := json.Marshal(result.StructuredContent); err == nil {
-			out = string(data)
+			out += rendered
 		}
 	}
 	return out
@@ -11,10 +11,12 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 
 	"consensys-asko11y-app/pkg/mcp"
+	"consensys-asko11y-app/pkg/rbac"
 )
 
 // respondAsStream writes a ChatCompletionResponse as an OpenAI-compatible SSE stream.
@@ -157,6 +159,66 @@ func TestAgentLoop_SimpleTextResponse(t *testing.T) {
 	}
 }
 
+func TestAgentLoop_UsesPinnedToolCatalog(t *testing.T) {
+	receivedTools := make(chan []string, 1)
+	receivedSystem := make(chan string, 1)
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		var names []string
+		for _, tool := range req.Tools {
+			names = append(names, tool.Function.Name)
+		}
+		receivedTools <- names
+		receivedSystem <- req.Messages[0].Content
+		respondAsStream(w, ChatCompletionResponse{
+			ID: "1",
+			Choices: []Choice{{
+				Message:      Message{Role: "assistant", Content: "ok"},
+				FinishReason: "stop",
+			}},
+		})
+	}))
+	defer llmServer.Close()
+
+	llmClient := NewLLMClient(log.DefaultLogger, &http.Client{Timeout: llmTimeout})
+	mcpProxy := mcp.NewProxy(context.Background(), log.DefaultLogger)
+	loop := NewAgentLoop(llmClient, mcpProxy, log.DefaultLogger)
+
+	eventCh := make(chan SSEEvent, 32)
+	req := LoopRequest{
+		Messages:     []Message{{Role: "user", Content: "hello"}},
+		SystemPrompt: "sys",
+		GrafanaURL:   llmServer.URL,
+		AuthToken:    "test-token",
+		UserRole:     "Admin",
+		OrgID:        "1",
+		ToolCatalog: &mcp.ToolCatalog{
+			Version:     "v1",
+			Tools:       []mcp.Tool{{Name: "svc_query", Description: "Run a query"}},
+			Unavailable: []string{"Loki"},
+		},
+	}
+
+	go loop.Run(context.Background(), req, eventCh)
+	events := collectEvents(eventCh)
+
+	if got := <-receivedTools; len(got) != 1 || got[0] != "svc_query" {
+		t.Fatalf("expected pinned tools to be sent, got %v", got)
+	}
+	if system := <-receivedSystem; !strings.HasPrefix(system, "sys") || !strings.Contains(system, "Unavailable Data Sources") || !strings.Contains(system, "Loki") {
+		t.Fatalf("expected unavailable servers note in system prompt, got %q", system)
+	}
+	if len(events) == 0 || events[0].Type != "tool_catalog" {
+		t.Fatalf("expected tool_catalog as first event, got %+v", events)
+	}
+	if data := events[0].Data.(ToolCatalogEvent); data.Version != "v1" || data.ToolCount != 1 {
+		t.Fatalf("unexpected tool catalog event: %+v", data)
+	}
+}
+
 func TestAgentLoop_ForwardsRequestedModel(t *testing.T) {
 	receivedModel := make(chan string, 1)
 	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -434,6 +496,100 @@ func TestAgentLoop_ContentWithToolCalls_DropsContent(t *testing.T) {
 	}
 }
 
+func TestAgentLoop_StreamContentDeltas(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, []ChatCompletionResponse{
+		{
+			ID: "tools",
+			Choices: []Choice{{
+				Message: Message{
+					Role:    "assistant",
+					Content: "Let me check.",
+					ToolCalls: []ToolCall{
+						{ID: "tc_1", Type: "function", Function: FunctionCall{Name: "unknown_tool", Arguments: `{}`}},
+					},
+				},
+				FinishReason: "tool_calls",
+			}},
+		},
+		{
+			ID: "final",
+			Choices: []Choice{{
+				Message:      Message{Role: "assistant", Content: "All good."},
+				FinishReason: "stop",
+			}},
+		},
+	})
+	defer cleanup()
+
+	eventCh := make(chan SSEEvent, 32)
+	req := LoopRequest{
+		Messages:            []Message{{Role: "user", Content: "hi"}},
+		SystemPrompt:        "sys",
+		GrafanaURL:          serverURL,
+		AuthToken:           "test-token",
+		UserRole:            "Admin",
+		OrgID:               "1",
+		StreamContentDeltas: true,
+	}
+
+	go loop.Run(context.Background(), req, eventCh)
+	events := collectEvents(eventCh)
+
+	var types []string
+	for _, e := range events {
+		if e.Type == "content_delta" {
+			d := e.Data.(ContentDeltaEvent)
+			types = append(types, fmt.Sprintf("content_delta(%q,%v)", d.Delta, d.Reset))
+			continue
+		}
+		types = append(types, e.Type)
+	}
+	// Text streamed alongside tool calls is retracted before the tools run; the
+	// final answer streams ahead of the assembled content event.
+	want := []string{
+		`content_delta("Let me check.",false)`,
+		`content_delta("",true)`,
+		"tool_call_start",
+		"tool_call_result",
+		`content_delta("All good.",false)`,
+		"final_report",
+		"content",
+		"done",
+	}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("events = %v\nwant     %v", types, want)
+	}
+}
+
+func TestAgentLoop_NoContentDeltasUnlessRequested(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, []ChatCompletionResponse{
+		{
+			ID: "final",
+			Choices: []Choice{{
+				Message:      Message{Role: "assistant", Content: "All good."},
+				FinishReason: "stop",
+			}},
+		},
+	})
+	defer cleanup()
+
+	eventCh := make(chan SSEEvent, 32)
+	go loop.Run(context.Background(), LoopRequest{
+		Messages:     []Message{{Role: "user", Content: "hi"}},
+		SystemPrompt: "sys",
+		GrafanaURL:   serverURL,
+		AuthToken:    "test-token",
+		UserRole:     "Admin",
+		OrgID:        "1",
+	}, eventCh)
+
+	for _, e := range collectEvents(eventCh) {
+		if e.Type == "content_delta" {
+			t.Fatalf("unexpected content_delta without StreamContentDeltas: %+v", e)
+		}
+	}
+}
+
 func TestCompletionTokenBudget(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -591,6 +747,73 @@ func TestAgentLoop_NearLimitWarningInjected(t *testing.T) {
 	}
 }
 
+func TestAgentLoop_TokenBudgetExhaustedStopsRun(t *testing.T) {
+	// The first response charges 120 tokens against a 100 token budget, so the
+	// check before the second LLM call ends the run.
+	toolCallResp := ChatCompletionResponse{
+		ID: "budget",
+		Choices: []Choice{{
+			Message: Message{
+				Role: "assistant",
+				ToolCalls: []ToolCall{{
+					ID: "tc", Type: "function",
+					Function: FunctionCall{Name: "some_tool", Arguments: "{}"},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: &Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}
+
+	var llmCalls atomic.Int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		llmCalls.Add(1)
+		respondAsStream(w, toolCallResp)
+	}))
+	defer llmServer.Close()
+
+	llmClient := NewLLMClient(log.DefaultLogger, &http.Client{Timeout: llmTimeout})
+	mcpProxy := mcp.NewProxy(context.Background(), log.DefaultLogger)
+	loop := NewAgentLoop(llmClient, mcpProxy, log.DefaultLogger)
+
+	var used atomic.Int64
+	eventCh := make(chan SSEEvent, 64)
+	req := LoopRequest{
+		Messages:      []Message{{Role: "user", Content: "spend tokens"}},
+		SystemPrompt:  "sys",
+		MaxIterations: 5,
+		GrafanaURL:    llmServer.URL,
+		AuthToken:     "t",
+		UserRole:      "Admin",
+		OrgID:         "1",
+		RecordTokenUsage: func(_ context.Context, usage ModelUsage) {
+			used.Add(int64(usage.TotalTokens))
+		},
+		CheckTokenBudget: func(context.Context) error {
+			if used.Load() >= 100 {
+				return fmt.Errorf("%w: user daily budget of 100 tokens used up", ErrTokenBudgetExhausted)
+			}
+			return nil
+		},
+	}
+	go loop.Run(context.Background(), req, eventCh)
+	events := collectEvents(eventCh)
+
+	if got := llmCalls.Load(); got != 1 {
+		t.Fatalf("LLM calls = %d, want 1", got)
+	}
+	if got := used.Load(); got != 120 {
+		t.Fatalf("recorded tokens = %d, want 120", got)
+	}
+	last := events[len(events)-1]
+	if last.Type != "error" {
+		t.Fatalf("last event = %q, want error", last.Type)
+	}
+	if code := last.Data.(ErrorEvent).Code; code != "token_budget_exhausted" {
+		t.Fatalf("error code = %q, want token_budget_exhausted", code)
+	}
+}
+
 func TestAgentLoop_TruncatedToolCall_CorrectiveRetryRecovers(t *testing.T) {
 	// A tool call cut off mid-arguments (finish_reason=length) must not be
 	// persisted. The loop drops it, injects a corrective nudge, and the model's
@@ -998,7 +1221,7 @@ func TestAgentLoop_AccumulatesUsageAndToolCallsIntoDoneEvent(t *testing.T) {
 				},
 				FinishReason: "tool_calls",
 			}},
-			Usage: &Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+			Usage: &Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, CacheReadInputTokens: 40},
 		},
 		{
 			ID: "2",
@@ -1006,7 +1229,12 @@ func TestAgentLoop_AccumulatesUsageAndToolCallsIntoDoneEvent(t *testing.T) {
 				Message:      Message{Role: "assistant", Content: "Based on the error..."},
 				FinishReason: "stop",
 			}},
-			Usage: &Usage{PromptTokens: 150, CompletionTokens: 30, TotalTokens: 180},
+			Usage: &Usage{
+				PromptTokens:        150,
+				CompletionTokens:    30,
+				TotalTokens:         180,
+				PromptTokensDetails: &PromptTokensDetails{CachedTokens: 80},
+			},
 		},
 	})
 	defer cleanup()
@@ -1035,6 +1263,9 @@ func TestAgentLoop_AccumulatesUsageAndToolCallsIntoDoneEvent(t *testing.T) {
 	if done.PromptTokens != 250 || done.CompletionTokens != 50 || done.TotalTokens != 300 {
 		t.Fatalf("unexpected token totals: %+v", done)
 	}
+	if done.CachedPromptTokens != 120 {
+		t.Fatalf("expected CachedPromptTokens 120, got %d", done.CachedPromptTokens)
+	}
 	if done.ToolCallCount != 1 {
 		t.Fatalf("expected ToolCallCount 1, got %d", done.ToolCallCount)
 	}
@@ -1045,7 +1276,7 @@ func TestAgentLoop_AccumulatesUsageAndToolCallsIntoDoneEvent(t *testing.T) {
 	if !ok {
 		t.Fatalf("expected UsageByModel base entry, got %+v", done.UsageByModel)
 	}
-	if baseUsage.PromptTokens != 250 || baseUsage.CompletionTokens != 50 || baseUsage.TotalTokens != 300 {
+	if baseUsage.PromptTokens != 250 || baseUsage.CompletionTokens != 50 || baseUsage.TotalTokens != 300 || baseUsage.CachedPromptTokens != 120 {
 		t.Fatalf("unexpected base usage: %+v", baseUsage)
 	}
 }
@@ -1157,3 +1388,348 @@ func TestAgentLoop_ContextCancellation(t *testing.T) {
 		}
 	}
 }
+
+// setupSlowToolServer serves an OpenAPI spec with GET (read-only) tools
+// read_a/read_b and POST (write) tools write_a/write_b. Every call sleeps for
+// delay (read_a sleeps twice as long, so it finishes last) and the returned
+// counter records the highest number of calls observed in flight at once.
+func setupSlowToolServer(t *testing.T, delay time.Duration) (string, *atomic.Int32) {
+	t.Helper()
+
+	var inflight, maxInflight atomic.Int32
+	spec := map[string]interface{}{
+		"openapi": "3.0.0",
+		"paths": map[string]interface{}{
+			"/read_a":  map[string]interface{}{"get": map[string]interface{}{"operationId": "read_a"}},
+			"/read_b":  map[string]interface{}{"get": map[string]interface{}{"operationId": "read_b"}},
+			"/write_a": map[string]interface{}{"post": map[string]interface{}{"operationId": "write_a"}},
+			"/write_b": map[string]interface{}{"post": map[string]interface{}{"operationId": "write_b"}},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.json" {
+			json.NewEncoder(w).Encode(spec) //nolint:errcheck
+			return
+		}
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			prev := maxInflight.Load()
+			if n <= prev || maxInflight.CompareAndSwap(prev, n) {
+				break
+			}
+		}
+		wait := delay
+		if r.URL.Path == "/read_a" {
+			wait *= 2
+		}
+		time.Sleep(wait)
+		w.Write([]byte("result from " + strings.TrimPrefix(r.URL.Path, "/"))) //nolint:errcheck
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &maxInflight
+}
+
+// runToolCallTurn drives one assistant turn issuing calls, then a final text
+// answer, and returns the emitted events plus the body of the second LLM
+// request (which carries the tool messages).
+func runToolCallTurn(t *testing.T, toolURL string, calls []ToolCall, req LoopRequest) ([]SSEEvent, string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies []string
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		idx := len(bodies) - 1
+		mu.Unlock()
+
+		if idx == 0 {
+			respondAsStream(w, ChatCompletionResponse{
+				ID: "tools",
+				Choices: []Choice{{
+					Message:      Message{Role: "assistant", ToolCalls: calls},
+					FinishReason: "tool_calls",
+				}},
+			})
+			return
+		}
+		respondAsStream(w, ChatCompletionResponse{
+			ID: "final",
+			Choices: []Choice{{
+				Message:      Message{Role: "assistant", Content: "done"},
+				FinishReason: "stop",
+			}},
+		})
+	}))
+	defer llmServer.Close()
+
+	llmClient := NewLLMClient(log.DefaultLogger, &http.Client{Timeout: llmTimeout})
+	mcpProxy := mcp.NewProxy(context.Background(), log.DefaultLogger)
+	if err := mcpProxy.EnsureServer(mcp.ServerConfig{ID: "srv", URL: toolURL, Type: "openapi", Enabled: true}); err != nil {
+		t.Fatalf("EnsureServer: %v", err)
+	}
+	// Pinned catalogs are listed before the run starts.
+	if _, err := mcpProxy.ListTools(); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	loop := NewAgentLoop(llmClient, mcpProxy, log.DefaultLogger)
+
+	req.Messages = []Message{{Role: "user", Content: "investigate"}}
+	req.SystemPrompt = "sys"
+	req.GrafanaURL = llmServer.URL
+	req.AuthToken = "test-token"
+	req.UserRole = "Admin"
+	req.OrgID = "1"
+
+	eventCh := make(chan SSEEvent, 64)
+	go loop.Run(context.Background(), req, eventCh)
+	events := collectEvents(eventCh)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 LLM requests, got %d", len(bodies))
+	}
+	return events, bodies[1]
+}
+
+func toolCall(id, name string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: FunctionCall{Name: name, Arguments: `{}`}}
+}
+
+func TestAgentLoop_ParallelToolCalls_RunConcurrentlyAndKeepOrder(t *testing.T) {
+	toolURL, maxInflight := setupSlowToolServer(t, 100*time.Millisecond)
+
+	events, secondRequest := runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_a", "srv_read_a"),
+		toolCall("tc_b", "srv_read_b"),
+	}, LoopRequest{MaxParallelToolCalls: 4})
+
+	if got := maxInflight.Load(); got != 2 {
+		t.Errorf("expected both tool calls in flight at once, max in flight was %d", got)
+	}
+
+	// read_b finishes first, but tool messages must follow the call order.
+	var sent ChatCompletionRequest
+	if err := json.Unmarshal([]byte(secondRequest), &sent); err != nil {
+		t.Fatalf("decode second LLM request: %v", err)
+	}
+	var toolMsgIDs []string
+	for _, m := range sent.Messages {
+		if m.Role == "tool" {
+			toolMsgIDs = append(toolMsgIDs, m.ToolCallID)
+			if !strings.Contains(m.Content, strings.TrimPrefix(m.ToolCallID, "tc_")) {
+				t.Errorf("tool message %s carries the wrong result: %q", m.ToolCallID, m.Content)
+			}
+		}
+	}
+	if fmt.Sprint(toolMsgIDs) != "[tc_a tc_b]" {
+		t.Errorf("expected tool messages in call order [tc_a tc_b], got %v", toolMsgIDs)
+	}
+
+	// Each call's start precedes its result, which precedes its evidence.
+	seen := map[string][]string{}
+	for _, e := range events {
+		switch d := e.Data.(type) {
+		case ToolCallStartEvent:
+			seen[d.ID] = append(seen[d.ID], e.Type)
+		case ToolCallResultEvent:
+			if d.IsError {
+				t.Errorf("tool %s failed: %s", d.ID, d.Content)
+			}
+			seen[d.ID] = append(seen[d.ID], e.Type)
+		case EvidenceEvent:
+			seen[d.ID] = append(seen[d.ID], e.Type)
+		}
+	}
+	for _, id := range []string{"tc_a", "tc_b"} {
+		if got := fmt.Sprint(seen[id]); got != "[tool_call_start tool_call_result evidence]" {
+			t.Errorf("events for %s out of order: %s", id, got)
+		}
+	}
+}
+
+func TestAgentLoop_ParallelToolCalls_DefaultsToSerial(t *testing.T) {
+	toolURL, maxInflight := setupSlowToolServer(t, 20*time.Millisecond)
+
+	runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_a", "srv_read_a"),
+		toolCall("tc_b", "srv_read_b"),
+	}, LoopRequest{})
+
+	if got := maxInflight.Load(); got != 1 {
+		t.Errorf("expected serial execution without MaxParallelToolCalls, max in flight was %d", got)
+	}
+}
+
+func TestAgentLoop_ParallelToolCalls_SerializesApprovalGatedCalls(t *testing.T) {
+	toolURL, maxInflight := setupSlowToolServer(t, 50*time.Millisecond)
+
+	var approvals []string
+	var approvalsMu sync.Mutex
+	events, _ := runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_w1", "srv_write_a"),
+		toolCall("tc_w2", "srv_write_b"),
+	}, LoopRequest{
+		MaxParallelToolCalls: 4,
+		ApprovalPolicy:       "approval-gated-writes",
+		RegisterApproval: func(ctx context.Context, req ApprovalRequestEvent) (ApprovalWaitFunc, error) {
+			approvalsMu.Lock()
+			approvals = append(approvals, req.ToolCallID)
+			approvalsMu.Unlock()
+			return func(ctx context.Context) (ApprovalResolvedEvent, error) {
+				return ApprovalResolvedEvent{ApprovalID: req.ApprovalID, Decision: "approved"}, nil
+			}, nil
+		},
+	})
+
+	if got := maxInflight.Load(); got != 1 {
+		t.Errorf("expected approval-gated calls to run one at a time, max in flight was %d", got)
+	}
+	if fmt.Sprint(approvals) != "[tc_w1 tc_w2]" {
+		t.Errorf("expected approvals requested in call order, got %v", approvals)
+	}
+	for _, e := range events {
+		if d, ok := e.Data.(ToolCallResultEvent); ok && d.IsError {
+			t.Errorf("tool %s failed: %s", d.ID, d.Content)
+		}
+	}
+}
+
+func TestAgentLoop_ToolPolicyDeniesCalls(t *testing.T) {
+	toolURL, _ := setupSlowToolServer(t, 0)
+
+	events, _ := runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_a", "srv_read_a"),
+		toolCall("tc_b", "srv_read_b"),
+	}, LoopRequest{ToolPolicies: []rbac.Rule{{Effect: rbac.EffectDeny, Description: "not b", Tools: []string{"srv_read_b"}}}})
+
+	results := map[string]ToolCallResultEvent{}
+	for _, e := range events {
+		if d, ok := e.Data.(ToolCallResultEvent); ok {
+			results[d.ID] = d
+		}
+	}
+	if results["tc_a"].IsError {
+		t.Errorf("srv_read_a failed: %s", results["tc_a"].Content)
+	}
+	if denied := results["tc_b"]; !denied.IsError || !strings.Contains(denied.Content, "Denied by policy rule 0: not b") {
+		t.Errorf("expected srv_read_b to be denied by the policy, got %+v", denied)
+	}
+}
+
+func TestAgentLoop_CircuitOpenCallsAreTransportFailures(t *testing.T) {
+	spec := map[string]interface{}{
+		"openapi": "3.0.0",
+		"paths": map[string]interface{}{
+			"/read_a": map[string]interface{}{"get": map[string]interface{}{"operationId": "read_a"}},
+			"/read_b": map[string]interface{}{"get": map[string]interface{}{"operationId": "read_b"}},
+		},
+	}
+	// The server lists its tools, then drops every call's connection, so
+	// the third failure opens its breaker.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.json" {
+			json.NewEncoder(w).Encode(spec) //nolint:errcheck
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	events, body := runToolCallTurn(t, server.URL, []ToolCall{
+		toolCall("tc_1", "srv_read_a"),
+		toolCall("tc_2", "srv_read_a"),
+		toolCall("tc_3", "srv_read_a"),
+		toolCall("tc_4", "srv_read_a"),
+		toolCall("tc_5", "srv_read_b"),
+	}, LoopRequest{})
+
+	var unavailable bool
+	results := map[string]ToolCallResultEvent{}
+	for _, e := range events {
+		switch d := e.Data.(type) {
+		case ToolCallResultEvent:
+			results[d.ID] = d
+		case MCPUnavailableEvent:
+			unavailable = true
+		}
+	}
+	for _, id := range []string{"tc_4", "tc_5"} {
+		if got := results[id]; !strings.Contains(got.Content, "circuit breaker open") || got.ErrorKind != "transport" {
+			t.Errorf("%s: expected a circuit-open transport failure, got %+v", id, got)
+		}
+	}
+	if !unavailable {
+		t.Error("expected circuit-open calls to count toward mcp_unavailable")
+	}
+	if !strings.Contains(body, "MCP transport failure for tool 'srv_read_b'") {
+		t.Error("expected the transport failure guidance in the tool message")
+	}
+}
+
+func TestAgentLoop_ResolvesToolCallsInPinnedCatalog(t *testing.T) {
+	toolURL, _ := setupSlowToolServer(t, 0)
+
+	// srv_read_b is live on the server but was not in the catalog the run
+	// was pinned to.
+	events, _ := runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_a", "srv_read_a"),
+		toolCall("tc_b", "srv_read_b"),
+	}, LoopRequest{ToolCatalog: &mcp.ToolCatalog{
+		Version: "v1",
+		Tools:   []mcp.Tool{{Name: "srv_read_a", ServerID: "srv", InputSchema: map[string]interface{}{"type": "object"}}},
+	}})
+
+	results := map[string]ToolCallResultEvent{}
+	for _, e := range events {
+		if d, ok := e.Data.(ToolCallResultEvent); ok {
+			results[d.ID] = d
+		}
+	}
+	if results["tc_a"].IsError {
+		t.Errorf("srv_read_a failed: %s", results["tc_a"].Content)
+	}
+	if unknown := results["tc_b"]; !unknown.IsError || !strings.Contains(unknown.Content, "Unknown tool") {
+		t.Errorf("expected srv_read_b to be unknown to the pinned catalog, got %+v", unknown)
+	}
+}
+
+func TestAgentLoop_ParallelToolCalls_GatedCallsKeepCallOrder(t *testing.T) {
+	toolURL, _ := setupSlowToolServer(t, 20*time.Millisecond)
+
+	events, _ := runToolCallTurn(t, toolURL, []ToolCall{
+		toolCall("tc_a", "srv_read_a"),
+		toolCall("tc_w", "srv_write_a"),
+		toolCall("tc_b", "srv_read_b"),
+	}, LoopRequest{
+		MaxParallelToolCalls: 4,
+		ApprovalPolicy:       "approval-gated-writes",
+		RegisterApproval: func(ctx context.Context, req ApprovalRequestEvent) (ApprovalWaitFunc, error) {
+			return func(ctx context.Context) (ApprovalResolvedEvent, error) {
+				return ApprovalResolvedEvent{ApprovalID: req.ApprovalID, Decision: "approved"}, nil
+			}, nil
+		},
+	})
+
+	// The write must not start before the earlier read finished, and the later
+	// read must not start before the write finished.
+	var order []string
+	for _, e := range events {
+		switch d := e.Data.(type) {
+		case ToolCallStartEvent:
+			order = append(order, "start:"+d.ID)
+		case ToolCallResultEvent:
+			order = append(order, "result:"+d.ID)
+		}
+	}
+	want := "[start:tc_a result:tc_a start:tc_w result:tc_w start:tc_b result:tc_b]"
+	if got := fmt.Sprint(order); got != want {
+		t.Errorf("expected tool calls to run in call order across the gated call\n got: %s\nwant: %s", got, want)
+	}
+}
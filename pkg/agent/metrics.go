@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// llmRequestDuration times whole ChatCompletionStream calls, retries
+	// included, by model and final outcome.
+	llmRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "asko11y_llm_request_duration_seconds",
+			Help:    "Latency of LLM chat completion calls, including retries, by model and outcome.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		},
+		[]string{"model", "status"},
+	)
+
+	// llmRetries counts LLM attempts that were retried, by model and reason.
+	llmRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_llm_retries_total",
+			Help: "Number of retried LLM attempts by model and reason (network, http, stream).",
+		},
+		[]string{"model", "reason"},
+	)
+
+	// contextTruncations counts TrimMessagesToTokenLimit calls that had to
+	// shrink the conversation, by the stage that finally made it fit.
+	contextTruncations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_agent_context_truncations_total",
+			Help: "Number of times the agent context was trimmed to fit the token budget, by stage.",
+		},
+		[]string{"stage"},
+	)
+
+	// approvalWait times how long gated tool calls waited for a decision.
+	approvalWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "asko11y_agent_approval_wait_seconds",
+			Help:    "Time gated tool calls waited for an approval decision, by decision (approved, rejected, canceled, error).",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+		},
+		[]string{"decision"},
+	)
+
+	// approvalDecisions counts approval outcomes by decision and tool risk.
+	approvalDecisions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_agent_approval_decisions_total",
+			Help: "Number of approval decisions for gated tool calls, by decision (approved, rejected, granted, canceled, error) and risk.",
+		},
+		[]string{"decision", "risk"},
+	)
+
+	// inputRequests counts MCP elicitation requests forwarded to users, by
+	// outcome.
+	inputRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_agent_input_requests_total",
+			Help: "Number of MCP server input requests by outcome (accept, decline, cancel, canceled, error, unattended).",
+		},
+		[]string{"outcome"},
+	)
+
+	// samplingTokens counts LLM tokens spent on MCP servers' sampling
+	// requests, by model.
+	samplingTokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_agent_sampling_tokens_total",
+			Help: "LLM tokens spent serving MCP sampling requests, by model.",
+		},
+		[]string{"model"},
+	)
+)
+
+// modelLabel bounds the model label to the aliases the plugin sends.
+func modelLabel(model string) string {
+	switch model {
+	case "":
+		return "default"
+	case "base", "large":
+		return model
+	default:
+		return "other"
+	}
+}
+
+// llmStatusLabel classifies a ChatCompletionStream outcome.
+func llmStatusLabel(ctx context.Context, err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	var httpErr *LLMHTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode >= 500 {
+			return "http_5xx"
+		}
+		return "http_4xx"
+	}
+	if errors.Is(err, errIncompleteStream) {
+		return "stream_error"
+	}
+	return "error"
+}
+
+// approvalDecisionLabel bounds resolved decisions to a fixed set.
+func approvalDecisionLabel(decision string) string {
+	switch decision {
+	case "approved", "rejected":
+		return decision
+	default:
+		return "other"
+	}
+}
+
+// inputActionLabel bounds input request answers to the protocol's actions.
+func inputActionLabel(action string) string {
+	switch action {
+	case "accept", "decline", "cancel":
+		return action
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"consensys-asko11y-app/pkg/mcp"
+)
+
+const (
+	// toolProgressInterval is the minimum gap between progress events for
+	// one tool call. Updates arriving sooner are dropped so chatty servers
+	// can't crowd the run event log.
+	toolProgressInterval = 500 * time.Millisecond
+
+	// maxToolLogEvents caps the server log messages forwarded per tool call.
+	maxToolLogEvents = 20
+)
+
+// toolCallEvents sends the events a tool call's server causes while the call
+// runs: progress, input requests and sampling approvals. Its context ends and
+// its sends stop when the call completes, so none is sent after the call's
+// tool_call_result, nor after Run closes the event channel.
+type toolCallEvents struct {
+	a       *AgentLoop
+	ctx     context.Context
+	cancel  context.CancelFunc
+	eventCh chan<- SSEEvent
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+func (a *AgentLoop) newToolCallEvents(ctx context.Context, eventCh chan<- SSEEvent) *toolCallEvents {
+	ctx, cancel := context.WithCancel(ctx)
+	return &toolCallEvents{a: a, ctx: ctx, cancel: cancel, eventCh: eventCh}
+}
+
+// send emits event unless the call has completed.
+func (e *toolCallEvents) send(event SSEEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+	e.a.send(e.ctx, e.eventCh, event)
+}
+
+// stop ends the call's context, which unblocks a send in progress, and waits
+// for it, so no event is sent once stop returns.
+func (e *toolCallEvents) stop() {
+	e.cancel()
+	e.mu.Lock()
+	e.stopped = true
+	e.mu.Unlock()
+}
+
+// toolProgressReporter turns a tool call's MCP progress and log
+// notifications into tool_call_progress events.
+type toolProgressReporter struct {
+	events *toolCallEvents
+	tc     ToolCall
+
+	mu           sync.Mutex
+	lastProgress time.Time
+	logs         int
+}
+
+func newToolProgressReporter(events *toolCallEvents, tc ToolCall) *toolProgressReporter {
+	return &toolProgressReporter{events: events, tc: tc}
+}
+
+func (r *toolProgressReporter) report(p mcp.ToolProgress) {
+	r.mu.Lock()
+	if p.Level == "" {
+		now := time.Now()
+		if now.Sub(r.lastProgress) < toolProgressInterval {
+			r.mu.Unlock()
+			return
+		}
+		r.lastProgress = now
+	} else {
+		if r.logs >= maxToolLogEvents {
+			r.mu.Unlock()
+			return
+		}
+		r.logs++
+	}
+	r.mu.Unlock()
+	r.events.send(SSEEvent{
+		Type: "tool_call_progress",
+		Data: ToolCallProgressEvent{
+			ID:       r.tc.ID,
+			Name:     r.tc.Function.Name,
+			Progress: p.Progress,
+			Total:    p.Total,
+			Message:  p.Message,
+			Level:    p.Level,
+			Logger:   p.Logger,
+		},
+	})
+}
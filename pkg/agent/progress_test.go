@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"consensys-asko11y-app/pkg/mcp"
+)
+
+func TestToolProgressReporter_ThrottlesProgressAndCapsLogs(t *testing.T) {
+	eventCh := make(chan SSEEvent, 64)
+	tc := ToolCall{ID: "call_1", Function: FunctionCall{Name: "loki_query"}}
+	events := (&AgentLoop{}).newToolCallEvents(context.Background(), eventCh)
+	r := newToolProgressReporter(events, tc)
+
+	r.report(mcp.ToolProgress{Progress: 1, Total: 4, Message: "scanning"})
+	r.report(mcp.ToolProgress{Progress: 2, Total: 4, Message: "scanning"})
+	r.lastProgress = time.Now().Add(-toolProgressInterval)
+	r.report(mcp.ToolProgress{Progress: 3, Total: 4, Message: "scanning"})
+	for range maxToolLogEvents + 5 {
+		r.report(mcp.ToolProgress{Message: "shard done", Level: "info"})
+	}
+	events.stop()
+	r.report(mcp.ToolProgress{Message: "late", Level: "info"})
+	close(eventCh)
+
+	var progress []float64
+	logs := 0
+	for e := range eventCh {
+		if e.Type != "tool_call_progress" {
+			t.Fatalf("unexpected event type %q", e.Type)
+		}
+		data := e.Data.(ToolCallProgressEvent)
+		if data.ID != "call_1" || data.Name != "loki_query" {
+			t.Fatalf("event not tied to the tool call: %+v", data)
+		}
+		if data.Level == "" {
+			progress = append(progress, data.Progress)
+		} else {
+			logs++
+		}
+	}
+	if len(progress) != 2 || progress[0] != 1 || progress[1] != 3 {
+		t.Errorf("expected progress 1 and 3 to pass the throttle, got %v", progress)
+	}
+	if logs != maxToolLogEvents {
+		t.Errorf("expected %d log events, got %d", maxToolLogEvents, logs)
+	}
+}
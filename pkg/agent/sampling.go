@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var errSamplingDisabled = errors.New("sampling is not enabled for this run")
+
+// runUsage accumulates a run's token usage by model. Sampling requests add to
+// it from tool call goroutines, so it is locked.
+type runUsage struct {
+	mu      sync.Mutex
+	byModel map[string]ModelUsage
+}
+
+func newRunUsage() *runUsage {
+	return &runUsage{byModel: make(map[string]ModelUsage)}
+}
+
+// add records one LLM response's usage and returns it as a ModelUsage.
+func (u *runUsage) add(model string, usage *Usage) ModelUsage {
+	delta := ModelUsage{
+		Model:              model,
+		PromptTokens:       usage.PromptTokens,
+		CompletionTokens:   usage.CompletionTokens,
+		TotalTokens:        usage.TotalTokens,
+		CachedPromptTokens: usage.CachedTokens(),
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	total := u.byModel[model]
+	total.Model = model
+	total.PromptTokens += delta.PromptTokens
+	total.CompletionTokens += delta.CompletionTokens
+	total.TotalTokens += delta.TotalTokens
+	total.CachedPromptTokens += delta.CachedPromptTokens
+	u.byModel[model] = total
+	return delta
+}
+
+func (u *runUsage) snapshot() map[string]ModelUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make(map[string]ModelUsage, len(u.byModel))
+	for k, v := range u.byModel {
+		out[k] = v
+	}
+	return out
+}
+
+// runSampling serves the sampling requests MCP servers make while a run's
+// tools execute: with the run's Grafana credentials and model, within its
+// sampling token budget, and under its approval policy.
+type runSampling struct {
+	loop   *AgentLoop
+	runCtx context.Context
+	req    LoopRequest
+	usage  *runUsage
+
+	mu       sync.Mutex
+	spent    int
+	requests int
+}
+
+func (a *AgentLoop) newRunSampling(ctx context.Context, req LoopRequest, usage *runUsage) *runSampling {
+	return &runSampling{loop: a, runCtx: ctx, req: req, usage: usage}
+}
+
+// forCall returns the sampler handed to one tool call, so approvals name it.
+// It stops serving requests when the call's events stop.
+func (r *runSampling) forCall(events *toolCallEvents, tc ToolCall) mcp.Sampler {
+	return &toolSampler{run: r, events: events, tc: tc}
+}
+
+// reserve takes what a request may spend out of the budget, so concurrent
+// requests can't overspend it: the estimated prompt plus the completion
+// tokens it may use, at most want. It returns the completion limit, the total
+// reserved and a sequence number for the request's approval ID.
+func (r *runSampling) reserve(prompt, want int) (maxTokens, reserved, seq int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := r.req.SamplingTokenBudget - r.spent
+	if remaining <= 0 {
+		return 0, 0, 0, fmt.Errorf("sampling token budget of %d tokens is exhausted", r.req.SamplingTokenBudget)
+	}
+	if prompt >= remaining {
+		return 0, 0, 0, fmt.Errorf("sampling prompt of about %d tokens exceeds the %d tokens left in the sampling budget", prompt, remaining)
+	}
+	maxTokens = min(remaining-prompt, defaultMaxCompletionTokens)
+	if want > 0 {
+		maxTokens = min(maxTokens, want)
+	}
+	reserved = prompt + maxTokens
+	r.spent += reserved
+	r.requests++
+	return maxTokens, reserved, r.requests, nil
+}
+
+// settle replaces a reservation with the tokens actually spent.
+func (r *runSampling) settle(reserved, spent int) {
+	r.mu.Lock()
+	r.spent += spent - reserved
+	r.mu.Unlock()
+}
+
+// requiresApproval reports whether a sampling request from the server behind
+// toolName needs the user's approval. Sampling spends the user's LLM access
+// on a prompt the model never saw, so it is gated like an external action
+// unless the server is trusted.
+func (r *runSampling) requiresApproval(toolName string) bool {
+	if !approvalPolicyEnabled(r.req.ApprovalPolicy) {
+		return false
+	}
+	tool, found := r.req.ToolCatalog.FindTool(toolName)
+	if !found {
+		return true
+	}
+	return !mcp.ClassifyToolRisk(tool, r.req.MCPServers).Trusted
+}
+
+type toolSampler struct {
+	run    *runSampling
+	events *toolCallEvents
+	tc     ToolCall
+}
+
+func (s *toolSampler) CreateMessage(ctx context.Context, sreq mcp.SamplingRequest) (*mcp.SamplingResult, error) {
+	r := s.run
+	if r.req.SamplingTokenBudget <= 0 {
+		return nil, errSamplingDisabled
+	}
+	if s.events.ctx.Err() != nil {
+		return nil, errToolCallCompleted
+	}
+	// The request arrives on the MCP session's context; stop with the call too.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.events.ctx, cancel)()
+
+	if r.req.CheckTokenBudget != nil {
+		if err := r.req.CheckTokenBudget(ctx); errors.Is(err, ErrTokenBudgetExhausted) {
+			return nil, err
+		}
+	}
+	messages := make([]Message, 0, len(sreq.Messages)+1)
+	if sreq.SystemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: sreq.SystemPrompt})
+	}
+	for _, m := range sreq.Messages {
+		messages = append(messages, Message{Role: m.Role, Content: m.Text})
+	}
+	maxTokens, reserved, seq, err := r.reserve(estimateMessagesTokens(messages, nil), sreq.MaxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.requiresApproval(sreq.ToolName) {
+		approval := ApprovalRequestEvent{
+			ApprovalID: fmt.Sprintf("%s-sampling-%d", s.tc.ID, seq),
+			ToolCallID: s.tc.ID,
+			ToolName:   "sampling:" + sreq.ServerID,
+			Risk:       "sampling",
+			Reason:     fmt.Sprintf("MCP server %s asks to run an LLM completion of up to %d tokens while %s runs", sreq.ServerID, maxTokens, sreq.ToolName),
+			Arguments:  samplingApprovalArguments(sreq, maxTokens),
+			ServerID:   sreq.ServerID,
+		}
+		if approved, refusal, _ := r.loop.awaitApproval(ctx, s.events.send, approval, "Sampling by "+sreq.ServerID, r.req); !approved {
+			if refusal == "" {
+				refusal = context.Canceled.Error()
+			}
+			r.settle(reserved, 0)
+			return nil, errors.New(refusal)
+		}
+	}
+
+	// Request the model usage is recorded under instead of leaving the
+	// choice to the LLM app.
+	model := r.req.Model
+	if model == "" {
+		model = "base"
+	}
+
+	resp, err := r.loop.llmClient.ChatCompletion(ctx, ChatCompletionRequest{
+		Model:     model,
+		Messages:  messages,
+		MaxTokens: maxTokens,
+	}, r.req.GrafanaURL, r.req.AuthToken, r.req.OrgID)
+	if err != nil {
+		r.settle(reserved, 0)
+		return nil, fmt.Errorf("sampling LLM call failed: %w", err)
+	}
+
+	// Responses without usage are charged the whole reservation.
+	spent := reserved
+	if resp.Usage != nil {
+		spent = resp.Usage.TotalTokens
+		recorded := r.usage.add(model, resp.Usage)
+		if r.req.RecordTokenUsage != nil {
+			r.req.RecordTokenUsage(r.runCtx, recorded)
+		}
+	}
+	r.settle(reserved, spent)
+	samplingTokens.WithLabelValues(modelLabel(model)).Add(float64(spent))
+
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("sampling LLM call returned no choices")
+	}
+	choice := resp.Choices[0]
+	stopReason := "endTurn"
+	if choice.FinishReason == "length" {
+		stopReason = "maxTokens"
+	}
+	return &mcp.SamplingResult{Text: choice.Message.Content, Model: model, StopReason: stopReason}, nil
+}
+
+// samplingApprovalArguments shows the user what the server wants sampled.
+func samplingApprovalArguments(sreq mcp.SamplingRequest, maxTokens int) string {
+	data, err := json.Marshal(struct {
+		SystemPrompt string                `json:"systemPrompt,omitempty"`
+		Messages     []mcp.SamplingMessage `json:"messages"`
+		MaxTokens    int                   `json:"maxTokens"`
+	}{sreq.SystemPrompt, sreq.Messages, maxTokens})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
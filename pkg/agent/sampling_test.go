@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func samplingRequest(maxTokens int) mcp.SamplingRequest {
+	return mcp.SamplingRequest{
+		ServerID:     "loki",
+		ToolName:     "loki_summarize",
+		SystemPrompt: "Summarize log lines.",
+		Messages:     []mcp.SamplingMessage{{Role: "user", Text: "ERROR a"}},
+		MaxTokens:    maxTokens,
+	}
+}
+
+func TestToolSampler_RecordsUsageAndSpendsBudget(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, []ChatCompletionResponse{{
+		ID:      "1",
+		Choices: []Choice{{Message: Message{Role: "assistant", Content: "one error"}, FinishReason: "length"}},
+		Usage:   &Usage{PromptTokens: 60, CompletionTokens: 40, TotalTokens: 100},
+	}})
+	defer cleanup()
+
+	var recorded []ModelUsage
+	req := LoopRequest{
+		GrafanaURL:          serverURL,
+		Model:               "large",
+		ApprovalPolicy:      "off",
+		SamplingTokenBudget: 150,
+		RecordTokenUsage:    func(_ context.Context, u ModelUsage) { recorded = append(recorded, u) },
+	}
+	usage := newRunUsage()
+	sampling := loop.newRunSampling(context.Background(), req, usage)
+	sampler := sampling.forCall(loop.newToolCallEvents(context.Background(), make(chan SSEEvent, 4)), ToolCall{ID: "call_1"})
+
+	result, err := sampler.CreateMessage(context.Background(), samplingRequest(500))
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if result.Text != "one error" || result.Model != "large" || result.StopReason != "maxTokens" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if got := usage.snapshot()["large"]; got.TotalTokens != 100 || got.PromptTokens != 60 {
+		t.Errorf("sampling usage not added to the run: %+v", got)
+	}
+	if len(recorded) != 1 || recorded[0].TotalTokens != 100 {
+		t.Errorf("sampling usage not recorded against the user's budget: %+v", recorded)
+	}
+
+	// 50 tokens remain. A prompt that doesn't fit is refused; one that does
+	// leaves the rest for the completion, and once both are reserved further
+	// requests are refused.
+	if _, _, _, err := sampling.reserve(50, 500); err == nil || !strings.Contains(err.Error(), "prompt") {
+		t.Fatalf("expected a prompt larger than the remaining budget to be refused, got %v", err)
+	}
+	maxTokens, reserved, _, err := sampling.reserve(20, 500)
+	if err != nil || maxTokens != 30 || reserved != 50 {
+		t.Fatalf("expected 30 completion tokens of a 50 token reservation, got %d of %d, %v", maxTokens, reserved, err)
+	}
+	if _, err := sampler.CreateMessage(context.Background(), samplingRequest(500)); err == nil || !strings.Contains(err.Error(), "budget") {
+		t.Fatalf("expected exhausted budget, got %v", err)
+	}
+}
+
+func TestToolSampler_RequestsTheModelUsageIsRecordedUnder(t *testing.T) {
+	requested := make(chan string, 1)
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		requested <- req.Model
+		respondAsStream(w, ChatCompletionResponse{
+			ID:      "1",
+			Choices: []Choice{{Message: Message{Role: "assistant", Content: "ok"}, FinishReason: "stop"}},
+			Usage:   &Usage{PromptTokens: 60, CompletionTokens: 10, TotalTokens: 70},
+		})
+	}))
+	defer llmServer.Close()
+	loop := NewAgentLoop(NewLLMClient(log.DefaultLogger, llmServer.Client()), mcp.NewProxy(context.Background(), log.DefaultLogger), log.DefaultLogger)
+
+	usage := newRunUsage()
+	sampling := loop.newRunSampling(context.Background(), LoopRequest{GrafanaURL: llmServer.URL, ApprovalPolicy: "off", SamplingTokenBudget: 150}, usage)
+	result, err := sampling.forCall(loop.newToolCallEvents(context.Background(), nil), ToolCall{ID: "call_1"}).CreateMessage(context.Background(), samplingRequest(100))
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if model := <-requested; model != "base" || result.Model != "base" {
+		t.Fatalf("expected the base model to be requested and reported, got %q and %q", model, result.Model)
+	}
+	if got := usage.snapshot()["base"]; got.TotalTokens != 70 {
+		t.Errorf("expected usage recorded under base, got %+v", usage.snapshot())
+	}
+}
+
+func TestToolSampler_RefusesWhenDisabled(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, nil)
+	defer cleanup()
+
+	sampling := loop.newRunSampling(context.Background(), LoopRequest{GrafanaURL: serverURL}, newRunUsage())
+	if _, err := sampling.forCall(loop.newToolCallEvents(context.Background(), nil), ToolCall{ID: "call_1"}).CreateMessage(context.Background(), samplingRequest(100)); err != errSamplingDisabled {
+		t.Fatalf("expected sampling to be disabled, got %v", err)
+	}
+}
+
+func TestToolSampler_RequiresApprovalFromUntrustedServers(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, nil)
+	defer cleanup()
+
+	var asked []ApprovalRequestEvent
+	req := LoopRequest{
+		GrafanaURL:          serverURL,
+		ApprovalPolicy:      "approval-gated-writes",
+		SamplingTokenBudget: 1000,
+		RegisterApproval: func(_ context.Context, a ApprovalRequestEvent) (ApprovalWaitFunc, error) {
+			asked = append(asked, a)
+			return func(context.Context) (ApprovalResolvedEvent, error) {
+				return ApprovalResolvedEvent{ApprovalID: a.ApprovalID, Decision: "rejected"}, nil
+			}, nil
+		},
+	}
+	sampling := loop.newRunSampling(context.Background(), req, newRunUsage())
+	eventCh := make(chan SSEEvent, 4)
+	_, err := sampling.forCall(loop.newToolCallEvents(context.Background(), eventCh), ToolCall{ID: "call_1"}).CreateMessage(context.Background(), samplingRequest(100))
+	if err == nil || !strings.Contains(err.Error(), "Sampling by loki was not approved") {
+		t.Fatalf("expected rejected sampling, got %v", err)
+	}
+	if len(asked) != 1 || asked[0].ApprovalID != "call_1-sampling-1" || asked[0].ToolName != "sampling:loki" || asked[0].Risk != "sampling" {
+		t.Fatalf("unexpected approval request: %+v", asked)
+	}
+	if !strings.Contains(asked[0].Arguments, `"ERROR a"`) {
+		t.Errorf("approval should show the prompt, got %s", asked[0].Arguments)
+	}
+	if sampling.spent != 0 {
+		t.Errorf("a rejected request must release its reservation, %d tokens spent", sampling.spent)
+	}
+}
+
+func TestToolSampler_StopsWithTheToolCall(t *testing.T) {
+	loop, serverURL, cleanup := setupTestLoop(t, nil)
+	defer cleanup()
+
+	req := LoopRequest{
+		GrafanaURL:          serverURL,
+		ApprovalPolicy:      "approval-gated-writes",
+		SamplingTokenBudget: 1000,
+		RegisterApproval: func(_ context.Context, a ApprovalRequestEvent) (ApprovalWaitFunc, error) {
+			return func(ctx context.Context) (ApprovalResolvedEvent, error) {
+				<-ctx.Done()
+				return ApprovalResolvedEvent{}, ctx.Err()
+			}, nil
+		},
+	}
+	sampling := loop.newRunSampling(context.Background(), req, newRunUsage())
+	eventCh := make(chan SSEEvent, 4)
+	events := loop.newToolCallEvents(context.Background(), eventCh)
+	sampler := sampling.forCall(events, ToolCall{ID: "call_1"})
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sampler.CreateMessage(context.Background(), samplingRequest(100))
+		errCh <- err
+	}()
+	if event := <-eventCh; event.Type != "approval_request" {
+		t.Fatalf("expected approval_request, got %q", event.Type)
+	}
+
+	// The call returns while the approval is pending.
+	events.stop()
+	if err := <-errCh; err == nil {
+		t.Fatal("expected the pending request to end with the call")
+	}
+	if _, err := sampler.CreateMessage(context.Background(), samplingRequest(100)); err != errToolCallCompleted {
+		t.Fatalf("expected requests after the call to be refused, got %v", err)
+	}
+	close(eventCh)
+	for event := range eventCh {
+		t.Errorf("unexpected event after the call completed: %q", event.Type)
+	}
+	if sampling.spent != 0 {
+		t.Errorf("an unanswered request must release its reservation, %d tokens spent", sampling.spent)
+	}
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxStructuredRows caps the rows rendered per table; the rest are
+	// summarized by count.
+	maxStructuredRows = 50
+
+	// maxStructuredCell caps a table cell, in bytes. Values outside tables
+	// are rendered whole.
+	maxStructuredCell = 200
+)
+
+// renderStructuredContent renders a tool's structured content for the LLM.
+// Arrays of objects become Markdown tables and objects become indented
+// key: value lines, which take far fewer tokens than indented JSON and are
+// easier for the model to read.
+func renderStructuredContent(content interface{}) string {
+	// Normalize typed values into their JSON form.
+	data, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	var b strings.Builder
+	writeStructured(&b, value, "")
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// normalizeStructured returns content in its decoded JSON form, so it can be
+// compared with text blocks that serialize it. Unencodable content is nil.
+func normalizeStructured(content interface{}) interface{} {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// isStructuredDuplicate reports whether text is a serialization of the
+// normalized structured content, which tools send for backwards
+// compatibility. Other JSON text, such as "42", carries its own data.
+func isStructuredDuplicate(text string, normalized interface{}) bool {
+	if normalized == nil {
+		return false
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(value, normalized)
+}
+
+func writeStructured(b *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeStructuredField(b, k, v[k], indent)
+		}
+	case []interface{}:
+		if rows, ok := objectRows(v); ok {
+			writeTable(b, rows, indent)
+			return
+		}
+		b.WriteString(indent + formatStructuredList(v) + "\n")
+	default:
+		b.WriteString(indent + formatStructuredScalar(v) + "\n")
+	}
+}
+
+func writeStructuredField(b *strings.Builder, key string, value interface{}, indent string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			b.WriteString(indent + key + ": {}\n")
+			return
+		}
+		b.WriteString(indent + key + ":\n")
+		writeStructured(b, v, indent+"  ")
+	case []interface{}:
+		if rows, ok := objectRows(v); ok {
+			fmt.Fprintf(b, "%s%s (%d rows):\n", indent, key, len(rows))
+			writeTable(b, rows, indent+"  ")
+			return
+		}
+		b.WriteString(indent + key + ": " + formatStructuredList(v) + "\n")
+	default:
+		b.WriteString(indent + key + ": " + formatStructuredScalar(v) + "\n")
+	}
+}
+
+// objectRows returns items as objects if every item is one.
+func objectRows(items []interface{}) ([]map[string]interface{}, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		rows[i] = row
+	}
+	return rows, true
+}
+
+// writeTable renders rows as a Markdown table whose columns are the union of
+// the rows' keys, in sorted order.
+func writeTable(b *strings.Builder, rows []map[string]interface{}, indent string) {
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	b.WriteString(indent + "| " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString(indent + "|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	for i, row := range rows {
+		if i == maxStructuredRows {
+			fmt.Fprintf(b, "%s(%d more rows not shown)\n", indent, len(rows)-maxStructuredRows)
+			break
+		}
+		cells := make([]string, len(columns))
+		for j, col := range columns {
+			if cell, ok := row[col]; ok {
+				cells[j] = formatTableCell(cell)
+			}
+		}
+		b.WriteString(indent + "| " + strings.Join(cells, " | ") + " |\n")
+	}
+}
+
+func formatTableCell(value interface{}) string {
+	var s string
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(value)
+		s = string(data)
+	default:
+		s = formatStructuredScalar(value)
+	}
+	s = strings.ReplaceAll(truncateCell(s), "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func formatStructuredList(items []interface{}) string {
+	if len(items) == 0 {
+		return "[]"
+	}
+	parts := make([]string, 0, min(len(items), maxStructuredRows))
+	for i, item := range items {
+		if i == maxStructuredRows {
+			parts = append(parts, fmt.Sprintf("... (%d more)", len(items)-maxStructuredRows))
+			break
+		}
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			data, _ := json.Marshal(item)
+			parts = append(parts, string(data))
+		default:
+			parts = append(parts, formatStructuredScalar(item))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatStructuredScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func truncateCell(s string) string {
+	if len(s) <= maxStructuredCell {
+		return s
+	}
+	cut := maxStructuredCell
+	// Don't split a multi-byte rune.
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
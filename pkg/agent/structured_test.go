@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"consensys-asko11y-app/pkg/mcp"
+)
+
+func TestRenderStructuredContent_TablesForArraysOfObjects(t *testing.T) {
+	got := renderStructuredContent(map[string]interface{}{
+		"status": "success",
+		"series": []interface{}{
+			map[string]interface{}{"job": "api", "value": 0.5},
+			map[string]interface{}{"job": "db|primary", "value": 2, "instance": "db-1"},
+		},
+		"meta": map[string]interface{}{"warnings": []interface{}{"partial", "slow"}},
+	})
+
+	want := strings.Join([]string{
+		"meta:",
+		"  warnings: partial, slow",
+		"series (2 rows):",
+		"  | instance | job | value |",
+		"  | --- | --- | --- |",
+		"  |  | api | 0.5 |",
+		`  | db-1 | db\|primary | 2 |`,
+		"status: success",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderStructuredContent_CapsRowsAndCells(t *testing.T) {
+	rows := make([]interface{}, maxStructuredRows+5)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"line": strings.Repeat("x", maxStructuredCell+10)}
+	}
+
+	got := renderStructuredContent(rows)
+
+	if !strings.HasSuffix(got, "(5 more rows not shown)") {
+		t.Fatalf("expected truncated row count, got tail %q", got[len(got)-40:])
+	}
+	if strings.Contains(got, strings.Repeat("x", maxStructuredCell+1)) {
+		t.Fatal("expected long cells to be truncated")
+	}
+}
+
+func TestRenderStructuredContent_KeepsLongValuesOutsideTables(t *testing.T) {
+	long := strings.Repeat("x", maxStructuredCell+10)
+
+	got := renderStructuredContent(map[string]interface{}{"message": long, "tags": []interface{}{long}})
+
+	if want := "message: " + long + "\ntags: " + long; got != want {
+		t.Fatalf("expected values outside tables to be kept whole, got %q", got)
+	}
+}
+
+func TestExtractText_PrefersRenderedStructuredContent(t *testing.T) {
+	result := &mcp.CallToolResult{
+		Content: []mcp.ContentBlock{
+			{Type: "text", Text: "Found 1 alert."},
+			{Type: "text", Text: `{"alerts":[{"name":"HighLatency","state":"firing"}]}`},
+		},
+		StructuredContent: map[string]interface{}{
+			"alerts": []interface{}{map[string]interface{}{"name": "HighLatency", "state": "firing"}},
+		},
+	}
+
+	got := extractText(result)
+
+	if strings.Contains(got, `{"alerts"`) {
+		t.Fatalf("serialized JSON duplicate should be dropped, got %q", got)
+	}
+	if !strings.HasPrefix(got, "Found 1 alert.\nalerts (1 rows):") || !strings.Contains(got, "| HighLatency | firing |") {
+		t.Fatalf("unexpected rendering %q", got)
+	}
+}
+
+func TestExtractText_KeepsJSONTextWithoutStructuredContent(t *testing.T) {
+	result := &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"ok":true}`}}}
+	if got := extractText(result); got != `{"ok":true}` {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestExtractText_KeepsJSONTextThatDiffersFromStructuredContent(t *testing.T) {
+	result := &mcp.CallToolResult{
+		Content: []mcp.ContentBlock{
+			{Type: "text", Text: "42"},
+			{Type: "text", Text: `{"ok":true}`},
+		},
+		StructuredContent: map[string]interface{}{"count": 42},
+	}
+	if got := extractText(result); got != "42\n{\"ok\":true}\ncount: 42" {
+		t.Fatalf("got %q", got)
+	}
+}
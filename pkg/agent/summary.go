@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const summaryMaxCompletionTokens = 1024
+
+// summaryMessageCharLimit caps each transcript entry handed to the summarizer so
+// one oversized assistant report can't crowd out the rest of the dropped turns.
+const summaryMessageCharLimit = 4000
+
+const summarySystemPrompt = `You maintain a rolling summary of an observability assistant conversation.
+Older turns are removed from the assistant's context window and survive only through this summary.
+Merge the existing summary (if any) with the new turns into one updated summary.
+Keep: the user's goals and open questions, services/datasources/dashboards involved, exact identifiers (UIDs, metric names, label values, queries, time ranges), findings and conclusions, and any decisions or follow-ups.
+Drop: greetings, filler, and raw tool output that has already been interpreted.
+Write concise plain prose or short bullet points, no more than 300 words. Output only the summary.`
+
+// SummarizeConversation folds messages into previousSummary with the base model
+// and returns the updated summary. Usage may be nil if the provider omitted it.
+func (a *AgentLoop) SummarizeConversation(ctx context.Context, previousSummary string, messages []Message, grafanaURL, authToken, orgID string) (string, *Usage, error) {
+	if len(messages) == 0 {
+		return previousSummary, nil, nil
+	}
+
+	req := ChatCompletionRequest{
+		Model: "base",
+		Messages: []Message{
+			{Role: "system", Content: summarySystemPrompt},
+			{Role: "user", Content: buildSummaryInput(previousSummary, messages)},
+		},
+		MaxTokens: summaryMaxCompletionTokens,
+	}
+
+	resp, err := a.llmClient.ChatCompletion(ctx, req, grafanaURL, authToken, orgID)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarize conversation: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", resp.Usage, fmt.Errorf("summarize conversation: no choices in LLM response")
+	}
+
+	summary := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if summary == "" {
+		return "", resp.Usage, fmt.Errorf("summarize conversation: empty summary")
+	}
+	return summary, resp.Usage, nil
+}
+
+func buildSummaryInput(previousSummary string, messages []Message) string {
+	var b strings.Builder
+	if previousSummary != "" {
+		b.WriteString("Existing summary:\n")
+		b.WriteString(previousSummary)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("New turns to fold in:\n")
+	for _, m := range messages {
+		content := strings.TrimSpace(m.Content)
+		if content == "" {
+			continue
+		}
+		if len(content) > summaryMessageCharLimit {
+			content = content[:summaryMessageCharLimit] + "\n[...truncated]"
+		}
+		b.WriteString("\n[")
+		b.WriteString(m.Role)
+		b.WriteString("]\n")
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
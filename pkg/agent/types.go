@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"consensys-asko11y-app/pkg/mcp"
 	"context"
 	"encoding/json"
 )
@@ -63,9 +64,27 @@ type Choice struct {
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	// CacheReadInputTokens is how Anthropic-style gateways report cache hits.
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// PromptTokensDetails breaks down prompt tokens; CachedTokens were served
+// from the provider's prompt cache.
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CachedTokens returns the prompt tokens the provider read from its cache,
+// whichever way it reported them.
+func (u *Usage) CachedTokens() int {
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		return u.PromptTokensDetails.CachedTokens
+	}
+	return u.CacheReadInputTokens
 }
 
 type SSEEvent struct {
@@ -78,6 +97,15 @@ type ContentEvent struct {
 	Content string `json:"content"`
 }
 
+// ContentDeltaEvent carries assistant text as it streams from the LLM, ahead of
+// the assembled content event. Reset tells the client to discard the deltas it
+// has shown since the last content event: the stream was retried, or the turn
+// ended in tool calls and its text was dropped.
+type ContentDeltaEvent struct {
+	Delta string `json:"delta,omitempty"`
+	Reset bool   `json:"reset,omitempty"`
+}
+
 type ToolCallStartEvent struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
@@ -91,7 +119,7 @@ type ToolCallResultEvent struct {
 	IsError bool   `json:"isError"`
 	// ErrorKind classifies a failed tool call so the UI can show a different
 	// treatment for transport outages vs tool-layer errors. Empty on success.
-	// Values: "transport" | "tool" | "protocol" | "".
+	// Values: "transport" | "tool" | "protocol" | "schema" | "".
 	ErrorKind string `json:"errorKind,omitempty"`
 }
 
@@ -113,6 +141,11 @@ type ApprovalRequestEvent struct {
 	Risk       string `json:"risk"`
 	Reason     string `json:"reason"`
 	Arguments  string `json:"arguments"`
+	// ServerID and Annotations describe the tool as the run's pinned catalog
+	// has it, so approval policies are checked against that definition.
+	// Sampling approvals carry the server that asked.
+	ServerID    string               `json:"serverId,omitempty"`
+	Annotations *mcp.ToolAnnotations `json:"annotations,omitempty"`
 }
 
 type ApprovalResolvedEvent struct {
@@ -122,6 +155,27 @@ type ApprovalResolvedEvent struct {
 	ResolvedAt string `json:"resolvedAt,omitempty"`
 }
 
+// InputRequestEvent asks the user for input an MCP server needs to finish a
+// tool call (MCP elicitation), as a form described by Schema.
+type InputRequestEvent struct {
+	InputID    string                 `json:"inputId"`
+	ToolCallID string                 `json:"toolCallId"`
+	ToolName   string                 `json:"toolName"`
+	ServerID   string                 `json:"serverId"`
+	Message    string                 `json:"message"`
+	Schema     map[string]interface{} `json:"schema"`
+}
+
+// InputResolvedEvent is the user's answer to an InputRequestEvent. Action is
+// "accept", "decline" or "cancel"; Content is only set on accept.
+type InputResolvedEvent struct {
+	InputID    string                 `json:"inputId"`
+	Action     string                 `json:"action"`
+	Content    map[string]interface{} `json:"content,omitempty"`
+	Comment    string                 `json:"comment,omitempty"`
+	ResolvedAt string                 `json:"resolvedAt,omitempty"`
+}
+
 type FinalReportEvent struct {
 	Verdict     string   `json:"verdict,omitempty"`
 	Confidence  string   `json:"confidence,omitempty"`
@@ -144,15 +198,19 @@ type ModelUsage struct {
 	PromptTokens     int    `json:"promptTokens"`     // Input Tokens
 	CompletionTokens int    `json:"completionTokens"` // Output Tokens
 	TotalTokens      int    `json:"totalTokens"`
+	// CachedPromptTokens is the part of PromptTokens served from the
+	// provider's prompt cache.
+	CachedPromptTokens int `json:"cachedPromptTokens,omitempty"`
 }
 
 type DoneEvent struct {
-	TotalIterations  int                    `json:"totalIterations"`
-	PromptTokens     int64                  `json:"promptTokens"`
-	CompletionTokens int64                  `json:"completionTokens"`
-	TotalTokens      int64                  `json:"totalTokens"`
-	ToolCallCount    int                    `json:"toolCallCount"`
-	UsageByModel     map[string]ModelUsage `json:"usageByModel,omitempty"`
+	TotalIterations    int                   `json:"totalIterations"`
+	PromptTokens       int64                 `json:"promptTokens"`
+	CompletionTokens   int64                 `json:"completionTokens"`
+	TotalTokens        int64                 `json:"totalTokens"`
+	CachedPromptTokens int64                 `json:"cachedPromptTokens,omitempty"`
+	ToolCallCount      int                   `json:"toolCallCount"`
+	UsageByModel       map[string]ModelUsage `json:"usageByModel,omitempty"`
 }
 
 type ErrorEvent struct {
@@ -171,6 +229,27 @@ type RunRequest struct {
 	ScopeOrgID string `json:"scopeOrgId,omitempty"`
 }
 
+// ToolCallProgressEvent reports progress or a server log message for a
+// running tool call.
+type ToolCallProgressEvent struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Progress float64 `json:"progress,omitempty"`
+	// Total is omitted when the server doesn't know it.
+	Total   float64 `json:"total,omitempty"`
+	Message string  `json:"message,omitempty"`
+	// Level and Logger are set for server log messages and empty for
+	// progress updates.
+	Level  string `json:"level,omitempty"`
+	Logger string `json:"logger,omitempty"`
+}
+
+// ToolCatalogEvent records the tool catalog version a run was pinned to.
+type ToolCatalogEvent struct {
+	Version   string `json:"version"`
+	ToolCount int    `json:"toolCount"`
+}
+
 type RunStartedEvent struct {
 	RunID     string `json:"runId"`
 	SessionID string `json:"sessionId,omitempty"`
@@ -191,3 +270,13 @@ func MarshalSSE(event SSEEvent) ([]byte, error) {
 type ApprovalWaitFunc func(context.Context) (ApprovalResolvedEvent, error)
 type ApprovalRegistrar func(context.Context, ApprovalRequestEvent) (ApprovalWaitFunc, error)
 type ApprovalGrantChecker func(context.Context, ApprovalRequestEvent) (bool, error)
+
+type InputWaitFunc func(context.Context) (InputResolvedEvent, error)
+type InputRegistrar func(context.Context, InputRequestEvent) (InputWaitFunc, error)
+
+// TokenUsageRecorder charges one LLM response's usage to the run's owner.
+type TokenUsageRecorder func(context.Context, ModelUsage)
+
+// TokenBudgetChecker returns an error wrapping ErrTokenBudgetExhausted when
+// the run must not make another LLM call.
+type TokenBudgetChecker func(context.Context) error
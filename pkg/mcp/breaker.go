@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a server's circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen hides the server's tools and fails calls immediately.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single trial call through after the cooldown.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+const (
+	// breakerFailureThreshold matches the consecutive health-check failures
+	// at which HealthMonitor reports a server unhealthy.
+	breakerFailureThreshold = 3
+	// breakerCooldown is how long an open breaker waits before allowing a
+	// trial call. One health-check interval, so the next check is the trial
+	// when no user call comes first.
+	breakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for calls to a server whose breaker is open.
+var ErrCircuitOpen = errors.New("MCP server unavailable: circuit breaker open")
+
+// CircuitBreaker tracks consecutive failures of one MCP server. Health
+// checks and live tool calls both record outcomes. The zero value is a
+// closed breaker using the default threshold and cooldown.
+type CircuitBreaker struct {
+	mu            sync.Mutex
+	state         BreakerState
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+	// threshold and cooldown override the defaults when set.
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// State returns the current state. An open breaker whose cooldown has
+// elapsed reports half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *CircuitBreaker) stateLocked() BreakerState {
+	switch {
+	case b.state == "":
+		b.state = BreakerClosed
+	case b.state == BreakerOpen && b.clock().Sub(b.openedAt) >= b.cooldownOrDefault():
+		b.state = BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) cooldownOrDefault() time.Duration {
+	if b.cooldown > 0 {
+		return b.cooldown
+	}
+	return breakerCooldown
+}
+
+func (b *CircuitBreaker) thresholdOrDefault() int {
+	if b.threshold > 0 {
+		return b.threshold
+	}
+	return breakerFailureThreshold
+}
+
+// Allow reports whether a call may proceed. In the half-open state only one
+// trial call is allowed until its outcome is recorded.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.trialInFlight = false
+}
+
+// RecordFailure counts a failure, opening the breaker at the threshold or
+// re-opening it when a half-open trial fails.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trialInFlight = false
+	if b.stateLocked() == BreakerHalfOpen || b.failures >= b.thresholdOrDefault() {
+		b.state = BreakerOpen
+		b.openedAt = b.clock()
+	}
+}
+
+// Release ends a trial call without a verdict, e.g. when the caller
+// canceled it.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+// recordCallOutcome feeds a tool call result into the breaker. Transport
+// errors count as failures, and so does the tool timeout expiring while the
+// caller still waited: a server that accepts calls but hangs is as unusable
+// as one that refuses them. Any answer from the server, even an error one,
+// proves it reachable.
+func (b *CircuitBreaker) recordCallOutcome(ctx context.Context, err error) {
+	switch ClassifyError(ctx, err) {
+	case ErrKindTransport:
+		b.RecordFailure()
+	case ErrKindCanceled:
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			b.RecordFailure()
+			return
+		}
+		b.Release()
+	default:
+		b.RecordSuccess()
+	}
+}
+
+// serverLabel returns the name shown to users and the model for a server.
+func (c *Client) serverLabel() string {
+	if c.config.Name != "" {
+		return c.config.Name
+	}
+	return c.config.ID
+}
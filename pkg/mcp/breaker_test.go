@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	b := &CircuitBreaker{threshold: 2, cooldown: time.Minute, now: func() time.Time { return now }}
+
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatal("zero-value breaker must be closed")
+	}
+	b.RecordFailure()
+	if b.State() != BreakerClosed {
+		t.Fatal("breaker opened below the threshold")
+	}
+	b.RecordFailure()
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatal("breaker must open at the threshold and reject calls")
+	}
+
+	now = now.Add(time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected half-open after cooldown, got %s", b.State())
+	}
+	if !b.Allow() {
+		t.Fatal("half-open breaker must allow one trial")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker must reject calls while the trial is in flight")
+	}
+	b.RecordFailure()
+	if b.State() != BreakerOpen {
+		t.Fatal("failed trial must re-open the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Release()
+	if !b.Allow() {
+		t.Fatal("released trial must let another trial through")
+	}
+	b.RecordSuccess()
+	if b.State() != BreakerClosed {
+		t.Fatal("successful trial must close the breaker")
+	}
+}
+
+func TestCircuitBreakerRecordCallOutcome(t *testing.T) {
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	timeout := fmt.Errorf("query timed out after 30s: %w", context.DeadlineExceeded)
+
+	b := &CircuitBreaker{threshold: 1}
+	b.recordCallOutcome(canceled, timeout)
+	b.recordCallOutcome(ctx, context.Canceled)
+	b.recordCallOutcome(ctx, errors.New("invalid params"))
+	if b.State() != BreakerClosed {
+		t.Fatal("only transport errors and tool timeouts may open the breaker")
+	}
+	b.recordCallOutcome(ctx, io.EOF)
+	if b.State() != BreakerOpen {
+		t.Fatal("transport error must count as a failure")
+	}
+
+	b = &CircuitBreaker{threshold: 1}
+	b.recordCallOutcome(ctx, timeout)
+	if b.State() != BreakerOpen {
+		t.Fatal("a tool timeout while the caller waits must count as a failure")
+	}
+}
+
+func newBreakerTestProxy() (*Proxy, *Client) {
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	now := time.Now()
+	down := &Client{config: ServerConfig{ID: "loki", Name: "Loki"}, tools: []Tool{{Name: "loki_query"}}, toolsFetchedAt: now}
+	proxy.clients["loki"] = down
+	proxy.clients["prom"] = &Client{config: ServerConfig{ID: "prom"}, tools: []Tool{{Name: "prom_query"}}, toolsFetchedAt: now}
+	for range breakerFailureThreshold {
+		down.breaker.RecordFailure()
+	}
+	return proxy, down
+}
+
+func TestProxyHidesToolsOfOpenCircuitServers(t *testing.T) {
+	proxy, _ := newBreakerTestProxy()
+
+	catalog, err := proxy.ToolCatalog()
+	if err != nil {
+		t.Fatalf("ToolCatalog: %v", err)
+	}
+	if names := toolNames(catalog.Tools); len(names) != 1 || names[0] != "prom_query" {
+		t.Fatalf("expected only prom tools, got %v", names)
+	}
+	if len(catalog.Unavailable) != 1 || catalog.Unavailable[0] != "Loki" {
+		t.Fatalf("expected Loki reported unavailable, got %v", catalog.Unavailable)
+	}
+}
+
+func TestProxyCallToolFailsFastWhenCircuitOpen(t *testing.T) {
+	proxy, _ := newBreakerTestProxy()
+
+	ctx := context.Background()
+	_, err := proxy.CallToolWithContext(ctx, "loki_query", nil, "1", "", "")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if kind := ClassifyError(ctx, err); kind != ErrKindTransport {
+		t.Fatalf("expected transport error kind, got %q", kind)
+	}
+}
+
+func TestGetAllHealthReportsBreakerState(t *testing.T) {
+	proxy, _ := newBreakerTestProxy()
+	hm := proxy.GetHealthMonitor()
+	hm.health["loki"] = &ServerHealth{ServerID: "loki", Status: StatusUnhealthy}
+	hm.health["prom"] = &ServerHealth{ServerID: "prom", Status: StatusHealthy}
+
+	states := map[string]BreakerState{}
+	for _, h := range hm.GetAllHealth() {
+		states[h.ServerID] = h.Breaker
+	}
+	if states["loki"] != BreakerOpen || states["prom"] != BreakerClosed {
+		t.Fatalf("unexpected breaker states: %v", states)
+	}
+}
+
+func TestHealthCheckOpensBreakerOfOpenAPIServerServingCachedTools(t *testing.T) {
+	var ops atomic.Pointer[[]string]
+	ops.Store(&[]string{"query"})
+	server, _ := newCatalogServer(t, &ops)
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	if err := proxy.EnsureServer(ServerConfig{ID: "svc", URL: server.URL, Type: "openapi", Enabled: true}); err != nil {
+		t.Fatalf("EnsureServer: %v", err)
+	}
+	t.Cleanup(func() { deleteServerMetrics("svc") })
+	client := proxy.clients["svc"]
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+
+	// The server goes down while its tools are still cached.
+	ops.Store(nil)
+	hm := proxy.GetHealthMonitor()
+	for range breakerFailureThreshold {
+		hm.checkServer("svc", client)
+	}
+	if client.breaker.State() != BreakerOpen {
+		t.Fatalf("expected failed health checks to open the breaker, got %s", client.breaker.State())
+	}
+}
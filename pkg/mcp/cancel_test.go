@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// newBlockingOpenAPIServer serves a one-operation spec whose tool endpoint
+// blocks until the caller goes away, reporting on aborted when it does.
+func newBlockingOpenAPIServer(t *testing.T, aborted chan<- struct{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"paths":{"/slow":{"get":{"operationId":"slow"}}}}`))
+		case "/slow":
+			// Drain the body so the server starts watching the connection and
+			// cancels r.Context() when the client hangs up.
+			io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+				aborted <- struct{}{}
+			case <-time.After(5 * time.Second):
+				t.Errorf("tool request was not aborted")
+			}
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func newBlockingProxy(t *testing.T, config ServerConfig) *Proxy {
+	t.Helper()
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	if err := proxy.EnsureServer(config); err != nil {
+		t.Fatalf("EnsureServer failed: %v", err)
+	}
+	t.Cleanup(proxy.Close)
+	if _, err := proxy.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	return proxy
+}
+
+func TestCallToolWithContext_CancelAbortsInFlightRequest(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := newBlockingOpenAPIServer(t, aborted)
+	defer server.Close()
+
+	proxy := newBlockingProxy(t, ServerConfig{ID: "srv", URL: server.URL, Type: "openapi", Enabled: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := proxy.CallToolWithContext(ctx, "srv_slow", map[string]interface{}{}, "1", "Org1", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected prompt cancellation, took %v", elapsed)
+	}
+	waitForAbort(t, aborted)
+}
+
+func waitForAbort(t *testing.T, aborted <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server never saw the request aborted")
+	}
+}
+
+func TestCallToolWithContext_PerToolTimeout(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := newBlockingOpenAPIServer(t, aborted)
+	defer server.Close()
+
+	proxy := newBlockingProxy(t, ServerConfig{
+		ID:                 "srv",
+		URL:                server.URL,
+		Type:               "openapi",
+		Enabled:            true,
+		ToolTimeoutSeconds: 30,
+		ToolTimeouts:       map[string]int{"slow": 1},
+	})
+
+	start := time.Now()
+	_, err := proxy.CallToolWithContext(context.Background(), "srv_slow", map[string]interface{}{}, "", "", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Fatalf("expected the 1s per-tool timeout to apply, took %v", elapsed)
+	}
+	waitForAbort(t, aborted)
+}
+
+func TestToolCallTimeout_Resolution(t *testing.T) {
+	c := &Client{config: ServerConfig{ID: "srv"}}
+	if got := c.toolCallTimeout("query"); got != defaultToolCallTimeout {
+		t.Fatalf("expected default timeout, got %v", got)
+	}
+
+	c.config.ToolTimeoutSeconds = 90
+	if got := c.toolCallTimeout("query"); got != 90*time.Second {
+		t.Fatalf("expected server timeout, got %v", got)
+	}
+
+	c.config.ToolTimeouts = map[string]int{"query": 120}
+	if got := c.toolCallTimeout("query"); got != 120*time.Second {
+		t.Fatalf("expected per-tool override, got %v", got)
+	}
+	if got := c.toolCallTimeout("other"); got != 90*time.Second {
+		t.Fatalf("expected server timeout for tools without an override, got %v", got)
+	}
+}
+
+func TestCallToolWithContext_ClientCloseAbortsCall(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := newBlockingOpenAPIServer(t, aborted)
+	defer server.Close()
+
+	proxy := newBlockingProxy(t, ServerConfig{ID: "srv", URL: server.URL, Type: "openapi", Enabled: true})
+	time.AfterFunc(100*time.Millisecond, func() { proxy.RemoveServer("srv") })
+
+	_, err := proxy.CallToolWithContext(context.Background(), "srv_slow", map[string]interface{}{}, "", "", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled after client close, got %v", err)
+	}
+	waitForAbort(t, aborted)
+}
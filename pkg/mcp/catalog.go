@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultToolCatalogTTL is how long a server's tool list is trusted before
+// ListTools fetches it again.
+const DefaultToolCatalogTTL = 5 * time.Minute
+
+// ToolCatalog is the aggregated tool list at one point in time. Version
+// changes whenever any server adds, removes or changes a tool.
+type ToolCatalog struct {
+	Version string
+	Tools   []Tool
+	// Unavailable names the servers whose tools were left out because their
+	// circuit breaker was open.
+	Unavailable []string
+}
+
+// FindTool returns the catalog's definition of name. A nil catalog has no
+// tools.
+func (c *ToolCatalog) FindTool(name string) (Tool, bool) {
+	if c == nil {
+		return Tool{}, false
+	}
+	for _, tool := range c.Tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// CatalogVersion returns a short content hash of tools. Callers must pass
+// tools in a stable order; Proxy.ListTools sorts by name.
+func CatalogVersion(tools []Tool) string {
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// InvalidateTools makes the next ListTools fetch the tool list from the
+// server again.
+func (c *Client) InvalidateTools() {
+	c.toolsStale.Store(true)
+}
+
+// expireTools invalidates the cached tool list if it is older than ttl.
+// A ttl of zero disables expiry.
+func (c *Client) expireTools(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.RLock()
+	expired := c.tools != nil && time.Since(c.toolsFetchedAt) >= ttl
+	c.mu.RUnlock()
+	if expired {
+		c.InvalidateTools()
+	}
+}
+
+// cachedTool returns the advertised definition of toolName.
+func (c *Client) cachedTool(toolName string) (Tool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, tool := range c.tools {
+		if tool.Name == toolName {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// clientOptions returns the SDK client options shared by every session, so
+// servers that announce tools/list_changed get their catalog refreshed, and
+// progress and log notifications and sampling and elicitation requests reach
+// the calls waiting for them.
+func (c *Client) clientOptions() *mcpsdk.ClientOptions {
+	return &mcpsdk.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcpsdk.ToolListChangedRequest) {
+			c.logger.Debug("MCP server reported a tool list change", "server", c.config.ID)
+			c.InvalidateTools()
+		},
+		ProgressNotificationHandler: func(_ context.Context, req *mcpsdk.ProgressNotificationClientRequest) {
+			c.calls.onProgress(req.Params)
+		},
+		LoggingMessageHandler: func(_ context.Context, req *mcpsdk.LoggingMessageRequest) {
+			c.calls.onLog(req.Session, req.Params)
+		},
+		CreateMessageHandler: c.createMessage,
+		ElicitationHandler:   c.elicit,
+	}
+}
+
+// SetCatalogTTL sets how long each server's tool list is cached. Zero
+// disables expiry, leaving list_changed notifications as the only refresh.
+func (p *Proxy) SetCatalogTTL(ttl time.Duration) {
+	p.mu.Lock()
+	p.catalogTTL = ttl
+	p.mu.Unlock()
+}
+
+func (p *Proxy) getCatalogTTL() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.catalogTTL
+}
+
+// ToolCatalog returns the aggregated tools together with their version.
+func (p *Proxy) ToolCatalog() (*ToolCatalog, error) {
+	tools, unavailable, err := p.listTools()
+	if err != nil {
+		return nil, err
+	}
+	return &ToolCatalog{Version: CatalogVersion(tools), Tools: tools, Unavailable: unavailable}, nil
+}
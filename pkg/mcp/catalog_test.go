@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newCatalogServer serves an OpenAPI spec with one operation per name in
+// *ops, counting spec fetches. A nil *ops answers 500.
+func newCatalogServer(t *testing.T, ops *atomic.Pointer[[]string]) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		names := ops.Load()
+		if names == nil {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		paths := ""
+		for i, name := range *names {
+			if i > 0 {
+				paths += ","
+			}
+			paths += fmt.Sprintf(`"/%s": {"get": {"operationId": %q}}`, name, name)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"openapi": "3.1.0", "paths": {%s}}`, paths)
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func toolNames(tools []Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestClientListToolsRefetchesAfterInvalidate(t *testing.T) {
+	var ops atomic.Pointer[[]string]
+	ops.Store(&[]string{"alpha"})
+	server, fetches := newCatalogServer(t, &ops)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	ops.Store(&[]string{"alpha", "beta"})
+	tools, _ := client.ListTools()
+	if len(tools) != 1 || fetches.Load() != 1 {
+		t.Fatalf("expected cached list without refetch, got %v after %d fetches", toolNames(tools), fetches.Load())
+	}
+
+	client.InvalidateTools()
+	tools, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools after invalidate: %v", err)
+	}
+	if len(tools) != 2 || fetches.Load() != 2 {
+		t.Fatalf("expected refreshed list, got %v after %d fetches", toolNames(tools), fetches.Load())
+	}
+}
+
+func TestClientListToolsKeepsCacheWhenRefreshFails(t *testing.T) {
+	var ops atomic.Pointer[[]string]
+	ops.Store(&[]string{"alpha"})
+	server, _ := newCatalogServer(t, &ops)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	ops.Store(nil)
+	client.InvalidateTools()
+	tools, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("expected cached tools on refresh failure, got error %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "svc_alpha" {
+		t.Fatalf("expected previous tools, got %v", toolNames(tools))
+	}
+}
+
+func TestExpireToolsHonoursTTL(t *testing.T) {
+	client := &Client{tools: []Tool{{Name: "svc_alpha"}}, toolsFetchedAt: time.Now().Add(-2 * time.Minute)}
+
+	client.expireTools(0)
+	if client.toolsStale.Load() {
+		t.Fatal("zero TTL must not expire the catalog")
+	}
+	client.expireTools(5 * time.Minute)
+	if client.toolsStale.Load() {
+		t.Fatal("catalog younger than the TTL must not expire")
+	}
+	client.expireTools(time.Minute)
+	if !client.toolsStale.Load() {
+		t.Fatal("catalog older than the TTL must expire")
+	}
+}
+
+func TestToolListChangedHandlerInvalidatesTools(t *testing.T) {
+	client := &Client{config: ServerConfig{ID: "svc"}, logger: log.DefaultLogger}
+	client.clientOptions().ToolListChangedHandler(context.Background(), &mcpsdk.ToolListChangedRequest{})
+	if !client.toolsStale.Load() {
+		t.Fatal("expected list_changed to invalidate the tool cache")
+	}
+}
+
+func TestProxyToolCatalogVersionTracksChanges(t *testing.T) {
+	var ops atomic.Pointer[[]string]
+	ops.Store(&[]string{"alpha"})
+	server, _ := newCatalogServer(t, &ops)
+
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	proxy.SetCatalogTTL(time.Nanosecond)
+	if err := proxy.UpdateConfig([]ServerConfig{{ID: "svc", Name: "svc", URL: server.URL, Type: "openapi", Enabled: true}}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+
+	first, err := proxy.ToolCatalog()
+	if err != nil {
+		t.Fatalf("ToolCatalog: %v", err)
+	}
+	same, _ := proxy.ToolCatalog()
+	if first.Version == "" || same.Version != first.Version {
+		t.Fatalf("expected stable version for unchanged tools, got %q and %q", first.Version, same.Version)
+	}
+
+	ops.Store(&[]string{"alpha", "beta"})
+	changed, err := proxy.ToolCatalog()
+	if err != nil {
+		t.Fatalf("ToolCatalog: %v", err)
+	}
+	if changed.Version == first.Version || len(changed.Tools) != 2 {
+		t.Fatalf("expected new version with 2 tools, got %q with %v", changed.Version, toolNames(changed.Tools))
+	}
+	if len(first.Tools) != 1 {
+		t.Fatalf("earlier catalog must be unaffected, got %v", toolNames(first.Tools))
+	}
+}
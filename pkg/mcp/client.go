@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -22,6 +23,12 @@ type OperationMetadata struct {
 	Path    string
 	Method  string
 	BaseURL string
+	// Parameters route arguments to the path, query string, headers or
+	// cookies; the remaining arguments form the request body.
+	Parameters []OperationParameter
+	// BodyContentType is the requestBody media type, empty when the
+	// operation declares no JSON or form-encoded body.
+	BodyContentType string
 }
 
 // Client represents an MCP client for a single server
@@ -41,6 +48,28 @@ type Client struct {
 	// forceReconnect can dedupe reconnect storms when the on-call retry path
 	// has already refreshed the session within the last few seconds.
 	sessionCreatedAt time.Time
+	// stdioBackoff is the delay before the next restart of an exited stdio
+	// server. It carries over restarts until a session stays up for
+	// stdioStableUptime.
+	stdioBackoff time.Duration
+	// orgSessions holds the sessions opened with org headers; session is
+	// the one for calls without org context.
+	orgSessions orgSessionPool
+	// hasReadResourceTool is set when ListTools added the synthetic
+	// read_resource tool, so CallTool knows to serve it locally.
+	hasReadResourceTool bool
+	// toolsFetchedAt is when tools was last fetched from the server.
+	toolsFetchedAt time.Time
+	// toolsStale is set by InvalidateTools. It is atomic so list_changed
+	// notifications, which the SDK delivers while a request may hold c.mu,
+	// never block on it.
+	toolsStale atomic.Bool
+	// breaker hides the server's tools and fails its calls fast while it
+	// is unreachable.
+	breaker CircuitBreaker
+	// calls routes progress and log notifications and sampling and
+	// elicitation requests to in-flight calls.
+	calls callRegistry
 }
 
 // customRoundTripper wraps http.RoundTripper to add custom headers
@@ -94,13 +123,14 @@ func NewClient(parent context.Context, config ServerConfig, logger log.Logger, h
 		operationMetadata: make(map[string]OperationMetadata),
 		ctx:               ctx,
 		cancel:            cancel,
-		httpClient:        httpClient,
+		httpClient:        withOAuth(httpClient, config, logger),
 	}
 }
 
 // Close closes the MCP client session
 func (c *Client) Close() error {
 	c.cancel()
+	c.closeOrgSessions()
 	if c.session != nil {
 		return c.session.Close()
 	}
@@ -111,7 +141,11 @@ func (c *Client) Close() error {
 func (c *Client) connectMCP() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.connectMCPLocked()
+}
 
+// connectMCPLocked is connectMCP for callers that already hold c.mu.
+func (c *Client) connectMCPLocked() error {
 	if c.session != nil {
 		return nil // Already connected
 	}
@@ -120,7 +154,7 @@ func (c *Client) connectMCP() error {
 	c.mcpClient = mcpsdk.NewClient(&mcpsdk.Implementation{
 		Name:    "consensys-asko11y-app",
 		Version: "1.0.0",
-	}, nil)
+	}, c.clientOptions())
 
 	httpClient := c.httpClientWithHeaders()
 
@@ -140,6 +174,11 @@ func (c *Client) connectMCP() error {
 			MaxRetries:           3,
 			DisableStandaloneSSE: true,
 		}
+	case "stdio":
+		if c.config.Command == "" {
+			return fmt.Errorf("stdio MCP server %s has no command configured", c.config.ID)
+		}
+		transport = c.stdioTransport()
 	case "standard":
 		// For standard MCP, we'll use SSE as fallback or custom implementation
 		// The SDK doesn't have a generic HTTP JSON-RPC transport
@@ -158,7 +197,11 @@ func (c *Client) connectMCP() error {
 	if err != nil {
 		return fmt.Errorf("failed to connect to MCP server: %w", err)
 	}
+	c.enableServerLogging(connectCtx, c.session)
 	c.sessionCreatedAt = time.Now()
+	if c.config.Type == "stdio" {
+		go c.superviseStdio(c.session)
+	}
 
 	c.logger.Debug("Connected to MCP server", "type", c.config.Type, "url", c.config.URL)
 	return nil
@@ -200,14 +243,27 @@ func (c *Client) baseTransport() http.RoundTripper {
 }
 
 func (c *Client) sdkHTTPClientWithTransport(transport http.RoundTripper) *http.Client {
-	client := *c.httpClient
+	client := c.callHTTPClient()
 	client.Transport = transport
+	return client
+}
+
+// callHTTPClient returns a copy of the shared HTTP client without its overall
+// request timeout. Tool calls (and the sessions that carry them) are bounded by
+// their per-call context deadline instead, so a tool timeout configured above
+// the shared client's limit is honoured rather than silently capped.
+func (c *Client) callHTTPClient() *http.Client {
+	if c.httpClient == nil {
+		return &http.Client{}
+	}
+	client := *c.httpClient
+	client.Timeout = 0
 	return &client
 }
 
 func (c *Client) httpClientWithHeaders() *http.Client {
 	if len(c.config.Headers) == 0 {
-		return c.httpClient
+		return c.callHTTPClient()
 	}
 	return c.sdkHTTPClientWithTransport(&configHeaderRoundTripper{
 		base:    c.baseTransport(),
@@ -232,28 +288,15 @@ func (t *configHeaderRoundTripper) RoundTrip(req *http.Request) (*http.Response,
 	return t.base.RoundTrip(req)
 }
 
-// connectMCPWithOrgContext establishes a connection to an MCP server with a custom HTTP client that includes org headers.
-// This function always forces a reconnection to ensure the org headers are applied, even if a session already exists.
+// connectOrgSession opens a new session whose requests carry the org headers.
 // Headers forwarded to all MCP servers:
 //   - X-Grafana-Org-Id: Grafana's numeric organization ID
 //   - X-Scope-OrgID: Tenant identifier (scopeOrgId takes priority over orgName)
-func (c *Client) connectMCPWithOrgContext(orgID string, orgName string, scopeOrgId string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Always close existing session to ensure we reconnect with the new org context headers.
-	// This prevents race conditions where a stale session without org headers could be reused.
-	if c.session != nil {
-		c.logger.Debug("Closing existing session to reconnect with org context", "orgID", orgID, "orgName", orgName, "scopeOrgId", scopeOrgId)
-		c.session.Close()
-		c.session = nil
-	}
-
-	// Create MCP client
-	c.mcpClient = mcpsdk.NewClient(&mcpsdk.Implementation{
+func (c *Client) connectOrgSession(orgID string, orgName string, scopeOrgId string) (*mcpsdk.ClientSession, error) {
+	mcpClient := mcpsdk.NewClient(&mcpsdk.Implementation{
 		Name:    "consensys-asko11y-app",
 		Version: "1.0.0",
-	}, nil)
+	}, c.clientOptions())
 
 	customHTTPClient := c.sdkHTTPClientWithTransport(&customRoundTripper{
 		base:       c.baseTransport(),
@@ -264,7 +307,6 @@ func (c *Client) connectMCPWithOrgContext(orgID string, orgName string, scopeOrg
 	})
 
 	var transport mcpsdk.Transport
-	var err error
 
 	switch c.config.Type {
 	case "sse":
@@ -280,35 +322,87 @@ func (c *Client) connectMCPWithOrgContext(orgID string, orgName string, scopeOrg
 			DisableStandaloneSSE: true,
 		}
 	case "standard":
-		return fmt.Errorf("standard MCP type requires custom implementation")
-	case "openapi":
-		return nil
+		return nil, fmt.Errorf("standard MCP type requires custom implementation")
 	default:
-		return fmt.Errorf("unsupported MCP transport type: %s", c.config.Type)
+		return nil, fmt.Errorf("unsupported MCP transport type: %s", c.config.Type)
 	}
 
 	connectCtx, connectCancel := context.WithTimeout(c.ctx, connectDialTimeout)
 	defer connectCancel()
 
-	c.session, err = c.mcpClient.Connect(connectCtx, transport, nil)
+	session, err := mcpClient.Connect(connectCtx, transport, nil)
 	if err != nil {
-		return fmt.Errorf("failed to connect to MCP server with org context: %w", err)
+		return nil, fmt.Errorf("failed to connect to MCP server with org context: %w", err)
 	}
-	c.sessionCreatedAt = time.Now()
+	c.enableServerLogging(connectCtx, session)
 
 	c.logger.Debug("Connected to MCP server with org context", "type", c.config.Type, "url", c.config.URL, "orgID", orgID, "orgName", orgName, "scopeOrgId", scopeOrgId)
-	return nil
+	return session, nil
+}
+
+// orgSession returns the session to use for a call with the given org
+// context: the pooled session for that org, or the client's own session for
+// calls without org context. The caller calls release once it is done with
+// the session.
+func (c *Client) orgSession(orgID string, orgName string, scopeOrgId string) (session *mcpsdk.ClientSession, release func(), err error) {
+	orgID, orgName, scopeOrgId = c.sessionOrgContext(orgID, orgName, scopeOrgId)
+	if orgID != "" || orgName != "" || scopeOrgId != "" {
+		return c.ensureOrgSession(orgID, orgName, scopeOrgId)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connectMCPLocked(); err != nil {
+		return nil, nil, err
+	}
+	return c.session, func() {}, nil
 }
 
-// ListTools fetches tools from the MCP server
+// reconnectSession replaces failed with a fresh session carrying the same org
+// context. If another goroutine already replaced it, that session is returned
+// instead of being torn down again.
+func (c *Client) reconnectSession(failed *mcpsdk.ClientSession, orgID string, orgName string, scopeOrgId string) (session *mcpsdk.ClientSession, release func(), err error) {
+	orgID, orgName, scopeOrgId = c.sessionOrgContext(orgID, orgName, scopeOrgId)
+	if orgID != "" || orgName != "" || scopeOrgId != "" {
+		return c.reconnectOrgSession(failed, orgID, orgName, scopeOrgId)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session != nil && c.session != failed {
+		return c.session, func() {}, nil
+	}
+	if c.session != nil {
+		c.session.Close()
+		c.session = nil
+	}
+	if err := c.connectMCPLocked(); err != nil {
+		return nil, nil, err
+	}
+	return c.session, func() {}, nil
+}
+
+// ListTools returns the server's tools, fetching them again when the cache
+// was invalidated. If a refresh fails the previous tools are kept.
 func (c *Client) ListTools() ([]Tool, error) {
+	tools, err := c.listTools()
+	if tools != nil {
+		return tools, nil
+	}
+	return nil, err
+}
+
+// listTools is ListTools that also reports a failed refresh alongside the
+// previous tools, so health checks see a server that stopped answering.
+func (c *Client) listTools() ([]Tool, error) {
+	// Clear the flag before fetching so a change announced mid-fetch
+	// triggers another refresh.
+	stale := c.toolsStale.Swap(false)
 	c.mu.RLock()
-	if c.tools != nil {
-		cached := c.tools
-		c.mu.RUnlock()
+	cached := c.tools
+	c.mu.RUnlock()
+	if cached != nil && !stale {
 		return cached, nil
 	}
-	c.mu.RUnlock()
 
 	var tools []Tool
 	var err error
@@ -316,7 +410,7 @@ func (c *Client) ListTools() ([]Tool, error) {
 	switch c.config.Type {
 	case "openapi":
 		tools, err = c.listOpenAPITools()
-	case "sse", "streamable-http", "http+streamable":
+	case "sse", "streamable-http", "http+streamable", "stdio":
 		tools, err = c.listMCPTools()
 	default:
 		// Fallback to standard MCP protocol
@@ -324,16 +418,31 @@ func (c *Client) ListTools() ([]Tool, error) {
 	}
 
 	if err != nil {
+		if cached != nil {
+			c.logger.Warn("Failed to refresh tools, keeping previous list", "server", c.config.ID, "error", sanitizeError(err))
+			c.mu.Lock()
+			c.toolsFetchedAt = time.Now()
+			c.mu.Unlock()
+			return cached, err
+		}
 		return nil, err
 	}
 
+	resourceTool, hasResourceTool := c.readResourceTool(tools)
+	if hasResourceTool {
+		tools = append(tools, resourceTool)
+	}
+
 	// Prefix tool names with server ID to avoid conflicts
 	for i := range tools {
 		tools[i].Name = fmt.Sprintf("%s_%s", c.config.ID, tools[i].Name)
+		tools[i].ServerID = c.config.ID
 	}
 
 	c.mu.Lock()
 	c.tools = tools
+	c.toolsFetchedAt = time.Now()
+	c.hasReadResourceTool = hasResourceTool
 	c.mu.Unlock()
 
 	return tools, nil
@@ -395,6 +504,62 @@ func schemaToMap(schema interface{}) map[string]interface{} {
 	return normalizeJSONSchema(out)
 }
 
+// ping checks that an SDK session still answers. Other server types are
+// probed by listing their tools.
+func (c *Client) ping() error {
+	switch c.config.Type {
+	case "openapi":
+		return c.pingOpenAPI()
+	case "sse", "streamable-http", "http+streamable", "stdio":
+	default:
+		return nil
+	}
+	if err := c.connectMCP(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+	if session == nil {
+		return fmt.Errorf("no MCP session")
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, pingTimeout)
+	defer cancel()
+	if err := session.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// pingTimeout bounds the health-check ping of an MCP session.
+const pingTimeout = 5 * time.Second
+
+// pingOpenAPI checks that an OpenAPI server still serves its spec. Its tools
+// usually come from cache, so the health check wouldn't notice it going down
+// otherwise.
+func (c *Client) pingOpenAPI() error {
+	ctx, cancel := context.WithTimeout(c.ctx, pingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.openAPISpecURL(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping failed: OpenAPI spec returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // listMCPTools lists tools using the MCP SDK
 func (c *Client) listMCPTools() ([]Tool, error) {
 	if err := c.connectMCP(); err != nil {
@@ -443,22 +608,79 @@ func (c *Client) listMCPTools() ([]Tool, error) {
 }
 
 // CallTool calls a tool on the MCP server
-func (c *Client) CallTool(toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
-	return c.CallToolWithContext(toolName, arguments, "", "", "")
+func (c *Client) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
+	return c.CallToolWithContext(ctx, toolName, arguments, "", "", "")
 }
 
-func (c *Client) CallToolWithContext(toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+// CallToolWithContext calls a tool with org headers forwarded. The call is
+// aborted when ctx is done, when the tool's configured timeout elapses, or when
+// the client is closed, whichever comes first.
+func (c *Client) CallToolWithContext(ctx context.Context, toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
 	// Remove server ID prefix from tool name
 	originalName := strings.TrimPrefix(toolName, c.config.ID+"_")
 
+	ctx, cancel := c.toolCallContext(ctx, originalName)
+	defer cancel()
+
+	result, err := c.dispatchToolCall(ctx, originalName, arguments, orgID, orgName, scopeOrgId)
+	if err != nil && context.Cause(ctx) == context.DeadlineExceeded {
+		// The call context reports a suspendable timeout as a cancellation
+		// with a cause; surface it as the deadline it is.
+		return nil, fmt.Errorf("%s timed out after %s: %w", originalName, c.toolCallTimeout(originalName), context.DeadlineExceeded)
+	}
+	return result, err
+}
+
+func (c *Client) dispatchToolCall(ctx context.Context, originalName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+	c.mu.RLock()
+	syntheticRead := c.hasReadResourceTool && originalName == readResourceToolName
+	c.mu.RUnlock()
+	if syntheticRead {
+		return c.callReadResourceTool(ctx, arguments, orgID, orgName, scopeOrgId)
+	}
+
 	switch c.config.Type {
 	case "openapi":
-		return c.callOpenAPIToolWithContext(originalName, arguments, orgID, orgName, scopeOrgId)
-	case "sse", "streamable-http", "http+streamable":
-		return c.callMCPToolWithContext(originalName, arguments, orgID, orgName, scopeOrgId)
+		return c.callOpenAPIToolWithContext(ctx, originalName, arguments, orgID, orgName, scopeOrgId)
+	case "sse", "streamable-http", "http+streamable", "stdio":
+		return c.callMCPToolWithContext(ctx, originalName, arguments, orgID, orgName, scopeOrgId)
 	default:
 		// Fallback to standard MCP protocol
-		return c.callStandardTool(originalName, arguments)
+		return c.callStandardTool(ctx, originalName, arguments)
+	}
+}
+
+// defaultToolCallTimeout applies when neither ServerConfig.ToolTimeouts nor
+// ServerConfig.ToolTimeoutSeconds sets a budget for a tool.
+const defaultToolCallTimeout = 30 * time.Second
+
+// toolCallTimeout resolves the budget for one call of the unprefixed tool name.
+func (c *Client) toolCallTimeout(toolName string) time.Duration {
+	if secs := c.config.ToolTimeouts[toolName]; secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if c.config.ToolTimeoutSeconds > 0 {
+		return time.Duration(c.config.ToolTimeoutSeconds) * time.Second
+	}
+	return defaultToolCallTimeout
+}
+
+// toolCallContext derives the per-call context: the caller's ctx bounded by the
+// tool timeout, and additionally cancelled if the client itself is closed. The
+// timeout is suspended while the server waits on a sampling or elicitation
+// answer; those waits are bounded by the agent's input timeout instead.
+func (c *Client) toolCallContext(ctx context.Context, toolName string) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := withCallDeadline(ctx, c.toolCallTimeout(toolName))
+	if c.ctx == nil {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(c.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
 	}
 }
 
@@ -492,30 +714,32 @@ func jitteredDuration(base time.Duration, fraction float64) time.Duration {
 
 // callToolOncer is the inner function signature used for retry. Declared as a
 // type so tests can inject a stub without spinning up a streamable-http server.
-type callToolOncer func(toolName string, arguments map[string]interface{}, orgID, orgName, scopeOrgId string) (*CallToolResult, error)
+type callToolOncer func(ctx context.Context, toolName string, arguments map[string]interface{}, orgID, orgName, scopeOrgId string) (*CallToolResult, error)
 
 // callMCPToolWithContext wraps callMCPToolOnce with classification-aware retry.
 // Only ErrKindTransport errors are retried; tool-logic, protocol, and canceled
 // errors are returned immediately. After the last retry the underlying error
 // is wrapped in *TransportError so callers can distinguish transport outages
 // from tool-layer failures and avoid fabricating around missing data.
-func (c *Client) callMCPToolWithContext(toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
-	return c.callMCPToolWithRetry(c.callMCPToolOnce, toolName, arguments, orgID, orgName, scopeOrgId)
+func (c *Client) callMCPToolWithContext(ctx context.Context, toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+	return c.callMCPToolWithRetry(ctx, c.callMCPToolOnce, toolName, arguments, orgID, orgName, scopeOrgId)
 }
 
-func (c *Client) callMCPToolWithRetry(once callToolOncer, toolName string, arguments map[string]interface{}, orgID, orgName, scopeOrgId string) (*CallToolResult, error) {
+// callMCPToolWithRetry shares ctx across every attempt and backoff, so the
+// tool timeout bounds the whole retry sequence rather than each attempt.
+func (c *Client) callMCPToolWithRetry(ctx context.Context, once callToolOncer, toolName string, arguments map[string]interface{}, orgID, orgName, scopeOrgId string) (*CallToolResult, error) {
 	var lastErr error
 	maxAttempts := len(retrySchedule) + 1 // initial try + one retry per backoff slot
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		result, err := once(toolName, arguments, orgID, orgName, scopeOrgId)
+		result, err := once(ctx, toolName, arguments, orgID, orgName, scopeOrgId)
 		if err == nil {
 			return result, nil
 		}
 		lastErr = err
-		if c.ctx != nil && c.ctx.Err() != nil {
-			return nil, c.ctx.Err()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		kind := ClassifyError(c.ctx, err)
+		kind := ClassifyError(ctx, err)
 		if kind != ErrKindTransport {
 			return nil, err
 		}
@@ -529,14 +753,10 @@ func (c *Client) callMCPToolWithRetry(once callToolOncer, toolName string, argum
 			"attempt", attempt+1,
 			"wait_ms", wait.Milliseconds(),
 			"error", sanitizeError(err))
-		if c.ctx != nil {
-			select {
-			case <-time.After(wait):
-			case <-c.ctx.Done():
-				return nil, c.ctx.Err()
-			}
-		} else {
-			time.Sleep(wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 	return nil, &TransportError{Err: lastErr}
@@ -547,41 +767,24 @@ func (c *Client) callMCPToolWithRetry(once callToolOncer, toolName string, argum
 // is preserved here because it reuses the already-locked session path and has
 // been proven safe in production. The outer retry wrapper adds attempts on
 // top — these are two independent reliability layers.
-func (c *Client) callMCPToolOnce(toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
-	// Track whether we're using org context for potential reconnection
-	// Forward org headers to all MCP servers (not just specific ones)
+func (c *Client) callMCPToolOnce(ctx context.Context, toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+	// Use the pooled session carrying the org headers. The session is captured
+	// once so a concurrent reconnect can't swap it out from under this call.
 	useOrgContext := orgID != "" || orgName != "" || scopeOrgId != ""
-
-	// Reconnect with custom HTTP client that includes org headers.
-	// connectMCPWithOrgContext handles closing the existing session atomically to prevent race conditions.
 	if useOrgContext {
 		c.logger.Debug("Calling tool with org context", "server", c.config.ID, "tool", toolName, "orgID", orgID, "orgName", orgName, "scopeOrgId", scopeOrgId)
-
-		if err := c.connectMCPWithOrgContext(orgID, orgName, scopeOrgId); err != nil {
-			c.logger.Error("Failed to connect to server with org context", "server", c.config.ID, "error", sanitizeError(err))
-			return nil, err
-		}
-	} else {
-		if err := c.connectMCP(); err != nil {
-			return nil, err
-		}
 	}
-
-	// Capture session reference safely under lock to prevent race conditions.
-	// Another goroutine could close the session via connectMCPWithOrgContext between
-	// when we established the connection and when we use it.
-	c.mu.RLock()
-	session := c.session
-	c.mu.RUnlock()
-
+	session, release, err := c.orgSession(orgID, orgName, scopeOrgId)
+	if err != nil {
+		c.logger.Error("Failed to connect to server", "server", c.config.ID, "error", sanitizeError(err))
+		return nil, err
+	}
+	defer func() { release() }()
 	if session == nil {
 		return nil, fmt.Errorf("session not established for tool call")
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
-	defer cancel()
-
-	result, err := session.CallTool(ctx, &mcpsdk.CallToolParams{
+	result, err := c.callTool(ctx, session, &mcpsdk.CallToolParams{
 		Name:      toolName,
 		Arguments: arguments,
 	})
@@ -590,41 +793,20 @@ func (c *Client) callMCPToolOnce(toolName string, arguments map[string]interface
 		if strings.Contains(err.Error(), "connection closed") || strings.Contains(err.Error(), "client is closing") {
 			c.logger.Warn("Connection closed, attempting to reconnect", "error", sanitizeError(err), "server", c.config.ID)
 
-			// Try to reconnect - use the same connection method as the original call
-			// to preserve org context headers if they were used
-			var reconnectErr error
-			if useOrgContext {
-				// connectMCPWithOrgContext handles session cleanup atomically
-				reconnectErr = c.connectMCPWithOrgContext(orgID, orgName, scopeOrgId)
-			} else {
-				// Clear the session to force reconnection
-				c.mu.Lock()
-				if c.session != nil {
-					c.session.Close()
-					c.session = nil
-				}
-				c.mu.Unlock()
-				reconnectErr = c.connectMCP()
-			}
-
+			// Reconnect with the same org context, unless a concurrent call
+			// already replaced the failed session.
+			release()
+			newSession, newRelease, reconnectErr := c.reconnectSession(session, orgID, orgName, scopeOrgId)
 			if reconnectErr != nil {
 				c.logger.Error("Failed to reconnect after connection closed", "error", sanitizeError(reconnectErr), "server", c.config.ID)
 				return nil, fmt.Errorf("failed to reconnect: %w", reconnectErr)
 			}
-
-			// Capture session reference safely under lock after reconnection
-			c.mu.RLock()
-			session = c.session
-			c.mu.RUnlock()
-
+			session, release = newSession, newRelease
 			if session == nil {
 				return nil, fmt.Errorf("session not established after reconnection")
 			}
 
-			retryCtx, retryCancel := context.WithTimeout(c.ctx, 30*time.Second)
-			defer retryCancel()
-
-			result, err = session.CallTool(retryCtx, &mcpsdk.CallToolParams{
+			result, err = c.callTool(ctx, session, &mcpsdk.CallToolParams{
 				Name:      toolName,
 				Arguments: arguments,
 			})
@@ -646,44 +828,7 @@ func (c *Client) callMCPToolOnce(toolName string, arguments map[string]interface
 	// Convert SDK result to our CallToolResult type
 	content := make([]ContentBlock, len(result.Content))
 	for i, sdkContent := range result.Content {
-		// The SDK content is an interface, we need to type assert
-		switch c := sdkContent.(type) {
-		case *mcpsdk.TextContent:
-			content[i] = ContentBlock{
-				Type: "text",
-				Text: c.Text,
-			}
-		case *mcpsdk.ImageContent:
-			content[i] = imageContentBlock(c)
-		case *mcpsdk.AudioContent:
-			content[i] = audioContentBlock(c)
-		case *mcpsdk.ResourceLink:
-			content[i] = ContentBlock{
-				Type:        "resource_link",
-				URI:         c.URI,
-				Name:        c.Name,
-				Title:       c.Title,
-				Description: c.Description,
-				MimeType:    c.MIMEType,
-			}
-		case *mcpsdk.EmbeddedResource:
-			block := ContentBlock{Type: "resource"}
-			if c.Resource != nil {
-				block.Resource = map[string]interface{}{
-					"uri":      c.Resource.URI,
-					"mimeType": c.Resource.MIMEType,
-					"text":     c.Resource.Text,
-					"blob":     string(c.Resource.Blob),
-				}
-			}
-			content[i] = block
-		default:
-			// Unknown content type
-			content[i] = ContentBlock{
-				Type: "text",
-				Text: "[Unknown content type]",
-			}
-		}
+		content[i] = contentBlockFromSDK(sdkContent)
 	}
 
 	return &CallToolResult{
@@ -693,6 +838,48 @@ func (c *Client) callMCPToolOnce(toolName string, arguments map[string]interface
 	}, nil
 }
 
+// contentBlockFromSDK converts one SDK content item (tool result or prompt
+// message) to our ContentBlock.
+func contentBlockFromSDK(sdkContent mcpsdk.Content) ContentBlock {
+	switch c := sdkContent.(type) {
+	case *mcpsdk.TextContent:
+		return ContentBlock{
+			Type: "text",
+			Text: c.Text,
+		}
+	case *mcpsdk.ImageContent:
+		return imageContentBlock(c)
+	case *mcpsdk.AudioContent:
+		return audioContentBlock(c)
+	case *mcpsdk.ResourceLink:
+		return ContentBlock{
+			Type:        "resource_link",
+			URI:         c.URI,
+			Name:        c.Name,
+			Title:       c.Title,
+			Description: c.Description,
+			MimeType:    c.MIMEType,
+		}
+	case *mcpsdk.EmbeddedResource:
+		block := ContentBlock{Type: "resource"}
+		if c.Resource != nil {
+			block.Resource = map[string]interface{}{
+				"uri":      c.Resource.URI,
+				"mimeType": c.Resource.MIMEType,
+				"text":     c.Resource.Text,
+				"blob":     string(c.Resource.Blob),
+			}
+		}
+		return block
+	default:
+		// Unknown content type
+		return ContentBlock{
+			Type: "text",
+			Text: "[Unknown content type]",
+		}
+	}
+}
+
 func imageContentBlock(content *mcpsdk.ImageContent) ContentBlock {
 	return ContentBlock{
 		Type:     "image",
@@ -709,8 +896,8 @@ func audioContentBlock(content *mcpsdk.AudioContent) ContentBlock {
 	}
 }
 
-// listOpenAPITools lists tools from an OpenAPI specification
-func (c *Client) listOpenAPITools() ([]Tool, error) {
+// openAPISpecURL is where an OpenAPI server serves its spec.
+func (c *Client) openAPISpecURL() string {
 	specURL := c.config.URL
 	if !strings.HasSuffix(specURL, "/openapi.json") {
 		if strings.HasSuffix(specURL, "/") {
@@ -719,7 +906,12 @@ func (c *Client) listOpenAPITools() ([]Tool, error) {
 			specURL += "/openapi.json"
 		}
 	}
+	return specURL
+}
 
+// listOpenAPITools lists tools from an OpenAPI specification
+func (c *Client) listOpenAPITools() ([]Tool, error) {
+	specURL := c.openAPISpecURL()
 	req, err := http.NewRequest("GET", specURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -785,7 +977,16 @@ func (c *Client) listOpenAPITools() ([]Tool, error) {
 			}
 
 			// Build input schema from parameters and request body
-			schema := c.buildInputSchema(spec, operationMap)
+			schema := c.buildInputSchema(spec, pathItemMap, operationMap)
+
+			var params []OperationParameter
+			for _, param := range c.operationParameters(spec, pathItemMap, operationMap) {
+				name, _ := param["name"].(string)
+				in, _ := param["in"].(string)
+				required, _ := param["required"].(bool)
+				params = append(params, OperationParameter{Name: name, In: in, Required: required})
+			}
+			bodyContentType, _ := c.requestBodySchema(spec, operationMap)
 
 			tools = append(tools, Tool{
 				Name:        operationID,
@@ -797,8 +998,11 @@ func (c *Client) listOpenAPITools() ([]Tool, error) {
 			// Store operation metadata
 			c.mu.Lock()
 			c.operationMetadata[operationID] = OperationMetadata{
-				Path:   path,
-				Method: strings.ToUpper(method),
+				Path:            path,
+				Method:          strings.ToUpper(method),
+				BaseURL:         openAPIServerURL(c.config.URL, specURL, spec, pathItemMap, operationMap),
+				Parameters:      params,
+				BodyContentType: bodyContentType,
 			}
 			c.mu.Unlock()
 		}
@@ -808,64 +1012,46 @@ func (c *Client) listOpenAPITools() ([]Tool, error) {
 }
 
 // buildInputSchema builds an input schema from OpenAPI operation
-func (c *Client) buildInputSchema(spec map[string]interface{}, operation map[string]interface{}) map[string]interface{} {
+func (c *Client) buildInputSchema(spec, pathItem, operation map[string]interface{}) map[string]interface{} {
 	schema := map[string]interface{}{
 		"type":       "object",
 		"properties": make(map[string]interface{}),
 	}
 	var required []string
 
-	// Add parameters (query, path, header)
-	if params, ok := operation["parameters"].([]interface{}); ok {
-		properties := schema["properties"].(map[string]interface{})
-		for _, param := range params {
-			paramMap, ok := param.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			name, _ := paramMap["name"].(string)
-			if paramSchema, ok := paramMap["schema"].(map[string]interface{}); ok {
-				properties[name] = paramSchema
+	// Add parameters (path, query, header, cookie), including path-level ones
+	properties := schema["properties"].(map[string]interface{})
+	for _, paramMap := range c.operationParameters(spec, pathItem, operation) {
+		name, _ := paramMap["name"].(string)
+		if paramSchema, ok := paramMap["schema"].(map[string]interface{}); ok {
+			if desc, ok := paramMap["description"].(string); ok && paramSchema["description"] == nil {
+				withDesc := make(map[string]interface{}, len(paramSchema)+1)
+				for k, v := range paramSchema {
+					withDesc[k] = v
+				}
+				withDesc["description"] = desc
+				paramSchema = withDesc
 			}
+			properties[name] = paramSchema
+		}
 
-			if req, ok := paramMap["required"].(bool); ok && req {
-				required = append(required, name)
-			}
+		if req, ok := paramMap["required"].(bool); ok && req {
+			required = append(required, name)
 		}
 	}
 
-	// Add request body schema
-	if requestBody, ok := operation["requestBody"].(map[string]interface{}); ok {
-		if content, ok := requestBody["content"].(map[string]interface{}); ok {
-			if jsonContent, ok := content["application/json"].(map[string]interface{}); ok {
-				if schemaRef, ok := jsonContent["schema"].(map[string]interface{}); ok {
-					// Resolve $ref if present
-					var bodySchema map[string]interface{}
-					if ref, ok := schemaRef["$ref"].(string); ok {
-						bodySchema = c.resolveRef(spec, ref)
-						if bodySchema == nil {
-							c.logger.Warn("Failed to resolve schema reference", "ref", ref)
-							bodySchema = schemaRef
-						}
-					} else {
-						bodySchema = schemaRef
-					}
-
-					if props, ok := bodySchema["properties"].(map[string]interface{}); ok {
-						properties := schema["properties"].(map[string]interface{})
-						for k, v := range props {
-							properties[k] = v
-						}
-					}
+	// Add request body schema (JSON or form-encoded)
+	if _, bodySchema := c.requestBodySchema(spec, operation); bodySchema != nil {
+		if props, ok := bodySchema["properties"].(map[string]interface{}); ok {
+			for k, v := range props {
+				properties[k] = v
+			}
+		}
 
-					if req, ok := bodySchema["required"].([]interface{}); ok {
-						for _, r := range req {
-							if reqStr, ok := r.(string); ok {
-								required = append(required, reqStr)
-							}
-						}
-					}
+		if req, ok := bodySchema["required"].([]interface{}); ok {
+			for _, r := range req {
+				if reqStr, ok := r.(string); ok {
+					required = append(required, reqStr)
 				}
 			}
 		}
@@ -957,37 +1143,11 @@ func (c *Client) validateOpenAPIArguments(toolName string, arguments map[string]
 	}
 
 	// Get the request body schema
-	requestBody, ok := operation["requestBody"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-
-	content, ok := requestBody["content"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-
-	jsonContent, ok := content["application/json"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-
-	schemaRef, ok := jsonContent["schema"].(map[string]interface{})
-	if !ok {
+	_, schema := c.requestBodySchema(spec, operation)
+	if schema == nil {
 		return nil
 	}
 
-	// Resolve $ref if present
-	var schema map[string]interface{}
-	if ref, ok := schemaRef["$ref"].(string); ok {
-		schema = c.resolveRef(spec, ref)
-		if schema == nil {
-			return fmt.Errorf("failed to resolve schema reference: %s", ref)
-		}
-	} else {
-		schema = schemaRef
-	}
-
 	// Validate required fields
 	if required, ok := schema["required"].([]interface{}); ok {
 		for _, reqField := range required {
@@ -1079,7 +1239,7 @@ func getJSONType(value interface{}) string {
 
 // callOpenAPIToolWithContext calls a tool on an OpenAPI server with additional context (e.g., Org ID, Org Name, Scope Org ID)
 // Org headers are forwarded to all OpenAPI servers - each server can use whichever headers it needs.
-func (c *Client) callOpenAPIToolWithContext(toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+func (c *Client) callOpenAPIToolWithContext(ctx context.Context, toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
 	// Track whether we're using org context
 	useOrgContext := orgID != "" || orgName != "" || scopeOrgId != ""
 
@@ -1110,25 +1270,17 @@ func (c *Client) callOpenAPIToolWithContext(toolName string, arguments map[strin
 		return nil, fmt.Errorf("argument validation failed: %w", err)
 	}
 
-	// Construct the full URL
-	// The config.URL already includes any base path (e.g., http://mcpo:8000/time)
-	// So we only need to append the operation path
-	baseURL := strings.TrimSuffix(c.config.URL, "/")
-	url := baseURL + opMetadata.Path
-
-	// Marshal arguments as request body
-	body, err := json.Marshal(arguments)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+	// Route arguments to the path, query string, headers and body. BaseURL
+	// comes from the spec's servers[] or, without one, config.URL, which
+	// already includes any base path (e.g., http://mcpo:8000/time).
+	if opMetadata.BaseURL == "" {
+		opMetadata.BaseURL = strings.TrimSuffix(c.config.URL, "/")
 	}
-
-	req, err := http.NewRequest(opMetadata.Method, url, bytes.NewReader(body))
+	req, err := newOpenAPIRequest(ctx, opMetadata, arguments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	// Forward org headers to all OpenAPI servers - each server can use whichever headers it needs.
 	// X-Grafana-Org-Id: Grafana's numeric organization ID
 	if orgID != "" {
@@ -1148,7 +1300,7 @@ func (c *Client) callOpenAPIToolWithContext(toolName string, arguments map[strin
 		req.Header.Set(key, value)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.callHTTPClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call tool: %w", err)
 	}
@@ -1187,7 +1339,7 @@ func (c *Client) callOpenAPIToolWithContext(toolName string, arguments map[strin
 }
 
 // callStandardTool calls a tool on a standard MCP server
-func (c *Client) callStandardTool(toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
+func (c *Client) callStandardTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
 	url := c.config.URL
 	if !strings.HasSuffix(url, "/") {
 		url += "/"
@@ -1216,7 +1368,7 @@ func (c *Client) callStandardTool(toolName string, arguments map[string]interfac
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1226,7 +1378,7 @@ func (c *Client) callStandardTool(toolName string, arguments map[string]interfac
 		req.Header.Set(key, value)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.callHTTPClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call tool: %w", err)
 	}
@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// callDeadline is a tool call's timeout that can be suspended while the
+// server waits on someone else: a user answering an elicitation, or the
+// model and the user's approval answering a sampling request. Those waits
+// are bounded by their own timeouts, so they don't count against the tool's
+// budget. Expiry cancels the call's context with context.DeadlineExceeded
+// as the cause.
+type callDeadline struct {
+	cancel context.CancelCauseFunc
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	remaining time.Duration
+	started   time.Time
+	suspended int
+	expired   bool
+}
+
+type callDeadlineKey struct{}
+
+// withCallDeadline derives a context that is cancelled once timeout has
+// elapsed outside suspensions.
+func withCallDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	d := &callDeadline{cancel: cancel, remaining: timeout, started: time.Now()}
+	d.timer = time.AfterFunc(timeout, d.expire)
+	return context.WithValue(ctx, callDeadlineKey{}, d), func() {
+		d.timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func callDeadlineFrom(ctx context.Context) *callDeadline {
+	d, _ := ctx.Value(callDeadlineKey{}).(*callDeadline)
+	return d
+}
+
+func (d *callDeadline) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.suspended > 0 || d.expired {
+		return
+	}
+	d.expired = true
+	d.cancel(context.DeadlineExceeded)
+}
+
+// suspend stops the clock until the returned resume is called. Overlapping
+// suspensions keep it stopped until the last one resumes. A nil deadline
+// suspends nothing.
+func (d *callDeadline) suspend() (resume func()) {
+	if d == nil {
+		return func() {}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired {
+		return func() {}
+	}
+	d.suspended++
+	if d.suspended == 1 {
+		d.timer.Stop()
+		d.remaining -= time.Since(d.started)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(d.resume)
+	}
+}
+
+func (d *callDeadline) resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.suspended--
+	if d.suspended > 0 || d.expired {
+		return
+	}
+	d.started = time.Now()
+	d.timer.Reset(max(d.remaining, 0))
+}
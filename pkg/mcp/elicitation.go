@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ElicitationRequest is a server's request for input from the user, such as
+// a missing parameter or a confirmation, while one of its tools runs.
+type ElicitationRequest struct {
+	ServerID string
+	// ToolName is the prefixed name of the tool call the request serves.
+	ToolName string
+	Message  string
+	// Schema is the JSON schema of the requested form: an object whose
+	// properties are strings, numbers, booleans or enums.
+	Schema map[string]interface{}
+}
+
+// ElicitationResult is the user's answer. Action is "accept", "decline" or
+// "cancel"; Content holds the form values and is only set on accept.
+type ElicitationResult struct {
+	Action  string
+	Content map[string]interface{}
+}
+
+// Elicitor asks the user of a run for the input a server requests.
+type Elicitor interface {
+	Elicit(ctx context.Context, req ElicitationRequest) (*ElicitationResult, error)
+}
+
+type elicitorKey struct{}
+
+// WithElicitor returns a context whose tool calls let their server ask the
+// user for input through e. Elicitation requests that arrive while no such
+// call is in flight are refused.
+func WithElicitor(ctx context.Context, e Elicitor) context.Context {
+	return context.WithValue(ctx, elicitorKey{}, e)
+}
+
+func elicitorFrom(ctx context.Context) Elicitor {
+	e, _ := ctx.Value(elicitorKey{}).(Elicitor)
+	return e
+}
+
+// elicit handles a server's elicitation/create request by handing it to the
+// elicitor of the tool call it serves. Only form elicitation is supported.
+func (c *Client) elicit(ctx context.Context, req *mcpsdk.ElicitRequest) (*mcpsdk.ElicitResult, error) {
+	if req.Params.Mode != "" && req.Params.Mode != "form" {
+		return nil, fmt.Errorf("%s elicitation is not supported", req.Params.Mode)
+	}
+	call, err := c.calls.soleCall(req.Session, "elicitation", func(call inflightCall) bool { return call.elicitor != nil })
+	if err != nil {
+		c.logger.Warn("Refused MCP elicitation request", "server", c.config.ID, "error", err)
+		return nil, err
+	}
+
+	schema, err := elicitationSchema(req.Params.RequestedSchema)
+	if err != nil {
+		return nil, err
+	}
+	resume := call.deadline.suspend()
+	result, err := call.elicitor.Elicit(ctx, ElicitationRequest{
+		ServerID: c.config.ID,
+		ToolName: call.toolName,
+		Message:  req.Params.Message,
+		Schema:   schema,
+	})
+	resume()
+	if err != nil {
+		c.logger.Warn("MCP elicitation request failed", "server", c.config.ID, "tool", call.toolName, "error", err)
+		return nil, err
+	}
+	return &mcpsdk.ElicitResult{Action: result.Action, Content: result.Content}, nil
+}
+
+// elicitationSchema returns the requested schema in its JSON form.
+func elicitationSchema(schema any) (map[string]interface{}, error) {
+	if schema == nil {
+		return map[string]interface{}{"type": "object"}, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid elicitation schema: %w", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid elicitation schema: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type fakeElicitor struct {
+	got    []ElicitationRequest
+	result ElicitationResult
+	delay  time.Duration
+}
+
+func (e *fakeElicitor) Elicit(ctx context.Context, req ElicitationRequest) (*ElicitationResult, error) {
+	e.got = append(e.got, req)
+	select {
+	case <-time.After(e.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	result := e.result
+	return &result, nil
+}
+
+// newElicitationMCPServer serves a tool that asks which namespace to query and
+// returns the answer, or the elicitation error, as its text.
+func newElicitationMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "k8s", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "restart"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		res, err := req.Session.Elicit(ctx, &mcpsdk.ElicitParams{
+			Message: "Which namespace?",
+			RequestedSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"namespace": map[string]any{"type": "string"}},
+				"required":   []string{"namespace"},
+			},
+		})
+		if err != nil {
+			return &mcpsdk.CallToolResult{IsError: true, Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: err.Error()}}}, nil, nil
+		}
+		text := res.Action
+		if res.Action == "accept" {
+			text = fmt.Sprintf("%s %v", res.Action, res.Content["namespace"])
+		}
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: text}}}, nil, nil
+	})
+	return newStreamableTestServer(t, server)
+}
+
+func TestCallToolServesElicitationThroughElicitor(t *testing.T) {
+	ts := newElicitationMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "k8s", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	elicitor := &fakeElicitor{result: ElicitationResult{Action: "accept", Content: map[string]interface{}{"namespace": "payments"}}}
+	result, err := c.CallToolWithContext(WithElicitor(context.Background(), elicitor), "k8s_restart", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || result.Content[0].Text != "accept payments" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(elicitor.got) != 1 {
+		t.Fatalf("expected one elicitation request, got %d", len(elicitor.got))
+	}
+	got := elicitor.got[0]
+	if got.ServerID != "k8s" || got.ToolName != "k8s_restart" || got.Message != "Which namespace?" {
+		t.Errorf("unexpected request: %+v", got)
+	}
+	if props, _ := got.Schema["properties"].(map[string]interface{}); props["namespace"] == nil {
+		t.Errorf("expected the requested schema, got %+v", got.Schema)
+	}
+}
+
+func TestCallToolPassesElicitationDecline(t *testing.T) {
+	ts := newElicitationMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "k8s", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	elicitor := &fakeElicitor{result: ElicitationResult{Action: "decline"}}
+	result, err := c.CallToolWithContext(WithElicitor(context.Background(), elicitor), "k8s_restart", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || result.Content[0].Text != "decline" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestCallToolTimeoutExcludesElicitationWait(t *testing.T) {
+	ts := newElicitationMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "k8s", URL: ts.URL, Type: "streamable-http", Enabled: true, ToolTimeouts: map[string]int{"restart": 1}}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	// The user answers well after the tool's one second budget.
+	elicitor := &fakeElicitor{result: ElicitationResult{Action: "accept", Content: map[string]interface{}{"namespace": "payments"}}, delay: 1500 * time.Millisecond}
+	result, err := c.CallToolWithContext(WithElicitor(context.Background(), elicitor), "k8s_restart", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || result.Content[0].Text != "accept payments" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestCallToolRefusesElicitationWithoutElicitor(t *testing.T) {
+	ts := newElicitationMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "k8s", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	result, err := c.CallToolWithContext(context.Background(), "k8s_restart", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "elicitation is only available while a tool call is running") {
+		t.Fatalf("expected elicitation to be refused, got %+v", result)
+	}
+}
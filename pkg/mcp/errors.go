@@ -19,6 +19,8 @@ const (
 	ErrKindTool      ErrorKind = "tool"
 	ErrKindProtocol  ErrorKind = "protocol"
 	ErrKindCanceled  ErrorKind = "canceled"
+	// ErrKindSchema marks a result that violates the tool's output schema.
+	ErrKindSchema ErrorKind = "schema"
 )
 
 // TransportError wraps an underlying network/transport error exhausted after
@@ -42,6 +44,21 @@ func (e *TransportError) Unwrap() error {
 	return e.Err
 }
 
+// OutputSchemaError reports a tool result whose structured content does not
+// match the output schema the tool declared.
+type OutputSchemaError struct {
+	Tool string
+	Err  error
+}
+
+func (e *OutputSchemaError) Error() string {
+	return "output of " + e.Tool + " does not match its output schema: " + e.Err.Error()
+}
+
+func (e *OutputSchemaError) Unwrap() error {
+	return e.Err
+}
+
 // transportSubstrings covers SDK-wrapped errors where errors.Is/As doesn't
 // reach the root cause. Keep lowercase; we lowercase the error string too.
 var transportSubstrings = []string{
@@ -79,6 +96,15 @@ func ClassifyError(ctx context.Context, err error) ErrorKind {
 		return ErrKindCanceled
 	}
 
+	if errors.Is(err, ErrCircuitOpen) {
+		return ErrKindTransport
+	}
+
+	var schemaErr *OutputSchemaError
+	if errors.As(err, &schemaErr) {
+		return ErrKindSchema
+	}
+
 	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 		return ErrKindTransport
 	}
@@ -40,6 +40,7 @@ func TestClassifyError(t *testing.T) {
 		{"forbidden", bgCtx, errors.New("forbidden"), ErrKindProtocol},
 		{"method not found", bgCtx, errors.New("jsonrpc error: method not found"), ErrKindProtocol},
 		{"unknown error defaults to protocol", bgCtx, errors.New("something weird"), ErrKindProtocol},
+		{"output schema violation", bgCtx, &OutputSchemaError{Tool: "svc_q", Err: errors.New("count: expected integer, got string")}, ErrKindSchema},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
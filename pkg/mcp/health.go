@@ -33,6 +33,9 @@ type ServerHealth struct {
 	LastError           string       `json:"lastError,omitempty"`
 	Tools               []Tool       `json:"tools"`
 	ToolCount           int          `json:"toolCount"`
+	// Breaker is the server's circuit breaker state. Tools of servers with
+	// an open breaker are hidden from the agent.
+	Breaker BreakerState `json:"breaker"`
 }
 
 // HealthMonitor monitors the health of MCP servers
@@ -132,10 +135,22 @@ func (hm *HealthMonitor) CheckServerNow(serverID string) {
 func (hm *HealthMonitor) checkServer(serverID string, client *Client) {
 	startTime := time.Now()
 
-	// List tools to check server health
-	tools, err := client.ListTools()
+	// List tools to check server health, refreshing an expired catalog
+	client.expireTools(hm.proxy.getCatalogTTL())
+	tools, err := client.listTools()
+	if err == nil {
+		// Tools are usually served from cache, so also check the session.
+		err = client.ping()
+	}
+	client.checkOrgSessions()
 	responseTime := time.Since(startTime).Milliseconds()
 
+	if err != nil {
+		client.breaker.RecordFailure()
+	} else {
+		client.breaker.RecordSuccess()
+	}
+
 	needReconnect := false
 	serverName := ""
 
@@ -205,6 +220,7 @@ func (hm *HealthMonitor) checkServer(serverID string, client *Client) {
 				"responseTime", responseTime,
 				"toolCount", len(tools))
 		}
+		setServerStatusMetric(serverID, health.Status)
 	}()
 
 	// Do the potentially-blocking reconnect outside the health-map lock to keep
@@ -220,12 +236,22 @@ func (hm *HealthMonitor) checkServer(serverID string, client *Client) {
 
 // GetAllHealth returns the health status of all servers
 func (hm *HealthMonitor) GetAllHealth() []ServerHealth {
+	breakers := make(map[string]BreakerState)
+	for _, client := range hm.proxy.snapshotClients() {
+		breakers[client.config.ID] = client.breaker.State()
+	}
+
 	hm.mu.RLock()
 	defer hm.mu.RUnlock()
 
 	result := make([]ServerHealth, 0, len(hm.health))
 	for _, health := range hm.health {
-		result = append(result, *health)
+		h := *health
+		h.Breaker = breakers[h.ServerID]
+		if h.Breaker == "" {
+			h.Breaker = BreakerClosed
+		}
+		result = append(result, h)
 	}
 
 	return result
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// serverStatuses lists every ServerStatus so the status gauge can zero the
+// series a server has left.
+var serverStatuses = []ServerStatus{StatusHealthy, StatusDegraded, StatusUnhealthy, StatusDisconnected, StatusConnecting}
+
+var (
+	// toolCallDuration times proxied tool calls by server, tool and outcome.
+	// Server IDs come from admin configuration; tool names are only used when
+	// the server advertised them, so both labels stay bounded.
+	toolCallDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "asko11y_mcp_tool_call_duration_seconds",
+			Help:    "Latency of MCP tool calls by server, tool and outcome (ok, tool_error, or the error kind).",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"server", "tool", "outcome"},
+	)
+
+	// unknownServerToolCalls counts tool calls naming a server the proxy
+	// doesn't have. They never reach a server, so they are kept out of
+	// toolCallDuration, and the server ID is not a label because callers
+	// choose it.
+	unknownServerToolCalls = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "asko11y_mcp_unknown_server_tool_calls_total",
+			Help: "MCP tool calls whose name did not match a configured server.",
+		},
+	)
+
+	// serverStatus is 1 for each server's current health status and 0 for
+	// the others.
+	serverStatus = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "asko11y_mcp_server_status",
+			Help: "Current MCP server health status (1 for the active status, 0 otherwise).",
+		},
+		[]string{"server", "status"},
+	)
+
+	// sessionPoolRequests counts org session lookups that reused a pooled
+	// session (hit) or had to open one (miss).
+	sessionPoolRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_mcp_session_pool_requests_total",
+			Help: "MCP org session pool lookups by server and result (hit or miss).",
+		},
+		[]string{"server", "result"},
+	)
+
+	// sessionPoolEvictions counts pooled sessions closed because they were
+	// idle, least recently used when the pool was full, or unhealthy.
+	sessionPoolEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "asko11y_mcp_session_pool_evictions_total",
+			Help: "MCP org sessions closed by the pool by server and reason (idle, capacity or unhealthy).",
+		},
+		[]string{"server", "reason"},
+	)
+
+	// sessionPoolSize is the number of org sessions each server's pool holds.
+	sessionPoolSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "asko11y_mcp_session_pool_sessions",
+			Help: "Open MCP org sessions per server.",
+		},
+		[]string{"server"},
+	)
+)
+
+// toolCallOutcome classifies a tool call result for the outcome label.
+func toolCallOutcome(result *CallToolResult, kind ErrorKind) string {
+	switch {
+	case kind != "":
+		return string(kind)
+	case result != nil && result.IsError:
+		return "tool_error"
+	default:
+		return "ok"
+	}
+}
+
+// toolLabel returns toolName if the client has advertised it, "unknown"
+// otherwise, so LLM-invented names can't grow the series.
+func (c *Client) toolLabel(toolName string) string {
+	if _, ok := c.cachedTool(toolName); ok {
+		return toolName
+	}
+	return "unknown"
+}
+
+func setServerStatusMetric(serverID string, status ServerStatus) {
+	for _, s := range serverStatuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		serverStatus.WithLabelValues(serverID, string(s)).Set(value)
+	}
+}
+
+func deleteServerMetrics(serverID string) {
+	serverStatus.DeletePartialMatch(prometheus.Labels{"server": serverID})
+	sessionPoolRequests.DeletePartialMatch(prometheus.Labels{"server": serverID})
+	sessionPoolEvictions.DeletePartialMatch(prometheus.Labels{"server": serverID})
+	sessionPoolSize.DeletePartialMatch(prometheus.Labels{"server": serverID})
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestToolCallOutcome(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *CallToolResult
+		kind   ErrorKind
+		want   string
+	}{
+		{"ok", &CallToolResult{}, "", "ok"},
+		{"tool error result", &CallToolResult{IsError: true}, "", "tool_error"},
+		{"transport error", nil, ErrKindTransport, "transport"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolCallOutcome(tt.result, tt.kind); got != tt.want {
+				t.Fatalf("toolCallOutcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerStatusMetric(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(serverStatus)
+	statuses := func() map[string]float64 {
+		t.Helper()
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("Gather failed: %v", err)
+		}
+		got := make(map[string]float64)
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "status" {
+						got[label.GetValue()] = metric.GetGauge().GetValue()
+					}
+				}
+			}
+		}
+		return got
+	}
+
+	setServerStatusMetric("metrics-test", StatusDegraded)
+	setServerStatusMetric("metrics-test", StatusHealthy)
+	got := statuses()
+	if len(got) != len(serverStatuses) || got[string(StatusHealthy)] != 1 || got[string(StatusDegraded)] != 0 {
+		t.Fatalf("statuses = %v, want only healthy set", got)
+	}
+
+	deleteServerMetrics("metrics-test")
+	if got := statuses(); len(got) != 0 {
+		t.Fatalf("statuses after delete = %v, want none", got)
+	}
+}
+
+func TestClientToolLabelRejectsUnknownTools(t *testing.T) {
+	client := NewClient(context.Background(), ServerConfig{ID: "svc"}, log.DefaultLogger, &http.Client{})
+	client.tools = []Tool{{Name: "svc_query"}}
+
+	if got := client.toolLabel("svc_query"); got != "svc_query" {
+		t.Fatalf("toolLabel(known) = %q", got)
+	}
+	if got := client.toolLabel("svc_invented"); got != "unknown" {
+		t.Fatalf("toolLabel(unknown) = %q, want unknown", got)
+	}
+}
+
+func TestUnknownServerCallsStayOutOfLatencyHistogram(t *testing.T) {
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	t.Cleanup(proxy.Close)
+	before := counterValue(t, unknownServerToolCalls)
+
+	result, err := proxy.CallToolWithContext(context.Background(), "missing_query", nil, "", "", "")
+	if err != nil || !result.IsError {
+		t.Fatalf("expected a server not found result, got %+v, %v", result, err)
+	}
+	if got := counterValue(t, unknownServerToolCalls) - before; got != 1 {
+		t.Fatalf("unknown server calls increased by %v, want 1", got)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(toolCallDuration)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "server" && (label.GetValue() == "unknown" || label.GetValue() == "missing") {
+					t.Fatalf("expected no latency samples for unknown servers, got %v", metric)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// AuthTypeOAuth2ClientCredentials is the ServerAuth type for the OAuth 2
+// client-credentials grant.
+const AuthTypeOAuth2ClientCredentials = "oauth2-client-credentials"
+
+// oauthRefreshMargin is how long before expiry a cached token is replaced,
+// so requests in flight never carry a token that expires mid-call.
+const oauthRefreshMargin = 30 * time.Second
+
+// oauthTokenSource fetches and caches client-credentials tokens for one
+// server. Fetches are serialized so concurrent calls share one token.
+type oauthTokenSource struct {
+	auth      ServerAuth
+	serverURL string
+	// client makes token and discovery requests. It must not be the
+	// OAuth-wrapped client.
+	client *http.Client
+	logger log.Logger
+
+	mu       sync.Mutex
+	token    *oauth2.Token
+	tokenURL string
+	resource string
+}
+
+func newOAuthTokenSource(config ServerConfig, client *http.Client, logger log.Logger) *oauthTokenSource {
+	return &oauthTokenSource{
+		auth:      *config.Auth,
+		serverURL: config.URL,
+		client:    client,
+		logger:    logger,
+		tokenURL:  config.Auth.TokenURL,
+		resource:  config.Auth.Resource,
+	}
+}
+
+// Token returns a cached token, fetching a new one when there is none or it
+// expires within oauthRefreshMargin.
+func (s *oauthTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && (s.token.Expiry.IsZero() || time.Until(s.token.Expiry) > oauthRefreshMargin) {
+		return s.token, nil
+	}
+
+	if s.auth.Type != AuthTypeOAuth2ClientCredentials {
+		return nil, fmt.Errorf("unsupported auth type %q", s.auth.Type)
+	}
+	if s.tokenURL == "" {
+		tokenURL, resource, err := discoverTokenEndpoint(ctx, s.client, s.serverURL)
+		if err != nil {
+			return nil, err
+		}
+		s.tokenURL = tokenURL
+		if s.resource == "" {
+			s.resource = resource
+		}
+	}
+
+	cfg := clientcredentials.Config{
+		ClientID:     s.auth.ClientID,
+		ClientSecret: s.auth.ClientSecret,
+		TokenURL:     s.tokenURL,
+		Scopes:       s.auth.Scopes,
+	}
+	if s.resource != "" {
+		cfg.EndpointParams = url.Values{"resource": {s.resource}}
+	}
+	token, err := cfg.Token(context.WithValue(ctx, oauth2.HTTPClient, s.client))
+	if err != nil {
+		return nil, fmt.Errorf("fetch OAuth token: %w", err)
+	}
+	s.token = token
+	s.logger.Debug("Fetched OAuth token for MCP server", "url", s.serverURL, "expiry", token.Expiry)
+	return token, nil
+}
+
+// invalidate drops token if it is still the cached one, so the next Token
+// call fetches a fresh token.
+func (s *oauthTokenSource) invalidate(token *oauth2.Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// discoverTokenEndpoint follows MCP authorization discovery: the server's
+// OAuth protected resource metadata (RFC 9728) names its authorization
+// server, whose metadata (RFC 8414, or OpenID discovery) has the token
+// endpoint. It also returns the resource identifier to request tokens for.
+func discoverTokenEndpoint(ctx context.Context, client *http.Client, serverURL string) (string, string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", "", fmt.Errorf("parse server URL: %w", err)
+	}
+
+	var prm *oauthex.ProtectedResourceMetadata
+	var prmErrs []error
+	for _, metadataURL := range wellKnownURLs(u, "oauth-protected-resource") {
+		var err error
+		prm, err = oauthex.GetProtectedResourceMetadata(ctx, metadataURL, serverURL, client)
+		if err == nil {
+			break
+		}
+		prmErrs = append(prmErrs, err)
+	}
+	if prm == nil {
+		return "", "", fmt.Errorf("discover OAuth protected resource metadata: %w", errors.Join(prmErrs...))
+	}
+	if len(prm.AuthorizationServers) == 0 {
+		return "", "", fmt.Errorf("protected resource metadata lists no authorization servers")
+	}
+
+	issuer := prm.AuthorizationServers[0]
+	iu, err := url.Parse(issuer)
+	if err != nil {
+		return "", "", fmt.Errorf("parse authorization server URL: %w", err)
+	}
+	var asmErrs []error
+	for _, metadataURL := range authServerMetadataURLs(iu) {
+		tokenEndpoint, err := getTokenEndpoint(ctx, client, metadataURL, issuer)
+		if err != nil {
+			asmErrs = append(asmErrs, err)
+			continue
+		}
+		if tokenEndpoint != "" {
+			return tokenEndpoint, prm.Resource, nil
+		}
+	}
+	if len(asmErrs) > 0 {
+		return "", "", fmt.Errorf("discover OAuth authorization server metadata: %w", errors.Join(asmErrs...))
+	}
+	return "", "", fmt.Errorf("no token endpoint found for authorization server %s", issuer)
+}
+
+// wellKnownURLs returns the path-specific and root well-known URLs for u,
+// in the order RFC 8414 and RFC 9728 clients try them.
+func wellKnownURLs(u *url.URL, suffix string) []string {
+	origin := u.Scheme + "://" + u.Host
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		return []string{origin + "/.well-known/" + suffix}
+	}
+	return []string{origin + "/.well-known/" + suffix + path, origin + "/.well-known/" + suffix}
+}
+
+// authServerMetadataURLs returns the metadata URLs to try for the issuer iu:
+// RFC 8414's, which insert the well-known path before the issuer's path,
+// then OpenID Connect Discovery's, which appends it to the issuer, as
+// Keycloak realms and other issuers with a path expect.
+func authServerMetadataURLs(iu *url.URL) []string {
+	oidc := strings.TrimSuffix(iu.String(), "/") + "/.well-known/openid-configuration"
+	return append(wellKnownURLs(iu, "oauth-authorization-server"), oidc)
+}
+
+// getTokenEndpoint fetches authorization server metadata from metadataURL
+// and returns its token endpoint, or "" when there is no metadata there.
+// Unlike oauthex.GetAuthServerMeta it doesn't require PKCE support, which
+// the client-credentials grant doesn't use.
+func getTokenEndpoint(ctx context.Context, client *http.Client, metadataURL, issuer string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", metadataURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: unexpected status %s", metadataURL, resp.Status)
+	}
+	var meta struct {
+		Issuer        string `json:"issuer"`
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&meta); err != nil {
+		return "", fmt.Errorf("%s: decode metadata: %w", metadataURL, err)
+	}
+	if meta.Issuer != issuer {
+		return "", fmt.Errorf("%s: metadata issuer %q does not match issuer URL %q", metadataURL, meta.Issuer, issuer)
+	}
+	if meta.TokenEndpoint == "" {
+		return "", nil
+	}
+	tu, err := url.Parse(meta.TokenEndpoint)
+	if err != nil || (tu.Scheme != "https" && tu.Scheme != "http") {
+		return "", fmt.Errorf("%s: invalid token endpoint %q", metadataURL, meta.TokenEndpoint)
+	}
+	return meta.TokenEndpoint, nil
+}
+
+// oauthRoundTripper adds a bearer token to every request, retrying once with
+// a fresh token when the server answers 401.
+type oauthRoundTripper struct {
+	base   http.RoundTripper
+	tokens *oauthTokenSource
+}
+
+func (t *oauthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// A body that can't be replayed can't be retried.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	t.tokens.invalidate(token)
+	fresh, err := t.tokens.Token(req.Context())
+	if err != nil {
+		return resp, nil
+	}
+	retry := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return t.base.RoundTrip(withBearer(retry, fresh))
+}
+
+func withBearer(req *http.Request, token *oauth2.Token) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	return req
+}
+
+// withOAuth returns httpClient with its transport wrapped to authenticate
+// as config.Auth describes, or httpClient itself when no auth is configured.
+func withOAuth(httpClient *http.Client, config ServerConfig, logger log.Logger) *http.Client {
+	if config.Auth == nil {
+		return httpClient
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *httpClient
+	wrapped.Transport = &oauthRoundTripper{base: base, tokens: newOAuthTokenSource(config, httpClient, logger)}
+	return &wrapped
+}
@@ -0,0 +1,277 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// tokenServer is a local client-credentials token endpoint that hands out
+// numbered tokens.
+type tokenServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	issued    int
+	expiresIn int
+	forms     []map[string]string
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, secret, _ := r.BasicAuth()
+		if id == "" {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" || id != "asko11y" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		ts.mu.Lock()
+		ts.issued++
+		n := ts.issued
+		ts.forms = append(ts.forms, map[string]string{"scope": r.PostForm.Get("scope"), "resource": r.PostForm.Get("resource")})
+		ts.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   ts.expiresIn,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) issuedCount() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.issued
+}
+
+// newOAuthTestClient returns an HTTP client authenticating against ts and
+// the URL of a resource server running handler resource.
+func newOAuthTestClient(t *testing.T, ts *tokenServer, resource http.HandlerFunc) (*http.Client, string) {
+	t.Helper()
+	rs := httptest.NewServer(resource)
+	t.Cleanup(rs.Close)
+	config := ServerConfig{ID: "oauth", URL: rs.URL, Auth: &ServerAuth{
+		Type:         AuthTypeOAuth2ClientCredentials,
+		TokenURL:     ts.URL,
+		ClientID:     "asko11y",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"tools:read"},
+	}}
+	return withOAuth(&http.Client{}, config, log.DefaultLogger), rs.URL
+}
+
+func TestOAuthAcquiresAndReusesToken(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	var mu sync.Mutex
+	var seen []string
+	client, url := newOAuthTestClient(t, ts, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization"))
+		mu.Unlock()
+	})
+
+	for range 3 {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if ts.issuedCount() != 1 {
+		t.Fatalf("expected one token fetch, got %d", ts.issuedCount())
+	}
+	for _, h := range seen {
+		if h != "Bearer token-1" {
+			t.Fatalf("unexpected Authorization headers %q", seen)
+		}
+	}
+	if ts.forms[0]["scope"] != "tools:read" {
+		t.Fatalf("expected configured scope, got %q", ts.forms[0]["scope"])
+	}
+}
+
+func TestOAuthRefreshesTokenBeforeExpiry(t *testing.T) {
+	// Tokens expiring within the refresh margin are never reused.
+	ts := newTokenServer(t, int(oauthRefreshMargin.Seconds())-1)
+	client, url := newOAuthTestClient(t, ts, func(w http.ResponseWriter, r *http.Request) {})
+
+	for range 2 {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if ts.issuedCount() != 2 {
+		t.Fatalf("expected a fresh token per request, got %d fetches", ts.issuedCount())
+	}
+}
+
+func TestOAuthRetriesOnceOn401WithFreshToken(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	var mu sync.Mutex
+	var attempts []string
+	client, url := newOAuthTestClient(t, ts, func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 64)
+		n, _ := r.Body.Read(body)
+		mu.Lock()
+		attempts = append(attempts, r.Header.Get("Authorization")+" "+string(body[:n]))
+		mu.Unlock()
+		// The server revoked the first token.
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	resp, err := client.Post(url, "application/json", strings.NewReader(`{"ping":1}`))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %d", resp.StatusCode)
+	}
+	want := []string{`Bearer token-1 {"ping":1}`, `Bearer token-2 {"ping":1}`}
+	if len(attempts) != 2 || attempts[0] != want[0] || attempts[1] != want[1] {
+		t.Fatalf("got attempts %q, want %q", attempts, want)
+	}
+}
+
+func TestOAuthDoesNotRetryMoreThanOnce(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	calls := 0
+	client, url := newOAuthTestClient(t, ts, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || calls != 2 {
+		t.Fatalf("expected 401 after exactly one retry, got %d after %d calls", resp.StatusCode, calls)
+	}
+}
+
+func TestOAuthDiscoversTokenEndpoint(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"resource":              server.URL + "/mcp",
+			"authorization_servers": []string{server.URL + "/auth"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                           server.URL + "/auth",
+			"authorization_endpoint":           server.URL + "/auth/authorize",
+			"token_endpoint":                   ts.URL,
+			"response_types_supported":         []string{"code"},
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	var gotAuth string
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	})
+
+	client := withOAuth(&http.Client{}, ServerConfig{ID: "oauth", URL: server.URL + "/mcp", Auth: &ServerAuth{
+		Type:         AuthTypeOAuth2ClientCredentials,
+		ClientID:     "asko11y",
+		ClientSecret: "s3cret",
+	}}, log.DefaultLogger)
+	resp, err := client.Get(server.URL + "/mcp")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotAuth != "Bearer token-1" {
+		t.Fatalf("expected discovered token, got %q", gotAuth)
+	}
+	if ts.forms[0]["resource"] != server.URL+"/mcp" {
+		t.Fatalf("expected resource parameter from metadata, got %q", ts.forms[0]["resource"])
+	}
+}
+
+func TestOAuthDiscoversOpenIDConfigurationOfPathIssuer(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer := server.URL + "/realms/ops"
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"resource":              server.URL,
+			"authorization_servers": []string{issuer},
+		})
+	})
+	// Another issuer's metadata at the root must be skipped, not fail
+	// discovery.
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"issuer": server.URL, "token_endpoint": server.URL + "/token"})
+	})
+	// Keycloak-style OpenID discovery, appended to the issuer, without PKCE.
+	mux.HandleFunc("/realms/ops/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"issuer": issuer, "token_endpoint": ts.URL})
+	})
+
+	tokenURL, resource, err := discoverTokenEndpoint(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("discoverTokenEndpoint: %v", err)
+	}
+	if tokenURL != ts.URL || resource != server.URL {
+		t.Fatalf("got token endpoint %q and resource %q", tokenURL, resource)
+	}
+}
+
+func TestOAuthOverridesStaticAuthorizationHeader(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	var gotAuth string
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(rs.Close)
+
+	c := NewClient(t.Context(), ServerConfig{
+		ID:      "oauth",
+		URL:     rs.URL,
+		Headers: map[string]string{"Authorization": "Bearer stale"},
+		Auth:    &ServerAuth{Type: AuthTypeOAuth2ClientCredentials, TokenURL: ts.URL, ClientID: "asko11y", ClientSecret: "s3cret"},
+	}, log.DefaultLogger, &http.Client{})
+	resp, err := c.httpClientWithHeaders().Get(rs.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if gotAuth != "Bearer token-1" {
+		t.Fatalf("expected OAuth token to replace the static header, got %q", gotAuth)
+	}
+}
@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OperationParameter is an OpenAPI parameter of an operation: where its
+// argument goes ("path", "query", "header" or "cookie") and whether it must be
+// present.
+type OperationParameter struct {
+	Name     string
+	In       string
+	Required bool
+}
+
+const (
+	openAPIContentTypeJSON = "application/json"
+	openAPIContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// operationParameters returns the path item's parameters merged with the
+// operation's, with $refs resolved. Operation-level parameters replace
+// path-level ones with the same name and location, as the spec requires.
+func (c *Client) operationParameters(spec, pathItem, operation map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	index := make(map[string]int)
+	for _, source := range []map[string]interface{}{pathItem, operation} {
+		list, _ := source["parameters"].([]interface{})
+		for _, param := range list {
+			paramMap, ok := param.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if ref, ok := paramMap["$ref"].(string); ok {
+				resolved := c.resolveRef(spec, ref)
+				if resolved == nil {
+					c.logger.Warn("Failed to resolve parameter reference", "ref", ref)
+					continue
+				}
+				paramMap = resolved
+			}
+			name, _ := paramMap["name"].(string)
+			in, _ := paramMap["in"].(string)
+			if name == "" {
+				continue
+			}
+			key := in + ":" + name
+			if i, ok := index[key]; ok {
+				params[i] = paramMap
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, paramMap)
+		}
+	}
+	return params
+}
+
+// requestBodySchema returns the media type arguments are sent as and the
+// resolved body schema. JSON is preferred over form encoding; operations with
+// neither (or no requestBody) return "".
+func (c *Client) requestBodySchema(spec, operation map[string]interface{}) (string, map[string]interface{}) {
+	requestBody, ok := operation["requestBody"].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	if ref, ok := requestBody["$ref"].(string); ok {
+		requestBody = c.resolveRef(spec, ref)
+	}
+	content, ok := requestBody["content"].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+
+	contentType := ""
+	for _, candidate := range []string{openAPIContentTypeJSON, openAPIContentTypeForm} {
+		if _, ok := content[candidate]; ok {
+			contentType = candidate
+			break
+		}
+	}
+	if contentType == "" {
+		for mediaType := range content {
+			if strings.HasSuffix(mediaType, "+json") {
+				contentType = mediaType
+				break
+			}
+		}
+	}
+	if contentType == "" {
+		return "", nil
+	}
+
+	media, _ := content[contentType].(map[string]interface{})
+	schemaRef, ok := media["schema"].(map[string]interface{})
+	if !ok {
+		return contentType, nil
+	}
+	if ref, ok := schemaRef["$ref"].(string); ok {
+		if resolved := c.resolveRef(spec, ref); resolved != nil {
+			return contentType, resolved
+		}
+		c.logger.Warn("Failed to resolve schema reference", "ref", ref)
+	}
+	return contentType, schemaRef
+}
+
+// openAPIServerURL returns the base URL operation paths are appended to: the
+// first servers[].url of the operation, path item or spec (most specific
+// wins), with variables set to their defaults and relative URLs resolved
+// against the spec's URL. Without servers it's the configured server URL.
+func openAPIServerURL(configURL, specURL string, spec, pathItem, operation map[string]interface{}) string {
+	fallback := strings.TrimSuffix(strings.TrimSuffix(configURL, "/openapi.json"), "/")
+
+	var server map[string]interface{}
+	for _, source := range []map[string]interface{}{operation, pathItem, spec} {
+		if servers, ok := source["servers"].([]interface{}); ok && len(servers) > 0 {
+			server, _ = servers[0].(map[string]interface{})
+			break
+		}
+	}
+	raw, _ := server["url"].(string)
+	if raw == "" {
+		return fallback
+	}
+	if variables, ok := server["variables"].(map[string]interface{}); ok {
+		for name, variable := range variables {
+			variableMap, _ := variable.(map[string]interface{})
+			if def, ok := variableMap["default"].(string); ok {
+				raw = strings.ReplaceAll(raw, "{"+name+"}", def)
+			}
+		}
+	}
+
+	base, err := url.Parse(specURL)
+	if err != nil {
+		return fallback
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return fallback
+	}
+	return strings.TrimSuffix(base.ResolveReference(ref).String(), "/")
+}
+
+// newOpenAPIRequest builds the HTTP request for an operation call, routing
+// each argument by its parameter's "in" field: path template substitution,
+// query string, header or cookie. Arguments that aren't parameters form the
+// request body, encoded as op.BodyContentType. Operations that declare no
+// body send leftover arguments as query parameters for GET, HEAD, DELETE and
+// OPTIONS and as a JSON body otherwise.
+func newOpenAPIRequest(ctx context.Context, op OperationMetadata, arguments map[string]interface{}) (*http.Request, error) {
+	path := op.Path
+	query := url.Values{}
+	header := http.Header{}
+	var cookies []*http.Cookie
+	claimed := make(map[string]bool, len(op.Parameters))
+
+	for _, param := range op.Parameters {
+		value, ok := arguments[param.Name]
+		if !ok || value == nil {
+			if param.Required && param.In == "path" {
+				return nil, fmt.Errorf("missing required path parameter: %s", param.Name)
+			}
+			continue
+		}
+		claimed[param.Name] = true
+		values := openAPIParamValues(value)
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(strings.Join(values, ",")))
+		case "query":
+			for _, v := range values {
+				query.Add(param.Name, v)
+			}
+		case "header":
+			header.Set(param.Name, strings.Join(values, ","))
+		case "cookie":
+			cookies = append(cookies, &http.Cookie{Name: param.Name, Value: strings.Join(values, ",")})
+		default:
+			claimed[param.Name] = false
+		}
+	}
+
+	body := make(map[string]interface{})
+	for name, value := range arguments {
+		if !claimed[name] {
+			body[name] = value
+		}
+	}
+
+	contentType := op.BodyContentType
+	if contentType == "" && len(body) > 0 {
+		switch op.Method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+			for name, value := range body {
+				for _, v := range openAPIParamValues(value) {
+					query.Add(name, v)
+				}
+			}
+		default:
+			contentType = openAPIContentTypeJSON
+		}
+	}
+
+	var bodyReader io.Reader
+	switch {
+	case contentType == openAPIContentTypeForm:
+		form := url.Values{}
+		for name, value := range body {
+			for _, v := range openAPIParamValues(value) {
+				form.Add(name, v)
+			}
+		}
+		bodyReader = strings.NewReader(form.Encode())
+	case contentType != "":
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+		}
+		bodyReader = bytes.NewReader(encoded)
+	}
+
+	target := strings.TrimSuffix(op.BaseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, op.Method, target, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if bodyReader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// openAPIParamValues formats an argument for a URL, header or form field.
+// Arrays become one value per element (the spec's default "form" explode
+// style); objects are JSON-encoded.
+func openAPIParamValues(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, formatOpenAPIValue(item))
+		}
+		return values
+	}
+	return []string{formatOpenAPIValue(value)}
+}
+
+func formatOpenAPIValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+const routingSpec = `{
+  "openapi": "3.1.0",
+  "servers": [{"url": "/{prefix}/v1", "variables": {"prefix": {"default": "svc"}}}],
+  "paths": {
+    "/items/{id}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/ItemID"}
+      ],
+      "get": {
+        "operationId": "get_item",
+        "parameters": [
+          {"name": "verbose", "in": "query", "schema": {"type": "boolean"}},
+          {"name": "tag", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}},
+          {"name": "X-Trace", "in": "header", "schema": {"type": "string"}}
+        ]
+      }
+    },
+    "/items/{id}/notes": {
+      "post": {
+        "operationId": "add_note",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Note"}}}
+        }
+      }
+    },
+    "/login": {
+      "post": {
+        "operationId": "login",
+        "servers": [{"url": "https://auth.example.com/"}],
+        "requestBody": {
+          "content": {"application/x-www-form-urlencoded": {"schema": {
+            "type": "object",
+            "properties": {"user": {"type": "string"}, "scope": {"type": "array"}},
+            "required": ["user"]
+          }}}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "ItemID": {"name": "id", "in": "path", "required": true, "description": "Item ID", "schema": {"type": "integer"}}
+    },
+    "schemas": {
+      "Note": {"type": "object", "properties": {"text": {"type": "string"}}, "required": ["text"]}
+    }
+  }
+}`
+
+type recordedRequest struct {
+	method, path, rawQuery, contentType, trace, body string
+}
+
+func newRoutingOpenAPIServer(t *testing.T) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(routingSpec))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			rawQuery:    r.URL.RawQuery,
+			contentType: r.Header.Get("Content-Type"),
+			trace:       r.Header.Get("X-Trace"),
+			body:        string(body),
+		})
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestCallOpenAPITool_RoutesPathQueryAndHeaderParameters(t *testing.T) {
+	server, requests := newRoutingOpenAPIServer(t)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	_, err := client.CallToolWithContext(context.Background(), "svc_get_item", map[string]interface{}{
+		"id":      float64(42),
+		"verbose": true,
+		"tag":     []interface{}{"a", "b c"},
+		"X-Trace": "abc",
+	}, "", "", "")
+	if err != nil {
+		t.Fatalf("CallToolWithContext failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("requests = %+v, want 1", got)
+	}
+	want := recordedRequest{method: "GET", path: "/svc/v1/items/42", rawQuery: "tag=a&tag=b+c&verbose=true", trace: "abc"}
+	if got[0] != want {
+		t.Fatalf("request = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestCallOpenAPITool_EncodesJSONAndFormBodies(t *testing.T) {
+	server, requests := newRoutingOpenAPIServer(t)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	if _, err := client.CallToolWithContext(context.Background(), "svc_add_note", map[string]interface{}{
+		"id":   "x/1",
+		"text": "hello",
+	}, "", "", ""); err != nil {
+		t.Fatalf("add_note failed: %v", err)
+	}
+	got := requests()
+	want := recordedRequest{method: "POST", path: "/svc/v1/items/x/1/notes", contentType: "application/json", body: `{"text":"hello"}`}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("requests = %+v, want %+v", got, want)
+	}
+
+	client.mu.RLock()
+	login := client.operationMetadata["login"]
+	client.mu.RUnlock()
+	if login.BaseURL != "https://auth.example.com" || login.BodyContentType != openAPIContentTypeForm {
+		t.Fatalf("login metadata = %+v", login)
+	}
+	req, err := newOpenAPIRequest(context.Background(), login, map[string]interface{}{
+		"user":  "alice",
+		"scope": []interface{}{"read", "write"},
+	})
+	if err != nil {
+		t.Fatalf("newOpenAPIRequest failed: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.URL.String() != "https://auth.example.com/login" || string(body) != "scope=read&scope=write&user=alice" {
+		t.Fatalf("request = %s %q", req.URL, body)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != openAPIContentTypeForm {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+}
+
+func TestCallOpenAPITool_MissingPathParameter(t *testing.T) {
+	server, requests := newRoutingOpenAPIServer(t)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	if _, err := client.CallToolWithContext(context.Background(), "svc_get_item", map[string]interface{}{}, "", "", ""); err == nil {
+		t.Fatal("expected error for missing path parameter")
+	}
+	if got := requests(); len(got) != 0 {
+		t.Fatalf("requests = %+v, want none", got)
+	}
+}
+
+func TestBuildInputSchema_IncludesPathLevelParameters(t *testing.T) {
+	server, _ := newRoutingOpenAPIServer(t)
+	client := NewClient(context.Background(), ServerConfig{ID: "svc", URL: server.URL, Type: "openapi"}, log.DefaultLogger, &http.Client{})
+	tools, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	for _, tool := range tools {
+		if tool.Name != "svc_get_item" {
+			continue
+		}
+		props := tool.InputSchema["properties"].(map[string]interface{})
+		id, ok := props["id"].(map[string]interface{})
+		if !ok || id["type"] != "integer" || id["description"] != "Item ID" {
+			t.Fatalf("id property = %+v", props["id"])
+		}
+		if req, _ := tool.InputSchema["required"].([]string); len(req) != 1 || req[0] != "id" {
+			t.Fatalf("required = %v, want [id]", tool.InputSchema["required"])
+		}
+		return
+	}
+	t.Fatalf("svc_get_item not listed: %+v", tools)
+}
+
+func TestOpenAPIServerURL(t *testing.T) {
+	spec := map[string]interface{}{}
+	tests := []struct {
+		name      string
+		configURL string
+		servers   []interface{}
+		want      string
+	}{
+		{"no servers uses config URL", "http://mcpo:8000/time/", nil, "http://mcpo:8000/time"},
+		{"config URL pointing at spec", "http://mcpo:8000/time/openapi.json", nil, "http://mcpo:8000/time"},
+		{"relative server", "http://mcpo:8000/time", []interface{}{map[string]interface{}{"url": "/time"}}, "http://mcpo:8000/time"},
+		{"absolute server", "http://mcpo:8000/time", []interface{}{map[string]interface{}{"url": "https://api.example.com/v2/"}}, "https://api.example.com/v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.servers != nil {
+				spec["servers"] = tt.servers
+			} else {
+				delete(spec, "servers")
+			}
+			specURL := "http://mcpo:8000/time/openapi.json"
+			if got := openAPIServerURL(tt.configURL, specURL, spec, nil, nil); got != tt.want {
+				t.Fatalf("openAPIServerURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolProgress is a progress update or a server log message received while a
+// tool call runs.
+type ToolProgress struct {
+	Progress float64
+	// Total is zero when the server doesn't know it.
+	Total   float64
+	Message string
+	// Level is the log level of a server log message, and empty for
+	// progress updates.
+	Level  string
+	Logger string
+}
+
+// ProgressFunc receives a tool call's progress. It is called from the
+// session's notification goroutine and must not block for long.
+type ProgressFunc func(ToolProgress)
+
+type progressFuncKey struct{}
+
+// WithProgress returns a context whose tool calls report progress and
+// server log messages to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressFuncKey{}, fn)
+}
+
+func progressFuncFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressFuncKey{}).(ProgressFunc)
+	return fn
+}
+
+// inflightCall is a tool call waiting for its result, with what it wants to
+// receive while it runs.
+type inflightCall struct {
+	session  *mcpsdk.ClientSession
+	toolName string
+	report   ProgressFunc
+	sampler  Sampler
+	elicitor Elicitor
+	deadline *callDeadline
+}
+
+// callRegistry routes a client's notifications and server requests to
+// in-flight calls: progress by the token sent with the call, log messages,
+// sampling and elicitation requests (which the protocol doesn't tie to a
+// request) by the session they arrive on.
+type callRegistry struct {
+	mu       sync.Mutex
+	seq      uint64
+	inflight map[string]inflightCall
+}
+
+func (r *callRegistry) register(call inflightCall) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	token := "asko11y-" + strconv.FormatUint(r.seq, 10)
+	if r.inflight == nil {
+		r.inflight = make(map[string]inflightCall)
+	}
+	r.inflight[token] = call
+	return token
+}
+
+func (r *callRegistry) unregister(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inflight, token)
+}
+
+func (r *callRegistry) onProgress(params *mcpsdk.ProgressNotificationParams) {
+	token, _ := params.ProgressToken.(string)
+	r.mu.Lock()
+	call, ok := r.inflight[token]
+	r.mu.Unlock()
+	if ok && call.report != nil {
+		call.report(ToolProgress{Progress: params.Progress, Total: params.Total, Message: params.Message})
+	}
+}
+
+func (r *callRegistry) onLog(session *mcpsdk.ClientSession, params *mcpsdk.LoggingMessageParams) {
+	var reports []ProgressFunc
+	r.mu.Lock()
+	for _, call := range r.inflight {
+		if call.session == session && call.report != nil {
+			reports = append(reports, call.report)
+		}
+	}
+	r.mu.Unlock()
+	if len(reports) == 0 {
+		return
+	}
+	update := ToolProgress{Message: logMessageText(params.Data), Level: string(params.Level), Logger: params.Logger}
+	for _, report := range reports {
+		report(update)
+	}
+}
+
+func logMessageText(data any) string {
+	if s, ok := data.(string); ok {
+		return s
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// callTool calls the tool on session. Calls whose ctx asks for progress or
+// carries a sampler or elicitor are registered while they run, and send a
+// progress token when they want progress.
+func (c *Client) callTool(ctx context.Context, session *mcpsdk.ClientSession, params *mcpsdk.CallToolParams) (*mcpsdk.CallToolResult, error) {
+	call := inflightCall{
+		session:  session,
+		toolName: c.prefixed(params.Name),
+		report:   progressFuncFrom(ctx),
+		sampler:  samplerFrom(ctx),
+		elicitor: elicitorFrom(ctx),
+		deadline: callDeadlineFrom(ctx),
+	}
+	if call.report != nil || call.sampler != nil || call.elicitor != nil {
+		token := c.calls.register(call)
+		defer c.calls.unregister(token)
+		if call.report != nil {
+			params.SetProgressToken(token)
+		}
+	}
+	return session.CallTool(ctx, params)
+}
+
+// enableServerLogging asks servers that support logging to send info and
+// higher log messages, so they can be shown with the calls that produce
+// them.
+func (c *Client) enableServerLogging(ctx context.Context, session *mcpsdk.ClientSession) {
+	init := session.InitializeResult()
+	if init == nil || init.Capabilities == nil || init.Capabilities.Logging == nil {
+		return
+	}
+	if err := session.SetLoggingLevel(ctx, &mcpsdk.SetLoggingLevelParams{Level: "info"}); err != nil {
+		c.logger.Debug("Failed to enable MCP server logging", "server", c.config.ID, "error", sanitizeError(err))
+	}
+}
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newProgressMCPServer serves a tool that reports progress and logs, then
+// waits for release before returning, like a long-running query. The SDK
+// delivers notifications asynchronously, so a tool that returned at once
+// could finish before its notifications are handled.
+func newProgressMCPServer(t *testing.T, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "loki", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "query"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		if token := req.Params.GetProgressToken(); token != nil {
+			for i := 1; i <= 2; i++ {
+				_ = req.Session.NotifyProgress(ctx, &mcpsdk.ProgressNotificationParams{
+					ProgressToken: token,
+					Progress:      float64(i),
+					Total:         2,
+					Message:       "scanning chunks",
+				})
+			}
+		}
+		_ = req.Session.Log(ctx, &mcpsdk.LoggingMessageParams{Level: "info", Logger: "querier", Data: "split into 4 shards"})
+		_ = req.Session.Log(ctx, &mcpsdk.LoggingMessageParams{Level: "debug", Data: "below the requested level"})
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "done"}}}, nil, nil
+	})
+	return newStreamableTestServer(t, server)
+}
+
+func TestCallToolReportsProgressAndServerLogs(t *testing.T) {
+	release := make(chan struct{})
+	ts := newProgressMCPServer(t, release)
+	c := NewClient(context.Background(), ServerConfig{ID: "loki", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	var mu sync.Mutex
+	var updates []ToolProgress
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = WithProgress(ctx, func(p ToolProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		updates = append(updates, p)
+		if len(updates) == 3 {
+			close(release)
+		}
+	})
+	if _, err := c.CallToolWithContext(ctx, "loki_query", nil, "", "", ""); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []ToolProgress{
+		{Progress: 1, Total: 2, Message: "scanning chunks"},
+		{Progress: 2, Total: 2, Message: "scanning chunks"},
+		{Message: "split into 4 shards", Level: "info", Logger: "querier"},
+	}
+	if !slices.Equal(updates, want) {
+		t.Fatalf("got %+v, want %+v", updates, want)
+	}
+	c.calls.mu.Lock()
+	defer c.calls.mu.Unlock()
+	if len(c.calls.inflight) != 0 {
+		t.Fatalf("expected call to unregister its progress token, %d left", len(c.calls.inflight))
+	}
+}
+
+func TestCallToolWithoutProgressSendsNoToken(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	ts := newProgressMCPServer(t, release)
+	c := NewClient(context.Background(), ServerConfig{ID: "loki", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	if _, err := c.CallToolWithContext(context.Background(), "loki_query", nil, "", "", ""); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if c.calls.seq != 0 {
+		t.Fatal("calls without a progress func must not register a token")
+	}
+}
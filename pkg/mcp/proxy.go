@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -20,14 +21,17 @@ type Proxy struct {
 	mu            sync.RWMutex
 	healthMonitor *HealthMonitor
 	ctx           context.Context
+	// catalogTTL bounds how long each server's tool list is cached.
+	catalogTTL time.Duration
 }
 
 // NewProxy creates a new MCP proxy
 func NewProxy(ctx context.Context, logger log.Logger) *Proxy {
 	p := &Proxy{
-		clients: make(map[string]*Client),
-		logger:  logger,
-		ctx:     ctx,
+		clients:    make(map[string]*Client),
+		logger:     logger,
+		ctx:        ctx,
+		catalogTTL: DefaultToolCatalogTTL,
 	}
 	p.healthMonitor = NewHealthMonitor(p, logger)
 	return p
@@ -71,6 +75,7 @@ func (p *Proxy) UpdateConfig(configs []ServerConfig) error {
 		if _, exists := newConfigs[id]; !exists {
 			stale = append(stale, p.clients[id])
 			delete(p.clients, id)
+			deleteServerMetrics(id)
 			p.logger.Info("Removed MCP client", "id", id)
 		}
 	}
@@ -90,17 +95,29 @@ func (p *Proxy) UpdateConfig(configs []ServerConfig) error {
 	return nil
 }
 
-// ListTools aggregates tools from all configured MCP servers
+// ListTools aggregates tools from all configured MCP servers. Servers whose
+// circuit breaker is open are left out.
 func (p *Proxy) ListTools() ([]Tool, error) {
-	p.mu.RLock()
-	clients := make([]*Client, 0, len(p.clients))
-	for _, client := range p.clients {
+	tools, _, err := p.listTools()
+	return tools, err
+}
+
+// listTools is ListTools that also returns the names of servers left out
+// because their circuit breaker is open.
+func (p *Proxy) listTools() ([]Tool, []string, error) {
+	var clients []*Client
+	var unavailable []string
+	for _, client := range p.snapshotClients() {
+		if client.breaker.State() == BreakerOpen {
+			unavailable = append(unavailable, client.serverLabel())
+			continue
+		}
 		clients = append(clients, client)
 	}
-	p.mu.RUnlock()
+	slices.Sort(unavailable)
 
 	if len(clients) == 0 {
-		return []Tool{}, nil
+		return []Tool{}, unavailable, nil
 	}
 
 	// Fetch tools from all servers concurrently
@@ -109,9 +126,11 @@ func (p *Proxy) ListTools() ([]Tool, error) {
 		err   error
 	}
 
+	ttl := p.getCatalogTTL()
 	results := make(chan result, len(clients))
 	for _, client := range clients {
 		go func(c *Client) {
+			c.expireTools(ttl)
 			tools, err := c.ListTools()
 			results <- result{tools: tools, err: err}
 		}(client)
@@ -132,21 +151,136 @@ func (p *Proxy) ListTools() ([]Tool, error) {
 	}
 
 	if len(allTools) == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("all servers failed: %s", strings.Join(errors, "; "))
+		return nil, unavailable, fmt.Errorf("all servers failed: %s", strings.Join(errors, "; "))
+	}
+
+	// Servers answer in any order; sort so the tools array sent to the LLM is
+	// identical across iterations and runs, which provider prompt caching needs.
+	slices.SortStableFunc(allTools, func(a, b Tool) int { return strings.Compare(a.Name, b.Name) })
+
+	p.logger.Debug("Listed tools from MCP servers", "total", len(allTools), "servers", len(clients), "unavailable", len(unavailable))
+
+	return allTools, unavailable, nil
+}
+
+// snapshotClients returns the current clients without holding p.mu during I/O.
+func (p *Proxy) snapshotClients() []*Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	clients := make([]*Client, 0, len(p.clients))
+	for _, client := range p.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// collectFromClients runs list concurrently against every server. Like
+// ListTools, it only fails when every server that was asked failed.
+func collectFromClients[T any](p *Proxy, what string, list func(*Client) ([]T, error)) ([]T, error) {
+	clients := p.snapshotClients()
+
+	type result struct {
+		items []T
+		err   error
+	}
+	results := make(chan result, len(clients))
+	for _, client := range clients {
+		go func(c *Client) {
+			items, err := list(c)
+			results <- result{items: items, err: err}
+		}(client)
 	}
 
-	p.logger.Debug("Listed tools from MCP servers", "total", len(allTools), "servers", len(clients))
+	all := []T{}
+	var errs []string
+	for range clients {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, sanitizeError(res.err))
+			p.logger.Warn("Failed to list "+what+" from server", "error", sanitizeError(res.err))
+			continue
+		}
+		all = append(all, res.items...)
+	}
+
+	if len(all) == 0 && len(errs) > 0 && len(errs) == len(clients) {
+		return nil, fmt.Errorf("all servers failed: %s", strings.Join(errs, "; "))
+	}
+	return all, nil
+}
 
-	return allTools, nil
+// ListResources aggregates resources from all MCP servers. URIs and names are
+// prefixed with "{serverID}_" like tool names.
+func (p *Proxy) ListResources(ctx context.Context) ([]Resource, error) {
+	return collectFromClients(p, "resources", func(c *Client) ([]Resource, error) {
+		return c.ListResources(ctx)
+	})
+}
+
+// ListResourceTemplates aggregates resource templates from all MCP servers.
+func (p *Proxy) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	return collectFromClients(p, "resource templates", func(c *Client) ([]ResourceTemplate, error) {
+		return c.ListResourceTemplates(ctx)
+	})
+}
+
+// ListPrompts aggregates prompts from all MCP servers.
+func (p *Proxy) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	return collectFromClients(p, "prompts", func(c *Client) ([]Prompt, error) {
+		return c.ListPrompts(ctx)
+	})
+}
+
+// ReadResource routes a read of a prefixed resource URI to its server.
+func (p *Proxy) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	return p.ReadResourceWithContext(ctx, uri, "", "", "")
+}
+
+// ReadResourceWithContext is ReadResource with org headers forwarded.
+func (p *Proxy) ReadResourceWithContext(ctx context.Context, uri string, orgID string, orgName string, scopeOrgId string) (*ReadResourceResult, error) {
+	client, err := p.clientForName(uri)
+	if err != nil {
+		return nil, err
+	}
+	return client.ReadResourceWithContext(ctx, uri, orgID, orgName, scopeOrgId)
+}
+
+// GetPrompt routes a prefixed prompt name to its server and renders it.
+func (p *Proxy) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error) {
+	return p.GetPromptWithContext(ctx, name, arguments, "", "", "")
+}
+
+// GetPromptWithContext is GetPrompt with org headers forwarded.
+func (p *Proxy) GetPromptWithContext(ctx context.Context, name string, arguments map[string]string, orgID string, orgName string, scopeOrgId string) (*GetPromptResult, error) {
+	client, err := p.clientForName(name)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPromptWithContext(ctx, name, arguments, orgID, orgName, scopeOrgId)
+}
+
+func (p *Proxy) clientForName(name string) (*Client, error) {
+	serverID, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return nil, fmt.Errorf("invalid name format: %s (expected serverid_name)", name)
+	}
+	p.mu.RLock()
+	client, exists := p.clients[serverID]
+	p.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("server not found: %s", serverID)
+	}
+	return client, nil
 }
 
 // CallTool routes a tool call to the appropriate MCP server
-func (p *Proxy) CallTool(toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
-	return p.CallToolWithContext(toolName, arguments, "", "", "")
+func (p *Proxy) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*CallToolResult, error) {
+	return p.CallToolWithContext(ctx, toolName, arguments, "", "", "")
 }
 
-// CallToolWithContext routes a tool call to the appropriate MCP server with additional context (e.g., Org ID, Org Name, Scope Org ID)
-func (p *Proxy) CallToolWithContext(toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+// CallToolWithContext routes a tool call to the appropriate MCP server with additional org context (e.g., Org ID, Org Name, Scope Org ID).
+// Cancelling ctx aborts the in-flight call, including any pending retries.
+func (p *Proxy) CallToolWithContext(ctx context.Context, toolName string, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
 	// Extract server ID from tool name prefix
 	parts := strings.SplitN(toolName, "_", 2)
 	if len(parts) < 2 {
@@ -160,6 +294,7 @@ func (p *Proxy) CallToolWithContext(toolName string, arguments map[string]interf
 	p.mu.RUnlock()
 
 	if !exists {
+		unknownServerToolCalls.Inc()
 		return &CallToolResult{
 			Content: []ContentBlock{
 				{
@@ -173,11 +308,30 @@ func (p *Proxy) CallToolWithContext(toolName string, arguments map[string]interf
 
 	p.logger.Debug("Calling tool on MCP server", "tool", toolName, "server", serverID, "orgID", orgID, "orgName", orgName, "scopeOrgId", scopeOrgId)
 
-	return client.CallToolWithContext(toolName, arguments, orgID, orgName, scopeOrgId)
+	if !client.breaker.Allow() {
+		toolCallDuration.WithLabelValues(serverID, client.toolLabel(toolName), "circuit_open").Observe(0)
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, client.serverLabel())
+	}
+
+	start := time.Now()
+	result, err := client.CallToolWithContext(ctx, toolName, arguments, orgID, orgName, scopeOrgId)
+	if err == nil {
+		if tool, ok := client.outputTool(ctx, toolName); ok {
+			if verr := ValidateOutput(tool, result); verr != nil {
+				err = &OutputSchemaError{Tool: toolName, Err: verr}
+			}
+		}
+	}
+	kind := ClassifyError(ctx, err)
+	client.breaker.recordCallOutcome(ctx, err)
+	toolCallDuration.WithLabelValues(serverID, client.toolLabel(toolName), toolCallOutcome(result, kind)).
+		Observe(time.Since(start).Seconds())
+	return result, err
 }
 
-// HandleMCPRequest handles an MCP JSON-RPC request
-func (p *Proxy) HandleMCPRequest(reqData []byte) ([]byte, error) {
+// HandleMCPRequest handles an MCP JSON-RPC request. ctx bounds any tool call
+// the request makes.
+func (p *Proxy) HandleMCPRequest(ctx context.Context, reqData []byte) ([]byte, error) {
 	var req MCPRequest
 	if err := json.Unmarshal(reqData, &req); err != nil {
 		return p.errorResponse(nil, -32700, "Parse error", nil)
@@ -189,7 +343,17 @@ func (p *Proxy) HandleMCPRequest(reqData []byte) ([]byte, error) {
 	case "tools/list":
 		return p.handleListTools(req)
 	case "tools/call":
-		return p.handleCallTool(req)
+		return p.handleCallTool(ctx, req)
+	case "resources/list":
+		return p.handleListResources(ctx, req)
+	case "resources/templates/list":
+		return p.handleListResourceTemplates(ctx, req)
+	case "resources/read":
+		return p.handleReadResource(ctx, req)
+	case "prompts/list":
+		return p.handleListPrompts(ctx, req)
+	case "prompts/get":
+		return p.handleGetPrompt(ctx, req)
 	case "initialize":
 		return p.handleInitialize(req)
 	default:
@@ -201,7 +365,9 @@ func (p *Proxy) handleInitialize(req MCPRequest) ([]byte, error) {
 	result := map[string]interface{}{
 		"protocolVersion": "2024-11-05",
 		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
+			"prompts":   map[string]interface{}{},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "consensys-mcp-proxy",
@@ -225,13 +391,13 @@ func (p *Proxy) handleListTools(req MCPRequest) ([]byte, error) {
 	return p.successResponse(req.ID, result)
 }
 
-func (p *Proxy) handleCallTool(req MCPRequest) ([]byte, error) {
+func (p *Proxy) handleCallTool(ctx context.Context, req MCPRequest) ([]byte, error) {
 	var params CallToolParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return p.errorResponse(req.ID, -32602, "Invalid params", err.Error())
 	}
 
-	result, err := p.CallTool(params.Name, params.Arguments)
+	result, err := p.CallTool(ctx, params.Name, params.Arguments)
 	if err != nil {
 		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
 	}
@@ -239,6 +405,56 @@ func (p *Proxy) handleCallTool(req MCPRequest) ([]byte, error) {
 	return p.successResponse(req.ID, result)
 }
 
+func (p *Proxy) handleListResources(ctx context.Context, req MCPRequest) ([]byte, error) {
+	resources, err := p.ListResources(ctx)
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
+	}
+	return p.successResponse(req.ID, ListResourcesResult{Resources: resources})
+}
+
+func (p *Proxy) handleListResourceTemplates(ctx context.Context, req MCPRequest) ([]byte, error) {
+	templates, err := p.ListResourceTemplates(ctx)
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
+	}
+	return p.successResponse(req.ID, ListResourceTemplatesResult{ResourceTemplates: templates})
+}
+
+func (p *Proxy) handleReadResource(ctx context.Context, req MCPRequest) ([]byte, error) {
+	var params ReadResourceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return p.errorResponse(req.ID, -32602, "Invalid params", "uri is required")
+	}
+
+	result, err := p.ReadResource(ctx, params.URI)
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
+	}
+	return p.successResponse(req.ID, result)
+}
+
+func (p *Proxy) handleListPrompts(ctx context.Context, req MCPRequest) ([]byte, error) {
+	prompts, err := p.ListPrompts(ctx)
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
+	}
+	return p.successResponse(req.ID, ListPromptsResult{Prompts: prompts})
+}
+
+func (p *Proxy) handleGetPrompt(ctx context.Context, req MCPRequest) ([]byte, error) {
+	var params GetPromptParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+		return p.errorResponse(req.ID, -32602, "Invalid params", "name is required")
+	}
+
+	result, err := p.GetPrompt(ctx, params.Name, params.Arguments)
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, "Internal error", sanitizeError(err))
+	}
+	return p.successResponse(req.ID, result)
+}
+
 func (p *Proxy) successResponse(id interface{}, result interface{}) ([]byte, error) {
 	resp := MCPResponse{
 		JSONRPC: "2.0",
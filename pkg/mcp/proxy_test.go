@@ -128,3 +128,29 @@ func TestEnsureServerNoopWhenConfigMatchesExisting(t *testing.T) {
 		t.Fatal("expected existing client to be kept when config is unchanged")
 	}
 }
+
+func TestListToolsIsSortedRegardlessOfServerOrder(t *testing.T) {
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	now := time.Now()
+	proxy.mu.Lock()
+	proxy.clients["zeta"] = &Client{config: ServerConfig{ID: "zeta"}, tools: []Tool{{Name: "zeta_query"}, {Name: "zeta_alerts"}}, toolsFetchedAt: now}
+	proxy.clients["alpha"] = &Client{config: ServerConfig{ID: "alpha"}, tools: []Tool{{Name: "alpha_search"}}, toolsFetchedAt: now}
+	proxy.clients["mid"] = &Client{config: ServerConfig{ID: "mid"}, tools: []Tool{{Name: "mid_b"}, {Name: "mid_a"}}, toolsFetchedAt: now}
+	proxy.mu.Unlock()
+
+	want := []string{"alpha_search", "mid_a", "mid_b", "zeta_alerts", "zeta_query"}
+	for range 10 {
+		tools, err := proxy.ListTools()
+		if err != nil {
+			t.Fatalf("ListTools failed: %v", err)
+		}
+		if len(tools) != len(want) {
+			t.Fatalf("tools = %+v, want %v", tools, want)
+		}
+		for i, tool := range tools {
+			if tool.Name != want[i] {
+				t.Fatalf("tool %d = %q, want %q (order %+v)", i, tool.Name, want[i], tools)
+			}
+		}
+	}
+}
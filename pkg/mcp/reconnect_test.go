@@ -2,10 +2,15 @@ package mcp
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestForceReconnect_DedupesRecentSession(t *testing.T) {
@@ -40,3 +45,130 @@ func TestForceReconnect_DedupesRecentSession(t *testing.T) {
 		t.Fatalf("expected nil error, got %v", err)
 	}
 }
+
+func TestCallToolWithContext_ConcurrentSameOrgShareSession(t *testing.T) {
+	ts, stats := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+
+	// Start from a session without org headers, as connectMCP or the health
+	// monitor's forceReconnect would leave it.
+	if err := c.connectMCP(); err != nil {
+		t.Fatalf("connectMCP failed: %v", err)
+	}
+
+	const calls = 8
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, calls)
+	for range calls {
+		wg.Go(func() {
+			<-start
+			result, err := c.CallToolWithContext(context.Background(), "tenant_query", map[string]interface{}{}, "2", "", "tenant-a")
+			if err == nil && result.IsError {
+				err = fmt.Errorf("tool error: %+v", result.Content)
+			}
+			errs <- err
+		})
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent call failed: %v", err)
+		}
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.scopes["tenant-a"] != calls {
+		t.Fatalf("expected all %d calls to carry the org scope, got %v", calls, stats.scopes)
+	}
+	// One session from connectMCP plus one for the org context; concurrent
+	// calls must not tear each other's session down and reconnect.
+	if stats.sessions != 2 {
+		t.Fatalf("expected 2 sessions, got %d", stats.sessions)
+	}
+}
+
+func TestReconnectSession_ReusesSessionReplacedByConcurrentCall(t *testing.T) {
+	ts, stats := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+
+	failed, _, err := c.ensureOrgSession("2", "", "tenant-a")
+	if err != nil {
+		t.Fatalf("ensureOrgSession failed: %v", err)
+	}
+
+	// Two calls on the same session both see "connection closed"; the first
+	// reconnects, the second must pick up that session rather than close it.
+	first, _, err := c.reconnectSession(failed, "2", "", "tenant-a")
+	if err != nil {
+		t.Fatalf("first reconnect failed: %v", err)
+	}
+	second, _, err := c.reconnectSession(failed, "2", "", "tenant-a")
+	if err != nil {
+		t.Fatalf("second reconnect failed: %v", err)
+	}
+	if first == failed || second != first {
+		t.Fatal("expected the second reconnect to reuse the first one's session")
+	}
+
+	// A replacement opened for another org is not reusable.
+	other, _, err := c.reconnectSession(failed, "3", "", "tenant-b")
+	if err != nil {
+		t.Fatalf("reconnect for other org failed: %v", err)
+	}
+	if other == first {
+		t.Fatal("expected a new session for a different org context")
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.sessions != 3 {
+		t.Fatalf("expected 3 sessions, got %d", stats.sessions)
+	}
+}
+
+type tenantServerStats struct {
+	mu       sync.Mutex
+	sessions int
+	scopes   map[string]int
+}
+
+// newTenantMCPServer serves a streamable-HTTP MCP server whose query tool
+// records the X-Scope-OrgID it was called with.
+func newTenantMCPServer(t *testing.T) (*httptest.Server, *tenantServerStats) {
+	t.Helper()
+	stats := &tenantServerStats{scopes: map[string]int{}}
+
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "tenant", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "query"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		stats.mu.Lock()
+		stats.scopes[req.Extra.Header.Get("X-Scope-OrgID")]++
+		stats.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "ok"}}}, nil, nil
+	})
+	server.AddReceivingMiddleware(func(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+		return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+			if method == "initialize" {
+				stats.mu.Lock()
+				stats.sessions++
+				stats.mu.Unlock()
+				// Slow session setup widens the window in which concurrent
+				// callers could each decide to reconnect.
+				time.Sleep(20 * time.Millisecond)
+			}
+			return next(ctx, method, req)
+		}
+	})
+	return newStreamableTestServer(t, server), stats
+}
+
+func newTenantClient(t *testing.T, url string) *Client {
+	t.Helper()
+	c := NewClient(context.Background(), ServerConfig{ID: "tenant", URL: url, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
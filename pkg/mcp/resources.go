@@ -0,0 +1,395 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readResourceToolName is the synthetic, read-only tool added to servers that
+// expose resources, so the agent loop can pull runbooks or documents into the
+// conversation through the normal tool path.
+const readResourceToolName = "read_resource"
+
+// readResourceToolMaxListed caps how many resources the synthetic tool's
+// description enumerates; the rest are still readable by URI.
+const readResourceToolMaxListed = 50
+
+const resourceListTimeout = 10 * time.Second
+
+// mcpSession returns the SDK session for servers that speak MCP natively,
+// opened with the given org headers like a tool call's would be. It returns
+// nil for OpenAPI and standard servers, which have no resources or prompts.
+// The caller calls release once it is done with the session.
+func (c *Client) mcpSession(orgID string, orgName string, scopeOrgId string) (session *mcpsdk.ClientSession, release func(), err error) {
+	switch c.config.Type {
+	case "sse", "streamable-http", "http+streamable", "stdio":
+	default:
+		return nil, func() {}, nil
+	}
+	session, release, err = c.orgSession(orgID, orgName, scopeOrgId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		release()
+		return nil, nil, fmt.Errorf("session not established")
+	}
+	return session, release, nil
+}
+
+func sessionCapabilities(session *mcpsdk.ClientSession) *mcpsdk.ServerCapabilities {
+	if res := session.InitializeResult(); res != nil && res.Capabilities != nil {
+		return res.Capabilities
+	}
+	return &mcpsdk.ServerCapabilities{}
+}
+
+func (c *Client) prefixed(name string) string {
+	return c.config.ID + "_" + name
+}
+
+func (c *Client) unprefixed(name string) string {
+	return strings.TrimPrefix(name, c.config.ID+"_")
+}
+
+// ListResources lists the server's resources with prefixed URIs and names.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	resources, err := c.listResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resources {
+		resources[i].URI = c.prefixed(resources[i].URI)
+		resources[i].Name = c.prefixed(resources[i].Name)
+	}
+	return resources, nil
+}
+
+func (c *Client) listResources(ctx context.Context) ([]Resource, error) {
+	session, release, err := c.mcpSession("", "", "")
+	if err != nil || session == nil {
+		return nil, err
+	}
+	defer release()
+	if sessionCapabilities(session).Resources == nil {
+		return nil, nil
+	}
+
+	var resources []Resource
+	for r, err := range session.Resources(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resources: %w", err)
+		}
+		resources = append(resources, Resource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Title:       r.Title,
+			Description: r.Description,
+			MimeType:    r.MIMEType,
+			Size:        r.Size,
+		})
+	}
+	return resources, nil
+}
+
+// ListResourceTemplates lists the server's resource templates with prefixed
+// URI templates and names.
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	templates, err := c.listResourceTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		templates[i].URITemplate = c.prefixed(templates[i].URITemplate)
+		templates[i].Name = c.prefixed(templates[i].Name)
+	}
+	return templates, nil
+}
+
+func (c *Client) listResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	session, release, err := c.mcpSession("", "", "")
+	if err != nil || session == nil {
+		return nil, err
+	}
+	defer release()
+	if sessionCapabilities(session).Resources == nil {
+		return nil, nil
+	}
+
+	var templates []ResourceTemplate
+	for t, err := range session.ResourceTemplates(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resource templates: %w", err)
+		}
+		templates = append(templates, ResourceTemplate{
+			URITemplate: t.URITemplate,
+			Name:        t.Name,
+			Title:       t.Title,
+			Description: t.Description,
+			MimeType:    t.MIMEType,
+		})
+	}
+	return templates, nil
+}
+
+// ReadResource reads a resource by its prefixed URI. Returned content URIs are
+// prefixed the same way.
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	return c.ReadResourceWithContext(ctx, uri, "", "", "")
+}
+
+// ReadResourceWithContext is ReadResource with org headers forwarded, so a
+// read made for one org never goes over a session opened for another.
+func (c *Client) ReadResourceWithContext(ctx context.Context, uri string, orgID string, orgName string, scopeOrgId string) (*ReadResourceResult, error) {
+	result, err := c.readResource(ctx, c.unprefixed(uri), orgID, orgName, scopeOrgId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Contents {
+		result.Contents[i].URI = c.prefixed(result.Contents[i].URI)
+	}
+	return result, nil
+}
+
+func (c *Client) readResource(ctx context.Context, uri string, orgID string, orgName string, scopeOrgId string) (*ReadResourceResult, error) {
+	session, release, err := c.mcpSession(orgID, orgName, scopeOrgId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if session == nil {
+		return nil, fmt.Errorf("server %s does not support resources", c.config.ID)
+	}
+
+	// Resource reads share the server's tool timeout; per-tool overrides don't apply.
+	ctx, cancel := c.toolCallContext(ctx, "")
+	defer cancel()
+
+	res, err := session.ReadResource(ctx, &mcpsdk.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+
+	result := &ReadResourceResult{Contents: make([]ResourceContents, 0, len(res.Contents))}
+	for _, rc := range res.Contents {
+		if rc == nil {
+			continue
+		}
+		contents := ResourceContents{
+			URI:      rc.URI,
+			MimeType: rc.MIMEType,
+			Text:     rc.Text,
+		}
+		if len(rc.Blob) > 0 {
+			contents.Blob = base64.StdEncoding.EncodeToString(rc.Blob)
+		}
+		result.Contents = append(result.Contents, contents)
+	}
+	return result, nil
+}
+
+// ListPrompts lists the server's prompts with prefixed names.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	session, release, err := c.mcpSession("", "", "")
+	if err != nil || session == nil {
+		return nil, err
+	}
+	defer release()
+	if sessionCapabilities(session).Prompts == nil {
+		return nil, nil
+	}
+
+	var prompts []Prompt
+	for p, err := range session.Prompts(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prompts: %w", err)
+		}
+		prompt := Prompt{
+			Name:        c.prefixed(p.Name),
+			Title:       p.Title,
+			Description: p.Description,
+		}
+		for _, arg := range p.Arguments {
+			if arg == nil {
+				continue
+			}
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{
+				Name:        arg.Name,
+				Title:       arg.Title,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// GetPrompt renders a prompt by its prefixed name.
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error) {
+	return c.GetPromptWithContext(ctx, name, arguments, "", "", "")
+}
+
+// GetPromptWithContext is GetPrompt with org headers forwarded.
+func (c *Client) GetPromptWithContext(ctx context.Context, name string, arguments map[string]string, orgID string, orgName string, scopeOrgId string) (*GetPromptResult, error) {
+	session, release, err := c.mcpSession(orgID, orgName, scopeOrgId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if session == nil {
+		return nil, fmt.Errorf("server %s does not support prompts", c.config.ID)
+	}
+
+	ctx, cancel := c.toolCallContext(ctx, "")
+	defer cancel()
+
+	res, err := session.GetPrompt(ctx, &mcpsdk.GetPromptParams{
+		Name:      c.unprefixed(name),
+		Arguments: arguments,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	result := &GetPromptResult{
+		Description: res.Description,
+		Messages:    make([]PromptMessage, 0, len(res.Messages)),
+	}
+	for _, m := range res.Messages {
+		if m == nil {
+			continue
+		}
+		result.Messages = append(result.Messages, PromptMessage{
+			Role:    string(m.Role),
+			Content: contentBlockFromSDK(m.Content),
+		})
+	}
+	return result, nil
+}
+
+// readResourceTool builds the synthetic read_resource tool for this server, or
+// returns false when the server exposes no resources or already has a tool of
+// that name. Failures to list resources only drop the synthetic tool.
+func (c *Client) readResourceTool(tools []Tool) (Tool, bool) {
+	for _, t := range tools {
+		if t.Name == readResourceToolName {
+			return Tool{}, false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, resourceListTimeout)
+	defer cancel()
+
+	resources, err := c.listResources(ctx)
+	if err != nil {
+		c.logger.Debug("Skipping read_resource tool: failed to list resources", "server", c.config.ID, "error", sanitizeError(err))
+		return Tool{}, false
+	}
+	templates, err := c.listResourceTemplates(ctx)
+	if err != nil {
+		c.logger.Debug("Failed to list resource templates", "server", c.config.ID, "error", sanitizeError(err))
+	}
+	if len(resources) == 0 && len(templates) == 0 {
+		return Tool{}, false
+	}
+
+	serverName := c.config.Name
+	if serverName == "" {
+		serverName = c.config.ID
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Read a resource (runbook, document, dashboard, etc.) published by the %s MCP server and return its contents.", serverName)
+	if len(resources) > 0 {
+		b.WriteString("\n\nAvailable resources:")
+		for i, r := range resources {
+			if i == readResourceToolMaxListed {
+				fmt.Fprintf(&b, "\n- ... and %d more", len(resources)-i)
+				break
+			}
+			fmt.Fprintf(&b, "\n- %s", r.URI)
+			if label := firstNonEmpty(r.Title, r.Name); label != "" {
+				fmt.Fprintf(&b, " (%s)", label)
+			}
+			if r.Description != "" {
+				fmt.Fprintf(&b, ": %s", r.Description)
+			}
+		}
+	}
+	if len(templates) > 0 {
+		b.WriteString("\n\nURI templates:")
+		for _, t := range templates {
+			fmt.Fprintf(&b, "\n- %s", t.URITemplate)
+			if t.Description != "" {
+				fmt.Fprintf(&b, ": %s", t.Description)
+			}
+		}
+	}
+
+	return Tool{
+		Name:        readResourceToolName,
+		Title:       "Read resource",
+		Description: b.String(),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"uri": map[string]interface{}{
+					"type":        "string",
+					"description": "Resource URI from the list above, or a URI built from one of the templates",
+				},
+			},
+			"required": []string{"uri"},
+		},
+		Annotations: &ToolAnnotations{
+			ReadOnlyHint:   boolPtr(true),
+			IdempotentHint: boolPtr(true),
+			OpenWorldHint:  boolPtr(false),
+			Title:          "Read resource",
+		},
+	}, true
+}
+
+// callReadResourceTool serves the synthetic read_resource tool. Text contents
+// are returned as-is; binary contents are summarized since the LLM can't use them.
+func (c *Client) callReadResourceTool(ctx context.Context, arguments map[string]interface{}, orgID string, orgName string, scopeOrgId string) (*CallToolResult, error) {
+	uri, _ := arguments["uri"].(string)
+	if uri == "" {
+		return &CallToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "Missing required argument: uri"}},
+			IsError: true,
+		}, nil
+	}
+
+	result, err := c.readResource(ctx, uri, orgID, orgName, scopeOrgId)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]ContentBlock, 0, len(result.Contents))
+	for _, rc := range result.Contents {
+		text := rc.Text
+		if text == "" && rc.Blob != "" {
+			text = fmt.Sprintf("[binary resource %s, %s, %d base64 bytes omitted]", rc.URI, firstNonEmpty(rc.MimeType, "unknown type"), len(rc.Blob))
+		}
+		content = append(content, ContentBlock{Type: "text", Text: text})
+	}
+	if len(content) == 0 {
+		content = append(content, ContentBlock{Type: "text", Text: fmt.Sprintf("Resource %s is empty", uri)})
+	}
+	return &CallToolResult{Content: content}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newResourceMCPServer serves a streamable-HTTP MCP server with one tool, one
+// runbook resource, one resource template and one prompt. The runbook names
+// the X-Scope-OrgID tenant it was read for.
+func newResourceMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "search"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "ok"}}}, nil, nil
+	})
+	server.AddResource(&mcpsdk.Resource{
+		URI:         "file:///runbooks/db.md",
+		Name:        "db-runbook",
+		Description: "Database failover runbook",
+		MIMEType:    "text/markdown",
+	}, func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+		text := "# DB failover\n1. Promote the replica"
+		if scope := req.Extra.Header.Get("X-Scope-OrgID"); scope != "" {
+			text += "\n(tenant " + scope + ")"
+		}
+		return &mcpsdk.ReadResourceResult{Contents: []*mcpsdk.ResourceContents{{
+			URI:      req.Params.URI,
+			MIMEType: "text/markdown",
+			Text:     text,
+		}}}, nil
+	})
+	server.AddResourceTemplate(&mcpsdk.ResourceTemplate{
+		URITemplate: "file:///services/{name}.md",
+		Name:        "service-doc",
+	}, func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+		return &mcpsdk.ReadResourceResult{Contents: []*mcpsdk.ResourceContents{{URI: req.Params.URI, Text: "service doc"}}}, nil
+	})
+	server.AddPrompt(&mcpsdk.Prompt{
+		Name:      "triage",
+		Arguments: []*mcpsdk.PromptArgument{{Name: "service", Required: true}},
+	}, func(ctx context.Context, req *mcpsdk.GetPromptRequest) (*mcpsdk.GetPromptResult, error) {
+		return &mcpsdk.GetPromptResult{
+			Description: "Triage a service",
+			Messages: []*mcpsdk.PromptMessage{{
+				Role:    "user",
+				Content: &mcpsdk.TextContent{Text: "Triage " + req.Params.Arguments["service"]},
+			}},
+		}, nil
+	})
+
+	return newStreamableTestServer(t, server)
+}
+
+// newStreamableTestServer serves server over streamable HTTP until the test
+// ends.
+func newStreamableTestServer(t *testing.T, server *mcpsdk.Server) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newResourceProxy(t *testing.T) *Proxy {
+	t.Helper()
+	ts := newResourceMCPServer(t)
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	if err := proxy.EnsureServer(ServerConfig{ID: "docs", Name: "Docs", URL: ts.URL, Type: "streamable-http", Enabled: true}); err != nil {
+		t.Fatalf("EnsureServer failed: %v", err)
+	}
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+// mcpCall sends one JSON-RPC request through HandleMCPRequest and decodes the result into out.
+func mcpCall(t *testing.T, proxy *Proxy, method string, params interface{}, out interface{}) {
+	t.Helper()
+	raw, _ := json.Marshal(params)
+	reqData, _ := json.Marshal(MCPRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: raw})
+	respData, err := proxy.HandleMCPRequest(context.Background(), reqData)
+	if err != nil {
+		t.Fatalf("%s: HandleMCPRequest failed: %v", method, err)
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *MCPError       `json:"error"`
+	}
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		t.Fatalf("%s: decode response: %v", method, err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("%s: unexpected error %+v", method, resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		t.Fatalf("%s: decode result: %v", method, err)
+	}
+}
+
+func TestHandleMCPRequest_Resources(t *testing.T) {
+	proxy := newResourceProxy(t)
+
+	var list ListResourcesResult
+	mcpCall(t, proxy, "resources/list", map[string]interface{}{}, &list)
+	if len(list.Resources) != 1 {
+		t.Fatalf("expected 1 resource, got %+v", list.Resources)
+	}
+	res := list.Resources[0]
+	if res.URI != "docs_file:///runbooks/db.md" || res.Name != "docs_db-runbook" {
+		t.Fatalf("expected prefixed URI and name, got %+v", res)
+	}
+
+	var templates ListResourceTemplatesResult
+	mcpCall(t, proxy, "resources/templates/list", map[string]interface{}{}, &templates)
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != "docs_file:///services/{name}.md" {
+		t.Fatalf("unexpected templates %+v", templates.ResourceTemplates)
+	}
+
+	var read ReadResourceResult
+	mcpCall(t, proxy, "resources/read", ReadResourceParams{URI: res.URI}, &read)
+	if len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, "Promote the replica") {
+		t.Fatalf("unexpected contents %+v", read.Contents)
+	}
+	if read.Contents[0].URI != res.URI {
+		t.Fatalf("expected content URI %q, got %q", res.URI, read.Contents[0].URI)
+	}
+
+	mcpCall(t, proxy, "resources/read", ReadResourceParams{URI: "docs_file:///services/checkout.md"}, &read)
+	if len(read.Contents) != 1 || read.Contents[0].Text != "service doc" {
+		t.Fatalf("expected templated resource to be readable, got %+v", read.Contents)
+	}
+}
+
+func TestHandleMCPRequest_Prompts(t *testing.T) {
+	proxy := newResourceProxy(t)
+
+	var list ListPromptsResult
+	mcpCall(t, proxy, "prompts/list", map[string]interface{}{}, &list)
+	if len(list.Prompts) != 1 || list.Prompts[0].Name != "docs_triage" {
+		t.Fatalf("unexpected prompts %+v", list.Prompts)
+	}
+	if len(list.Prompts[0].Arguments) != 1 || !list.Prompts[0].Arguments[0].Required {
+		t.Fatalf("expected required service argument, got %+v", list.Prompts[0].Arguments)
+	}
+
+	var got GetPromptResult
+	mcpCall(t, proxy, "prompts/get", GetPromptParams{Name: "docs_triage", Arguments: map[string]string{"service": "checkout"}}, &got)
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content.Text != "Triage checkout" {
+		t.Fatalf("unexpected prompt messages %+v", got.Messages)
+	}
+}
+
+func TestHandleMCPRequest_ReadResourceUnknownServer(t *testing.T) {
+	proxy := newResourceProxy(t)
+
+	reqData, _ := json.Marshal(MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: json.RawMessage(`{"uri":"nope_file:///x"}`)})
+	respData, err := proxy.HandleMCPRequest(context.Background(), reqData)
+	if err != nil {
+		t.Fatalf("HandleMCPRequest failed: %v", err)
+	}
+	var resp MCPResponse
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Error == nil {
+		t.Fatal("expected an error for an unknown server prefix")
+	}
+}
+
+func TestListTools_AddsReadResourceTool(t *testing.T) {
+	proxy := newResourceProxy(t)
+
+	tools, err := proxy.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	tool, found := proxy.FindToolByName("docs_read_resource")
+	if !found {
+		t.Fatalf("expected synthetic read_resource tool, got %+v", tools)
+	}
+	if tool.Annotations == nil || tool.Annotations.ReadOnlyHint == nil || !*tool.Annotations.ReadOnlyHint {
+		t.Fatalf("expected read_resource to be read-only, got %+v", tool.Annotations)
+	}
+	if !strings.Contains(tool.Description, "file:///runbooks/db.md") || !strings.Contains(tool.Description, "file:///services/{name}.md") {
+		t.Fatalf("expected description to list resources and templates, got %q", tool.Description)
+	}
+	if risk := ClassifyToolRisk(tool, nil); risk.RequiresApproval {
+		t.Fatalf("expected read_resource not to require approval, got %+v", risk)
+	}
+
+	result, err := proxy.CallTool(context.Background(), "docs_read_resource", map[string]interface{}{"uri": "file:///runbooks/db.md"})
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "Promote the replica") {
+		t.Fatalf("unexpected read_resource result %+v", result)
+	}
+
+	result, err = proxy.CallTool(context.Background(), "docs_search", map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("expected real tools to keep working, got %+v, %v", result, err)
+	}
+}
+
+func TestReadResourceTool_UsesCallersOrgSession(t *testing.T) {
+	proxy := newResourceProxy(t)
+	if _, err := proxy.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	// Leave a session open for another tenant, as a concurrent run would.
+	if _, err := proxy.CallToolWithContext(context.Background(), "docs_search", map[string]interface{}{}, "3", "", "tenant-b"); err != nil {
+		t.Fatalf("CallToolWithContext failed: %v", err)
+	}
+
+	result, err := proxy.CallToolWithContext(context.Background(), "docs_read_resource", map[string]interface{}{"uri": "file:///runbooks/db.md"}, "2", "", "tenant-a")
+	if err != nil {
+		t.Fatalf("read_resource failed: %v", err)
+	}
+	if len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "(tenant tenant-a)") {
+		t.Fatalf("expected the read to carry the caller's tenant, got %+v", result.Content)
+	}
+
+	read, err := proxy.ReadResourceWithContext(context.Background(), "docs_file:///runbooks/db.md", "3", "", "tenant-b")
+	if err != nil {
+		t.Fatalf("ReadResourceWithContext failed: %v", err)
+	}
+	if len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, "(tenant tenant-b)") {
+		t.Fatalf("expected the read to carry tenant-b, got %+v", read.Contents)
+	}
+}
+
+func TestListResources_SkipsServersWithoutResources(t *testing.T) {
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	t.Cleanup(proxy.Close)
+	proxy.mu.Lock()
+	proxy.clients["api"] = NewClient(context.Background(), ServerConfig{ID: "api", Type: "openapi"}, log.DefaultLogger, nil)
+	proxy.mu.Unlock()
+
+	resources, err := proxy.ListResources(context.Background())
+	if err != nil || len(resources) != 0 {
+		t.Fatalf("expected no resources and no error, got %+v, %v", resources, err)
+	}
+	prompts, err := proxy.ListPrompts(context.Background())
+	if err != nil || len(prompts) != 0 {
+		t.Fatalf("expected no prompts and no error, got %+v, %v", prompts, err)
+	}
+}
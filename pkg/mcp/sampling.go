@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SamplingRequest is a server's sampling/createMessage request, reduced to
+// the text conversation the agent's LLM can complete.
+type SamplingRequest struct {
+	ServerID string
+	// ToolName is the prefixed name of the tool call the request serves.
+	ToolName     string
+	SystemPrompt string
+	Messages     []SamplingMessage
+	// MaxTokens is the most the server asked for; the client may use fewer.
+	MaxTokens int
+}
+
+// SamplingMessage is one turn of a sampling conversation.
+type SamplingMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// SamplingResult is the completion returned to the server.
+type SamplingResult struct {
+	Text  string
+	Model string
+	// StopReason is "endTurn" or "maxTokens".
+	StopReason string
+}
+
+// Sampler completes sampling requests for the tool calls of a run, with the
+// run's LLM credentials.
+type Sampler interface {
+	CreateMessage(ctx context.Context, req SamplingRequest) (*SamplingResult, error)
+}
+
+type samplerKey struct{}
+
+// WithSampler returns a context whose tool calls let their server sample
+// through s. Sampling requests that arrive while no such call is in flight
+// are refused.
+func WithSampler(ctx context.Context, s Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, s)
+}
+
+func samplerFrom(ctx context.Context) Sampler {
+	s, _ := ctx.Value(samplerKey{}).(Sampler)
+	return s
+}
+
+// soleCall returns the in-flight call on session that a server request for
+// what (sampling, elicitation) serves, among the calls has accepts. The
+// protocol doesn't say which call that is, so the request is refused when
+// several such calls share the session: they may belong to different runs,
+// and must not spend each other's credentials or ask each other's users.
+func (r *callRegistry) soleCall(session *mcpsdk.ClientSession, what string, has func(inflightCall) bool) (inflightCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []inflightCall
+	for _, call := range r.inflight {
+		if call.session == session && has(call) {
+			found = append(found, call)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return inflightCall{}, fmt.Errorf("%s is only available while a tool call is running", what)
+	case 1:
+		return found[0], nil
+	default:
+		return inflightCall{}, fmt.Errorf("%s request is ambiguous: %d tool calls are running on this session", what, len(found))
+	}
+}
+
+// createMessage handles a server's sampling/createMessage request by handing
+// it to the sampler of the tool call it serves.
+func (c *Client) createMessage(ctx context.Context, req *mcpsdk.CreateMessageRequest) (*mcpsdk.CreateMessageResult, error) {
+	call, err := c.calls.soleCall(req.Session, "sampling", func(call inflightCall) bool { return call.sampler != nil })
+	if err != nil {
+		c.logger.Warn("Refused MCP sampling request", "server", c.config.ID, "error", err)
+		return nil, err
+	}
+
+	params := req.Params
+	sreq := SamplingRequest{
+		ServerID:     c.config.ID,
+		ToolName:     call.toolName,
+		SystemPrompt: params.SystemPrompt,
+		MaxTokens:    int(params.MaxTokens),
+	}
+	for _, m := range params.Messages {
+		text, ok := m.Content.(*mcpsdk.TextContent)
+		if !ok {
+			return nil, errors.New("sampling supports text messages only")
+		}
+		sreq.Messages = append(sreq.Messages, SamplingMessage{Role: string(m.Role), Text: text.Text})
+	}
+
+	resume := call.deadline.suspend()
+	result, err := call.sampler.CreateMessage(ctx, sreq)
+	resume()
+	if err != nil {
+		c.logger.Warn("MCP sampling request failed", "server", c.config.ID, "tool", call.toolName, "error", err)
+		return nil, err
+	}
+	return &mcpsdk.CreateMessageResult{
+		Content:    &mcpsdk.TextContent{Text: result.Text},
+		Model:      result.Model,
+		Role:       "assistant",
+		StopReason: result.StopReason,
+	}, nil
+}
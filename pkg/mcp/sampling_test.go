@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type fakeSampler struct {
+	got []SamplingRequest
+}
+
+func (s *fakeSampler) CreateMessage(ctx context.Context, req SamplingRequest) (*SamplingResult, error) {
+	s.got = append(s.got, req)
+	return &SamplingResult{Text: "3 error bursts", Model: "base", StopReason: "endTurn"}, nil
+}
+
+// newSamplingMCPServer serves a tool that summarizes through sampling and
+// returns the summary, or the sampling error, as its text.
+func newSamplingMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "loki", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "summarize"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		res, err := req.Session.CreateMessage(ctx, &mcpsdk.CreateMessageParams{
+			SystemPrompt: "Summarize log lines.",
+			Messages:     []*mcpsdk.SamplingMessage{{Role: "user", Content: &mcpsdk.TextContent{Text: "ERROR a\nERROR b"}}},
+			MaxTokens:    200,
+		})
+		if err != nil {
+			return &mcpsdk.CallToolResult{IsError: true, Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: err.Error()}}}, nil, nil
+		}
+		text := res.Content.(*mcpsdk.TextContent).Text
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: text + " (" + res.Model + ")"}}}, nil, nil
+	})
+	return newStreamableTestServer(t, server)
+}
+
+func TestCallToolServesSamplingThroughSampler(t *testing.T) {
+	ts := newSamplingMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "loki", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	sampler := &fakeSampler{}
+	result, err := c.CallToolWithContext(WithSampler(context.Background(), sampler), "loki_summarize", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || result.Content[0].Text != "3 error bursts (base)" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(sampler.got) != 1 {
+		t.Fatalf("expected one sampling request, got %d", len(sampler.got))
+	}
+	got := sampler.got[0]
+	if got.ServerID != "loki" || got.ToolName != "loki_summarize" || got.SystemPrompt != "Summarize log lines." || got.MaxTokens != 200 {
+		t.Errorf("unexpected request: %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0] != (SamplingMessage{Role: "user", Text: "ERROR a\nERROR b"}) {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+}
+
+func TestCallToolRefusesSamplingWithoutSampler(t *testing.T) {
+	ts := newSamplingMCPServer(t)
+	c := NewClient(context.Background(), ServerConfig{ID: "loki", URL: ts.URL, Type: "streamable-http", Enabled: true}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+
+	result, err := c.CallToolWithContext(context.Background(), "loki_summarize", nil, "", "", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "sampling is only available while a tool call is running") {
+		t.Fatalf("expected sampling to be refused, got %+v", result)
+	}
+}
+
+func TestCallRegistryRefusesAmbiguousSampling(t *testing.T) {
+	var r callRegistry
+	session := &mcpsdk.ClientSession{}
+	r.register(inflightCall{session: session, toolName: "loki_a", sampler: &fakeSampler{}})
+	hasSampler := func(call inflightCall) bool { return call.sampler != nil }
+	if call, err := r.soleCall(session, "sampling", hasSampler); err != nil || call.toolName != "loki_a" {
+		t.Fatalf("expected the only call's sampler, got %+v, %v", call, err)
+	}
+	r.register(inflightCall{session: session, toolName: "loki_b", sampler: &fakeSampler{}})
+	if _, err := r.soleCall(session, "sampling", hasSampler); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
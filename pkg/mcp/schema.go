@@ -0,0 +1,466 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxSchemaErrors caps how many violations ValidateArguments reports, so
+	// a badly wrong call doesn't flood the model's context.
+	maxSchemaErrors = 10
+
+	// maxSchemaRefDepth stops recursive $ref chains.
+	maxSchemaRefDepth = 32
+)
+
+// ValidateArguments checks args against a tool's JSON Schema input schema
+// and fixes, in place, the mistakes LLMs commonly make: numbers and booleans
+// sent as strings, numbers sent where strings are expected, arrays or objects
+// sent as JSON-encoded strings, single values sent where an array is
+// expected, and null sent for optional properties. The error lists each
+// remaining violation with its argument path so the model can correct its
+// call. A nil schema accepts anything.
+func ValidateArguments(schema map[string]interface{}, args map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	v := &schemaValidator{root: schema, rootName: "arguments", coerce: true}
+	var value interface{} = args
+	if args == nil {
+		value = map[string]interface{}{}
+	}
+	out := v.validate(schema, value, "", 0)
+	if len(v.errs) > 0 {
+		return v.err()
+	}
+	if coerced, ok := out.(map[string]interface{}); ok && args != nil {
+		for k := range args {
+			if _, kept := coerced[k]; !kept {
+				delete(args, k)
+			}
+		}
+		for k, val := range coerced {
+			args[k] = val
+		}
+	}
+	return nil
+}
+
+type catalogToolKey struct{}
+
+// WithCatalogTool returns a context whose tool call is checked against tool,
+// the definition the caller resolved the call in, rather than the server's
+// current one. A run pinned to a catalog keeps the output schemas it started
+// with after a refresh changes them.
+func WithCatalogTool(ctx context.Context, tool Tool) context.Context {
+	return context.WithValue(ctx, catalogToolKey{}, tool)
+}
+
+// outputTool returns the definition a call's output is validated against.
+func (c *Client) outputTool(ctx context.Context, toolName string) (Tool, bool) {
+	if tool, ok := ctx.Value(catalogToolKey{}).(Tool); ok && tool.Name == toolName {
+		return tool, true
+	}
+	return c.cachedTool(toolName)
+}
+
+// ValidateOutput checks a successful result's structured content against
+// the tool's declared output schema. Unlike arguments, output is never
+// coerced: a server that breaks its own contract is reported, not fixed.
+func ValidateOutput(tool Tool, result *CallToolResult) error {
+	if len(tool.OutputSchema) == 0 || result == nil || result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return fmt.Errorf("tool declares an output schema but returned no structured content")
+	}
+	// Normalize typed values into their JSON form before validating.
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return fmt.Errorf("encode structured content: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("decode structured content: %w", err)
+	}
+	v := &schemaValidator{root: tool.OutputSchema, rootName: "output"}
+	v.validate(tool.OutputSchema, value, "", 0)
+	if len(v.errs) > 0 {
+		return v.err()
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root map[string]interface{}
+	// rootName names the validated value in messages about its top level.
+	rootName string
+	// coerce enables fixing common LLM mistakes instead of reporting them.
+	coerce bool
+	errs   []string
+	total  int
+}
+
+func (v *schemaValidator) addf(path string, format string, a ...interface{}) {
+	v.total++
+	if len(v.errs) < maxSchemaErrors {
+		v.errs = append(v.errs, v.displayPath(path)+": "+fmt.Sprintf(format, a...))
+	}
+}
+
+func (v *schemaValidator) err() error {
+	msg := strings.Join(v.errs, "; ")
+	if v.total > len(v.errs) {
+		msg += fmt.Sprintf("; and %d more", v.total-len(v.errs))
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (v *schemaValidator) displayPath(path string) string {
+	if path == "" {
+		return v.rootName
+	}
+	return path
+}
+
+// validate checks value against schema and returns it with coercions
+// applied. Containers are copied rather than modified, so a failed anyOf
+// branch leaves no trace.
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string, depth int) interface{} {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved := v.resolveRef(ref)
+		if resolved == nil || depth >= maxSchemaRefDepth {
+			return value
+		}
+		return v.validate(resolved, value, path, depth+1)
+	}
+	if ss, ok := value.([]string); ok {
+		items := make([]interface{}, len(ss))
+		for i, s := range ss {
+			items[i] = s
+		}
+		value = items
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				value = v.validate(subSchema, value, path, depth+1)
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if alts, ok := schema[key].([]interface{}); ok {
+			value = v.validateAlternatives(alts, value, path, depth)
+		}
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		coerced, ok := coerceToTypes(value, types, v.coerce)
+		if !ok {
+			v.addf(path, "expected %s, got %s", strings.Join(types, " or "), getJSONType(value))
+			return value
+		}
+		value = coerced
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSONValue(enum, value) {
+		v.addf(path, "must be one of %s, got %s", formatJSONValues(enum), formatJSONValue(value))
+	}
+	if constant, ok := schema["const"]; ok && !jsonValuesEqual(constant, value) {
+		v.addf(path, "must be %s, got %s", formatJSONValue(constant), formatJSONValue(value))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		return v.validateObject(schema, val, path, depth)
+	case []interface{}:
+		return v.validateArray(schema, val, path, depth)
+	}
+	return value
+}
+
+// validateAlternatives returns value as coerced by the first alternative it
+// satisfies, or reports the first alternative's violations.
+func (v *schemaValidator) validateAlternatives(alts []interface{}, value interface{}, path string, depth int) interface{} {
+	var firstErrs []string
+	for _, alt := range alts {
+		altSchema, ok := alt.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sub := &schemaValidator{root: v.root, rootName: v.rootName, coerce: v.coerce}
+		out := sub.validate(altSchema, value, path, depth+1)
+		if len(sub.errs) == 0 {
+			return out
+		}
+		if firstErrs == nil {
+			firstErrs = sub.errs
+		}
+	}
+	if firstErrs != nil {
+		v.addf(path, "does not match any allowed schema (first mismatch: %s)", firstErrs[0])
+	}
+	return value
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string, depth int) interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	required := map[string]bool{}
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, r := range names {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			required[name] = true
+			if _, exists := obj[name]; !exists {
+				v.addf(joinPath(path, name), "required property is missing")
+			}
+		}
+	}
+
+	out := make(map[string]interface{}, len(obj))
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val := obj[name]
+		if propSchema, ok := properties[name].(map[string]interface{}); ok {
+			// An explicit null for an optional property means "not set".
+			if v.coerce && val == nil && !required[name] && !allowsNull(propSchema) {
+				continue
+			}
+			out[name] = v.validate(propSchema, val, joinPath(path, name), depth+1)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.addf(joinPath(path, name), "unknown property (allowed: %s)", strings.Join(sortedKeys(properties), ", "))
+			}
+		case map[string]interface{}:
+			val = v.validate(additional, val, joinPath(path, name), depth+1)
+		}
+		out[name] = val
+	}
+	return out
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, arr []interface{}, path string, depth int) interface{} {
+	if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(arr)) < n {
+		v.addf(path, "must have at least %v items, got %d", n, len(arr))
+	}
+	if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(arr)) > n {
+		v.addf(path, "must have at most %v items, got %d", n, len(arr))
+	}
+	items, ok := schema["items"].(map[string]interface{})
+	if !ok {
+		return arr
+	}
+	out := make([]interface{}, len(arr))
+	for i, item := range arr {
+		out[i] = v.validate(items, item, fmt.Sprintf("%s[%d]", v.displayPath(path), i), depth+1)
+	}
+	return out
+}
+
+// resolveRef resolves local references such as "#/$defs/Filter". Remote
+// references are not followed.
+func (v *schemaValidator) resolveRef(ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	var node interface{} = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	resolved, _ := node.(map[string]interface{})
+	return resolved
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaTypes returns the schema's "type" keyword as a list.
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// coerceToTypes returns value unchanged if it already has one of types, or,
+// when coerce is set, converted to the first type it can be coerced to.
+func coerceToTypes(value interface{}, types []string, coerce bool) (interface{}, bool) {
+	for _, t := range types {
+		if hasJSONType(value, t) {
+			return value, true
+		}
+	}
+	if !coerce {
+		return value, false
+	}
+	for _, t := range types {
+		if coerced, ok := coerceJSONValue(value, t); ok {
+			return coerced, true
+		}
+	}
+	return value, false
+}
+
+func allowsNull(schema map[string]interface{}) bool {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJSONType(value interface{}, typ string) bool {
+	switch typ {
+	case "integer":
+		f, ok := schemaNumber(value)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := schemaNumber(value)
+		return ok
+	case "string", "boolean", "array", "object", "null":
+		return getJSONType(value) == typ
+	}
+	// Unknown types are not enforced.
+	return true
+}
+
+func coerceJSONValue(value interface{}, typ string) (interface{}, bool) {
+	if s, ok := value.(string); ok {
+		trimmed := strings.TrimSpace(s)
+		switch typ {
+		case "integer":
+			if f, err := strconv.ParseFloat(trimmed, 64); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return f, true
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return f, true
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(strings.ToLower(trimmed)); err == nil {
+				return b, true
+			}
+		case "object":
+			var obj map[string]interface{}
+			if strings.HasPrefix(trimmed, "{") && json.Unmarshal([]byte(trimmed), &obj) == nil {
+				return obj, true
+			}
+		case "array":
+			var arr []interface{}
+			if strings.HasPrefix(trimmed, "[") && json.Unmarshal([]byte(trimmed), &arr) == nil {
+				return arr, true
+			}
+		}
+	}
+	switch typ {
+	case "string":
+		if f, ok := schemaNumber(value); ok {
+			return strconv.FormatFloat(f, 'f', -1, 64), true
+		}
+	case "array":
+		if value != nil {
+			return []interface{}{value}, true
+		}
+	}
+	return nil, false
+}
+
+func schemaNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if jsonValuesEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonValuesEqual(a, b interface{}) bool {
+	if fa, ok := schemaNumber(a); ok {
+		fb, ok := schemaNumber(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatJSONValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatJSONValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = formatJSONValue(value)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const querySchemaJSON = `{
+	"type": "object",
+	"properties": {
+		"query":  {"type": "string"},
+		"limit":  {"type": "integer"},
+		"step":   {"type": "number"},
+		"raw":    {"type": "boolean"},
+		"labels": {"type": "array", "items": {"type": "string"}},
+		"range":  {"$ref": "#/$defs/Range"},
+		"filters": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"op":    {"type": "string", "enum": ["eq", "neq"]},
+					"value": {"type": ["string", "null"]}
+				},
+				"required": ["op"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["query"],
+	"$defs": {
+		"Range": {
+			"type": "object",
+			"properties": {"from": {"type": "string"}, "to": {"type": "string"}},
+			"required": ["from"]
+		}
+	}
+}`
+
+func querySchema(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(querySchemaJSON), &schema); err != nil {
+		t.Fatalf("bad schema: %v", err)
+	}
+	return schema
+}
+
+func parseArgs(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &args); err != nil {
+		t.Fatalf("bad args: %v", err)
+	}
+	return args
+}
+
+func TestValidateArgumentsCoercesCommonMistakes(t *testing.T) {
+	args := parseArgs(t, `{
+		"query": 42,
+		"limit": "10",
+		"step": " 1.5 ",
+		"raw": "TRUE",
+		"labels": "job",
+		"range": "{\"from\": \"now-1h\"}",
+		"filters": "[{\"op\": \"eq\", \"value\": null}]"
+	}`)
+
+	if err := ValidateArguments(querySchema(t), args); err != nil {
+		t.Fatalf("ValidateArguments: %v", err)
+	}
+
+	want := parseArgs(t, `{
+		"query": "42",
+		"limit": 10,
+		"step": 1.5,
+		"raw": true,
+		"labels": ["job"],
+		"range": {"from": "now-1h"},
+		"filters": [{"op": "eq", "value": null}]
+	}`)
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("got %v, want %v", args, want)
+	}
+}
+
+func TestValidateArgumentsDropsNullOptionalProperties(t *testing.T) {
+	args := parseArgs(t, `{"query": "up", "limit": null}`)
+	if err := ValidateArguments(querySchema(t), args); err != nil {
+		t.Fatalf("ValidateArguments: %v", err)
+	}
+	if _, ok := args["limit"]; ok {
+		t.Fatalf("expected null limit to be dropped, got %v", args)
+	}
+}
+
+func TestValidateArgumentsReportsViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want []string
+	}{
+		{
+			name: "missing required",
+			args: `{}`,
+			want: []string{"query: required property is missing"},
+		},
+		{
+			name: "wrong type",
+			args: `{"query": "up", "limit": "ten"}`,
+			want: []string{"limit: expected integer, got string"},
+		},
+		{
+			name: "fractional integer",
+			args: `{"query": "up", "limit": 2.5}`,
+			want: []string{"limit: expected integer, got number"},
+		},
+		{
+			name: "nested enum and unknown property",
+			args: `{"query": "up", "filters": [{"op": "eq"}, {"op": "equals", "val": "x"}]}`,
+			want: []string{
+				`filters[1].op: must be one of ["eq", "neq"], got "equals"`,
+				"filters[1].val: unknown property (allowed: op, value)",
+			},
+		},
+		{
+			name: "nested required through ref",
+			args: `{"query": "up", "range": {"to": "now"}}`,
+			want: []string{"range.from: required property is missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(querySchema(t), parseArgs(t, tt.args))
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateArgumentsLeavesArgsUntouchedOnError(t *testing.T) {
+	args := parseArgs(t, `{"limit": "10"}`)
+	if err := ValidateArguments(querySchema(t), args); err == nil {
+		t.Fatal("expected missing query to fail")
+	}
+	if args["limit"] != "10" {
+		t.Fatalf("args must not be coerced when validation fails, got %v", args)
+	}
+}
+
+func TestValidateArgumentsAnyOf(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"window": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "integer"},
+					map[string]interface{}{"type": "string", "enum": []interface{}{"auto"}},
+				},
+			},
+		},
+	}
+	args := map[string]interface{}{"window": "30"}
+	if err := ValidateArguments(schema, args); err != nil {
+		t.Fatalf("ValidateArguments: %v", err)
+	}
+	if args["window"] != float64(30) {
+		t.Fatalf("expected first matching branch to coerce, got %v", args["window"])
+	}
+	if err := ValidateArguments(schema, map[string]interface{}{"window": true}); err == nil || !strings.Contains(err.Error(), "window: does not match any allowed schema") {
+		t.Fatalf("expected anyOf mismatch, got %v", err)
+	}
+}
+
+func TestValidateArgumentsAcceptsInjectedStringSlices(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"group_ids": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	args := map[string]interface{}{"group_ids": []string{"org_1"}}
+	if err := ValidateArguments(schema, args); err != nil {
+		t.Fatalf("ValidateArguments: %v", err)
+	}
+}
+
+func TestValidateArgumentsCapsErrorCount(t *testing.T) {
+	required := make([]interface{}, maxSchemaErrors+3)
+	for i := range required {
+		required[i] = string(rune('a' + i))
+	}
+	err := ValidateArguments(map[string]interface{}{"type": "object", "required": required}, nil)
+	if err == nil || !strings.HasSuffix(err.Error(), "and 3 more") {
+		t.Fatalf("expected capped error list, got %v", err)
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	tool := Tool{Name: "svc_alerts", OutputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer"},
+		},
+		"required": []interface{}{"count"},
+	}}
+
+	tests := []struct {
+		name    string
+		result  *CallToolResult
+		wantErr string
+	}{
+		{name: "valid", result: &CallToolResult{StructuredContent: map[string]interface{}{"count": 3}}},
+		{name: "typed value", result: &CallToolResult{StructuredContent: struct {
+			Count int `json:"count"`
+		}{3}}},
+		{name: "tool error skipped", result: &CallToolResult{IsError: true}},
+		{name: "missing structured content", result: &CallToolResult{}, wantErr: "no structured content"},
+		// Output is never coerced, unlike arguments.
+		{name: "wrong type", result: &CallToolResult{StructuredContent: map[string]interface{}{"count": "3"}}, wantErr: "count: expected integer, got string"},
+		{name: "not an object", result: &CallToolResult{StructuredContent: []interface{}{}}, wantErr: "output: expected object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOutput(tool, tt.result)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := ValidateOutput(Tool{Name: "svc_plain"}, &CallToolResult{}); err != nil {
+		t.Fatalf("tools without an output schema must not be checked: %v", err)
+	}
+}
+
+func TestProxyReportsOutputSchemaViolations(t *testing.T) {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "alerts", Version: "1.0.0"}, nil)
+	server.AddTool(&mcpsdk.Tool{
+		Name:        "count",
+		InputSchema: map[string]any{"type": "object"},
+		OutputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+			"required":   []any{"count"},
+		},
+	}, func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		return &mcpsdk.CallToolResult{StructuredContent: map[string]any{"count": "many"}}, nil
+	})
+	ts := newStreamableTestServer(t, server)
+
+	proxy := NewProxy(context.Background(), log.DefaultLogger)
+	t.Cleanup(proxy.Close)
+	if err := proxy.EnsureServer(ServerConfig{ID: "alerts", URL: ts.URL, Type: "streamable-http", Enabled: true}); err != nil {
+		t.Fatalf("EnsureServer: %v", err)
+	}
+	if _, err := proxy.ListTools(); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+
+	ctx := context.Background()
+	_, err := proxy.CallToolWithContext(ctx, "alerts_count", nil, "", "", "")
+	var schemaErr *OutputSchemaError
+	if !errors.As(err, &schemaErr) || !strings.Contains(err.Error(), "count: expected integer, got string") {
+		t.Fatalf("expected output schema error, got %v", err)
+	}
+	if kind := ClassifyError(ctx, err); kind != ErrKindSchema {
+		t.Fatalf("expected schema error kind, got %q", kind)
+	}
+
+	// A run pinned to a catalog from before the server declared its output
+	// schema checks the result against that catalog's definition.
+	pinned := WithCatalogTool(ctx, Tool{Name: "alerts_count", ServerID: "alerts"})
+	if _, err := proxy.CallToolWithContext(pinned, "alerts_count", nil, "", "", ""); err != nil {
+		t.Fatalf("expected the pinned definition to accept the result, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxOrgSessions bounds the org sessions a client keeps open to its server.
+// Opening one more closes the least recently used.
+const maxOrgSessions = 16
+
+// orgSessionIdleTimeout is how long an org session may go unused before the
+// health monitor closes it.
+const orgSessionIdleTimeout = 10 * time.Minute
+
+var errClientClosed = errors.New("client is closed")
+
+// orgSessionPool keeps one SDK session per org context (orgID, orgName,
+// scopeOrgId), so calls from different orgs and tenants each reuse their own
+// session instead of reconnecting the client's single one whenever the org
+// changes.
+type orgSessionPool struct {
+	mu       sync.Mutex
+	sessions map[string]*pooledSession
+	closed   bool
+}
+
+// pooledSession is the session of one org context. ready is closed once its
+// connect finished; until then, calls for the same org wait for it rather
+// than opening a session of their own. inflight counts the calls holding the
+// session; eviction leaves busy sessions alone.
+type pooledSession struct {
+	ready    chan struct{}
+	session  *mcpsdk.ClientSession
+	err      error
+	lastUsed time.Time
+	inflight int
+}
+
+func (s *pooledSession) connected() bool {
+	select {
+	case <-s.ready:
+		return s.err == nil
+	default:
+		return false
+	}
+}
+
+// ensureOrgSession returns the pooled session for the org context, opening
+// it on a miss. The session stays in use, and is never evicted, until the
+// caller calls release.
+func (c *Client) ensureOrgSession(orgID string, orgName string, scopeOrgId string) (session *mcpsdk.ClientSession, release func(), err error) {
+	key := orgSessionKey(orgID, orgName, scopeOrgId)
+	pool := &c.orgSessions
+
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return nil, nil, errClientClosed
+	}
+	if entry, ok := pool.sessions[key]; ok {
+		entry.lastUsed = time.Now()
+		entry.inflight++
+		pool.mu.Unlock()
+		sessionPoolRequests.WithLabelValues(c.config.ID, "hit").Inc()
+		<-entry.ready
+		release := pool.releaser(entry)
+		if entry.err != nil {
+			release()
+			return nil, nil, entry.err
+		}
+		return entry.session, release, nil
+	}
+	if pool.sessions == nil {
+		pool.sessions = make(map[string]*pooledSession)
+	}
+	var evicted *mcpsdk.ClientSession
+	if len(pool.sessions) >= maxOrgSessions {
+		evicted = pool.removeLeastRecentlyUsedLocked()
+	}
+	entry := &pooledSession{ready: make(chan struct{}), lastUsed: time.Now(), inflight: 1}
+	pool.sessions[key] = entry
+	sessionPoolSize.WithLabelValues(c.config.ID).Set(float64(len(pool.sessions)))
+	pool.mu.Unlock()
+	sessionPoolRequests.WithLabelValues(c.config.ID, "miss").Inc()
+
+	if evicted != nil {
+		c.logger.Debug("Closing least recently used org session", "server", c.config.ID)
+		sessionPoolEvictions.WithLabelValues(c.config.ID, "capacity").Inc()
+		evicted.Close()
+	}
+
+	session, err = c.connectOrgSession(orgID, orgName, scopeOrgId)
+
+	pool.mu.Lock()
+	if err == nil && pool.closed {
+		session.Close()
+		session, err = nil, errClientClosed
+	}
+	entry.session, entry.err = session, err
+	if err != nil {
+		entry.inflight--
+		if pool.sessions[key] == entry {
+			delete(pool.sessions, key)
+			sessionPoolSize.WithLabelValues(c.config.ID).Set(float64(len(pool.sessions)))
+		}
+	}
+	pool.mu.Unlock()
+	close(entry.ready)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, pool.releaser(entry), nil
+}
+
+// releaser returns the function that ends one call's use of entry, counting
+// the session as used at that moment.
+func (p *orgSessionPool) releaser(entry *pooledSession) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			entry.inflight--
+			entry.lastUsed = time.Now()
+		})
+	}
+}
+
+// idle reports whether the session is connected and no call is using it.
+func (s *pooledSession) idle() bool {
+	return s.connected() && s.inflight == 0
+}
+
+// removeLeastRecentlyUsedLocked drops the idle session used longest ago and
+// returns it for the caller to close. Sessions still connecting or in use
+// are never evicted, so the pool may briefly exceed maxOrgSessions.
+func (p *orgSessionPool) removeLeastRecentlyUsedLocked() *mcpsdk.ClientSession {
+	var oldestKey string
+	var oldest *pooledSession
+	for key, entry := range p.sessions {
+		if entry.idle() && (oldest == nil || entry.lastUsed.Before(oldest.lastUsed)) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	delete(p.sessions, oldestKey)
+	return oldest.session
+}
+
+// removeOrgSession drops session from the pool if it is still the session of
+// its org context, and reports whether it did.
+func (c *Client) removeOrgSession(key string, session *mcpsdk.ClientSession) bool {
+	pool := &c.orgSessions
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	entry, ok := pool.sessions[key]
+	if !ok || !entry.connected() || entry.session != session {
+		return false
+	}
+	delete(pool.sessions, key)
+	sessionPoolSize.WithLabelValues(c.config.ID).Set(float64(len(pool.sessions)))
+	return true
+}
+
+// reconnectOrgSession replaces failed with a fresh session for the org
+// context. If another call already replaced it, that session is returned
+// instead of being torn down again.
+func (c *Client) reconnectOrgSession(failed *mcpsdk.ClientSession, orgID string, orgName string, scopeOrgId string) (*mcpsdk.ClientSession, func(), error) {
+	if c.removeOrgSession(orgSessionKey(orgID, orgName, scopeOrgId), failed) {
+		failed.Close()
+	}
+	return c.ensureOrgSession(orgID, orgName, scopeOrgId)
+}
+
+// checkOrgSessions closes org sessions unused for longer than
+// orgSessionIdleTimeout and pings the rest, dropping those that no longer
+// answer so their next call reconnects. The health monitor calls it on every
+// check.
+func (c *Client) checkOrgSessions() {
+	pool := &c.orgSessions
+	now := time.Now()
+	active := make(map[string]*mcpsdk.ClientSession)
+	var idle []*mcpsdk.ClientSession
+
+	pool.mu.Lock()
+	for key, entry := range pool.sessions {
+		if !entry.connected() {
+			continue
+		}
+		if entry.idle() && now.Sub(entry.lastUsed) > orgSessionIdleTimeout {
+			delete(pool.sessions, key)
+			idle = append(idle, entry.session)
+			continue
+		}
+		active[key] = entry.session
+	}
+	sessionPoolSize.WithLabelValues(c.config.ID).Set(float64(len(pool.sessions)))
+	pool.mu.Unlock()
+
+	for _, session := range idle {
+		sessionPoolEvictions.WithLabelValues(c.config.ID, "idle").Inc()
+		session.Close()
+	}
+
+	var wg sync.WaitGroup
+	for key, session := range active {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(c.ctx, pingTimeout)
+			defer cancel()
+			if err := session.Ping(ctx, nil); err == nil || c.ctx.Err() != nil {
+				return
+			}
+			if c.removeOrgSession(key, session) {
+				c.logger.Debug("Closing org session that stopped answering", "server", c.config.ID)
+				sessionPoolEvictions.WithLabelValues(c.config.ID, "unhealthy").Inc()
+				session.Close()
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// closeOrgSessions closes every pooled session; sessions still connecting
+// are closed once they connect.
+func (c *Client) closeOrgSessions() {
+	pool := &c.orgSessions
+	pool.mu.Lock()
+	pool.closed = true
+	var sessions []*mcpsdk.ClientSession
+	for _, entry := range pool.sessions {
+		if entry.connected() {
+			sessions = append(sessions, entry.session)
+		}
+	}
+	pool.sessions = nil
+	pool.mu.Unlock()
+
+	for _, session := range sessions {
+		session.Close()
+	}
+}
+
+func orgSessionKey(orgID string, orgName string, scopeOrgId string) string {
+	return orgID + "\x00" + orgName + "\x00" + scopeOrgId
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func callTenantQuery(t *testing.T, c *Client, scope string) {
+	t.Helper()
+	result, err := c.CallToolWithContext(context.Background(), "tenant_query", map[string]interface{}{}, "2", "", scope)
+	if err != nil {
+		t.Fatalf("call for %s failed: %v", scope, err)
+	}
+	if result.IsError {
+		t.Fatalf("tool error for %s: %+v", scope, result.Content)
+	}
+}
+
+func TestOrgSessionPool_AlternatingOrgsReuseTheirSessions(t *testing.T) {
+	ts, stats := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+	hits := sessionPoolRequests.WithLabelValues("tenant", "hit")
+	misses := sessionPoolRequests.WithLabelValues("tenant", "miss")
+	hitsBefore, missesBefore := counterValue(t, hits), counterValue(t, misses)
+
+	for range 3 {
+		callTenantQuery(t, c, "tenant-a")
+		callTenantQuery(t, c, "tenant-b")
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.sessions != 2 {
+		t.Fatalf("expected one session per tenant, got %d", stats.sessions)
+	}
+	if stats.scopes["tenant-a"] != 3 || stats.scopes["tenant-b"] != 3 {
+		t.Fatalf("calls carried the wrong scopes: %v", stats.scopes)
+	}
+	if got := counterValue(t, misses) - missesBefore; got != 2 {
+		t.Errorf("misses = %v, want 2", got)
+	}
+	if got := counterValue(t, hits) - hitsBefore; got != 4 {
+		t.Errorf("hits = %v, want 4", got)
+	}
+}
+
+func TestOrgSessionPool_EvictsLeastRecentlyUsedWhenFull(t *testing.T) {
+	ts, stats := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+
+	for i := range maxOrgSessions {
+		callTenantQuery(t, c, fmt.Sprintf("tenant-%d", i))
+	}
+	// tenant-0 is used again, so tenant-1 is now the least recently used.
+	callTenantQuery(t, c, "tenant-0")
+	callTenantQuery(t, c, "tenant-new")
+
+	c.orgSessions.mu.Lock()
+	size := len(c.orgSessions.sessions)
+	_, kept := c.orgSessions.sessions[orgSessionKey("2", "", "tenant-0")]
+	_, evicted := c.orgSessions.sessions[orgSessionKey("2", "", "tenant-1")]
+	c.orgSessions.mu.Unlock()
+	if size != maxOrgSessions || !kept || evicted {
+		t.Fatalf("pool size %d, tenant-0 kept %v, tenant-1 kept %v", size, kept, evicted)
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.sessions != maxOrgSessions+1 {
+		t.Fatalf("expected %d sessions, got %d", maxOrgSessions+1, stats.sessions)
+	}
+}
+
+func TestCheckOrgSessions_ClosesIdleAndDeadSessions(t *testing.T) {
+	ts, stats := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+	callTenantQuery(t, c, "tenant-idle")
+	callTenantQuery(t, c, "tenant-dead")
+	callTenantQuery(t, c, "tenant-live")
+
+	idleKey := orgSessionKey("2", "", "tenant-idle")
+	deadKey := orgSessionKey("2", "", "tenant-dead")
+	c.orgSessions.mu.Lock()
+	c.orgSessions.sessions[idleKey].lastUsed = time.Now().Add(-orgSessionIdleTimeout - time.Minute)
+	c.orgSessions.sessions[deadKey].session.Close()
+	c.orgSessions.mu.Unlock()
+
+	c.checkOrgSessions()
+
+	c.orgSessions.mu.Lock()
+	_, idleKept := c.orgSessions.sessions[idleKey]
+	_, deadKept := c.orgSessions.sessions[deadKey]
+	_, liveKept := c.orgSessions.sessions[orgSessionKey("2", "", "tenant-live")]
+	c.orgSessions.mu.Unlock()
+	if idleKept || deadKept || !liveKept {
+		t.Fatalf("idle kept %v, dead kept %v, live kept %v", idleKept, deadKept, liveKept)
+	}
+
+	// The dead tenant's next call reconnects.
+	callTenantQuery(t, c, "tenant-dead")
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.sessions != 4 {
+		t.Fatalf("expected 4 sessions, got %d", stats.sessions)
+	}
+}
+
+func TestOrgSessionPool_KeepsSessionsWithCallsInFlight(t *testing.T) {
+	ts, _ := newTenantMCPServer(t)
+	c := newTenantClient(t, ts.URL)
+
+	// tenant-busy is the least recently used session, but a long call holds it.
+	busyKey := orgSessionKey("2", "", "tenant-busy")
+	_, release, err := c.ensureOrgSession("2", "", "tenant-busy")
+	if err != nil {
+		t.Fatalf("ensureOrgSession failed: %v", err)
+	}
+	c.orgSessions.mu.Lock()
+	c.orgSessions.sessions[busyKey].lastUsed = time.Now().Add(-orgSessionIdleTimeout - time.Minute)
+	c.orgSessions.mu.Unlock()
+	for i := range maxOrgSessions - 1 {
+		callTenantQuery(t, c, fmt.Sprintf("tenant-%d", i))
+	}
+
+	callTenantQuery(t, c, "tenant-new")
+	c.checkOrgSessions()
+
+	c.orgSessions.mu.Lock()
+	_, busyKept := c.orgSessions.sessions[busyKey]
+	_, lruKept := c.orgSessions.sessions[orgSessionKey("2", "", "tenant-0")]
+	c.orgSessions.mu.Unlock()
+	if !busyKept || lruKept {
+		t.Fatalf("busy session kept %v, least recently used idle session kept %v", busyKept, lruKept)
+	}
+
+	release()
+	c.orgSessions.mu.Lock()
+	entry := c.orgSessions.sessions[busyKey]
+	inflight, sinceUse := entry.inflight, time.Since(entry.lastUsed)
+	c.orgSessions.mu.Unlock()
+	if inflight != 0 || sinceUse > time.Minute {
+		t.Fatalf("after release: inflight %d, last used %s ago", inflight, sinceUse)
+	}
+}
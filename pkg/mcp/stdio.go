@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// stdioRestartMinBackoff and stdioRestartMaxBackoff bound the delay
+	// between attempts to restart an exited stdio server.
+	stdioRestartMinBackoff = 1 * time.Second
+	stdioRestartMaxBackoff = 30 * time.Second
+
+	// stdioStableUptime is how long a restarted server must stay up before
+	// the restart backoff starts over from stdioRestartMinBackoff.
+	stdioStableUptime = 1 * time.Minute
+
+	// stdioTerminateTimeout is how long a closing session waits for the
+	// process to exit after closing its stdin before sending SIGTERM.
+	stdioTerminateTimeout = 5 * time.Second
+
+	// stderrMaxLine caps how much of an unterminated stderr line is buffered
+	// before it is logged anyway.
+	stderrMaxLine = 4096
+)
+
+// stdioInheritedEnv lists the plugin environment variables passed to stdio
+// servers. Everything else, including Grafana's own GF_* settings, must be
+// configured explicitly through ServerConfig.Env.
+var stdioInheritedEnv = []string{"PATH", "HOME", "TMPDIR", "LANG"}
+
+// stdioTransport builds the transport that spawns the server's command. The
+// process is tied to the client's context so Close always reaps it.
+func (c *Client) stdioTransport() *mcpsdk.CommandTransport {
+	cmd := exec.CommandContext(c.ctx, c.config.Command, c.config.Args...)
+	cmd.Env = stdioEnv(c.config.Env)
+	cmd.Stderr = &stderrLogger{logger: c.logger, serverID: c.config.ID}
+	return &mcpsdk.CommandTransport{Command: cmd, TerminateDuration: stdioTerminateTimeout}
+}
+
+// sessionOrgContext drops the org context for stdio servers: one process
+// serves every org and there are no request headers to carry it.
+func (c *Client) sessionOrgContext(orgID string, orgName string, scopeOrgId string) (string, string, string) {
+	if c.config.Type == "stdio" {
+		return "", "", ""
+	}
+	return orgID, orgName, scopeOrgId
+}
+
+// stdioEnv returns the inherited variables followed by the configured ones,
+// in a stable order.
+func stdioEnv(configured map[string]string) []string {
+	var env []string
+	for _, name := range stdioInheritedEnv {
+		if _, overridden := configured[name]; overridden {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+configured[name])
+	}
+	return env
+}
+
+// superviseStdio waits for session to end and, unless it was closed on
+// purpose, restarts the server with exponential backoff.
+func (c *Client) superviseStdio(session *mcpsdk.ClientSession) {
+	waitErr := session.Wait()
+
+	c.mu.Lock()
+	current := c.session == session
+	if current {
+		c.session = nil
+		// The backoff outlives this supervisor so a server that crashes
+		// right after each restart is not restarted every second forever.
+		if time.Since(c.sessionCreatedAt) >= stdioStableUptime {
+			c.stdioBackoff = 0
+		}
+	}
+	c.mu.Unlock()
+	// Sessions replaced by a reconnect, or closed with the client, are not
+	// restarted here.
+	if !current || c.ctx.Err() != nil {
+		return
+	}
+
+	c.logger.Warn("MCP stdio server exited, restarting", "server", c.config.ID, "error", waitErr)
+	for {
+		backoff := c.stdioRestartDelay()
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		err := c.connectMCP()
+		if err == nil {
+			// The new process may advertise different tools.
+			c.InvalidateTools()
+			c.logger.Info("MCP stdio server restarted", "server", c.config.ID)
+			return
+		}
+		c.logger.Warn("Failed to restart MCP stdio server", "server", c.config.ID, "error", err)
+	}
+}
+
+// stdioRestartDelay returns how long to wait before the next restart attempt
+// and doubles the delay for the one after, up to stdioRestartMaxBackoff.
+func (c *Client) stdioRestartDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delay := max(c.stdioBackoff, stdioRestartMinBackoff)
+	c.stdioBackoff = min(delay*2, stdioRestartMaxBackoff)
+	return delay
+}
+
+// stderrLogger writes a stdio server's stderr to the plugin log, one entry
+// per line.
+type stderrLogger struct {
+	logger   log.Logger
+	serverID string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *stderrLogger) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= stderrMaxLine {
+		w.logLine(w.buf)
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *stderrLogger) logLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	w.logger.Info("MCP stdio server stderr", "server", w.serverID, "line", string(line))
+}
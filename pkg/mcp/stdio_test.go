@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const stdioHelperEnv = "ASKO11Y_STDIO_MCP_HELPER"
+
+// TestStdioHelperProcess is not a real test: stdio tests re-run the test
+// binary with stdioHelperEnv set to get an MCP server speaking over stdio.
+func TestStdioHelperProcess(t *testing.T) {
+	if os.Getenv(stdioHelperEnv) != "1" {
+		t.Skip("helper process for stdio tests")
+	}
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "helper", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "pid"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: strconv.Itoa(os.Getpid())}}}, nil, nil
+	})
+	fmt.Fprintln(os.Stderr, "helper started")
+	_ = server.Run(context.Background(), &mcpsdk.StdioTransport{})
+	os.Exit(0)
+}
+
+func newStdioHelperClient(t *testing.T) *Client {
+	t.Helper()
+	c := NewClient(context.Background(), ServerConfig{
+		ID:      "local",
+		Type:    "stdio",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestStdioHelperProcess$"},
+		Env:     map[string]string{stdioHelperEnv: "1"},
+		Enabled: true,
+	}, log.DefaultLogger, &http.Client{})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func callPid(t *testing.T, c *Client) int {
+	t.Helper()
+	// Org context must be ignored rather than opening a second process.
+	result, err := c.CallToolWithContext(context.Background(), "local_pid", nil, "2", "tenant", "")
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	pid, err := strconv.Atoi(result.Content[0].Text)
+	if err != nil {
+		t.Fatalf("unexpected pid result %+v", result)
+	}
+	return pid
+}
+
+func TestStdioClientListsAndCallsTools(t *testing.T) {
+	c := newStdioHelperClient(t)
+
+	tools, err := c.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "local_pid" {
+		t.Fatalf("expected prefixed pid tool, got %v", toolNames(tools))
+	}
+	if first, second := callPid(t, c), callPid(t, c); first != second {
+		t.Fatalf("calls must share one process, got pids %d and %d", first, second)
+	}
+	if err := c.ping(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+}
+
+func TestStdioClientRestartsExitedProcess(t *testing.T) {
+	c := newStdioHelperClient(t)
+	pid := callPid(t, c)
+	c.mu.RLock()
+	old := c.session
+	c.mu.RUnlock()
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatalf("FindProcess: %v", err)
+	}
+	if err := proc.Kill(); err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+
+	// The supervisor restarts the process without waiting for a call.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		c.mu.RLock()
+		current := c.session
+		c.mu.RUnlock()
+		if current != nil && current != old {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stdio server was not restarted")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if newPid := callPid(t, c); newPid == pid {
+		t.Fatalf("expected a new process, still talking to pid %d", pid)
+	}
+}
+
+func TestStdioEnvPassesOnlyAllowedAndConfiguredVariables(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("HOME", "/home/grafana")
+	t.Setenv("GF_SECURITY_ADMIN_PASSWORD", "secret")
+
+	env := stdioEnv(map[string]string{"HOME": "/srv", "B_TOKEN": "b", "A_REGION": "eu"})
+
+	if slices.Contains(env, "GF_SECURITY_ADMIN_PASSWORD=secret") {
+		t.Fatalf("plugin environment leaked to stdio server: %v", env)
+	}
+	for _, want := range []string{"PATH=/usr/bin", "HOME=/srv", "A_REGION=eu", "B_TOKEN=b"} {
+		if !slices.Contains(env, want) {
+			t.Fatalf("expected %q in %v", want, env)
+		}
+	}
+	if slices.Contains(env, "HOME=/home/grafana") {
+		t.Fatalf("configured HOME must override the inherited one: %v", env)
+	}
+	if slices.Index(env, "A_REGION=eu") > slices.Index(env, "B_TOKEN=b") {
+		t.Fatalf("configured variables must be sorted: %v", env)
+	}
+}
+
+type lineRecordingLogger struct {
+	log.Logger
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *lineRecordingLogger) Info(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "line" {
+			l.lines = append(l.lines, args[i+1].(string))
+		}
+	}
+}
+
+func TestStderrLoggerLogsWholeLines(t *testing.T) {
+	logger := &lineRecordingLogger{Logger: log.DefaultLogger}
+	w := &stderrLogger{logger: logger, serverID: "local"}
+
+	fmt.Fprint(w, "starting\r\nlistening on ")
+	fmt.Fprint(w, "stdin\n\n")
+	if want := []string{"starting", "listening on stdin"}; !slices.Equal(logger.lines, want) {
+		t.Fatalf("got lines %q, want %q", logger.lines, want)
+	}
+
+	fmt.Fprint(w, string(make([]byte, stderrMaxLine)))
+	if len(logger.lines) != 3 {
+		t.Fatalf("expected an overlong partial line to be flushed, got %d lines", len(logger.lines))
+	}
+}
+
+func TestStdioRestartBackoffCarriesOverRestarts(t *testing.T) {
+	c := &Client{}
+
+	// Each restart is scheduled by a new supervisor, but the delays keep
+	// growing up to the cap.
+	var delays []time.Duration
+	for range 7 {
+		delays = append(delays, c.stdioRestartDelay())
+	}
+	want := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("got delays %v, want %v", delays, want)
+	}
+}
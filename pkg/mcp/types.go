@@ -44,6 +44,9 @@ type Tool struct {
 	InputSchema  map[string]interface{} `json:"inputSchema"`
 	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
 	Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
+	// ServerID is the ID of the server the tool was listed from. Name's
+	// "{serverID}_" prefix is ambiguous when server IDs contain underscores.
+	ServerID string `json:"serverId,omitempty"`
 }
 
 // ListToolsResult represents the result of listing tools
@@ -92,12 +95,42 @@ type ServerConfig struct {
 	ID             string                      `json:"id"`
 	Name           string                      `json:"name"`
 	URL            string                      `json:"url"`
-	Type           string                      `json:"type"` // "openapi", "standard", "sse", "streamable-http"
+	Type           string                      `json:"type"` // "openapi", "standard", "sse", "streamable-http", "stdio"
 	Enabled        bool                        `json:"enabled"`
 	Trusted        bool                        `json:"trusted,omitempty"`
 	Headers        map[string]string           `json:"headers,omitempty"`
 	ToolSelections map[string]bool             `json:"toolSelections,omitempty"`
 	RiskOverrides  map[string]ToolRiskOverride `json:"riskOverrides,omitempty"`
+	// ToolTimeoutSeconds bounds every tool call on this server (default 30s).
+	// ToolTimeouts overrides it per tool, keyed by the unprefixed tool name.
+	// Time the server spends waiting on a sampling or elicitation answer
+	// doesn't count; those waits have their own input timeout.
+	ToolTimeoutSeconds int            `json:"toolTimeoutSeconds,omitempty"`
+	ToolTimeouts       map[string]int `json:"toolTimeouts,omitempty"`
+	// Command, Args and Env configure stdio servers, which the plugin spawns
+	// and talks to over stdin/stdout. Secret Env values come from
+	// secureJsonData.
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Auth makes the plugin obtain and refresh bearer tokens itself instead
+	// of relying on a static Authorization header.
+	Auth *ServerAuth `json:"auth,omitempty"`
+}
+
+// ServerAuth configures OAuth 2 client-credentials authentication for a
+// server. ClientSecret comes from secureJsonData.
+type ServerAuth struct {
+	Type string `json:"type"` // "oauth2-client-credentials"
+	// TokenURL is discovered from the server's OAuth protected resource
+	// metadata when empty.
+	TokenURL     string   `json:"tokenUrl,omitempty"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"-"`
+	Scopes       []string `json:"scopes,omitempty"`
+	// Resource is sent as the RFC 8707 resource parameter. It defaults to
+	// the resource named in the discovered metadata.
+	Resource string `json:"resource,omitempty"`
 }
 
 // ToolRiskOverride lets administrators override a tool's MCP annotations or
@@ -109,3 +142,90 @@ type ToolRiskOverride struct {
 	OpenWorld        *bool  `json:"openWorld,omitempty"`
 	Reason           string `json:"reason,omitempty"`
 }
+
+// Resource represents an MCP resource. URI and Name carry the same
+// "{serverID}_" prefix as tool names so reads can be routed back.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ResourceTemplate represents a parameterized MCP resource (RFC 6570 URI template).
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is one part of a resource read. Blob is base64-encoded.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ListResourcesResult represents the result of listing resources
+type ListResourcesResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ListResourceTemplatesResult represents the result of listing resource templates
+type ListResourceTemplatesResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+// ReadResourceParams represents parameters for reading a resource
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ReadResourceResult represents the result of reading a resource
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// Prompt represents an MCP prompt template. Name is "{serverID}_" prefixed.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes one argument a prompt accepts
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// ListPromptsResult represents the result of listing prompts
+type ListPromptsResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// GetPromptParams represents parameters for rendering a prompt
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt
+type PromptMessage struct {
+	Role    string       `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+// GetPromptResult represents a rendered prompt
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
@@ -0,0 +1,379 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"consensys-asko11y-app/pkg/mcp"
+	"consensys-asko11y-app/pkg/rbac"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// agentMCPServerName is the implementation name IDE and CLI agents see when
+// they connect to /mcp.
+const agentMCPServerName = "ask-o11y"
+
+// agentMCPCaller is the Grafana user an /mcp request came from. Runs started
+// through the agent tools belong to them, exactly like runs started from the
+// app.
+type agentMCPCaller struct {
+	// ctx is the request context; startAgentRun reads the Grafana config
+	// from it.
+	ctx          context.Context
+	userID       int64
+	login        string
+	role         string
+	orgID        string
+	numericOrgID int64
+}
+
+func agentMCPCallerFromRequest(r *http.Request) agentMCPCaller {
+	orgID := r.Header.Get("X-Grafana-Org-Id")
+	if orgID == "" {
+		orgID = "1"
+	}
+	return agentMCPCaller{
+		ctx:          r.Context(),
+		userID:       getUserID(r),
+		login:        getUserLogin(r),
+		role:         getUserRole(r),
+		orgID:        orgID,
+		numericOrgID: getOrgID(r),
+	}
+}
+
+// isStreamableMCPRequest reports whether r comes from a streamable-HTTP MCP
+// client rather than a plain JSON-RPC POST to the proxy.
+func isStreamableMCPRequest(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveAgentMCP serves /mcp over streamable HTTP: the agent tools plus the
+// downstream tools the caller may use. The server is stateless, so any replica
+// can answer any request, and each request gets a server bound to its caller.
+func (p *Plugin) serveAgentMCP(w http.ResponseWriter, r *http.Request) {
+	handler := mcpsdk.NewStreamableHTTPHandler(func(r *http.Request) *mcpsdk.Server {
+		return p.newAgentMCPServer(agentMCPCallerFromRequest(r))
+	}, &mcpsdk.StreamableHTTPOptions{Stateless: true})
+	handler.ServeHTTP(w, r)
+}
+
+type askInput struct {
+	Question  string `json:"question" jsonschema:"The question or task for the agent"`
+	SessionID string `json:"sessionId,omitempty" jsonschema:"Continue this Ask O11y session instead of starting a new one"`
+	Model     string `json:"model,omitempty" jsonschema:"base or large; chosen from the question when omitted"`
+}
+
+type investigateAlertInput struct {
+	AlertName   string            `json:"alertName" jsonschema:"Name of the firing alert"`
+	Labels      map[string]string `json:"labels,omitempty" jsonschema:"Alert labels, such as namespace or service"`
+	Annotations map[string]string `json:"annotations,omitempty" jsonschema:"Alert annotations, such as summary or runbook_url"`
+	SessionID   string            `json:"sessionId,omitempty" jsonschema:"Continue this Ask O11y session instead of starting a new one"`
+}
+
+type getSessionInput struct {
+	SessionID string `json:"sessionId" jsonschema:"ID of one of your Ask O11y sessions"`
+}
+
+// agentMCPRunResult is what the agent tools return once the run finishes.
+type agentMCPRunResult struct {
+	RunID       string                  `json:"runId"`
+	SessionID   string                  `json:"sessionId"`
+	Status      string                  `json:"status"`
+	Answer      string                  `json:"answer"`
+	FinalReport *agent.FinalReportEvent `json:"finalReport,omitempty"`
+	Evidence    []agent.EvidenceEvent   `json:"evidence,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+}
+
+type agentMCPSessionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type agentMCPSession struct {
+	SessionID   string                   `json:"sessionId"`
+	Title       string                   `json:"title"`
+	Messages    []agentMCPSessionMessage `json:"messages"`
+	ActiveRunID string                   `json:"activeRunId,omitempty"`
+	Model       string                   `json:"model,omitempty"`
+}
+
+func (p *Plugin) newAgentMCPServer(caller agentMCPCaller) *mcpsdk.Server {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: agentMCPServerName, Version: "1.0.0"}, nil)
+	p.addProxyTools(server, caller)
+
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "ask_o11y_ask",
+		Description: "Ask the Ask O11y observability agent a question. It investigates with Grafana's metrics, logs and traces as you, and returns its answer with the evidence it collected.",
+	}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in askInput) (*mcpsdk.CallToolResult, agentMCPRunResult, error) {
+		if strings.TrimSpace(in.Question) == "" {
+			return nil, agentMCPRunResult{}, errors.New("question is required")
+		}
+		switch in.Model {
+		case "", agentModelBase, agentModelLarge:
+		default:
+			return nil, agentMCPRunResult{}, errors.New("model must be base or large")
+		}
+		return p.runAgentForMCP(ctx, req, caller, agentRunParams{
+			Request:        agent.RunRequest{Message: in.Question, SessionID: in.SessionID},
+			RequestedModel: in.Model,
+		})
+	})
+
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "ask_o11y_investigate_alert",
+		Description: "Run an Ask O11y root-cause investigation of a firing alert and return the final report with its verdict, evidence and next steps.",
+	}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in investigateAlertInput) (*mcpsdk.CallToolResult, agentMCPRunResult, error) {
+		if strings.TrimSpace(in.AlertName) == "" {
+			return nil, agentMCPRunResult{}, errors.New("alertName is required")
+		}
+		labels := make(map[string]string, len(in.Labels)+1)
+		for k, v := range in.Labels {
+			labels[k] = v
+		}
+		labels["alertname"] = in.AlertName
+		return p.runAgentForMCP(ctx, req, caller, agentRunParams{
+			Request: agent.RunRequest{
+				Message:   "alertName:" + in.AlertName,
+				Type:      "investigation",
+				SessionID: in.SessionID,
+			},
+			PromptSuffix: alertPromptDetails(WebhookAlert{Status: "firing", Labels: labels, Annotations: in.Annotations}),
+		})
+	})
+
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "ask_o11y_get_session",
+		Description: "Read one of your Ask O11y sessions: its conversation so far and the run still in progress, if any.",
+		Annotations: &mcpsdk.ToolAnnotations{ReadOnlyHint: true},
+	}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in getSessionInput) (*mcpsdk.CallToolResult, agentMCPSession, error) {
+		if !isValidSecureID(in.SessionID) {
+			return nil, agentMCPSession{}, errors.New("invalid sessionId")
+		}
+		session, err := p.sessionStore.GetSession(in.SessionID, caller.userID, caller.numericOrgID)
+		if err != nil {
+			return nil, agentMCPSession{}, errors.New("session not found")
+		}
+		out := agentMCPSession{
+			SessionID:   session.ID,
+			Title:       session.Title,
+			Messages:    make([]agentMCPSessionMessage, 0, len(session.Messages)),
+			ActiveRunID: session.ActiveRunID,
+			Model:       session.Model,
+		}
+		for _, msg := range session.Messages {
+			out.Messages = append(out.Messages, agentMCPSessionMessage{Role: msg.Role, Content: msg.Content})
+		}
+		return nil, out, nil
+	})
+
+	return server
+}
+
+// addProxyTools re-exports the downstream tools caller may use, as
+// /api/mcp/tools lists them, so streamable clients keep the tools the
+// JSON-RPC proxy offers.
+func (p *Plugin) addProxyTools(server *mcpsdk.Server, caller agentMCPCaller) {
+	tools, err := p.mcpProxy.ListTools()
+	if err != nil {
+		p.logger.Warn("Failed to list tools for MCP server", "error", err)
+		return
+	}
+	policy := p.toolPolicy()
+	subject := p.policySubject(caller.ctx, policy.Rules, caller.numericOrgID, caller.login, caller.role)
+	tools = rbac.FilterToolsByRole(tools, subject, policy)
+	tools = mcp.FilterToolsBySelection(tools, p.settingsForFilter())
+	for _, tool := range tools {
+		var exported mcpsdk.Tool
+		data, err := json.Marshal(tool)
+		if err == nil {
+			err = json.Unmarshal(data, &exported)
+		}
+		if err != nil || exported.InputSchema == nil {
+			p.logger.Warn("Skipping tool the MCP server cannot export", "tool", tool.Name, "error", err)
+			continue
+		}
+		server.AddTool(&exported, p.proxyToolHandler(caller, tool))
+	}
+}
+
+func (p *Plugin) proxyToolHandler(caller agentMCPCaller, tool mcp.Tool) mcpsdk.ToolHandler {
+	return func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		var args map[string]interface{}
+		if len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		mcp.EnsureScopedGraphitiArgs(tool, args, caller.orgID)
+
+		var out mcpsdk.CallToolResult
+		result, err := p.mcpProxy.CallToolWithContext(ctx, tool.Name, args, caller.orgID, "", "")
+		if err != nil {
+			p.logger.Error("Failed to call tool", "error", err, "tool", tool.Name)
+			out.SetError(errors.New("failed to call tool"))
+			return &out, nil
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	}
+}
+
+// runAgentForMCP starts a run for caller and waits for it, reporting its
+// tool calls and pending approvals as progress notifications. If the client
+// goes away first the run keeps going; ask_o11y_get_session reads its answer
+// later.
+func (p *Plugin) runAgentForMCP(ctx context.Context, req *mcpsdk.CallToolRequest, caller agentMCPCaller, params agentRunParams) (*mcpsdk.CallToolResult, agentMCPRunResult, error) {
+	params.UserID = caller.userID
+	params.UserLogin = caller.login
+	params.UserRole = caller.role
+	params.OrgID = caller.orgID
+	params.NumericOrgID = caller.numericOrgID
+	if params.Request.OrgName == "" {
+		params.Request.OrgName = "Org" + caller.orgID
+	}
+	if params.Request.SessionID != "" && !isValidSecureID(params.Request.SessionID) {
+		return nil, agentMCPRunResult{}, errors.New("invalid sessionId")
+	}
+
+	started, err := p.startAgentRun(caller.ctx, params)
+	if err != nil {
+		var runErr *agentRunError
+		if errors.As(err, &runErr) {
+			return nil, agentMCPRunResult{}, errors.New(runErr.message)
+		}
+		return nil, agentMCPRunResult{}, errors.New("failed to start agent run")
+	}
+
+	run, events, unsub, err := p.runStore.SubscribeAndSnapshot(started.RunID)
+	if err != nil {
+		return nil, agentMCPRunResult{}, fmt.Errorf("agent run %s not found", started.RunID)
+	}
+	if unsub != nil {
+		defer unsub()
+	}
+
+	progress := agentMCPProgress{req: req}
+	for _, event := range run.Events {
+		progress.report(ctx, event)
+	}
+	for events != nil {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			progress.report(ctx, event)
+		case <-ctx.Done():
+			return nil, agentMCPRunResult{}, fmt.Errorf("stopped waiting for agent run %s in session %s; it keeps running, read its answer with ask_o11y_get_session", started.RunID, started.SessionID)
+		}
+	}
+
+	finished, err := p.runStore.GetRun(started.RunID)
+	if err != nil {
+		return nil, agentMCPRunResult{}, fmt.Errorf("agent run %s not found", started.RunID)
+	}
+	out := agentMCPRunResult{
+		RunID:     finished.RunID,
+		SessionID: started.SessionID,
+		Status:    string(finished.Status),
+		Answer:    reconstructAssistantMessage(finished.Events).Content,
+		Error:     finished.Error,
+	}
+	if finished.Trace != nil {
+		out.FinalReport = finished.Trace.FinalReport
+		out.Evidence = finished.Trace.Evidence
+	}
+
+	text := out.Answer
+	if finished.Status != RunStatusCompleted {
+		text = fmt.Sprintf("Agent run %s %s: %s", out.RunID, out.Status, out.Error)
+	}
+	return &mcpsdk.CallToolResult{
+		Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: text}},
+		IsError: finished.Status != RunStatusCompleted,
+	}, out, nil
+}
+
+// agentMCPProgress turns run events into progress notifications for clients
+// that asked for them.
+type agentMCPProgress struct {
+	req   *mcpsdk.CallToolRequest
+	count float64
+}
+
+func (p *agentMCPProgress) report(ctx context.Context, event agent.SSEEvent) {
+	token := p.req.Params.GetProgressToken()
+	if token == nil {
+		return
+	}
+	message := agentMCPProgressMessage(event)
+	if message == "" {
+		return
+	}
+	p.count++
+	// Notifications are best effort; a client that stopped reading them
+	// still gets the result.
+	_ = p.req.Session.NotifyProgress(ctx, &mcpsdk.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      p.count,
+		Message:       message,
+	})
+}
+
+// agentMCPProgressMessage describes the run events worth telling the client
+// about. Events read back from Redis are maps rather than typed structs, so
+// the fields are read from their JSON form.
+func agentMCPProgressMessage(event agent.SSEEvent) string {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Name     string `json:"name"`
+		ToolName string `json:"toolName"`
+		ServerID string `json:"serverId"`
+		Message  string `json:"message"`
+		Title    string `json:"title"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	switch event.Type {
+	case "tool_call_start":
+		return "Calling " + fields.Name
+	case "tool_call_progress":
+		if fields.Message == "" {
+			return ""
+		}
+		return fields.Name + ": " + fields.Message
+	case "evidence":
+		return "Evidence: " + fields.Title
+	case "approval_request":
+		return fmt.Sprintf("Waiting for approval of %s in Ask O11y", fields.ToolName)
+	case "input_request":
+		return fmt.Sprintf("%s is waiting for your input in Ask O11y: %s", fields.ServerID, fields.Message)
+	}
+	return ""
+}
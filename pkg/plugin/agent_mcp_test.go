@@ -0,0 +1,221 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"consensys-asko11y-app/pkg/mcp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newAgentMCPTestSession serves p's /mcp endpoint as Grafana would proxy it
+// for user 7 in org 2, and connects a streamable-HTTP MCP client to it.
+func newAgentMCPTestSession(t *testing.T, p *Plugin, grafanaURL, role string) *mcpsdk.ClientSession {
+	t.Helper()
+	cfg := backend.NewGrafanaCfg(map[string]string{
+		"GF_APP_URL":                  grafanaURL,
+		"GF_PLUGIN_APP_CLIENT_SECRET": "test-token",
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-Grafana-Org-Id", "2")
+		r.Header.Set("X-Grafana-User-Id", "7")
+		r.Header.Set("X-Grafana-User-Role", role)
+		p.handleMCP(w, r.WithContext(backend.WithGrafanaConfig(r.Context(), cfg)))
+	}))
+	t.Cleanup(ts.Close)
+
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "ide", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), &mcpsdk.StreamableClientTransport{Endpoint: ts.URL}, nil)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestAgentMCPListsAgentTools(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	session := newAgentMCPTestSession(t, p, "http://grafana.test", "Viewer")
+
+	res, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+		if tool.OutputSchema == nil {
+			t.Errorf("%s has no output schema", tool.Name)
+		}
+	}
+	want := "ask_o11y_ask,ask_o11y_get_session,ask_o11y_investigate_alert"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("tools = %s, want %s", got, want)
+	}
+}
+
+func TestAgentMCPReexportsToolsTheCallersRoleAllows(t *testing.T) {
+	downstream := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "grafana", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(downstream, &mcpsdk.Tool{Name: "get_dashboard", Annotations: &mcpsdk.ToolAnnotations{ReadOnlyHint: true}}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct {
+		UID string `json:"uid"`
+	}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "dashboard " + in.UID}}}, nil, nil
+	})
+	mcpsdk.AddTool(downstream, &mcpsdk.Tool{Name: "create_dashboard"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{}, nil, nil
+	})
+	ts := httptest.NewServer(mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return downstream }, nil))
+	defer ts.Close()
+
+	p := newAgentRunTestPlugin(t)
+	if err := p.mcpProxy.EnsureServer(mcp.ServerConfig{ID: "grafana", URL: ts.URL, Type: "streamable-http", Enabled: true}); err != nil {
+		t.Fatalf("EnsureServer: %v", err)
+	}
+	session := newAgentMCPTestSession(t, p, "http://grafana.test", "Viewer")
+
+	res, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+	}
+	want := "ask_o11y_ask,ask_o11y_get_session,ask_o11y_investigate_alert,grafana_get_dashboard"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("tools = %s, want %s", got, want)
+	}
+
+	call, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "grafana_get_dashboard",
+		Arguments: map[string]any{"uid": "checkout"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if call.IsError || call.Content[0].(*mcpsdk.TextContent).Text != "dashboard checkout" {
+		t.Fatalf("unexpected result: %+v", call.Content)
+	}
+}
+
+func TestAgentMCPAskRunsAgentAsCaller(t *testing.T) {
+	llmServer, received := newAgentRunLLMServer(t)
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	session := newAgentMCPTestSession(t, p, llmServer.URL, "Viewer")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := session.CallTool(ctx, &mcpsdk.CallToolParams{
+		Name:      "ask_o11y_ask",
+		Arguments: map[string]any{"question": "is checkout healthy?"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("unexpected tool error: %+v", res.Content[0])
+	}
+	if text := res.Content[0].(*mcpsdk.TextContent).Text; text != "ok" {
+		t.Fatalf("answer = %q, want ok", text)
+	}
+	receiveAgentRunLLMRequest(t, received)
+
+	data, _ := json.Marshal(res.StructuredContent)
+	var out agentMCPRunResult
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("decode structured content: %v", err)
+	}
+	if out.Status != string(RunStatusCompleted) || out.Answer != "ok" || out.RunID == "" {
+		t.Fatalf("unexpected structured content: %+v", out)
+	}
+
+	// The run belongs to the caller, so its session is readable through the
+	// app and through ask_o11y_get_session. The assistant message is appended
+	// just after the run finishes.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, err := p.sessionStore.GetSession(out.SessionID, 7, 2)
+		if err != nil {
+			t.Fatalf("session not owned by the caller: %v", err)
+		}
+		if len(stored.Messages) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	res, err = session.CallTool(ctx, &mcpsdk.CallToolParams{
+		Name:      "ask_o11y_get_session",
+		Arguments: map[string]any{"sessionId": out.SessionID},
+	})
+	if err != nil || res.IsError {
+		t.Fatalf("get_session: %+v, %v", res, err)
+	}
+	data, _ = json.Marshal(res.StructuredContent)
+	var got agentMCPSession
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode session: %v", err)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Content != "is checkout healthy?" || got.Messages[1].Content != "ok" {
+		t.Fatalf("unexpected session messages: %+v", got.Messages)
+	}
+}
+
+func TestAgentMCPGetSessionRejectsOtherUsersSessions(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	other, err := p.sessionStore.CreateSession(8, 2, "someone else's", []SessionMessage{{Role: "user", Content: "secret"}})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	session := newAgentMCPTestSession(t, p, "http://grafana.test", "Admin")
+
+	res, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "ask_o11y_get_session",
+		Arguments: map[string]any{"sessionId": other.ID},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !res.IsError || !strings.Contains(res.Content[0].(*mcpsdk.TextContent).Text, "session not found") {
+		t.Fatalf("expected session not found, got %+v", res.Content)
+	}
+}
+
+func TestHandleMCPKeepsJSONRPCProxy(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	p.handleMCP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "consensys-mcp-proxy") {
+		t.Fatalf("expected the JSON-RPC proxy to answer, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAgentMCPProgressMessage(t *testing.T) {
+	tests := []struct {
+		event agent.SSEEvent
+		want  string
+	}{
+		{agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_1", Name: "grafana_query_prometheus"}}, "Calling grafana_query_prometheus"},
+		{agent.SSEEvent{Type: "approval_request", Data: agent.ApprovalRequestEvent{ApprovalID: "tc_1", ToolName: "grafana_create_incident"}}, "Waiting for approval of grafana_create_incident in Ask O11y"},
+		// Runs read back from Redis carry decoded maps.
+		{agent.SSEEvent{Type: "evidence", Data: map[string]interface{}{"id": "ev_1", "title": "5xx spike"}}, "Evidence: 5xx spike"},
+		{agent.SSEEvent{Type: "content", Data: agent.ContentEvent{Content: "partial"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := agentMCPProgressMessage(tt.event); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.event.Type, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// alertWebhookRole is the role webhook-started runs execute with. Nobody is
+// around to approve write tools, so unattended investigations are read-only.
+const alertWebhookRole = "Viewer"
+
+// AlertWebhookPayload is the subset of the Alertmanager / Grafana-alerting
+// webhook body (version 4) the endpoint uses.
+type AlertWebhookPayload struct {
+	Receiver     string            `json:"receiver"`
+	Status       string            `json:"status"`
+	Alerts       []WebhookAlert    `json:"alerts"`
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	ExternalURL  string            `json:"externalURL,omitempty"`
+}
+
+type WebhookAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	Fingerprint  string            `json:"fingerprint,omitempty"`
+}
+
+// AlertWebhookResult reports what happened to one alert in the payload.
+type AlertWebhookResult struct {
+	Fingerprint string `json:"fingerprint"`
+	AlertName   string `json:"alertName"`
+	// Status is "started", "deduplicated", "skipped" (not firing or over the
+	// per-payload limit) or "failed".
+	Status    string `json:"status"`
+	RunID     string `json:"runId,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// AlertDedupeStore remembers which alert fingerprints already have an
+// investigation so Alertmanager's repeat notifications don't start new runs.
+type AlertDedupeStore interface {
+	// Claim returns true if the caller is first to see fingerprint in orgID
+	// within ttl.
+	Claim(orgID int64, fingerprint string, ttl time.Duration) (bool, error)
+	// Release forgets a claim, e.g. when the run could not be started.
+	Release(orgID int64, fingerprint string) error
+}
+
+type InMemoryAlertDedupeStore struct {
+	mu     sync.Mutex
+	claims map[string]time.Time
+}
+
+func NewInMemoryAlertDedupeStore() *InMemoryAlertDedupeStore {
+	return &InMemoryAlertDedupeStore{claims: make(map[string]time.Time)}
+}
+
+func alertDedupeKey(orgID int64, fingerprint string) string {
+	return fmt.Sprintf("alertwebhook:%d:%s", orgID, fingerprint)
+}
+
+func (s *InMemoryAlertDedupeStore) Claim(orgID int64, fingerprint string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, expires := range s.claims {
+		if now.After(expires) {
+			delete(s.claims, key)
+		}
+	}
+
+	key := alertDedupeKey(orgID, fingerprint)
+	if _, ok := s.claims[key]; ok {
+		return false, nil
+	}
+	s.claims[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *InMemoryAlertDedupeStore) Release(orgID int64, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claims, alertDedupeKey(orgID, fingerprint))
+	return nil
+}
+
+type RedisAlertDedupeStore struct {
+	client *redis.Client
+	logger log.Logger
+	ctx    context.Context
+}
+
+func NewRedisAlertDedupeStore(ctx context.Context, client *redis.Client, logger log.Logger) *RedisAlertDedupeStore {
+	return &RedisAlertDedupeStore{client: client, logger: logger, ctx: ctx}
+}
+
+func (s *RedisAlertDedupeStore) Claim(orgID int64, fingerprint string, ttl time.Duration) (bool, error) {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+	ok, err := s.client.SetNX(ctx, alertDedupeKey(orgID, fingerprint), time.Now().UTC().Format(time.RFC3339), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim alert fingerprint: %w", err)
+	}
+	return ok, nil
+}
+
+func (s *RedisAlertDedupeStore) Release(orgID int64, fingerprint string) error {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+	if err := s.client.Del(ctx, alertDedupeKey(orgID, fingerprint)).Err(); err != nil {
+		return fmt.Errorf("failed to release alert fingerprint: %w", err)
+	}
+	return nil
+}
+
+// alertFingerprint returns the Alertmanager fingerprint, or a stable hash of
+// the labels for senders that omit it.
+func alertFingerprint(alert WebhookAlert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	keys := make([]string, 0, len(alert.Labels))
+	for k := range alert.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(alert.Labels[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// alertPromptDetails renders the alert's labels, annotations and start time so
+// the investigation starts from what the pager saw rather than just the name.
+func alertPromptDetails(alert WebhookAlert) string {
+	var b strings.Builder
+	b.WriteString("\n\nAlert details from the notification webhook:\n")
+	if !alert.StartsAt.IsZero() {
+		fmt.Fprintf(&b, "- Started at: %s\n", alert.StartsAt.UTC().Format(time.RFC3339))
+	}
+	writeSortedMap(&b, "Labels", alert.Labels)
+	writeSortedMap(&b, "Annotations", alert.Annotations)
+	if alert.GeneratorURL != "" {
+		fmt.Fprintf(&b, "- Source: %s\n", alert.GeneratorURL)
+	}
+	return b.String()
+}
+
+func writeSortedMap(b *strings.Builder, title string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(b, "- %s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(b, "  - %s: %s\n", k, m[k])
+	}
+}
+
+// alertWebhookOrgScope resolves the org name and X-Scope-OrgID tenant for
+// webhook runs. Interactive runs send the Grafana org name as both; webhook
+// runs take them from the contact point URL's orgName and scopeOrgId query
+// parameters, falling back to the configured org name. With neither, no
+// tenant header is forwarded.
+func alertWebhookOrgScope(r *http.Request, configuredOrgName string) (orgName, scopeOrgID string) {
+	query := r.URL.Query()
+	orgName = strings.TrimSpace(query.Get("orgName"))
+	if orgName == "" {
+		orgName = configuredOrgName
+	}
+	scopeOrgID = strings.TrimSpace(query.Get("scopeOrgId"))
+	if scopeOrgID == "" {
+		scopeOrgID = orgName
+	}
+	return orgName, scopeOrgID
+}
+
+// handleAlertWebhook accepts Alertmanager / Grafana-alerting webhook payloads
+// and starts one background investigation run per new firing alert. Sessions
+// are owned by the configured alertWebhookUser so the on-call can sign in as
+// that user, or share the session, to read the finished RCA.
+func (p *Plugin) handleAlertWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role := getUserRole(r)
+	if role != "Admin" && role != "Editor" {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	p.settingsMu.RLock()
+	owner := strings.TrimSpace(p.settings.AlertWebhookUser)
+	orgName := strings.TrimSpace(p.settings.AlertWebhookOrgName)
+	p.settingsMu.RUnlock()
+	if owner == "" {
+		http.Error(w, "Alert webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload AlertWebhookPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		p.logger.Warn("Invalid alert webhook body", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	orgID := r.Header.Get("X-Grafana-Org-Id")
+	if orgID == "" {
+		orgID = "1"
+	}
+	numericOrgID := getOrgID(r)
+	orgName, scopeOrgID := alertWebhookOrgScope(r, orgName)
+
+	results := make([]AlertWebhookResult, 0, len(payload.Alerts))
+	started := 0
+	for _, alert := range payload.Alerts {
+		result := AlertWebhookResult{
+			Fingerprint: alertFingerprint(alert),
+			AlertName:   alert.Labels["alertname"],
+		}
+		if result.AlertName == "" {
+			result.AlertName = payload.CommonLabels["alertname"]
+		}
+
+		if alert.Status != "firing" || result.AlertName == "" || started >= AlertWebhookMaxRunsPerPayload {
+			result.Status = "skipped"
+			results = append(results, result)
+			continue
+		}
+
+		claimed, err := p.alertDedupe.Claim(numericOrgID, result.Fingerprint, AlertWebhookDedupeTTL)
+		if err != nil {
+			// Fail open: a duplicate investigation is better than a missed one.
+			p.logger.Warn("Alert dedupe check failed", "error", err, "fingerprint", result.Fingerprint)
+			claimed = true
+		}
+		if !claimed {
+			result.Status = "deduplicated"
+			results = append(results, result)
+			continue
+		}
+
+		run, err := p.startAgentRun(r.Context(), agentRunParams{
+			Request: agent.RunRequest{
+				Message:    "alertName:" + result.AlertName,
+				Type:       "investigation",
+				OrgName:    orgName,
+				ScopeOrgID: scopeOrgID,
+			},
+			UserID:       userIDForLogin(owner),
+			UserLogin:    owner,
+			UserRole:     alertWebhookRole,
+			OrgID:        orgID,
+			NumericOrgID: numericOrgID,
+			PromptSuffix: alertPromptDetails(alert),
+		})
+		if err != nil {
+			p.logger.Error("Failed to start alert investigation", "error", err, "fingerprint", result.Fingerprint, "alertName", result.AlertName)
+			if relErr := p.alertDedupe.Release(numericOrgID, result.Fingerprint); relErr != nil {
+				p.logger.Warn("Failed to release alert fingerprint", "error", relErr, "fingerprint", result.Fingerprint)
+			}
+			result.Status = "failed"
+			results = append(results, result)
+			continue
+		}
+
+		started++
+		result.Status = "started"
+		result.RunID = run.RunID
+		result.SessionID = run.SessionID
+		results = append(results, result)
+	}
+
+	p.logger.Info("Alert webhook processed",
+		"receiver", payload.Receiver,
+		"orgID", orgID,
+		"alerts", len(payload.Alerts),
+		"started", started,
+		"owner", owner,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+	})
+}
@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+const testAlertWebhookPayload = `{
+	"receiver": "ask-o11y",
+	"status": "firing",
+	"commonLabels": {"alertname": "HighLatency"},
+	"alerts": [
+		{
+			"status": "firing",
+			"labels": {"alertname": "HighLatency", "service": "checkout"},
+			"annotations": {"summary": "p99 above 2s"},
+			"startsAt": "2026-10-16T08:00:00Z",
+			"fingerprint": "abc123"
+		},
+		{
+			"status": "resolved",
+			"labels": {"alertname": "DiskFull", "instance": "db-1"},
+			"fingerprint": "def456"
+		}
+	]
+}`
+
+func postAlertWebhook(t *testing.T, p *Plugin, grafanaURL, role string) (*httptest.ResponseRecorder, []AlertWebhookResult) {
+	t.Helper()
+	req := newAgentRunRequest(t, grafanaURL, "/api/alerts/webhook", testAlertWebhookPayload)
+	req.Header.Set("X-Grafana-User-Role", role)
+	rec := httptest.NewRecorder()
+
+	p.handleAlertWebhook(rec, req)
+
+	var body struct {
+		Results []AlertWebhookResult `json:"results"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rec, body.Results
+}
+
+func TestHandleAlertWebhookDisabledWithoutServiceUser(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+
+	rec, _ := postAlertWebhook(t, p, "http://grafana.invalid", "Admin")
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleAlertWebhookRequiresEditorOrAdmin(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.settings.AlertWebhookUser = "oncall-bot"
+
+	rec, _ := postAlertWebhook(t, p, "http://grafana.invalid", "Viewer")
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandleAlertWebhookStartsInvestigationAndDedupes(t *testing.T) {
+	llmServer, received := newAgentRunLLMServer(t)
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	p.settings.AlertWebhookUser = "oncall-bot"
+
+	rec, results := postAlertWebhook(t, p, llmServer.URL, "Editor")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].Status != "started" || results[0].RunID == "" || results[0].SessionID == "" {
+		t.Fatalf("expected firing alert to start a run, got %+v", results[0])
+	}
+	if results[1].Status != "skipped" {
+		t.Fatalf("expected resolved alert to be skipped, got %+v", results[1])
+	}
+
+	session, err := p.sessionStore.GetSession(results[0].SessionID, userIDForLogin("oncall-bot"), 2)
+	if err != nil {
+		t.Fatalf("expected session owned by the service user: %v", err)
+	}
+	if session.Title != "Alert Investigation: HighLatency" {
+		t.Fatalf("unexpected session title %q", session.Title)
+	}
+
+	got := receiveAgentRunLLMRequest(t, received)
+	userPrompt := got.Messages[len(got.Messages)-1].Content
+	for _, want := range []string{"HighLatency", "service: checkout", "p99 above 2s", "2026-10-16T08:00:00Z"} {
+		if !strings.Contains(userPrompt, want) {
+			t.Fatalf("expected user prompt to contain %q, got %q", want, userPrompt)
+		}
+	}
+
+	rec, results = postAlertWebhook(t, p, llmServer.URL, "Editor")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if results[0].Status != "deduplicated" || results[0].RunID != "" {
+		t.Fatalf("expected repeat notification to be deduplicated, got %+v", results[0])
+	}
+}
+
+func TestAlertFingerprintFallsBackToLabelHash(t *testing.T) {
+	a := WebhookAlert{Labels: map[string]string{"alertname": "HighLatency", "service": "checkout"}}
+	b := WebhookAlert{Labels: map[string]string{"service": "checkout", "alertname": "HighLatency"}}
+	c := WebhookAlert{Labels: map[string]string{"alertname": "HighLatency", "service": "cart"}}
+
+	if alertFingerprint(a) != alertFingerprint(b) {
+		t.Fatal("expected label order not to affect the fingerprint")
+	}
+	if alertFingerprint(a) == alertFingerprint(c) {
+		t.Fatal("expected different labels to produce different fingerprints")
+	}
+	if got := alertFingerprint(WebhookAlert{Fingerprint: "abc123"}); got != "abc123" {
+		t.Fatalf("expected Alertmanager fingerprint to be used, got %q", got)
+	}
+}
+
+func TestAlertWebhookOrgScope(t *testing.T) {
+	cases := []struct {
+		target, configured, wantOrg, wantScope string
+	}{
+		{"/api/alerts/webhook", "", "", ""},
+		{"/api/alerts/webhook", "Main Org.", "Main Org.", "Main Org."},
+		{"/api/alerts/webhook?orgName=Payments", "Main Org.", "Payments", "Payments"},
+		{"/api/alerts/webhook?scopeOrgId=tenant-a", "Main Org.", "Main Org.", "tenant-a"},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, tc.target, nil)
+		org, scope := alertWebhookOrgScope(r, tc.configured)
+		if org != tc.wantOrg || scope != tc.wantScope {
+			t.Errorf("%s with %q: got (%q, %q), want (%q, %q)", tc.target, tc.configured, org, scope, tc.wantOrg, tc.wantScope)
+		}
+	}
+}
+
+func TestInMemoryAlertDedupeStoreExpiresAndReleases(t *testing.T) {
+	s := NewInMemoryAlertDedupeStore()
+
+	if ok, _ := s.Claim(1, "fp", time.Hour); !ok {
+		t.Fatal("expected first claim to succeed")
+	}
+	if ok, _ := s.Claim(1, "fp", time.Hour); ok {
+		t.Fatal("expected second claim to be deduplicated")
+	}
+	if ok, _ := s.Claim(2, "fp", time.Hour); !ok {
+		t.Fatal("expected claims to be scoped per org")
+	}
+
+	if err := s.Release(1, "fp"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if ok, _ := s.Claim(1, "fp", time.Millisecond); !ok {
+		t.Fatal("expected claim after release to succeed")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := s.Claim(1, "fp", time.Hour); !ok {
+		t.Fatal("expected claim after expiry to succeed")
+	}
+}
+
+func TestRedisAlertDedupeStoreClaim(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+	s := NewRedisAlertDedupeStore(t.Context(), client, log.DefaultLogger)
+
+	if ok, err := s.Claim(1, "fp", time.Minute); err != nil || !ok {
+		t.Fatalf("expected first claim to succeed, got %v, %v", ok, err)
+	}
+	if ok, err := s.Claim(1, "fp", time.Minute); err != nil || ok {
+		t.Fatalf("expected second claim to be deduplicated, got %v, %v", ok, err)
+	}
+	if err := s.Release(1, "fp"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if ok, err := s.Claim(1, "fp", time.Minute); err != nil || !ok {
+		t.Fatalf("expected claim after release to succeed, got %v, %v", ok, err)
+	}
+}
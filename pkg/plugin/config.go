@@ -34,12 +34,31 @@ const (
 	RunMaxAge                 = 1 * time.Hour
 	RunCleanupInterval        = 5 * time.Minute
 	RunMaxEventsPerRun        = 500
+	// RunLeaseTTL is how long a run survives its replica's last heartbeat
+	// before another replica marks it failed.
+	RunLeaseTTL               = 30 * time.Second
+	RunLeaseHeartbeatInterval = 10 * time.Second
+	RunOrphanSweepInterval    = 30 * time.Second
 )
 
 const (
 	SessionMaxPerUserOrg = 50
 )
 
+const (
+	// AlertWebhookDedupeTTL covers Alertmanager's default repeat_interval so a
+	// still-firing alert is investigated once, not on every re-notification.
+	AlertWebhookDedupeTTL         = 4 * time.Hour
+	AlertWebhookMaxRunsPerPayload = 5
+)
+
 const (
 	GraphitiDiscoveryMaxIter = 50
 )
+
+const (
+	// Token budget counters outlive their period by a margin so the admin API
+	// can still show yesterday's and last month's consumption.
+	TokenBudgetDayRetention   = 48 * time.Hour
+	TokenBudgetMonthRetention = 62 * 24 * time.Hour
+)
@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -45,7 +47,7 @@ type dsCacheEntry struct {
 // cached per-org for dsCacheTTL. Failures always return dsSnapshotFailOpen
 // rather than an empty string — the fail-open text itself reinforces the
 // "call list_datasources" rule in the prompt.
-func (p *Plugin) datasourceSnapshot(orgID, orgName, scopeOrgID string) string {
+func (p *Plugin) datasourceSnapshot(ctx context.Context, orgID, orgName, scopeOrgID string) string {
 	cacheKey := orgID
 	if cacheKey == "" {
 		cacheKey = orgName
@@ -58,47 +60,42 @@ func (p *Plugin) datasourceSnapshot(orgID, orgName, scopeOrgID string) string {
 		return snap
 	}
 
-	// MCP proxy call is synchronous; wrap it in a goroutine + timer to enforce
-	// the 2 s budget without blocking the user. If it times out we still cache
-	// the fail-open answer for a shorter window (30 s) so we don't re-hammer
-	// a dead sidecar on every request.
-	done := make(chan string, 1)
-	go func() {
-		toolName, ok := p.findDatasourceListTool()
-		if !ok {
-			done <- dsSnapshotFailOpen
-			return
-		}
-		result, err := p.mcpProxy.CallToolWithContext(toolName, map[string]interface{}{}, orgID, orgName, scopeOrgID)
-		if err != nil {
-			p.logger.Warn("datasourceSnapshot: list_datasources failed", "error", err, "orgID", orgID)
-			done <- dsSnapshotFailOpen
-			return
-		}
-		if result == nil || result.IsError {
-			done <- dsSnapshotFailOpen
-			return
-		}
-		text := ""
-		if len(result.Content) > 0 {
-			text = result.Content[0].Text
-		}
-		snap := renderDatasourceSnapshot(text)
-		done <- snap
-	}()
-
-	var snapshot string
-	select {
-	case snapshot = <-done:
-	case <-time.After(dsMCPCallTimeout):
-		p.logger.Warn("datasourceSnapshot: timeout calling list_datasources", "orgID", orgID, "timeout", dsMCPCallTimeout)
-		snapshot = dsSnapshotFailOpen
-	}
+	// The deadline aborts the in-flight MCP call, so a slow sidecar costs the
+	// user at most dsMCPCallTimeout. Failures still cache the fail-open answer
+	// for a shorter window (30 s) so we don't re-hammer a dead sidecar on every
+	// request.
+	ctx, cancel := context.WithTimeout(ctx, dsMCPCallTimeout)
+	defer cancel()
 
+	snapshot := p.fetchDatasourceSnapshot(ctx, orgID, orgName, scopeOrgID)
 	p.storeDatasourceCacheWithTTL(cacheKey, snapshot, datasourceCacheTTL(snapshot))
 	return snapshot
 }
 
+func (p *Plugin) fetchDatasourceSnapshot(ctx context.Context, orgID, orgName, scopeOrgID string) string {
+	toolName, ok := p.findDatasourceListTool()
+	if !ok {
+		return dsSnapshotFailOpen
+	}
+	result, err := p.mcpProxy.CallToolWithContext(ctx, toolName, map[string]interface{}{}, orgID, orgName, scopeOrgID)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			p.logger.Warn("datasourceSnapshot: timeout calling list_datasources", "orgID", orgID, "timeout", dsMCPCallTimeout)
+		} else {
+			p.logger.Warn("datasourceSnapshot: list_datasources failed", "error", err, "orgID", orgID)
+		}
+		return dsSnapshotFailOpen
+	}
+	if result == nil || result.IsError {
+		return dsSnapshotFailOpen
+	}
+	text := ""
+	if len(result.Content) > 0 {
+		text = result.Content[0].Text
+	}
+	return renderDatasourceSnapshot(text)
+}
+
 // findDatasourceListTool searches all registered MCP servers for a tool whose
 // base name (the part after the server-id prefix) is "list_datasources".
 // This makes the snapshot work regardless of what id the Grafana MCP server
@@ -4,6 +4,7 @@ import (
 	"consensys-asko11y-app/pkg/mcp"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -241,7 +242,7 @@ func TestDatasourceSnapshot_UsesPrefixedDatasourceTool(t *testing.T) {
 				dsCacheMu: sync.Mutex{},
 			}
 
-			snapshot := p.datasourceSnapshot("1", "Org1", "")
+			snapshot := p.datasourceSnapshot(context.Background(), "1", "Org1", "")
 			if !strings.Contains(snapshot, "uid=abc123") {
 				t.Fatalf("expected datasource UID in snapshot, got:\n%s", snapshot)
 			}
@@ -251,3 +252,45 @@ func TestDatasourceSnapshot_UsesPrefixedDatasourceTool(t *testing.T) {
 		})
 	}
 }
+
+func TestDatasourceSnapshot_TimeoutAbortsCallAndFailsOpen(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mcp/list-tools":
+			_ = json.NewEncoder(w).Encode(struct {
+				Tools []mcp.Tool `json:"tools"`
+			}{Tools: []mcp.Tool{{Name: "list_datasources", InputSchema: map[string]interface{}{}}}})
+		case "/mcp/call-tool":
+			io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+				aborted <- struct{}{}
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	proxy := mcp.NewProxy(context.Background(), log.DefaultLogger)
+	if err := proxy.EnsureServer(mcp.ServerConfig{ID: "mcp-grafana", URL: server.URL, Type: "standard", Enabled: true}); err != nil {
+		t.Fatalf("failed to configure proxy: %v", err)
+	}
+	defer proxy.Close()
+
+	p := &Plugin{logger: log.DefaultLogger, mcpProxy: proxy, dsCache: map[string]dsCacheEntry{}}
+
+	start := time.Now()
+	snapshot := p.datasourceSnapshot(context.Background(), "1", "Org1", "")
+	if snapshot != dsSnapshotFailOpen {
+		t.Fatalf("expected fail-open snapshot, got:\n%s", snapshot)
+	}
+	if elapsed := time.Since(start); elapsed > dsMCPCallTimeout+time.Second {
+		t.Fatalf("expected snapshot within the %v budget, took %v", dsMCPCallTimeout, elapsed)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the timed-out list_datasources request to be aborted")
+	}
+}
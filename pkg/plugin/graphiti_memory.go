@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"slices"
@@ -107,13 +108,13 @@ func buildSessionMemoryBody(messages []ingestSessionMessage) (string, int) {
 	return trimGraphitiBody(strings.Join(lines, "\n"), graphitiMaxEpisodeChars), count
 }
 
-func ingestGraphitiMemory(proxy *mcp.Proxy, orgID int64, name, body, source, sourceDescription string) error {
+func ingestGraphitiMemory(ctx context.Context, proxy *mcp.Proxy, orgID int64, name, body, source, sourceDescription string) error {
 	body = trimGraphitiBody(body, graphitiMaxEpisodeChars)
 	if body == "" {
 		return nil
 	}
 
-	result, err := proxy.CallTool("graphiti_add_memory", map[string]interface{}{
+	result, err := proxy.CallTool(ctx, "graphiti_add_memory", map[string]interface{}{
 		"name":               name,
 		"group_id":           orgGroupID(orgID),
 		"episode_body":       body,
@@ -0,0 +1,329 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultInputTimeout = 10 * time.Minute
+
+// inputTimeout bounds how long an MCP server's input request waits for the
+// user before it is cancelled. It is shorter than approvalTimeout because
+// the server holds a tool call open meanwhile.
+var inputTimeout = defaultInputTimeout
+
+var (
+	errInputNotPending     = errors.New("input request is not pending")
+	errInputAlreadyPending = errors.New("input request already pending")
+)
+
+type inputConflictError struct {
+	action string
+}
+
+func (e *inputConflictError) Error() string {
+	if e.action == "" {
+		return "input request already answered"
+	}
+	return fmt.Sprintf("input request already answered with %s", e.action)
+}
+
+// InputBroker delivers the user's answers to MCP servers' input requests to
+// the run waiting for them, like ApprovalBroker does for approvals.
+type InputBroker interface {
+	Register(ctx context.Context, runID string, request agent.InputRequestEvent) (agent.InputWaitFunc, error)
+	Resolve(ctx context.Context, runID string, resolved agent.InputResolvedEvent) (agent.InputResolvedEvent, error)
+	Close()
+}
+
+func inputTimedOut(inputID string) agent.InputResolvedEvent {
+	return agent.InputResolvedEvent{
+		InputID:    inputID,
+		Action:     "cancel",
+		Comment:    "input request timed out",
+		ResolvedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+type InMemoryInputBroker struct {
+	mu       sync.Mutex
+	waiters  map[string]map[string]chan agent.InputResolvedEvent
+	resolved map[string]map[string]agent.InputResolvedEvent
+	closed   bool
+}
+
+func NewInMemoryInputBroker() *InMemoryInputBroker {
+	return &InMemoryInputBroker{
+		waiters:  make(map[string]map[string]chan agent.InputResolvedEvent),
+		resolved: make(map[string]map[string]agent.InputResolvedEvent),
+	}
+}
+
+func (b *InMemoryInputBroker) Register(ctx context.Context, runID string, request agent.InputRequestEvent) (agent.InputWaitFunc, error) {
+	if !isValidInputID(request.InputID) {
+		return nil, fmt.Errorf("invalid input id")
+	}
+
+	ch := make(chan agent.InputResolvedEvent, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, fmt.Errorf("input broker closed")
+	}
+	if resolved, exists := b.resolved[runID][request.InputID]; exists {
+		return func(context.Context) (agent.InputResolvedEvent, error) {
+			return resolved, nil
+		}, nil
+	}
+	if b.waiters[runID] == nil {
+		b.waiters[runID] = make(map[string]chan agent.InputResolvedEvent)
+	}
+	if _, exists := b.waiters[runID][request.InputID]; exists {
+		return nil, errInputAlreadyPending
+	}
+
+	b.waiters[runID][request.InputID] = ch
+
+	return func(waitCtx context.Context) (agent.InputResolvedEvent, error) {
+		defer b.removeWaiter(runID, request.InputID)
+		timer := time.NewTimer(inputTimeout)
+		defer timer.Stop()
+
+		select {
+		case resolved, ok := <-ch:
+			if !ok {
+				return agent.InputResolvedEvent{}, fmt.Errorf("input channel closed")
+			}
+			return resolved, nil
+		case <-timer.C:
+			return inputTimedOut(request.InputID), nil
+		case <-waitCtx.Done():
+			return agent.InputResolvedEvent{}, waitCtx.Err()
+		case <-ctx.Done():
+			return agent.InputResolvedEvent{}, ctx.Err()
+		}
+	}, nil
+}
+
+func (b *InMemoryInputBroker) Resolve(ctx context.Context, runID string, resolved agent.InputResolvedEvent) (agent.InputResolvedEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if existing, ok := b.resolved[runID][resolved.InputID]; ok {
+		if existing.Action == resolved.Action {
+			return existing, nil
+		}
+		return agent.InputResolvedEvent{}, &inputConflictError{action: existing.Action}
+	}
+
+	ch := b.waiters[runID][resolved.InputID]
+	if ch == nil {
+		return agent.InputResolvedEvent{}, errInputNotPending
+	}
+	if b.resolved[runID] == nil {
+		b.resolved[runID] = make(map[string]agent.InputResolvedEvent)
+	}
+
+	select {
+	case ch <- resolved:
+		b.resolved[runID][resolved.InputID] = resolved
+		return resolved, nil
+	case <-ctx.Done():
+		return agent.InputResolvedEvent{}, ctx.Err()
+	default:
+		return agent.InputResolvedEvent{}, fmt.Errorf("input delivery queue is full")
+	}
+}
+
+func (b *InMemoryInputBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, waiters := range b.waiters {
+		for _, ch := range waiters {
+			close(ch)
+		}
+	}
+	b.waiters = nil
+	b.resolved = nil
+}
+
+func (b *InMemoryInputBroker) removeWaiter(runID, inputID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	waiters := b.waiters[runID]
+	if waiters == nil {
+		return
+	}
+	delete(waiters, inputID)
+	if len(waiters) == 0 {
+		delete(b.waiters, runID)
+	}
+}
+
+// RedisInputBroker lets any replica answer an input request, using the same
+// pending/resolved/queue keys and scripts as RedisApprovalBroker.
+type RedisInputBroker struct {
+	ctx    context.Context
+	client *redis.Client
+	logger log.Logger
+}
+
+func NewRedisInputBroker(ctx context.Context, client *redis.Client, logger log.Logger) *RedisInputBroker {
+	return &RedisInputBroker{
+		ctx:    ctx,
+		client: client,
+		logger: logger,
+	}
+}
+
+func inputPendingKey(runID, inputID string) string {
+	return fmt.Sprintf("input:%s:%s:pending", runID, inputID)
+}
+
+func inputResolvedKey(runID, inputID string) string {
+	return fmt.Sprintf("input:%s:%s:resolved", runID, inputID)
+}
+
+func inputQueueKey(runID, inputID string) string {
+	return fmt.Sprintf("input:%s:%s:queue", runID, inputID)
+}
+
+func inputRedisTTL() time.Duration {
+	return inputTimeout + 5*time.Minute
+}
+
+func (b *RedisInputBroker) Register(ctx context.Context, runID string, request agent.InputRequestEvent) (agent.InputWaitFunc, error) {
+	if !isValidInputID(request.InputID) {
+		return nil, fmt.Errorf("invalid input id")
+	}
+
+	pendingJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal input request: %w", err)
+	}
+
+	registerCtx, cancel := redisContext(b.ctx, RedisOpTimeout)
+	defer cancel()
+	registration, err := registerApprovalScript.Run(registerCtx, b.client, []string{
+		inputPendingKey(runID, request.InputID),
+		inputQueueKey(runID, request.InputID),
+		inputResolvedKey(runID, request.InputID),
+	}, pendingJSON, int64(inputRedisTTL().Seconds())).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("register input request in redis: %w", err)
+	}
+	if len(registration) != 2 {
+		return nil, fmt.Errorf("malformed redis input registration")
+	}
+	status, _ := registration[0].(string)
+	payload, _ := registration[1].(string)
+	switch status {
+	case "registered":
+	case "resolved":
+		var existing agent.InputResolvedEvent
+		if err := json.Unmarshal([]byte(payload), &existing); err != nil {
+			return nil, fmt.Errorf("decode existing input answer: %w", err)
+		}
+		return func(context.Context) (agent.InputResolvedEvent, error) {
+			return existing, nil
+		}, nil
+	case "pending":
+		return nil, errInputAlreadyPending
+	default:
+		return nil, fmt.Errorf("unknown redis input registration status %q", status)
+	}
+
+	return func(waitCtx context.Context) (agent.InputResolvedEvent, error) {
+		defer b.cleanupPending(runID, request.InputID)
+
+		result, err := b.client.BLPop(waitCtx, inputTimeout, inputQueueKey(runID, request.InputID)).Result()
+		if err == redis.Nil {
+			return inputTimedOut(request.InputID), nil
+		}
+		if err != nil {
+			return agent.InputResolvedEvent{}, fmt.Errorf("wait for input answer: %w", err)
+		}
+		if len(result) != 2 {
+			return agent.InputResolvedEvent{}, fmt.Errorf("malformed input queue response")
+		}
+
+		var resolved agent.InputResolvedEvent
+		if err := json.Unmarshal([]byte(result[1]), &resolved); err != nil {
+			return agent.InputResolvedEvent{}, fmt.Errorf("decode input answer: %w", err)
+		}
+		return resolved, nil
+	}, nil
+}
+
+func (b *RedisInputBroker) Resolve(ctx context.Context, runID string, resolved agent.InputResolvedEvent) (agent.InputResolvedEvent, error) {
+	resolvedJSON, err := json.Marshal(resolved)
+	if err != nil {
+		return agent.InputResolvedEvent{}, fmt.Errorf("marshal input answer: %w", err)
+	}
+
+	keys := []string{
+		inputPendingKey(runID, resolved.InputID),
+		inputResolvedKey(runID, resolved.InputID),
+		inputQueueKey(runID, resolved.InputID),
+	}
+	resolveCtx, cancel := redisContext(ctx, RedisOpTimeout)
+	defer cancel()
+	result, err := resolveApprovalScript.Run(resolveCtx, b.client, keys, resolvedJSON, int64(inputRedisTTL().Seconds())).Slice()
+	if err != nil {
+		return agent.InputResolvedEvent{}, fmt.Errorf("resolve input request in redis: %w", err)
+	}
+	if len(result) != 2 {
+		return agent.InputResolvedEvent{}, fmt.Errorf("malformed redis input resolution")
+	}
+
+	status, _ := result[0].(string)
+	payload, _ := result[1].(string)
+	switch status {
+	case "stored":
+		return resolved, nil
+	case "resolved":
+		var existing agent.InputResolvedEvent
+		if err := json.Unmarshal([]byte(payload), &existing); err != nil {
+			return agent.InputResolvedEvent{}, fmt.Errorf("decode existing input answer: %w", err)
+		}
+		if existing.Action == resolved.Action {
+			return existing, nil
+		}
+		return agent.InputResolvedEvent{}, &inputConflictError{action: existing.Action}
+	case "missing":
+		return agent.InputResolvedEvent{}, errInputNotPending
+	default:
+		return agent.InputResolvedEvent{}, fmt.Errorf("unknown redis input resolution status %q", status)
+	}
+}
+
+func (b *RedisInputBroker) Close() {}
+
+func (b *RedisInputBroker) cleanupPending(runID, inputID string) {
+	ctx, cancel := redisContext(b.ctx, RedisOpTimeout)
+	defer cancel()
+	if err := b.client.Del(ctx, inputPendingKey(runID, inputID), inputQueueKey(runID, inputID)).Err(); err != nil {
+		b.logger.Warn("Failed to clean up input request keys", "error", err, "runId", runID, "inputId", inputID)
+	}
+}
+
+// isValidInputID accepts the same identifiers as approvals; input IDs are
+// derived from tool call IDs the same way.
+func isValidInputID(id string) bool {
+	return isValidApprovalID(id)
+}
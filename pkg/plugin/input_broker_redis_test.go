@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestRedisInputBrokerCrossInstanceDeliversAnswer(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	ctx := context.Background()
+	brokerA := NewRedisInputBroker(ctx, client, log.DefaultLogger)
+	brokerB := NewRedisInputBroker(ctx, client, log.DefaultLogger)
+
+	wait, err := brokerA.Register(ctx, "run-redis-input-1", agent.InputRequestEvent{InputID: "tc_1-input-1", Message: "Which namespace?"})
+	if err != nil {
+		t.Fatalf("register input failed: %v", err)
+	}
+
+	if _, err := brokerB.Resolve(ctx, "run-redis-input-1", agent.InputResolvedEvent{
+		InputID:    "tc_1-input-1",
+		Action:     "accept",
+		Content:    map[string]interface{}{"namespace": "payments"},
+		ResolvedAt: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		t.Fatalf("resolve input failed: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	delivered, err := wait(waitCtx)
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if delivered.Action != "accept" || delivered.Content["namespace"] != "payments" {
+		t.Fatalf("unexpected delivered answer: %+v", delivered)
+	}
+
+	_, err = brokerB.Resolve(ctx, "run-redis-input-1", agent.InputResolvedEvent{InputID: "tc_1-input-1", Action: "decline"})
+	var conflict *inputConflictError
+	if !errors.As(err, &conflict) || conflict.action != "accept" {
+		t.Fatalf("expected a conflict with accept, got %v", err)
+	}
+}
+
+func TestRedisInputBrokerUnknownInputIsNotPending(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	broker := NewRedisInputBroker(context.Background(), client, log.DefaultLogger)
+	_, err := broker.Resolve(context.Background(), "run-redis-input-2", agent.InputResolvedEvent{InputID: "tc_1-input-1", Action: "accept"})
+	if !errors.Is(err, errInputNotPending) {
+		t.Fatalf("expected errInputNotPending, got %v", err)
+	}
+}
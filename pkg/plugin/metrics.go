@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -21,8 +22,51 @@ var (
 		},
 		[]string{"user", "login", "model", "type", "org", "org_name"},
 	)
+
+	// agentRunDuration times agent runs from start to their final status, by
+	// status and conversation type. Its _count series is the run counter.
+	agentRunDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "asko11y_agent_run_duration_seconds",
+			Help:    "Duration of agent runs by final status and conversation type.",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800},
+		},
+		[]string{"status", "type"},
+	)
+
+	// agentRunIterations records how many loop iterations finished runs used.
+	agentRunIterations = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "asko11y_agent_run_iterations",
+			Help:    "Number of agent loop iterations per run by conversation type.",
+			Buckets: []float64{1, 2, 3, 5, 8, 10, 15, 20, 30, 50},
+		},
+		[]string{"type"},
+	)
 )
 
+// conversationTypeLabel bounds the client-supplied conversation type.
+func conversationTypeLabel(conversationType string) string {
+	switch conversationType {
+	case "":
+		return "chat"
+	case "chat", "investigation", "performance", "discovery":
+		return conversationType
+	default:
+		return "other"
+	}
+}
+
+// observeAgentRun records a finished run's duration, outcome and iterations.
+// Runs that ended before reporting iterations only count toward the duration.
+func observeAgentRun(conversationType string, status RunStatus, iterations int, started time.Time) {
+	typeLabel := conversationTypeLabel(conversationType)
+	agentRunDuration.WithLabelValues(string(status), typeLabel).Observe(time.Since(started).Seconds())
+	if iterations > 0 {
+		agentRunIterations.WithLabelValues(typeLabel).Observe(float64(iterations))
+	}
+}
+
 // sanitizeOrgNameLabel cleans and bounds a client-supplied org name before it
 // is used as a Prometheus label value.
 func sanitizeOrgNameLabel(name string) string {
@@ -5,7 +5,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestRegisterRoutesDoesNotExposeMetricsEndpoint(t *testing.T) {
@@ -48,3 +51,52 @@ func TestSanitizeOrgNameLabelStripsControlsAndDefaultsEmpty(t *testing.T) {
 		t.Fatalf("got %q, want unknown", got)
 	}
 }
+
+func TestObserveAgentRunBoundsConversationType(t *testing.T) {
+	for in, want := range map[string]string{"": "chat", "investigation": "investigation", "made-up-type": "other"} {
+		if got := conversationTypeLabel(in); got != want {
+			t.Fatalf("conversationTypeLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(agentRunDuration, agentRunIterations)
+	samples := func(name, status string) uint64 {
+		t.Helper()
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("Gather failed: %v", err)
+		}
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				labels := make(map[string]string)
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				if labels["type"] == "other" && labels["status"] == status {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+	completedBefore := samples("asko11y_agent_run_duration_seconds", "completed")
+	failedBefore := samples("asko11y_agent_run_duration_seconds", "failed")
+	iterationsBefore := samples("asko11y_agent_run_iterations", "")
+
+	observeAgentRun("made-up-type", RunStatusCompleted, 3, time.Now())
+	observeAgentRun("made-up-type", RunStatusFailed, 0, time.Now())
+
+	if got := samples("asko11y_agent_run_duration_seconds", "completed") - completedBefore; got != 1 {
+		t.Fatalf("completed runs observed = %d, want 1", got)
+	}
+	if got := samples("asko11y_agent_run_duration_seconds", "failed") - failedBefore; got != 1 {
+		t.Fatalf("failed runs observed = %d, want 1", got)
+	}
+	if got := samples("asko11y_agent_run_iterations", "") - iterationsBefore; got != 1 {
+		t.Fatalf("iteration samples = %d, want 1 (failed run reported no iterations)", got)
+	}
+}
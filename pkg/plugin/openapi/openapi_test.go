@@ -93,14 +93,20 @@ func TestSpecHasAllEndpoints(t *testing.T) {
 		"/api/mcp/tools",
 		"/api/mcp/call-tool",
 		"/api/mcp/servers",
+		"/api/rbac/explain",
+		"/api/teams",
 		"/api/agent/run",
 		"/api/agent/runs/{runId}",
 		"/api/agent/runs/{runId}/events",
 		"/api/agent/runs/{runId}/cancel",
 		"/api/agent/runs/{runId}/approvals/{approvalId}",
+		"/api/agent/runs/{runId}/inputs/{inputId}",
 		"/api/agent/evals",
 		"/api/agent/evals/run",
 		"/api/agent/topology",
+		"/api/alerts/webhook",
+		"/api/token-budgets",
+		"/api/token-budgets/reset",
 		"/api/prompt-defaults",
 		"/api/graphiti/status",
 		"/api/graphiti/discover",
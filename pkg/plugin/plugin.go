@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
@@ -90,6 +91,15 @@ type PluginSettings struct {
 	BuiltInMCPToolSelections map[string]bool                 `json:"builtInMCPToolSelections,omitempty"`
 	TrustedMCPServers        map[string]bool                 `json:"trustedMCPServers,omitempty"`
 	RiskOverrides            map[string]mcp.ToolRiskOverride `json:"riskOverrides,omitempty"`
+	// ToolPolicies are admin-defined rules that allow or deny tools beyond
+	// the role defaults.
+	ToolPolicies []rbac.Rule `json:"toolPolicies,omitempty"`
+	// ApprovalPolicies are rules deciding who may resolve a tool approval;
+	// without a matching rule only Admin and Editor can.
+	ApprovalPolicies []rbac.Rule `json:"approvalPolicies,omitempty"`
+	// ToolCatalogTTL is how long each MCP server's tool list is cached, as a
+	// Go duration ("10m"), or "off" to rely on list_changed notifications.
+	ToolCatalogTTL string `json:"toolCatalogTTL,omitempty"`
 
 	DefaultSystemPrompt string `json:"defaultSystemPrompt,omitempty"`
 	InvestigationPrompt string `json:"investigationPrompt,omitempty"`
@@ -112,39 +122,94 @@ type PluginSettings struct {
 	ApprovalPolicy          string `json:"approvalPolicy,omitempty"`
 	MaxParallelToolCalls    int    `json:"maxParallelToolCalls,omitempty"`
 	AgentEvalCaptureEnabled bool   `json:"agentEvalCaptureEnabled,omitempty"`
-}
-
-const mcpServerHeaderPrefix = "mcpServerHeader."
+	// SamplingTokenBudget caps the LLM tokens MCP servers may spend through
+	// sampling requests in one run. Negative values refuse sampling.
+	SamplingTokenBudget int `json:"samplingTokenBudget,omitempty"`
+
+	// AlertWebhookUser is the Grafana login that owns sessions created by
+	// /api/alerts/webhook. The endpoint is disabled while it is unset.
+	AlertWebhookUser string `json:"alertWebhookUser,omitempty"`
+	// AlertWebhookOrgName is the Grafana org name webhook runs use in prompts
+	// and forward as X-Scope-OrgID. The orgName and scopeOrgId query
+	// parameters on the webhook URL override it per contact point.
+	AlertWebhookOrgName string `json:"alertWebhookOrgName,omitempty"`
+
+	// ShareRedaction configures how share snapshots are scrubbed of tokens,
+	// credentials, emails and IP addresses.
+	ShareRedaction ShareRedactionSettings `json:"shareRedaction,omitempty"`
+
+	// TokenBudgets caps daily and monthly LLM tokens per user and per org.
+	TokenBudgets TokenBudgetSettings `json:"tokenBudgets,omitempty"`
+}
+
+const (
+	mcpServerHeaderPrefix = "mcpServerHeader."
+	mcpServerEnvPrefix    = "mcpServerEnv."
+	mcpServerAuthPrefix   = "mcpServerAuth."
+)
 
 // applySecureHeaders parses "mcpServerHeader.{serverID}.{headerName}" keys from
 // secureJsonData and populates the corresponding ServerConfig.Headers.
 // Server IDs must not contain dots (the UI auto-generates IDs like "mcp-1234567890").
 func applySecureHeaders(servers []mcp.ServerConfig, secure map[string]string) {
+	forEachSecureServerValue(servers, secure, mcpServerHeaderPrefix, func(server *mcp.ServerConfig, headerName, value string) {
+		if server.Headers == nil {
+			server.Headers = make(map[string]string)
+		}
+		server.Headers[headerName] = value
+	})
+}
+
+// applySecureEnv parses "mcpServerEnv.{serverID}.{VAR}" keys from
+// secureJsonData into the environment of stdio servers.
+func applySecureEnv(servers []mcp.ServerConfig, secure map[string]string) {
+	forEachSecureServerValue(servers, secure, mcpServerEnvPrefix, func(server *mcp.ServerConfig, name, value string) {
+		if server.Env == nil {
+			server.Env = make(map[string]string)
+		}
+		server.Env[name] = value
+	})
+}
+
+// applySecureAuth parses "mcpServerAuth.{serverID}.clientSecret" keys from
+// secureJsonData into the OAuth settings of servers that configure auth.
+func applySecureAuth(servers []mcp.ServerConfig, secure map[string]string) {
+	forEachSecureServerValue(servers, secure, mcpServerAuthPrefix, func(server *mcp.ServerConfig, name, value string) {
+		if server.Auth != nil && name == "clientSecret" {
+			server.Auth.ClientSecret = value
+		}
+	})
+}
+
+// forEachSecureServerValue calls apply for every "{prefix}{serverID}.{name}"
+// key in secure whose server ID matches a configured server.
+func forEachSecureServerValue(servers []mcp.ServerConfig, secure map[string]string, prefix string, apply func(server *mcp.ServerConfig, name, value string)) {
 	for key, value := range secure {
-		if !strings.HasPrefix(key, mcpServerHeaderPrefix) {
+		if !strings.HasPrefix(key, prefix) {
 			continue
 		}
-		rest := key[len(mcpServerHeaderPrefix):]
+		rest := key[len(prefix):]
 		dotIdx := strings.Index(rest, ".")
 		if dotIdx < 0 {
 			continue
 		}
-		serverID, headerName := rest[:dotIdx], rest[dotIdx+1:]
-		if serverID == "" || headerName == "" {
+		serverID, name := rest[:dotIdx], rest[dotIdx+1:]
+		if serverID == "" || name == "" {
 			continue
 		}
 		for i := range servers {
 			if servers[i].ID == serverID {
-				if servers[i].Headers == nil {
-					servers[i].Headers = make(map[string]string)
-				}
-				servers[i].Headers[headerName] = value
+				apply(&servers[i], name, value)
 				break
 			}
 		}
 	}
 }
 
+// defaultSamplingTokenBudget is the per-run sampling budget when none is
+// configured.
+const defaultSamplingTokenBudget = 20000
+
 func applyAgentRuntimeSettings(settings *PluginSettings) {
 	if settings.ApprovalPolicy == "" {
 		settings.ApprovalPolicy = "approval-gated-writes"
@@ -152,6 +217,9 @@ func applyAgentRuntimeSettings(settings *PluginSettings) {
 	if settings.MaxParallelToolCalls <= 0 {
 		settings.MaxParallelToolCalls = 4
 	}
+	if settings.SamplingTokenBudget == 0 {
+		settings.SamplingTokenBudget = defaultSamplingTokenBudget
+	}
 	for i := range settings.MCPServers {
 		if trusted, ok := settings.TrustedMCPServers[settings.MCPServers[i].ID]; ok {
 			settings.MCPServers[i].Trusted = trusted
@@ -174,7 +242,10 @@ type Plugin struct {
 	redisClient    *redis.Client
 	usingRedis     bool
 	approvalBroker ApprovalBroker
+	inputBroker    InputBroker
 	approvalGrants ApprovalGrantStore
+	alertDedupe    AlertDedupeStore
+	tokenBudgets   TokenBudgetStore
 	useBuiltInMCP  bool
 	promptRegistry *PromptRegistry
 	settings       PluginSettings
@@ -183,10 +254,20 @@ type Plugin struct {
 	cancel         context.CancelFunc
 	runCancelsMu   sync.Mutex
 	runCancels     map[string]context.CancelFunc
+	runLeases      RunLeaseStore
+	// grafanaClient calls the Grafana HTTP API with the plugin service
+	// account, such as the team lookups in teams.go.
+	grafanaClient *http.Client
 	// dsCache memoises the per-org datasource UID snapshot injected into the
 	// system prompt. See datasource_snapshot.go.
 	dsCache   map[string]dsCacheEntry
 	dsCacheMu sync.Mutex
+	// summarizing holds session IDs with a rolling summary in flight. See
+	// session_summary.go.
+	summarizing sync.Map
+	// teamCache memoises each user's Grafana teams. See teams.go.
+	teamCache   map[string]teamCacheEntry
+	teamCacheMu sync.Mutex
 }
 
 func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (instancemgmt.Instance, error) {
@@ -201,7 +282,11 @@ func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (insta
 	}
 
 	applySecureHeaders(pluginSettings.MCPServers, settings.DecryptedSecureJSONData)
+	applySecureEnv(pluginSettings.MCPServers, settings.DecryptedSecureJSONData)
+	applySecureAuth(pluginSettings.MCPServers, settings.DecryptedSecureJSONData)
 	applyAgentRuntimeSettings(&pluginSettings)
+	warnInvalidToolPolicies(pluginSettings.ToolPolicies, logger)
+	warnInvalidToolPolicies(pluginSettings.ApprovalPolicies, logger)
 
 	if pluginSettings.MaxTotalTokens <= 0 {
 		pluginSettings.MaxTotalTokens = agent.DefaultMaxTotalTokens
@@ -244,6 +329,7 @@ func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (insta
 		return nil, fmt.Errorf("failed to configure MCP servers: %w", err)
 	}
 
+	mcpProxy.SetCatalogTTL(parseToolCatalogTTL(pluginSettings.ToolCatalogTTL, logger))
 	mcpProxy.StartHealthMonitoring(MCPHealthMonitoringInterval)
 
 	var shareStore ShareStoreInterface
@@ -283,14 +369,26 @@ func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (insta
 	}
 
 	var approvalBroker ApprovalBroker
+	var inputBroker InputBroker
 	var approvalGrants ApprovalGrantStore
+	var alertDedupe AlertDedupeStore
+	var tokenBudgets TokenBudgetStore
+	var runLeases RunLeaseStore
 	if usingRedis && redisClient != nil {
 		approvalBroker = NewRedisApprovalBroker(pluginCtx, redisClient, logger)
+		inputBroker = NewRedisInputBroker(pluginCtx, redisClient, logger)
+		runLeases = NewRedisRunLeaseStore(pluginCtx, redisClient, logger)
 		approvalGrants = NewRedisApprovalGrantStore(pluginCtx, redisClient, logger)
+		alertDedupe = NewRedisAlertDedupeStore(pluginCtx, redisClient, logger)
+		tokenBudgets = NewRedisTokenBudgetStore(pluginCtx, redisClient, logger)
 		logger.Info("Using Redis for distributed approval coordination")
 	} else {
 		approvalBroker = NewInMemoryApprovalBroker()
+		inputBroker = NewInMemoryInputBroker()
+		runLeases = NewInMemoryRunLeaseStore()
 		approvalGrants = NewInMemoryApprovalGrantStore()
+		alertDedupe = NewInMemoryAlertDedupeStore()
+		tokenBudgets = NewInMemoryTokenBudgetStore()
 		logger.Warn("Using in-memory approval coordination; approval routing is unsafe with multiple Grafana replicas. Configure Redis for production.")
 	}
 
@@ -305,6 +403,17 @@ func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (insta
 		return nil, fmt.Errorf("failed to create SDK HTTP client for LLM: %w", err)
 	}
 	llmClient := agent.NewLLMClient(logger, llmHTTPClient)
+
+	grafanaHTTPClient, err := httpclient.New(httpclient.Options{
+		Timeouts: &httpclient.TimeoutOptions{
+			Timeout:     30 * time.Second,
+			DialTimeout: 10 * time.Second,
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create SDK HTTP client for Grafana API: %w", err)
+	}
 	agentLoop := agent.NewAgentLoop(llmClient, mcpProxy, logger)
 
 	var scout *Scout
@@ -327,15 +436,34 @@ func NewPlugin(ctx context.Context, settings backend.AppInstanceSettings) (insta
 		redisClient:    redisClient,
 		usingRedis:     usingRedis,
 		approvalBroker: approvalBroker,
+		inputBroker:    inputBroker,
 		approvalGrants: approvalGrants,
+		alertDedupe:    alertDedupe,
+		tokenBudgets:   tokenBudgets,
 		useBuiltInMCP:  pluginSettings.UseBuiltInMCP,
 		promptRegistry: promptRegistry,
 		settings:       pluginSettings,
 		ctx:            pluginCtx,
 		cancel:         cancel,
 		runCancels:     make(map[string]context.CancelFunc),
+		runLeases:      runLeases,
+		grafanaClient:  grafanaHTTPClient,
 	}
 
+	go runLeases.SubscribeCancels(pluginCtx, func(runID string) { p.cancelLocalRun(runID) })
+	go func() {
+		ticker := time.NewTicker(RunOrphanSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.failOrphanedRuns()
+			case <-pluginCtx.Done():
+				return
+			}
+		}
+	}()
+
 	if !usingRedis {
 		go func() {
 			ticker := time.NewTicker(ShareCleanupInterval)
@@ -385,6 +513,12 @@ func (p *Plugin) Dispose() {
 	if p.approvalBroker != nil {
 		p.approvalBroker.Close()
 	}
+	if p.inputBroker != nil {
+		p.inputBroker.Close()
+	}
+	if p.runLeases != nil {
+		p.runLeases.Close()
+	}
 	if p.approvalGrants != nil {
 		p.approvalGrants.Close()
 	}
@@ -443,7 +577,12 @@ func (p *Plugin) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/mcp/tools", p.handleMCPTools)
 	mux.HandleFunc("/api/mcp/call-tool", p.handleMCPCallTool)
 	mux.HandleFunc("/api/mcp/servers", p.handleMCPServers)
+	mux.HandleFunc("/api/rbac/explain", p.handleRBACExplain)
+	mux.HandleFunc("/api/teams", p.handleUserTeams)
 	mux.HandleFunc("/api/agent/run", p.handleAgentRun)
+	mux.HandleFunc("/api/alerts/webhook", p.handleAlertWebhook)
+	mux.HandleFunc("/api/token-budgets", p.handleTokenBudgets)
+	mux.HandleFunc("/api/token-budgets/reset", p.handleTokenBudgetReset)
 	mux.HandleFunc("/api/agent/runs/", p.handleAgentRuns)
 	mux.HandleFunc("/api/agent/evals", p.handleAgentEvals)
 	mux.HandleFunc("/api/agent/evals/run", p.handleAgentEvalRun)
@@ -589,6 +728,10 @@ func (p *Plugin) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
 }
 
 func (p *Plugin) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if isStreamableMCPRequest(r) {
+		p.serveAgentMCP(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -602,7 +745,10 @@ func (p *Plugin) handleMCP(w http.ResponseWriter, r *http.Request) {
 
 	p.logger.Debug("Handling MCP JSON-RPC request", "bodyLength", len(body))
 
-	response, err := p.mcpProxy.HandleMCPRequest(body)
+	response, handled := p.authorizeMCPToolRequest(r, body)
+	if !handled {
+		response, err = p.mcpProxy.HandleMCPRequest(r.Context(), body)
+	}
 	if err != nil {
 		p.logger.Error("Failed to handle MCP request", "error", err)
 		http.Error(w, "Internal error", http.StatusInternalServerError)
@@ -613,6 +759,93 @@ func (p *Plugin) handleMCP(w http.ResponseWriter, r *http.Request) {
 	w.Write(response)
 }
 
+// authorizeMCPToolRequest applies the caller's role, tool policies and tool
+// selection to a JSON-RPC tools/list or tools/call, as /api/mcp/tools and
+// /api/mcp/call-tool do. It answers tools/list itself with the tools the
+// caller may use, and refuses calls of other tools; every other request is
+// left for the proxy.
+func (p *Plugin) authorizeMCPToolRequest(r *http.Request, body []byte) ([]byte, bool) {
+	var req mcp.MCPRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false
+	}
+	switch req.Method {
+	case "tools/list":
+		tools, err := p.mcpProxy.ListTools()
+		if err != nil {
+			p.logger.Error("Failed to list tools", "error", err)
+			return mcpErrorResponse(req.ID, -32603, "Internal error"), true
+		}
+		tools = rbac.FilterToolsByRole(tools, p.toolSubject(r), p.toolPolicy())
+		tools = mcp.FilterToolsBySelection(tools, p.settingsForFilter())
+		response, _ := json.Marshal(mcp.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: mcp.ListToolsResult{Tools: tools}})
+		return response, true
+	case "tools/call":
+		var params mcp.CallToolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, false
+		}
+		tool, found := p.findTool(params.Name)
+		if !found {
+			return mcpErrorResponse(req.ID, -32602, fmt.Sprintf("Unknown tool: %s", params.Name)), true
+		}
+		if decision := p.toolPolicy().Decide(p.toolSubject(r), tool); !decision.Allowed {
+			p.logger.Warn("Access denied to tool", "tool", params.Name, "role", getUserRole(r), "rule", decision.Rule)
+			return mcpErrorResponse(req.ID, -32001, fmt.Sprintf("Access denied to tool %s: %s", params.Name, decision.Reason)), true
+		}
+		if !mcp.IsToolEnabled(params.Name, p.settingsForFilter()) {
+			p.logger.Warn("Tool disabled by user selection", "tool", params.Name)
+			return mcpErrorResponse(req.ID, -32001, "Tool is not enabled"), true
+		}
+	}
+	return nil, false
+}
+
+func mcpErrorResponse(id interface{}, code int, message string) []byte {
+	response, _ := json.Marshal(mcp.MCPResponse{JSONRPC: "2.0", ID: id, Error: &mcp.MCPError{Code: code, Message: message}})
+	return response
+}
+
+// findTool looks a prefixed tool name up in the proxy's tools, listing them
+// first if the cache hasn't been filled yet.
+func (p *Plugin) findTool(name string) (mcp.Tool, bool) {
+	tool, found := p.mcpProxy.FindToolByName(name)
+	if !found {
+		if _, err := p.mcpProxy.ListTools(); err == nil {
+			tool, found = p.mcpProxy.FindToolByName(name)
+		}
+	}
+	return tool, found
+}
+
+// parseToolCatalogTTL converts the toolCatalogTTL setting to a duration.
+// Empty or invalid values use mcp.DefaultToolCatalogTTL; "off" returns 0.
+func parseToolCatalogTTL(s string, logger log.Logger) time.Duration {
+	switch s {
+	case "":
+		return mcp.DefaultToolCatalogTTL
+	case "off":
+		return 0
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		logger.Warn("Invalid toolCatalogTTL, using default", "configured", s, "fallback", mcp.DefaultToolCatalogTTL)
+		return mcp.DefaultToolCatalogTTL
+	}
+	return ttl
+}
+
+// snapshotToolCatalog returns the tool catalog a new run is pinned to, or
+// nil to let the agent loop list tools itself.
+func (p *Plugin) snapshotToolCatalog() *mcp.ToolCatalog {
+	catalog, err := p.mcpProxy.ToolCatalog()
+	if err != nil {
+		p.logger.Warn("Failed to snapshot tool catalog", "error", err)
+		return nil
+	}
+	return catalog
+}
+
 func (p *Plugin) handleMCPTools(w http.ResponseWriter, r *http.Request) {
 	userRole := getUserRole(r)
 	p.logger.Debug("MCP tools request", "method", r.Method, "role", userRole)
@@ -626,15 +859,26 @@ func (p *Plugin) handleMCPTools(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filteredTools := rbac.FilterToolsByRole(tools, userRole)
+	filteredTools := rbac.FilterToolsByRole(tools, p.toolSubject(r), p.toolPolicy())
 	filteredTools = mcp.FilterToolsBySelection(filteredTools, p.settingsForFilter())
 
 	p.logger.Debug("Tools filtered", "role", userRole, "totalTools", len(tools), "filteredTools", len(filteredTools))
 
+	// The version covers the tools this caller sees, so role and selection
+	// changes also invalidate cached responses.
+	catalogVersion := mcp.CatalogVersion(filteredTools)
+	etag := `"` + catalogVersion + `"`
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && match == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	response := map[string]interface{}{
-		"tools": filteredTools,
+		"tools":          filteredTools,
+		"catalogVersion": catalogVersion,
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -663,20 +907,14 @@ func (p *Plugin) handleMCPCallTool(w http.ResponseWriter, r *http.Request) {
 
 	p.logger.Debug("MCP call tool request", "tool", req.Name, "role", userRole)
 
-	tool, found := p.mcpProxy.FindToolByName(req.Name)
+	tool, found := p.findTool(req.Name)
 	if !found {
-		// Tool cache may be empty if ListTools hasn't been called yet; populate it.
-		if _, err := p.mcpProxy.ListTools(); err == nil {
-			tool, found = p.mcpProxy.FindToolByName(req.Name)
-		}
-		if !found {
-			http.Error(w, fmt.Sprintf("Unknown tool: %s", req.Name), http.StatusNotFound)
-			return
-		}
+		http.Error(w, fmt.Sprintf("Unknown tool: %s", req.Name), http.StatusNotFound)
+		return
 	}
-	if !rbac.CanAccessTool(userRole, tool) {
-		p.logger.Warn("Access denied to tool", "tool", req.Name, "role", userRole)
-		http.Error(w, fmt.Sprintf("Access denied: %s role cannot access tool %s", userRole, req.Name), http.StatusForbidden)
+	if decision := p.toolPolicy().Decide(p.toolSubject(r), tool); !decision.Allowed {
+		p.logger.Warn("Access denied to tool", "tool", req.Name, "role", userRole, "rule", decision.Rule)
+		http.Error(w, fmt.Sprintf("Access denied to tool %s: %s", req.Name, decision.Reason), http.StatusForbidden)
 		return
 	}
 
@@ -694,7 +932,7 @@ func (p *Plugin) handleMCPCallTool(w http.ResponseWriter, r *http.Request) {
 
 	p.logger.Debug("Tool call context", "orgID", orgID, "orgName", req.OrgName, "scopeOrgId", req.ScopeOrgId, "tool", req.Name)
 
-	result, err := p.mcpProxy.CallToolWithContext(req.Name, req.Arguments, orgID, req.OrgName, req.ScopeOrgId)
+	result, err := p.mcpProxy.CallToolWithContext(r.Context(), req.Name, req.Arguments, orgID, req.OrgName, req.ScopeOrgId)
 	if err != nil {
 		p.logger.Error("Failed to call tool", "error", err)
 		http.Error(w, "Failed to call tool", http.StatusInternalServerError)
@@ -764,11 +1002,87 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := backend.GrafanaConfigFromContext(r.Context())
+	run, err := p.startAgentRun(ctx, agentRunParams{
+		Request:        req,
+		RequestedModel: requestedModel,
+		UserID:         userID,
+		UserLogin:      userLogin,
+		UserRole:       userRole,
+		OrgID:          orgID,
+		NumericOrgID:   getOrgID(r),
+		// Only interactive runs have someone watching the answer stream in.
+		StreamContentDeltas: true,
+	})
+	if err != nil {
+		var runErr *agentRunError
+		if errors.As(err, &runErr) {
+			http.Error(w, runErr.message, runErr.status)
+			return
+		}
+		http.Error(w, "Failed to start agent run", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"runId":       run.RunID,
+		"sessionId":   run.SessionID,
+		"status":      RunStatusRunning,
+		"model":       run.Model,
+		"modelSource": run.ModelSource,
+	})
+}
+
+// agentRunParams is everything startAgentRun needs once the caller has
+// authenticated and decoded the request.
+type agentRunParams struct {
+	Request        agent.RunRequest
+	RequestedModel string
+	UserID         int64
+	UserLogin      string
+	UserRole       string
+	OrgID          string
+	NumericOrgID   int64
+	// PromptSuffix is appended to the rendered user prompt, e.g. alert details
+	// for webhook-triggered investigations.
+	PromptSuffix        string
+	StreamContentDeltas bool
+}
+
+type agentRunStart struct {
+	RunID       string
+	SessionID   string
+	Model       string
+	ModelSource string
+}
+
+// agentRunError carries the HTTP status and client-safe message for a run
+// that could not be started.
+type agentRunError struct {
+	status  int
+	message string
+}
+
+func (e *agentRunError) Error() string { return e.message }
+
+func newAgentRunError(status int, message string) error {
+	return &agentRunError{status: status, message: message}
+}
+
+// startAgentRun builds the prompts, creates or extends the session, and starts
+// the agent loop in the background. The run outlives ctx; only its values
+// (Grafana config, trace span) are used.
+func (p *Plugin) startAgentRun(ctx context.Context, params agentRunParams) (*agentRunStart, error) {
+	req := params.Request
+	requestedModel := params.RequestedModel
+	userID, userLogin, userRole := params.UserID, params.UserLogin, params.UserRole
+	orgID, numericOrgID := params.OrgID, params.NumericOrgID
+	span := trace.SpanFromContext(ctx)
+
+	cfg := backend.GrafanaConfigFromContext(ctx)
 	if cfg == nil {
 		p.logger.Error("Grafana configuration not available in request context")
-		http.Error(w, "Grafana configuration not available", http.StatusInternalServerError)
-		return
+		return nil, newAgentRunError(http.StatusInternalServerError, "Grafana configuration not available")
 	}
 	saToken, err := cfg.PluginAppClientSecret()
 	if err != nil {
@@ -781,18 +1095,24 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 
 	if err := p.ensureBuiltInMCPRegistered(saToken, grafanaURL); err != nil {
 		p.logger.Error("Failed to register built-in MCP server", "error", err)
-		http.Error(w, "Failed to initialize MCP server", http.StatusInternalServerError)
-		return
+		return nil, newAgentRunError(http.StatusInternalServerError, "Failed to initialize MCP server")
+	}
+
+	if err := p.checkTokenBudget(numericOrgID, userLogin); err != nil {
+		if errors.Is(err, agent.ErrTokenBudgetExhausted) {
+			p.logger.Info("Agent run rejected: token budget exhausted", "orgID", orgID, "login", userLogin, "reason", err)
+			return nil, newAgentRunError(http.StatusTooManyRequests, err.Error())
+		}
+		// Fail open: a budget store outage shouldn't take the agent down.
+		p.logger.Warn("Token budget check failed", "error", err)
 	}
 
 	runID, err := generateShareID()
 	if err != nil {
 		p.logger.Error("Failed to generate run ID", "error", err)
-		http.Error(w, "Failed to generate run ID", http.StatusInternalServerError)
-		return
+		return nil, newAgentRunError(http.StatusInternalServerError, "Failed to generate run ID")
 	}
 
-	numericOrgID := getOrgID(r)
 	if p.scout != nil {
 		p.scout.SetOrgID(numericOrgID)
 		p.scout.SetGrafanaConfig(grafanaURL, saToken)
@@ -800,28 +1120,27 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 
 	toolCtx := BuildToolContext(req.OrgName, userRole)
 	toolCtx.ConversationType = req.Type
-	toolCtx.DatasourceSnapshot = p.datasourceSnapshot(orgID, req.OrgName, req.ScopeOrgID)
+	toolCtx.DatasourceSnapshot = p.datasourceSnapshot(ctx, orgID, req.OrgName, req.ScopeOrgID)
+	if p.isGraphitiAvailable() {
+		toolCtx.KnowledgeGraph = graphitiKnowledgePrompt(orgGroupID(numericOrgID))
+	}
 
 	systemPrompt, err := p.promptRegistry.BuildSystemPrompt(toolCtx)
 	if err != nil {
 		p.logger.Error("Failed to build system prompt", "error", err)
-		http.Error(w, "Failed to build system prompt", http.StatusInternalServerError)
-		return
-	}
-
-	if p.isGraphitiAvailable() {
-		systemPrompt += graphitiKnowledgePrompt(orgGroupID(numericOrgID))
+		return nil, newAgentRunError(http.StatusInternalServerError, "Failed to build system prompt")
 	}
 
 	userPrompt, err := p.promptRegistry.BuildUserPrompt(req.Type, req.Message, toolCtx)
 	if err != nil {
 		p.logger.Error("Failed to build user prompt", "error", err, "type", req.Type)
-		http.Error(w, "Failed to build user prompt", http.StatusBadRequest)
-		return
+		return nil, newAgentRunError(http.StatusBadRequest, "Failed to build user prompt")
 	}
+	userPrompt += params.PromptSuffix
 
 	var messages []agent.Message
 	var sessionID string
+	var sessionSummary string
 	runModel := requestedModel
 	modelSource := "auto"
 	if requestedModel != "" {
@@ -831,22 +1150,20 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 	if req.SessionID != "" {
 		session, err := p.sessionStore.GetSession(req.SessionID, userID, numericOrgID)
 		if err != nil {
-			http.Error(w, "Session not found", http.StatusNotFound)
-			return
+			return nil, newAgentRunError(http.StatusNotFound, "Session not found")
 		}
 		sessionID = req.SessionID
+		sessionSummary = session.Summary
 		if session.Model != "" {
 			if requestedModel != "" && requestedModel != session.Model {
-				http.Error(w, "Session model cannot be changed", http.StatusBadRequest)
-				return
+				return nil, newAgentRunError(http.StatusBadRequest, "Session model cannot be changed")
 			}
 			runModel = session.Model
 			modelSource = "session"
 		} else if requestedModel != "" {
 			if err := persistSessionModel(p.sessionStore, sessionID, userID, numericOrgID, requestedModel); err != nil {
 				p.logger.Error("Failed to persist session model", "error", err, "sessionId", sessionID)
-				http.Error(w, "Failed to persist session model", http.StatusInternalServerError)
-				return
+				return nil, newAgentRunError(http.StatusInternalServerError, "Failed to persist session model")
 			}
 		}
 
@@ -882,15 +1199,13 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 		}})
 		if err != nil {
 			p.logger.Error("Failed to create session", "error", err)
-			http.Error(w, "Failed to create session", http.StatusInternalServerError)
-			return
+			return nil, newAgentRunError(http.StatusInternalServerError, "Failed to create session")
 		}
 		sessionID = session.ID
 		if runModel != "" {
 			if err := persistSessionModel(p.sessionStore, sessionID, userID, numericOrgID, runModel); err != nil {
 				p.logger.Error("Failed to persist session model", "error", err, "sessionId", sessionID)
-				http.Error(w, "Failed to persist session model", http.StatusInternalServerError)
-				return
+				return nil, newAgentRunError(http.StatusInternalServerError, "Failed to persist session model")
 			}
 		}
 
@@ -927,11 +1242,15 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 		attribute.String("session_id", sessionID),
 	)
 
+	teamSubject := p.policySubject(ctx, p.settings.ToolPolicies, numericOrgID, userLogin, userRole)
+
 	eventCh := make(chan agent.SSEEvent, 16)
 
 	loopReq := agent.LoopRequest{
+		ToolCatalog:          p.snapshotToolCatalog(),
 		Messages:             messages,
 		SystemPrompt:         systemPrompt,
+		Summary:              sessionSummary,
 		MaxTotalTokens:       p.settings.MaxTotalTokens,
 		RecentMessageCount:   p.settings.RecentMessageCount,
 		MaxIterations:        resolveMaxIterations(req.Type, req.Message),
@@ -946,10 +1265,18 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 		ScopeOrgID:           req.ScopeOrgID,
 		ExcludeToolNames:     graphitiWriteToolNames,
 		MCPServers:           p.settingsForFilter(),
+		ToolPolicies:         p.settings.ToolPolicies,
+		UserTeams:            teamSubject.Teams,
+		UserTeamsUnresolved:  teamSubject.TeamsUnresolved,
+		StreamContentDeltas:  params.StreamContentDeltas,
 		ApprovalPolicy:       p.settings.ApprovalPolicy,
 		MaxParallelToolCalls: p.settings.MaxParallelToolCalls,
 		RegisterApproval:     p.approvalRegistrar(runID),
 		CheckApprovalGrant:   p.approvalGrantChecker(sessionID),
+		RegisterInput:        p.inputRegistrar(runID),
+		RecordTokenUsage:     p.tokenUsageRecorder(numericOrgID, userLogin),
+		CheckTokenBudget:     p.tokenBudgetChecker(numericOrgID, userLogin),
+		SamplingTokenBudget:  p.settings.SamplingTokenBudget,
 	}
 
 	detachedCtx := context.WithoutCancel(ctx)
@@ -958,28 +1285,34 @@ func (p *Plugin) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 	p.runCancelsMu.Lock()
 	p.runCancels[runID] = runCancel
 	p.runCancelsMu.Unlock()
+	p.acquireRunLease(runID)
 
+	runStarted := time.Now()
 	go p.agentLoop.Run(runCtx, loopReq, eventCh)
 	go func() {
-		p.consumeAgentEvents(runID, sessionID, userID, userLogin, numericOrgID, req.OrgName, effectiveRunModel, eventCh)
+		status, iterations := p.consumeAgentEvents(runID, sessionID, userID, userLogin, numericOrgID, req.OrgName, effectiveRunModel, eventCh)
+		observeAgentRun(req.Type, status, iterations, runStarted)
 		runCancel()
 		p.runCancelsMu.Lock()
 		delete(p.runCancels, runID)
 		p.runCancelsMu.Unlock()
+		p.releaseRunLease(runID)
+		p.summarizeSession(sessionID, userID, userLogin, numericOrgID, grafanaURL, saToken, orgID)
 	}()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"runId":       runID,
-		"sessionId":   sessionID,
-		"status":      RunStatusRunning,
-		"model":       effectiveRunModel,
-		"modelSource": modelSource,
-	})
+	return &agentRunStart{
+		RunID:       runID,
+		SessionID:   sessionID,
+		Model:       effectiveRunModel,
+		ModelSource: modelSource,
+	}, nil
 }
 
-func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userLogin string, orgID int64, orgName string, effectiveModel string, eventCh <-chan agent.SSEEvent) {
+// consumeAgentEvents persists a run's events and finishes it, returning the
+// final status and the number of loop iterations the run reported.
+func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userLogin string, orgID int64, orgName string, effectiveModel string, eventCh <-chan agent.SSEEvent) (RunStatus, int) {
 	var lastEvent agent.SSEEvent
+	iterations := 0
 	var allEvents []agent.SSEEvent
 	for event := range eventCh {
 		// Persist session stats before exposing the done event to reconnecting
@@ -987,6 +1320,7 @@ func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userL
 		// incrementing after the loop would race with GET /stats returning zeros.
 		if event.Type == "done" && sessionID != "" {
 			if de, ok := event.Data.(agent.DoneEvent); ok {
+				iterations = de.TotalIterations
 				delta := SessionStatsDelta{
 					RunCount:         1,
 					TotalIterations:  de.TotalIterations,
@@ -1002,6 +1336,11 @@ func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userL
 		}
 
 		p.runStore.AppendEvent(runID, event)
+		if event.Type == "content_delta" {
+			// Streaming preview only; the assembled content event is what
+			// gets persisted to the session.
+			continue
+		}
 		allEvents = append(allEvents, event)
 		lastEvent = event
 	}
@@ -1010,9 +1349,11 @@ func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userL
 	orgStr := fmt.Sprintf("%d", orgID)
 	orgName = sanitizeOrgNameLabel(orgName)
 
+	var status RunStatus
 	switch lastEvent.Type {
 	case "done":
-		p.runStore.FinishRun(runID, RunStatusCompleted, "")
+		status = RunStatusCompleted
+		p.runStore.FinishRun(runID, status, "")
 		var usageMap map[string]agent.ModelUsage
 		if de, ok := lastEvent.Data.(agent.DoneEvent); ok {
 			usageMap = de.UsageByModel
@@ -1041,19 +1382,23 @@ func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userL
 		if ee, ok := lastEvent.Data.(agent.ErrorEvent); ok {
 			errMsg = ee.Message
 		}
-		p.runStore.FinishRun(runID, RunStatusFailed, errMsg)
+		status = RunStatusFailed
+		p.runStore.FinishRun(runID, status, errMsg)
 	case "":
 		p.runCancelsMu.Lock()
 		_, stillCancellable := p.runCancels[runID]
 		p.runCancelsMu.Unlock()
 
 		if !stillCancellable {
-			p.runStore.FinishRun(runID, RunStatusCancelled, "run cancelled by user")
+			status = RunStatusCancelled
+			p.runStore.FinishRun(runID, status, "run cancelled by user")
 		} else {
-			p.runStore.FinishRun(runID, RunStatusFailed, "agent terminated without producing events")
+			status = RunStatusFailed
+			p.runStore.FinishRun(runID, status, "agent terminated without producing events")
 		}
 	default:
-		p.runStore.FinishRun(runID, RunStatusCancelled, "")
+		status = RunStatusCancelled
+		p.runStore.FinishRun(runID, status, "")
 	}
 
 	if sessionID != "" {
@@ -1063,6 +1408,7 @@ func (p *Plugin) consumeAgentEvents(runID, sessionID string, userID int64, userL
 		}
 		p.sessionStore.ClearActiveRunID(sessionID, userID, orgID)
 	}
+	return status, iterations
 }
 
 func initSSEWriter(w http.ResponseWriter) (http.Flusher, bool) {
@@ -1092,7 +1438,7 @@ func (p *Plugin) getAuthorizedRun(w http.ResponseWriter, r *http.Request, runID
 		return nil, false
 	}
 
-	return run, true
+	return p.checkRunOwner(r.Context(), run), true
 }
 
 func (p *Plugin) approvalRegistrar(runID string) agent.ApprovalRegistrar {
@@ -1106,6 +1452,17 @@ func (p *Plugin) approvalRegistrar(runID string) agent.ApprovalRegistrar {
 	}
 }
 
+func (p *Plugin) inputRegistrar(runID string) agent.InputRegistrar {
+	return func(ctx context.Context, request agent.InputRequestEvent) (agent.InputWaitFunc, error) {
+		broker := p.inputBroker
+		if broker == nil {
+			broker = NewInMemoryInputBroker()
+			p.inputBroker = broker
+		}
+		return broker.Register(ctx, runID, request)
+	}
+}
+
 func (p *Plugin) approvalGrantChecker(sessionID string) agent.ApprovalGrantChecker {
 	return func(ctx context.Context, request agent.ApprovalRequestEvent) (bool, error) {
 		store := p.approvalGrants
@@ -1150,6 +1507,25 @@ func (p *Plugin) handleAgentRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.Contains(remainder, "/inputs/") {
+		parts := strings.Split(remainder, "/")
+		if len(parts) != 3 || parts[1] != "inputs" {
+			http.Error(w, "Invalid input path format", http.StatusBadRequest)
+			return
+		}
+		runID, inputID := parts[0], parts[2]
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !isValidSecureID(runID) || !isValidInputID(inputID) {
+			http.Error(w, "Invalid input path identifiers", http.StatusBadRequest)
+			return
+		}
+		p.handleAgentInput(w, r, runID, inputID)
+		return
+	}
+
 	if runID, isCancel := strings.CutSuffix(remainder, "/cancel"); isCancel {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -1199,9 +1575,16 @@ func (p *Plugin) handleAgentApproval(w http.ResponseWriter, r *http.Request, run
 	if !ok {
 		return
 	}
-	role := getUserRole(r)
-	if role != "Admin" && role != "Editor" {
-		http.Error(w, "Access denied", http.StatusForbidden)
+	var tool mcp.Tool
+	if approval := runApproval(run, approvalID); approval != nil {
+		tool = approvalTool(*approval)
+	}
+	if decision := p.approvalPolicy().Decide(p.approvalSubject(r), tool); !decision.Allowed {
+		http.Error(w, "Access denied: "+decision.Reason, http.StatusForbidden)
+		return
+	}
+	if run.Status == RunStatusFailed && run.Error == runOrphanedMessage {
+		http.Error(w, "Run was abandoned by the replica executing it", http.StatusConflict)
 		return
 	}
 
@@ -1276,13 +1659,7 @@ func (p *Plugin) grantToolApproval(ctx context.Context, run *AgentRun, approvalI
 	if run == nil || run.Trace == nil {
 		return fmt.Errorf("run trace is unavailable")
 	}
-	var matched *RunApproval
-	for i := range run.Trace.Approvals {
-		if run.Trace.Approvals[i].ApprovalID == approvalID {
-			matched = &run.Trace.Approvals[i]
-			break
-		}
-	}
+	matched := runApproval(run, approvalID)
 	if matched == nil || strings.TrimSpace(matched.ToolName) == "" {
 		return fmt.Errorf("approval request metadata is unavailable")
 	}
@@ -1301,6 +1678,31 @@ func (p *Plugin) grantToolApproval(ctx context.Context, run *AgentRun, approvalI
 	})
 }
 
+// runApproval returns the traced approval request approvalID, or nil when
+// the run's trace doesn't have it.
+// approvalTool is the tool an approval is for, as the run's pinned catalog
+// described it when the approval was requested. Sampling approvals name the
+// server that asked as "sampling:<server>".
+func approvalTool(approval RunApproval) mcp.Tool {
+	tool := mcp.Tool{Name: approval.ToolName, ServerID: approval.ServerID, Annotations: approval.Annotations}
+	if serverID, ok := strings.CutPrefix(approval.ToolName, "sampling:"); ok && tool.ServerID == "" {
+		tool.ServerID = serverID
+	}
+	return tool
+}
+
+func runApproval(run *AgentRun, approvalID string) *RunApproval {
+	if run == nil || run.Trace == nil {
+		return nil
+	}
+	for i := range run.Trace.Approvals {
+		if run.Trace.Approvals[i].ApprovalID == approvalID {
+			return &run.Trace.Approvals[i]
+		}
+	}
+	return nil
+}
+
 func resolvedApprovalFromRun(run *AgentRun, approvalID string) (agent.ApprovalResolvedEvent, bool) {
 	if run == nil || run.Trace == nil {
 		return agent.ApprovalResolvedEvent{}, false
@@ -1330,6 +1732,132 @@ func approvalAlreadyResolvedMessage(decision string) string {
 	return fmt.Sprintf("Approval already resolved as %s", decision)
 }
 
+type inputAnswerRequest struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// handleAgentInput answers an MCP server's input request. Accepted content is
+// checked against the requested schema, and coerced to it the way tool
+// arguments are, before it reaches the server.
+func (p *Plugin) handleAgentInput(w http.ResponseWriter, r *http.Request, runID, inputID string) {
+	run, ok := p.getAuthorizedRun(w, r, runID)
+	if !ok {
+		return
+	}
+	if run.Status == RunStatusFailed && run.Error == runOrphanedMessage {
+		http.Error(w, "Run was abandoned by the replica executing it", http.StatusConflict)
+		return
+	}
+
+	var req inputAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	switch action {
+	case "accept", "decline", "cancel":
+	default:
+		http.Error(w, "Invalid input action", http.StatusBadRequest)
+		return
+	}
+
+	resolved := agent.InputResolvedEvent{
+		InputID:    inputID,
+		Action:     action,
+		ResolvedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if action == "accept" {
+		request, found := inputRequestFromRun(run, inputID)
+		if !found {
+			http.Error(w, "Input request is not pending or has expired", http.StatusConflict)
+			return
+		}
+		content := req.Content
+		if content == nil {
+			content = map[string]interface{}{}
+		}
+		if err := mcp.ValidateArguments(request.Schema, content); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid input: %v", err), http.StatusBadRequest)
+			return
+		}
+		resolved.Content = content
+	}
+
+	broker := p.inputBroker
+	if broker == nil {
+		broker = NewInMemoryInputBroker()
+		p.inputBroker = broker
+	}
+
+	delivered, err := broker.Resolve(r.Context(), runID, resolved)
+	if err != nil {
+		var conflict *inputConflictError
+		switch {
+		case errors.As(err, &conflict):
+			http.Error(w, inputAlreadyAnsweredMessage(conflict.action), http.StatusConflict)
+			return
+		case errors.Is(err, errInputNotPending):
+			if existing, exists := inputRequestFromRun(run, inputID); exists && existing.Action != "" {
+				if existing.Action == action {
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(resolvedInputFromRun(existing))
+					return
+				}
+				http.Error(w, inputAlreadyAnsweredMessage(existing.Action), http.StatusConflict)
+				return
+			}
+			http.Error(w, "Input request is not pending or has expired", http.StatusConflict)
+			return
+		default:
+			p.logger.Warn("Failed to answer input request", "error", err, "runId", runID, "inputId", inputID)
+			http.Error(w, "Input delivery failed", http.StatusGatewayTimeout)
+			return
+		}
+	}
+	if delivered.Action != action {
+		http.Error(w, inputAlreadyAnsweredMessage(delivered.Action), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivered)
+}
+
+func inputRequestFromRun(run *AgentRun, inputID string) (RunInput, bool) {
+	if run == nil || run.Trace == nil {
+		return RunInput{}, false
+	}
+	for _, input := range run.Trace.Inputs {
+		if input.InputID == inputID {
+			return input, true
+		}
+	}
+	return RunInput{}, false
+}
+
+func resolvedInputFromRun(input RunInput) agent.InputResolvedEvent {
+	resolvedAt := ""
+	if input.ResolvedAt != nil {
+		resolvedAt = input.ResolvedAt.UTC().Format(time.RFC3339)
+	}
+	return agent.InputResolvedEvent{
+		InputID:    input.InputID,
+		Action:     input.Action,
+		Content:    input.Content,
+		Comment:    input.Comment,
+		ResolvedAt: resolvedAt,
+	}
+}
+
+func inputAlreadyAnsweredMessage(action string) string {
+	if action == "" {
+		return "Input request already answered"
+	}
+	return fmt.Sprintf("Input request already answered with %s", action)
+}
+
 func (p *Plugin) handleCancelRun(w http.ResponseWriter, r *http.Request, runID string) {
 	run, ok := p.getAuthorizedRun(w, r, runID)
 	if !ok {
@@ -1341,12 +1869,13 @@ func (p *Plugin) handleCancelRun(w http.ResponseWriter, r *http.Request, runID s
 		return
 	}
 
-	p.runCancelsMu.Lock()
-	cancelFn, exists := p.runCancels[runID]
-	p.runCancelsMu.Unlock()
-
-	if exists {
-		cancelFn()
+	if !p.cancelLocalRun(runID) && p.runLeases != nil {
+		// Another replica executes the run; it cancels on the broadcast.
+		if err := p.runLeases.PublishCancel(r.Context(), runID); err != nil {
+			p.logger.Warn("Failed to publish run cancel", "error", err, "runId", runID)
+			http.Error(w, "Failed to cancel run", http.StatusServiceUnavailable)
+			return
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1553,6 +2082,7 @@ func (p *Plugin) handleAgentTopology(w http.ResponseWriter, r *http.Request) {
 		query = graphitiTopologyFactQuery()
 	}
 	result, err := p.mcpProxy.CallToolWithContext(
+		r.Context(),
 		toolName,
 		graphitiSearchFactsArgs(tools, toolName, orgID, query, maxEdges),
 		strconv.FormatInt(orgID, 10),
@@ -1575,6 +2105,7 @@ func (p *Plugin) handleAgentTopology(w http.ResponseWriter, r *http.Request) {
 	} else {
 		for _, nodeQuery := range graphitiTopologyNodeQueries() {
 			nodeResult, nodeErr := p.mcpProxy.CallToolWithContext(
+				r.Context(),
 				nodeToolName,
 				graphitiSearchNodesArgs(tools, nodeToolName, orgID, nodeQuery.query, hardTopologyMaxNodes, nodeQuery.entityTypes),
 				strconv.FormatInt(orgID, 10),
@@ -1604,6 +2135,7 @@ func (p *Plugin) handleAgentTopology(w http.ResponseWriter, r *http.Request) {
 		centerFactLimit := topologyCenteredFactLimit(maxEdges, len(centerNodes))
 		for _, node := range centerNodes {
 			nodeResult, nodeErr := p.mcpProxy.CallToolWithContext(
+				r.Context(),
 				toolName,
 				graphitiSearchFactsForNodeArgs(tools, toolName, orgID, graphitiTopologyFactQuery(), centerFactLimit, node.UUID),
 				strconv.FormatInt(orgID, 10),
@@ -1669,9 +2201,7 @@ func (p *Plugin) handleDefault(w http.ResponseWriter, r *http.Request) {
 func getUserID(r *http.Request) int64 {
 	pluginContext := httpadapter.PluginConfigFromContext(r.Context())
 	if pluginContext.User != nil && pluginContext.User.Login != "" {
-		h := fnv.New64a()
-		h.Write([]byte(pluginContext.User.Login))
-		return int64(h.Sum64() & 0x7FFFFFFFFFFFFFFF)
+		return userIDForLogin(pluginContext.User.Login)
 	}
 
 	if id, err := strconv.ParseInt(r.Header.Get("X-Grafana-User-Id"), 10, 64); err == nil {
@@ -1681,6 +2211,14 @@ func getUserID(r *http.Request) int64 {
 	return 0
 }
 
+// userIDForLogin maps a Grafana login to the numeric ID sessions and runs are
+// keyed by.
+func userIDForLogin(login string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(login))
+	return int64(h.Sum64() & 0x7FFFFFFFFFFFFFFF)
+}
+
 func getUserLogin(r *http.Request) string {
 	pluginContext := httpadapter.PluginConfigFromContext(r.Context())
 	if pluginContext.User != nil && pluginContext.User.Login != "" {
@@ -1710,7 +2248,7 @@ func (p *Plugin) handleGraphitiStatus(w http.ResponseWriter, r *http.Request) {
 	enabled := p.isGraphitiAvailable()
 	connected := false
 	if enabled {
-		result, err := p.mcpProxy.CallTool("graphiti_get_status", map[string]interface{}{})
+		result, err := p.mcpProxy.CallTool(r.Context(), "graphiti_get_status", map[string]interface{}{})
 		connected = err == nil && result != nil && !result.IsError
 		if err != nil {
 			p.logger.Warn("Knowledge graph status check failed", "error", err)
@@ -1768,24 +2306,30 @@ func (p *Plugin) handleGraphitiDiscover(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	teamSubject := p.policySubject(r.Context(), p.settings.ToolPolicies, orgID, getUserLogin(r), userRole)
+
 	p.runStore.CreateRun(runID, userID, orgID)
 
 	loopReq := agent.LoopRequest{
-		Messages:           []agent.Message{{Role: "user", Content: GraphitiDiscoveryMessage}},
-		SystemPrompt:       GraphitiDiscoverySystemPrompt,
-		MaxTotalTokens:     p.settings.MaxTotalTokens,
-		RecentMessageCount: p.settings.RecentMessageCount,
-		MaxIterations:      GraphitiDiscoveryMaxIter,
-		Model:              agentModelLarge,
-		GrafanaURL:         grafanaURL,
-		AuthToken:          saToken,
-		UserRole:           userRole,
-		OrgID:              strconv.FormatInt(orgID, 10),
-		OrgName:            "Org" + strconv.FormatInt(orgID, 10),
-		ExcludeToolNames:   graphitiWriteToolNames,
-		MCPServers:         p.settingsForFilter(),
-		ConversationType:   "discovery",
-		ApprovalPolicy:     "off",
+		ToolCatalog:         p.snapshotToolCatalog(),
+		Messages:            []agent.Message{{Role: "user", Content: GraphitiDiscoveryMessage}},
+		SystemPrompt:        GraphitiDiscoverySystemPrompt,
+		MaxTotalTokens:      p.settings.MaxTotalTokens,
+		RecentMessageCount:  p.settings.RecentMessageCount,
+		MaxIterations:       GraphitiDiscoveryMaxIter,
+		Model:               agentModelLarge,
+		GrafanaURL:          grafanaURL,
+		AuthToken:           saToken,
+		UserRole:            userRole,
+		OrgID:               strconv.FormatInt(orgID, 10),
+		OrgName:             "Org" + strconv.FormatInt(orgID, 10),
+		ExcludeToolNames:    graphitiWriteToolNames,
+		MCPServers:          p.settingsForFilter(),
+		ToolPolicies:        p.settings.ToolPolicies,
+		UserTeams:           teamSubject.Teams,
+		UserTeamsUnresolved: teamSubject.TeamsUnresolved,
+		ConversationType:    "discovery",
+		ApprovalPolicy:      "off",
 	}
 
 	eventCh := make(chan agent.SSEEvent, GraphitiDiscoveryMaxIter*6)
@@ -1795,14 +2339,18 @@ func (p *Plugin) handleGraphitiDiscover(w http.ResponseWriter, r *http.Request)
 	p.runCancelsMu.Lock()
 	p.runCancels[runID] = runCancel
 	p.runCancelsMu.Unlock()
+	p.acquireRunLease(runID)
 
+	runStarted := time.Now()
 	go p.agentLoop.Run(runCtx, loopReq, eventCh)
 	go func() {
-		p.consumeDiscoveryEvents(runID, orgID, eventCh)
+		status, iterations := p.consumeDiscoveryEvents(runID, orgID, eventCh)
+		observeAgentRun(loopReq.ConversationType, status, iterations, runStarted)
 		runCancel()
 		p.runCancelsMu.Lock()
 		delete(p.runCancels, runID)
 		p.runCancelsMu.Unlock()
+		p.releaseRunLease(runID)
 	}()
 
 	p.logger.Info("Knowledge graph discovery run started", "runId", runID, "orgID", orgID)
@@ -1818,7 +2366,8 @@ func (p *Plugin) handleGraphitiDiscover(w http.ResponseWriter, r *http.Request)
 // and — on success — calls graphiti_add_memory with the agent's synthesis.
 // We call it from Go rather than letting the agent call it, to avoid Anthropic
 // streaming-parser failures when tool argument JSON contains nested JSON content.
-func (p *Plugin) consumeDiscoveryEvents(runID string, orgID int64, eventCh <-chan agent.SSEEvent) {
+// It returns the final run status and the iterations the run reported.
+func (p *Plugin) consumeDiscoveryEvents(runID string, orgID int64, eventCh <-chan agent.SSEEvent) (RunStatus, int) {
 	lastEvent, synthesis := collectDiscoverySynthesis(eventCh, func(event agent.SSEEvent) {
 		p.runStore.AppendEvent(runID, event)
 	})
@@ -1827,6 +2376,7 @@ func (p *Plugin) consumeDiscoveryEvents(runID string, orgID int64, eventCh <-cha
 	case "done":
 		if synthesis != "" && p.isGraphitiAvailable() {
 			if err := ingestGraphitiMemory(
+				p.ctx,
 				p.mcpProxy,
 				orgID,
 				"discovery_synthesis",
@@ -1841,6 +2391,10 @@ func (p *Plugin) consumeDiscoveryEvents(runID string, orgID int64, eventCh <-cha
 		}
 		p.runStore.FinishRun(runID, RunStatusCompleted, "")
 		p.logger.Info("Discovery run completed", "runId", runID, "orgID", orgID)
+		if de, ok := lastEvent.Data.(agent.DoneEvent); ok {
+			return RunStatusCompleted, de.TotalIterations
+		}
+		return RunStatusCompleted, 0
 	case "error":
 		var errMsg string
 		if ee, ok := lastEvent.Data.(agent.ErrorEvent); ok {
@@ -1848,9 +2402,11 @@ func (p *Plugin) consumeDiscoveryEvents(runID string, orgID int64, eventCh <-cha
 		}
 		p.runStore.FinishRun(runID, RunStatusFailed, errMsg)
 		p.logger.Warn("Discovery run failed", "runId", runID, "error", errMsg)
+		return RunStatusFailed, 0
 	default:
 		p.runStore.FinishRun(runID, RunStatusCancelled, "")
 		p.logger.Warn("Discovery run cancelled", "runId", runID)
+		return RunStatusCancelled, 0
 	}
 }
 
@@ -1897,6 +2453,7 @@ func (p *Plugin) handleGraphitiIngestSession(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := ingestGraphitiMemory(
+		r.Context(),
 		p.mcpProxy,
 		orgID,
 		"investigation_session",
@@ -1989,11 +2546,14 @@ func (p *Plugin) handleCreateShare(w http.ResponseWriter, r *http.Request) {
 	orgID := getOrgID(r)
 	userID := getUserID(r)
 
+	// Any sessionData the client sends is ignored: the snapshot is built
+	// from the stored session so a share can't carry fabricated content.
 	var req struct {
-		SessionID      string          `json:"sessionId"`
-		SessionData    json.RawMessage `json:"sessionData"`
-		ExpiresInDays  *int            `json:"expiresInDays,omitempty"`
-		ExpiresInHours *int            `json:"expiresInHours,omitempty"`
+		SessionID      string `json:"sessionId"`
+		ExpiresInDays  *int   `json:"expiresInDays,omitempty"`
+		ExpiresInHours *int   `json:"expiresInHours,omitempty"`
+		// Teams limits the share to its creator and these Grafana teams.
+		Teams []string `json:"teams,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -2001,21 +2561,8 @@ func (p *Plugin) handleCreateShare(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	if err := ValidateSessionData(req.SessionData); err != nil {
-		p.logger.Warn("Invalid session data", "error", err)
-		http.Error(w, "Invalid session data", http.StatusBadRequest)
-		return
-	}
-
-	var sessionData map[string]interface{}
-	if err := json.Unmarshal(req.SessionData, &sessionData); err != nil {
-		http.Error(w, "Invalid session data format", http.StatusBadRequest)
-		return
-	}
-
-	if sessionID, ok := sessionData["id"].(string); !ok || sessionID != req.SessionID {
-		http.Error(w, "Session ID mismatch", http.StatusBadRequest)
+	if req.SessionID == "" {
+		http.Error(w, "sessionId is required", http.StatusBadRequest)
 		return
 	}
 
@@ -2035,7 +2582,29 @@ func (p *Plugin) handleCreateShare(w http.ResponseWriter, r *http.Request) {
 		expiresInHours = &hours
 	}
 
-	share, err := p.shareStore.CreateShare(req.SessionID, req.SessionData, orgID, userID, expiresInHours)
+	session, err := p.sessionStore.GetSession(req.SessionID, userID, orgID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	p.settingsMu.RLock()
+	redactionSettings := p.settings.ShareRedaction
+	p.settingsMu.RUnlock()
+
+	sessionData, redactions, err := buildShareSnapshot(session, NewShareRedactor(redactionSettings, p.logger))
+	if err != nil {
+		p.logger.Error("Failed to build share snapshot", "error", err, "sessionId", req.SessionID)
+		http.Error(w, "Failed to create share", http.StatusInternalServerError)
+		return
+	}
+	if err := ValidateSessionData(sessionData); err != nil {
+		p.logger.Warn("Invalid session data", "error", err)
+		http.Error(w, "Session has no messages to share", http.StatusBadRequest)
+		return
+	}
+
+	share, err := p.shareStore.CreateShare(req.SessionID, sessionData, orgID, userID, expiresInHours, req.Teams)
 	if err != nil {
 		if err.Error() == "rate limit exceeded: too many share requests" {
 			http.Error(w, "Too many share requests. Please try again later.", http.StatusTooManyRequests)
@@ -2055,9 +2624,10 @@ func (p *Plugin) handleCreateShare(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"shareId":   share.ShareID,
-		"shareUrl":  shareURL,
-		"expiresAt": expiresAtStr,
+		"shareId":    share.ShareID,
+		"shareUrl":   shareURL,
+		"expiresAt":  expiresAtStr,
+		"redactions": redactions,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -2082,8 +2652,6 @@ func (p *Plugin) handleGetSharedSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	orgID := getOrgID(r)
-
 	share, err := p.shareStore.GetShare(shareID)
 	if err != nil {
 		if err.Error() == "share not found" {
@@ -2099,7 +2667,7 @@ func (p *Plugin) handleGetSharedSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if share.OrgID != orgID {
+	if !p.canOpenShare(r, share) {
 		http.Error(w, "You don't have access to this shared session", http.StatusForbidden)
 		return
 	}
@@ -34,14 +34,20 @@ func newAgentRunTestPlugin(t *testing.T) *Plugin {
 		runStore:       NewRunStore(logger),
 		sessionStore:   NewSessionStore(logger),
 		approvalBroker: NewInMemoryApprovalBroker(),
+		inputBroker:    NewInMemoryInputBroker(),
 		approvalGrants: NewInMemoryApprovalGrantStore(),
+		alertDedupe:    NewInMemoryAlertDedupeStore(),
+		tokenBudgets:   NewInMemoryTokenBudgetStore(),
 		promptRegistry: promptRegistry,
 		settings: PluginSettings{
 			MaxTotalTokens:     agent.DefaultMaxTotalTokens,
 			RecentMessageCount: 10,
 		},
-		runCancels: make(map[string]context.CancelFunc),
-		dsCache:    make(map[string]dsCacheEntry),
+		ctx:           context.Background(),
+		runCancels:    make(map[string]context.CancelFunc),
+		runLeases:     NewInMemoryRunLeaseStore(),
+		grafanaClient: http.DefaultClient,
+		dsCache:       make(map[string]dsCacheEntry),
 	}
 }
 
@@ -302,6 +308,129 @@ func TestApprovalRegistrarTimesOutPendingApproval(t *testing.T) {
 	}
 }
 
+func newAgentInputRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/agent/runs/run-1/inputs/tc_1-input-1", strings.NewReader(body))
+	req.Header.Set("X-Grafana-Org-Id", "2")
+	req.Header.Set("X-Grafana-User-Id", "7")
+	req.Header.Set("X-Grafana-User-Role", "Viewer")
+	return req
+}
+
+func registerAgentInput(t *testing.T, p *Plugin) agent.InputWaitFunc {
+	t.Helper()
+	request := agent.InputRequestEvent{
+		InputID:  "tc_1-input-1",
+		ServerID: "k8s",
+		Message:  "How many replicas?",
+		Schema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"replicas": map[string]interface{}{"type": "integer"}},
+			"required":   []interface{}{"replicas"},
+		},
+	}
+	wait, err := p.inputBroker.Register(context.Background(), "run-1", request)
+	if err != nil {
+		t.Fatalf("register input failed: %v", err)
+	}
+	p.runStore.CreateRun("run-1", 7, 2, "session-1")
+	p.runStore.AppendEvent("run-1", agent.SSEEvent{Type: "input_request", Data: request})
+	return wait
+}
+
+func TestHandleAgentInputDeliversCoercedContent(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	wait := registerAgentInput(t, p)
+	rec := httptest.NewRecorder()
+
+	p.handleAgentInput(rec, newAgentInputRequest(`{"action":"accept","content":{"replicas":"3"}}`), "run-1", "tc_1-input-1")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	waitCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resolved, err := wait(waitCtx)
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if resolved.Action != "accept" || resolved.Content["replicas"] != float64(3) {
+		t.Fatalf("unexpected answer: %+v", resolved)
+	}
+}
+
+func TestHandleAgentInputRejectsContentNotMatchingSchema(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	registerAgentInput(t, p)
+	rec := httptest.NewRecorder()
+
+	p.handleAgentInput(rec, newAgentInputRequest(`{"action":"accept","content":{}}`), "run-1", "tc_1-input-1")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "replicas") {
+		t.Fatalf("expected the missing field to be named: %s", rec.Body.String())
+	}
+}
+
+func TestHandleAgentInputDeclineDropsContentAndConflictsWithAccept(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	wait := registerAgentInput(t, p)
+	rec := httptest.NewRecorder()
+
+	p.handleAgentInput(rec, newAgentInputRequest(`{"action":"decline","content":{"replicas":3}}`), "run-1", "tc_1-input-1")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resolved, err := wait(context.Background())
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if resolved.Action != "decline" || resolved.Content != nil {
+		t.Fatalf("unexpected answer: %+v", resolved)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleAgentInput(rec, newAgentInputRequest(`{"action":"accept","content":{"replicas":3}}`), "run-1", "tc_1-input-1")
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "already answered with decline") {
+		t.Fatalf("expected 409 for a conflicting answer, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleAgentInputRejectsUnknownAction(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	registerAgentInput(t, p)
+	rec := httptest.NewRecorder()
+
+	p.handleAgentInput(rec, newAgentInputRequest(`{"action":"maybe"}`), "run-1", "tc_1-input-1")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInputRegistrarTimesOutPendingInput(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	oldTimeout := inputTimeout
+	inputTimeout = 10 * time.Millisecond
+	defer func() { inputTimeout = oldTimeout }()
+
+	register := p.inputRegistrar("run-1")
+	wait, err := register(context.Background(), agent.InputRequestEvent{InputID: "tc_1-input-1"})
+	if err != nil {
+		t.Fatalf("register input failed: %v", err)
+	}
+
+	resolved, err := wait(context.Background())
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if resolved.Action != "cancel" || resolved.Comment != "input request timed out" {
+		t.Fatalf("unexpected timeout resolution: %+v", resolved)
+	}
+}
+
 func TestBuiltInMCPBaseURL(t *testing.T) {
 	t.Run("default returns localhost:3000", func(t *testing.T) {
 		got := builtInMCPBaseURL(PluginSettings{})
@@ -837,3 +966,49 @@ func TestHandleSessionRouter_DispatchesStats(t *testing.T) {
 		t.Errorf("unexpected stats body: %+v", body)
 	}
 }
+
+func TestHandleMCPTools_CatalogVersionAndETag(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+
+	rec := httptest.NewRecorder()
+	p.handleMCPTools(rec, httptest.NewRequest(http.MethodGet, "/api/mcp/tools", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		CatalogVersion string `json:"catalogVersion"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	etag := rec.Header().Get("ETag")
+	if body.CatalogVersion == "" || etag != `"`+body.CatalogVersion+`"` {
+		t.Fatalf("catalogVersion %q does not match ETag %q", body.CatalogVersion, etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/mcp/tools", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	p.handleMCPTools(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected empty 304 for matching If-None-Match, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseToolCatalogTTL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"", mcp.DefaultToolCatalogTTL},
+		{"off", 0},
+		{"90s", 90 * time.Second},
+		{"soon", mcp.DefaultToolCatalogTTL},
+		{"-1m", mcp.DefaultToolCatalogTTL},
+	}
+	for _, tt := range tests {
+		if got := parseToolCatalogTTL(tt.input, log.DefaultLogger); got != tt.want {
+			t.Errorf("parseToolCatalogTTL(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strings"
 	"text/template"
 )
 
@@ -30,14 +31,22 @@ type PromptContext struct {
 	OrgName  string
 	UserRole string
 
+	// KnowledgeGraph is the org-scoped Graphiti instructions, empty when the
+	// knowledge graph is unavailable.
+	KnowledgeGraph string
+
 	// DatasourceSnapshot is a short, per-run bullet list of real datasource UIDs
 	// injected at session start so the LLM cannot hallucinate UIDs. Empty string
-	// renders no block.
+	// renders no block. It changes between runs, so BuildSystemPrompt places it
+	// after everything else to keep the prompt prefix cacheable.
 	DatasourceSnapshot string
 }
 
 type PromptRegistry struct {
-	systemTemplate           *template.Template
+	systemTemplate *template.Template
+	// systemRendersSnapshot is set when a custom system template places
+	// {{.DatasourceSnapshot}} itself.
+	systemRendersSnapshot    bool
 	investigationTemplate    *template.Template
 	performanceTemplate      *template.Template
 	toolInstructionsTemplate *template.Template
@@ -51,6 +60,7 @@ func NewPromptRegistry(settings PluginSettings) (*PromptRegistry, error) {
 	if err != nil {
 		return nil, err
 	}
+	registry.systemRendersSnapshot = strings.Contains(registry.systemTemplate.Tree.Root.String(), ".DatasourceSnapshot")
 	registry.investigationTemplate, err = parseTemplateWithFallback("investigation", settings.InvestigationPrompt, DefaultInvestigationPrompt)
 	if err != nil {
 		return nil, err
@@ -102,6 +112,10 @@ func (r *PromptRegistry) BuildSystemPrompt(ctx PromptContext) (string, error) {
 	if ctx.ConversationType == "investigation" {
 		out += "\n\n---\n\n" + DefaultInvestigationModeSystemAddendum
 	}
+	out += ctx.KnowledgeGraph
+	if ctx.DatasourceSnapshot != "" && !r.systemRendersSnapshot {
+		out += "\n\n## Known Datasource UIDs (this run)\n\n" + ctx.DatasourceSnapshot + "\n"
+	}
 	return out, nil
 }
 
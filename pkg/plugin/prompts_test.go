@@ -99,3 +99,45 @@ func TestBuildSystemPrompt_DatasourceSnapshotSlot(t *testing.T) {
 		t.Fatal("expected snapshot UIDs rendered inside the block")
 	}
 }
+
+func TestBuildSystemPrompt_VolatileSnapshotAfterStablePrefix(t *testing.T) {
+	r, err := NewPromptRegistry(PluginSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := BuildToolContext("Org1", "Admin")
+	ctx.KnowledgeGraph = graphitiKnowledgePrompt("org_1")
+	stable, err := r.BuildSystemPrompt(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.DatasourceSnapshot = "- prometheus (mimir): uid=abc123"
+	withSnap, err := r.BuildSystemPrompt(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(withSnap, stable) {
+		t.Fatal("datasource snapshot must not change the prompt prefix")
+	}
+	if !strings.HasSuffix(strings.TrimSpace(withSnap), "uid=abc123") {
+		t.Fatalf("expected snapshot at the end of the prompt, got tail %q", withSnap[len(withSnap)-200:])
+	}
+}
+
+func TestBuildSystemPrompt_CustomTemplatePlacesSnapshot(t *testing.T) {
+	r, err := NewPromptRegistry(PluginSettings{DefaultSystemPrompt: "Custom.\n{{.DatasourceSnapshot}}\nEnd."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := BuildToolContext("Org1", "Admin")
+	ctx.DatasourceSnapshot = "- loki: uid=def456"
+	out, err := r.BuildSystemPrompt(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out, "uid=def456") != 1 || strings.Contains(out, "Known Datasource UIDs (this run)") {
+		t.Fatalf("custom template snapshot should render once in place, got %q", out)
+	}
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// RedactionRule is a custom share redaction pattern. Matches are replaced
+// with "[REDACTED:<name>]".
+type RedactionRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// ShareRedactionSettings configures the redaction applied to share snapshots.
+// The built-in rules (bearer, token, secret, email, ipv4, ipv6) run unless
+// listed in DisabledRules; CustomRules run after them.
+type ShareRedactionSettings struct {
+	DisabledRules []string        `json:"disabledRules,omitempty"`
+	CustomRules   []RedactionRule `json:"customRules,omitempty"`
+}
+
+// RedactionHit records how many matches a rule replaced in a share snapshot.
+type RedactionHit struct {
+	Rule  string `json:"rule"`
+	Count int    `json:"count"`
+}
+
+type redactionRule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+	// valid, if set, vets each match by its submatches; matches it rejects
+	// are left alone.
+	valid func(submatches []string) bool
+}
+
+// builtInRedactionRules run in order, so the bearer rule claims a JWT sent as
+// a bearer credential before the token rule sees it.
+var builtInRedactionRules = []redactionRule{
+	{
+		name:        "bearer",
+		re:          regexp.MustCompile(`(?i)\b(bearer|basic)\s+([A-Za-z0-9._~+/=-]{8,})`),
+		replacement: "${1} [REDACTED:bearer]",
+		valid:       isAuthCredential,
+	},
+	{
+		name: "token",
+		re: regexp.MustCompile(`\b(?:` +
+			`(?:glsa|glc|ghp|gho|ghs|ghu|github_pat)_[A-Za-z0-9_]{16,}` +
+			`|sk-[A-Za-z0-9_-]{20,}` +
+			`|xox[abprs]-[A-Za-z0-9-]{10,}` +
+			`|AKIA[0-9A-Z]{16}` +
+			`|eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}` +
+			`)\b`),
+		replacement: "[REDACTED:token]",
+	},
+	{
+		name:        "secret",
+		re:          regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)(["']?\s*[:=]\s*["']?)[^\s"',;&]{4,}`),
+		replacement: "${1}${2}[REDACTED:secret]",
+	},
+	{
+		name:        "email",
+		re:          regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+		replacement: "[REDACTED:email]",
+	},
+	{
+		name:        "ipv4",
+		re:          regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+		replacement: "[REDACTED:ipv4]",
+	},
+	{
+		name:        "ipv6",
+		re:          regexp.MustCompile(`\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b|\b(?:[0-9A-Fa-f]{1,4}:){1,6}:(?:[0-9A-Fa-f]{1,4}:){0,5}[0-9A-Fa-f]{1,4}\b`),
+		replacement: "[REDACTED:ipv6]",
+	},
+}
+
+// ShareRedactor scrubs secrets and personal data from share snapshots.
+type ShareRedactor struct {
+	rules []redactionRule
+}
+
+// NewShareRedactor builds the redaction pipeline for settings. Custom rules
+// with an empty name or an invalid pattern are skipped with a warning.
+func NewShareRedactor(settings ShareRedactionSettings, logger log.Logger) *ShareRedactor {
+	r := &ShareRedactor{}
+	for _, rule := range builtInRedactionRules {
+		if !slices.Contains(settings.DisabledRules, rule.name) {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	for _, custom := range settings.CustomRules {
+		if custom.Name == "" {
+			logger.Warn("Skipping share redaction rule without a name", "pattern", custom.Pattern)
+			continue
+		}
+		re, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			logger.Warn("Skipping invalid share redaction rule", "rule", custom.Name, "error", err)
+			continue
+		}
+		r.rules = append(r.rules, redactionRule{
+			name:        custom.Name,
+			re:          re,
+			replacement: "[REDACTED:" + custom.Name + "]",
+		})
+	}
+	return r
+}
+
+// isAuthCredential tells a Basic credential from prose such as "basic
+// troubleshooting": it must decode to user:password. Bearer tokens have no
+// such shape, so any token after "Bearer" counts.
+func isAuthCredential(submatches []string) bool {
+	scheme, credential := submatches[1], submatches[2]
+	if !strings.EqualFold(scheme, "basic") {
+		return true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(credential)
+	return err == nil && strings.Contains(string(decoded), ":")
+}
+
+// apply redacts the rule's matches in s and returns how many it redacted.
+func (rule redactionRule) apply(s string) (string, int) {
+	if rule.valid == nil {
+		n := len(rule.re.FindAllStringIndex(s, -1))
+		if n == 0 {
+			return s, 0
+		}
+		return rule.re.ReplaceAllString(s, rule.replacement), n
+	}
+
+	var out []byte
+	last, n := 0, 0
+	for _, m := range rule.re.FindAllStringSubmatchIndex(s, -1) {
+		submatches := make([]string, len(m)/2)
+		for i := range submatches {
+			if m[2*i] >= 0 {
+				submatches[i] = s[m[2*i]:m[2*i+1]]
+			}
+		}
+		if !rule.valid(submatches) {
+			continue
+		}
+		out = append(out, s[last:m[0]]...)
+		out = rule.re.ExpandString(out, rule.replacement, s, m)
+		last = m[1]
+		n++
+	}
+	if n == 0 {
+		return s, 0
+	}
+	return string(append(out, s[last:]...)), n
+}
+
+func (r *ShareRedactor) redactString(s string, hits map[string]int) string {
+	for _, rule := range r.rules {
+		var n int
+		if s, n = rule.apply(s); n > 0 {
+			hits[rule.name] += n
+		}
+	}
+	return s
+}
+
+// redactJSON redacts every string value in raw, leaving keys and structure
+// alone so the result is still valid JSON.
+func (r *ShareRedactor) redactJSON(raw json.RawMessage, hits map[string]int) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(r.redactValue(value, hits))
+}
+
+func (r *ShareRedactor) redactValue(value interface{}, hits map[string]int) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v, hits)
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactValue(v[i], hits)
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = r.redactValue(v[k], hits)
+		}
+	}
+	return value
+}
+
+// ShareSnapshot is the transcript stored for a share link. It's built from
+// the stored session rather than anything the browser sends, and Redactions
+// tells viewers which rules scrubbed it.
+type ShareSnapshot struct {
+	ID           string           `json:"id"`
+	Title        string           `json:"title"`
+	Messages     []SessionMessage `json:"messages"`
+	Summary      string           `json:"summary,omitempty"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	UpdatedAt    time.Time        `json:"updatedAt"`
+	MessageCount int              `json:"messageCount"`
+	Redactions   []RedactionHit   `json:"redactions"`
+}
+
+// buildShareSnapshot returns the redacted, JSON-encoded share snapshot for
+// session and the rules that fired, in pipeline order.
+func buildShareSnapshot(session *ChatSession, redactor *ShareRedactor) ([]byte, []RedactionHit, error) {
+	hits := make(map[string]int)
+	snapshot := ShareSnapshot{
+		ID:           session.ID,
+		Title:        redactor.redactString(session.Title, hits),
+		Messages:     make([]SessionMessage, 0, len(session.Messages)),
+		Summary:      redactor.redactString(session.Summary, hits),
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+		MessageCount: len(session.Messages),
+	}
+
+	for i, msg := range session.Messages {
+		redacted := SessionMessage{
+			Role:       msg.Role,
+			Content:    redactor.redactString(msg.Content, hits),
+			TokenUsage: msg.TokenUsage,
+		}
+		var err error
+		if redacted.ToolCalls, err = redactor.redactJSON(msg.ToolCalls, hits); err != nil {
+			return nil, nil, fmt.Errorf("failed to redact tool calls of message %d: %w", i, err)
+		}
+		if redacted.PageRefs, err = redactor.redactJSON(msg.PageRefs, hits); err != nil {
+			return nil, nil, fmt.Errorf("failed to redact page refs of message %d: %w", i, err)
+		}
+		snapshot.Messages = append(snapshot.Messages, redacted)
+	}
+
+	snapshot.Redactions = []RedactionHit{}
+	for _, rule := range redactor.rules {
+		if n := hits[rule.name]; n > 0 {
+			snapshot.Redactions = append(snapshot.Redactions, RedactionHit{Rule: rule.name, Count: n})
+			delete(hits, rule.name)
+		}
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal share snapshot: %w", err)
+	}
+	return data, snapshot.Redactions, nil
+}
@@ -0,0 +1,313 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// runOrphanedMessage is the error recorded on runs whose owning replica
+// stopped renewing its lease.
+const runOrphanedMessage = "run abandoned: the Grafana replica executing it stopped responding"
+
+// RunLeaseStore tracks which replica executes each agent run, so a cancel
+// received by any replica reaches the owner and runs whose owner died can be
+// marked failed.
+type RunLeaseStore interface {
+	// Acquire records that this replica executes runID and keeps the lease
+	// alive until Release.
+	Acquire(ctx context.Context, runID string) error
+	Release(ctx context.Context, runID string)
+	// PublishCancel asks every replica to cancel runID.
+	PublishCancel(ctx context.Context, runID string) error
+	// SubscribeCancels calls onCancel for every run ID published with
+	// PublishCancel until ctx is done.
+	SubscribeCancels(ctx context.Context, onCancel func(runID string))
+	// Alive reports whether runID's owner still holds its lease.
+	Alive(ctx context.Context, runID string) (bool, error)
+	// ClaimOrphans returns leased runs whose lease expired. Each orphan is
+	// returned to exactly one caller across replicas.
+	ClaimOrphans(ctx context.Context) ([]string, error)
+	Close()
+}
+
+// InMemoryRunLeaseStore is used without Redis. Runs live in the same process
+// as their store, so there is nothing to publish and no run can outlive its
+// owner.
+type InMemoryRunLeaseStore struct{}
+
+func NewInMemoryRunLeaseStore() *InMemoryRunLeaseStore {
+	return &InMemoryRunLeaseStore{}
+}
+
+func (s *InMemoryRunLeaseStore) Acquire(context.Context, string) error { return nil }
+
+func (s *InMemoryRunLeaseStore) Release(context.Context, string) {}
+
+func (s *InMemoryRunLeaseStore) PublishCancel(context.Context, string) error { return nil }
+
+func (s *InMemoryRunLeaseStore) SubscribeCancels(context.Context, func(string)) {}
+
+func (s *InMemoryRunLeaseStore) Alive(context.Context, string) (bool, error) { return true, nil }
+
+func (s *InMemoryRunLeaseStore) ClaimOrphans(context.Context) ([]string, error) { return nil, nil }
+
+func (s *InMemoryRunLeaseStore) Close() {}
+
+// RedisRunLeaseStore keeps a "run:<id>:lease" key per run, owned by this
+// replica and renewed every RunLeaseHeartbeatInterval. Leased run IDs are
+// also kept in a set so any replica can find runs whose lease expired.
+type RedisRunLeaseStore struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	client  *redis.Client
+	logger  log.Logger
+	ownerID string
+
+	mu    sync.Mutex
+	owned map[string]struct{}
+}
+
+const (
+	runLeasesSetKey  = "runs:leased"
+	runCancelChannel = "runs:cancel"
+)
+
+func runLeaseKey(runID string) string { return fmt.Sprintf("run:%s:lease", runID) }
+
+func NewRedisRunLeaseStore(ctx context.Context, client *redis.Client, logger log.Logger) *RedisRunLeaseStore {
+	ownerID, err := generateShareID()
+	if err != nil {
+		ownerID = fmt.Sprintf("replica-%d", time.Now().UnixNano())
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &RedisRunLeaseStore{
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  client,
+		logger:  logger,
+		ownerID: ownerID,
+		owned:   make(map[string]struct{}),
+	}
+	go s.heartbeat()
+	return s
+}
+
+func (s *RedisRunLeaseStore) Acquire(ctx context.Context, runID string) error {
+	opCtx, cancel := redisContext(ctx, RedisOpTimeout)
+	defer cancel()
+	pipe := s.client.TxPipeline()
+	pipe.Set(opCtx, runLeaseKey(runID), s.ownerID, RunLeaseTTL)
+	pipe.SAdd(opCtx, runLeasesSetKey, runID)
+	if _, err := pipe.Exec(opCtx); err != nil {
+		return fmt.Errorf("acquire run lease: %w", err)
+	}
+
+	s.mu.Lock()
+	s.owned[runID] = struct{}{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RedisRunLeaseStore) Release(ctx context.Context, runID string) {
+	s.mu.Lock()
+	delete(s.owned, runID)
+	s.mu.Unlock()
+
+	opCtx, cancel := redisContext(ctx, RedisOpTimeout)
+	defer cancel()
+	pipe := s.client.TxPipeline()
+	pipe.Del(opCtx, runLeaseKey(runID))
+	pipe.SRem(opCtx, runLeasesSetKey, runID)
+	if _, err := pipe.Exec(opCtx); err != nil {
+		s.logger.Warn("Failed to release run lease", "error", err, "runId", runID)
+	}
+}
+
+func (s *RedisRunLeaseStore) PublishCancel(ctx context.Context, runID string) error {
+	opCtx, cancel := redisContext(ctx, RedisOpTimeout)
+	defer cancel()
+	if err := s.client.Publish(opCtx, runCancelChannel, runID).Err(); err != nil {
+		return fmt.Errorf("publish run cancel: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisRunLeaseStore) SubscribeCancels(ctx context.Context, onCancel func(runID string)) {
+	sub := s.client.Subscribe(ctx, runCancelChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			onCancel(msg.Payload)
+		case <-ctx.Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *RedisRunLeaseStore) Alive(ctx context.Context, runID string) (bool, error) {
+	opCtx, cancel := redisContext(ctx, RedisOpTimeout)
+	defer cancel()
+	n, err := s.client.Exists(opCtx, runLeaseKey(runID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("check run lease: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *RedisRunLeaseStore) ClaimOrphans(ctx context.Context) ([]string, error) {
+	opCtx, cancel := redisContext(ctx, RedisBulkOpTimeout)
+	defer cancel()
+	runIDs, err := s.client.SMembers(opCtx, runLeasesSetKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list run leases: %w", err)
+	}
+
+	var orphans []string
+	for _, runID := range runIDs {
+		n, err := s.client.Exists(opCtx, runLeaseKey(runID)).Result()
+		if err != nil {
+			return orphans, fmt.Errorf("check run lease: %w", err)
+		}
+		if n == 1 {
+			continue
+		}
+		// SREM succeeds for one replica only, which then owns the cleanup.
+		removed, err := s.client.SRem(opCtx, runLeasesSetKey, runID).Result()
+		if err != nil {
+			return orphans, fmt.Errorf("claim orphaned run: %w", err)
+		}
+		if removed == 1 {
+			orphans = append(orphans, runID)
+		}
+	}
+	return orphans, nil
+}
+
+func (s *RedisRunLeaseStore) Close() {
+	s.cancel()
+}
+
+// heartbeat renews the leases of runs this replica executes. SET rather than
+// EXPIRE restores a lease lost to a Redis blip.
+func (s *RedisRunLeaseStore) heartbeat() {
+	ticker := time.NewTicker(RunLeaseHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			runIDs := make([]string, 0, len(s.owned))
+			for runID := range s.owned {
+				runIDs = append(runIDs, runID)
+			}
+			s.mu.Unlock()
+			if len(runIDs) == 0 {
+				continue
+			}
+
+			ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+			pipe := s.client.Pipeline()
+			for _, runID := range runIDs {
+				pipe.Set(ctx, runLeaseKey(runID), s.ownerID, RunLeaseTTL)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				s.logger.Warn("Failed to renew run leases", "error", err, "runs", len(runIDs))
+			}
+			cancel()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// cancelLocalRun cancels runID if this replica executes it.
+func (p *Plugin) cancelLocalRun(runID string) bool {
+	p.runCancelsMu.Lock()
+	cancelFn, exists := p.runCancels[runID]
+	p.runCancelsMu.Unlock()
+
+	if exists {
+		cancelFn()
+	}
+	return exists
+}
+
+func (p *Plugin) acquireRunLease(runID string) {
+	if p.runLeases == nil {
+		return
+	}
+	if err := p.runLeases.Acquire(p.ctx, runID); err != nil {
+		p.logger.Warn("Failed to acquire run lease; cancels from other replicas may not reach this run", "error", err, "runId", runID)
+	}
+}
+
+func (p *Plugin) releaseRunLease(runID string) {
+	if p.runLeases != nil {
+		p.runLeases.Release(p.ctx, runID)
+	}
+}
+
+// checkRunOwner marks run failed if it is still running but its owner's
+// lease has expired, and returns the run as stored afterwards. Runs updated
+// within the lease TTL are left alone, which covers the window between
+// CreateRun and Acquire.
+func (p *Plugin) checkRunOwner(ctx context.Context, run *AgentRun) *AgentRun {
+	if p.runLeases == nil || run.Status != RunStatusRunning || time.Since(run.UpdatedAt) < RunLeaseTTL {
+		return run
+	}
+	alive, err := p.runLeases.Alive(ctx, run.RunID)
+	if err != nil {
+		p.logger.Warn("Failed to check run lease", "error", err, "runId", run.RunID)
+		return run
+	}
+	if alive {
+		return run
+	}
+
+	p.failOrphanedRun(run)
+	if updated, err := p.runStore.GetRun(run.RunID); err == nil {
+		return updated
+	}
+	return run
+}
+
+// failOrphanedRuns marks failed every run whose owner's lease expired.
+func (p *Plugin) failOrphanedRuns() {
+	orphans, err := p.runLeases.ClaimOrphans(p.ctx)
+	if err != nil {
+		p.logger.Warn("Failed to claim orphaned runs", "error", err)
+	}
+	for _, runID := range orphans {
+		run, err := p.runStore.GetRun(runID)
+		if err != nil || run.Status != RunStatusRunning {
+			continue
+		}
+		p.failOrphanedRun(run)
+	}
+}
+
+func (p *Plugin) failOrphanedRun(run *AgentRun) {
+	p.logger.Warn("Marking orphaned run as failed", "runId", run.RunID)
+	p.runStore.AppendEvent(run.RunID, agent.SSEEvent{
+		Type: "error",
+		Data: agent.ErrorEvent{Message: runOrphanedMessage, Code: "run_orphaned"},
+	})
+	p.runStore.FinishRun(run.RunID, RunStatusFailed, runOrphanedMessage)
+	if run.SessionID != "" {
+		p.sessionStore.ClearActiveRunID(run.SessionID, run.UserID, run.OrgID)
+	}
+}
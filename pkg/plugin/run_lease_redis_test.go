@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestRedisRunLeaseStoreCancelReachesOtherReplica(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	owner := NewRedisRunLeaseStore(context.Background(), client, log.DefaultLogger)
+	defer owner.Close()
+	other := NewRedisRunLeaseStore(context.Background(), client, log.DefaultLogger)
+	defer other.Close()
+
+	if err := owner.Acquire(context.Background(), "run-lease-cancel"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer owner.Release(context.Background(), "run-lease-cancel")
+
+	cancelled := make(chan string, 1)
+	subCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go owner.SubscribeCancels(subCtx, func(runID string) { cancelled <- runID })
+
+	// Subscriptions are asynchronous; republish until the owner hears it.
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := other.PublishCancel(context.Background(), "run-lease-cancel"); err != nil {
+			t.Fatalf("PublishCancel failed: %v", err)
+		}
+		select {
+		case runID := <-cancelled:
+			if runID != "run-lease-cancel" {
+				t.Fatalf("cancelled %q", runID)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("cancel never reached the owning replica")
+		}
+	}
+}
+
+func TestRedisRunLeaseStoreClaimsExpiredLeaseOnce(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	owner := NewRedisRunLeaseStore(context.Background(), client, log.DefaultLogger)
+	defer owner.Close()
+	if err := owner.Acquire(context.Background(), "run-lease-orphan"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if alive, err := owner.Alive(context.Background(), "run-lease-orphan"); err != nil || !alive {
+		t.Fatalf("Alive = %v, %v; want true", alive, err)
+	}
+
+	// Simulate the owner dying: its lease expires without a release.
+	client.Del(context.Background(), runLeaseKey("run-lease-orphan"))
+
+	first := NewRedisRunLeaseStore(context.Background(), client, log.DefaultLogger)
+	defer first.Close()
+	second := NewRedisRunLeaseStore(context.Background(), client, log.DefaultLogger)
+	defer second.Close()
+
+	claimed, err := first.ClaimOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("ClaimOrphans failed: %v", err)
+	}
+	found := false
+	for _, runID := range claimed {
+		found = found || runID == "run-lease-orphan"
+	}
+	if !found {
+		t.Fatalf("claimed = %v, want run-lease-orphan", claimed)
+	}
+	again, err := second.ClaimOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("second ClaimOrphans failed: %v", err)
+	}
+	for _, runID := range again {
+		if runID == "run-lease-orphan" {
+			t.Fatal("orphan claimed twice")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remoteRunLeases simulates runs owned by other replicas: no lease is alive
+// and cancels are recorded instead of delivered.
+type remoteRunLeases struct {
+	InMemoryRunLeaseStore
+	mu        sync.Mutex
+	published []string
+	orphans   []string
+}
+
+func (s *remoteRunLeases) PublishCancel(_ context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published = append(s.published, runID)
+	return nil
+}
+
+func (s *remoteRunLeases) Alive(context.Context, string) (bool, error) { return false, nil }
+
+func (s *remoteRunLeases) ClaimOrphans(context.Context) ([]string, error) { return s.orphans, nil }
+
+func newRunLeaseRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Grafana-Org-Id", "2")
+	req.Header.Set("X-Grafana-User-Id", "7")
+	req.Header.Set("X-Grafana-User-Role", "Editor")
+	return req
+}
+
+func TestHandleCancelRunPublishesForRemoteRun(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	leases := &remoteRunLeases{}
+	p.runLeases = leases
+	p.runStore.CreateRun("run-1", 7, 2)
+
+	rec := httptest.NewRecorder()
+	p.handleCancelRun(rec, newRunLeaseRequest(http.MethodPost, "/api/agent/runs/run-1/cancel", ""), "run-1")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(leases.published) != 1 || leases.published[0] != "run-1" {
+		t.Fatalf("published = %v, want [run-1]", leases.published)
+	}
+}
+
+func TestHandleCancelRunCancelsLocalRunWithoutPublishing(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	leases := &remoteRunLeases{}
+	p.runLeases = leases
+	p.runStore.CreateRun("run-1", 7, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.runCancels["run-1"] = cancel
+
+	rec := httptest.NewRecorder()
+	p.handleCancelRun(rec, newRunLeaseRequest(http.MethodPost, "/api/agent/runs/run-1/cancel", ""), "run-1")
+
+	if rec.Code != http.StatusOK || ctx.Err() == nil {
+		t.Fatalf("status = %d, ctx err = %v; want local cancel", rec.Code, ctx.Err())
+	}
+	if len(leases.published) != 0 {
+		t.Fatalf("published = %v, want none", leases.published)
+	}
+}
+
+func TestOrphanedRunIsFailedAndRejectsApprovals(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.runLeases = &remoteRunLeases{}
+	session, err := p.sessionStore.CreateSession(7, 2, "incident", []SessionMessage{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	run := p.runStore.CreateRun("run-1", 7, 2, session.ID)
+	run.UpdatedAt = time.Now().Add(-2 * RunLeaseTTL)
+
+	rec := httptest.NewRecorder()
+	p.handleAgentApproval(rec, newRunLeaseRequest(http.MethodPost, "/api/agent/runs/run-1/approvals/tc_1", `{"decision":"approved"}`), "run-1", "tc_1")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("approval status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+
+	stored, err := p.runStore.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("GetRun failed: %v", err)
+	}
+	if stored.Status != RunStatusFailed || stored.Error != runOrphanedMessage {
+		t.Fatalf("run = %s %q, want failed with orphan reason", stored.Status, stored.Error)
+	}
+}
+
+func TestCheckRunOwnerLeavesRecentRunsAlone(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.runLeases = &remoteRunLeases{}
+	run := p.runStore.CreateRun("run-1", 7, 2)
+
+	if got := p.checkRunOwner(context.Background(), run); got.Status != RunStatusRunning {
+		t.Fatalf("status = %s, want running within the lease TTL", got.Status)
+	}
+}
+
+func TestFailOrphanedRunsFailsClaimedRuns(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.runLeases = &remoteRunLeases{orphans: []string{"run-1", "run-2", "missing"}}
+	p.runStore.CreateRun("run-1", 7, 2)
+	p.runStore.CreateRun("run-2", 7, 2)
+	p.runStore.FinishRun("run-2", RunStatusCompleted, "")
+
+	p.failOrphanedRuns()
+
+	if run, _ := p.runStore.GetRun("run-1"); run.Status != RunStatusFailed || run.Error != runOrphanedMessage {
+		t.Fatalf("run-1 = %s %q, want failed", run.Status, run.Error)
+	}
+	if run, _ := p.runStore.GetRun("run-2"); run.Status != RunStatusCompleted {
+		t.Fatalf("run-2 = %s, want completed left alone", run.Status)
+	}
+}
@@ -2,8 +2,10 @@ package plugin
 
 import (
 	"consensys-asko11y-app/pkg/agent"
+	"consensys-asko11y-app/pkg/mcp"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -37,20 +39,43 @@ type AgentRun struct {
 type AgentRunTrace struct {
 	Evidence    []agent.EvidenceEvent   `json:"evidence,omitempty"`
 	Approvals   []RunApproval           `json:"approvals,omitempty"`
+	Inputs      []RunInput              `json:"inputs,omitempty"`
 	FinalReport *agent.FinalReportEvent `json:"finalReport,omitempty"`
+	// ToolCatalogVersion is the MCP tool catalog the run was pinned to.
+	ToolCatalogVersion string `json:"toolCatalogVersion,omitempty"`
 }
 
 type RunApproval struct {
-	ApprovalID string     `json:"approvalId"`
-	ToolCallID string     `json:"toolCallId,omitempty"`
-	ToolName   string     `json:"toolName,omitempty"`
-	Risk       string     `json:"risk,omitempty"`
-	Reason     string     `json:"reason,omitempty"`
-	Arguments  string     `json:"arguments,omitempty"`
-	Decision   string     `json:"decision,omitempty"`
-	Comment    string     `json:"comment,omitempty"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	ApprovalID string `json:"approvalId"`
+	ToolCallID string `json:"toolCallId,omitempty"`
+	ToolName   string `json:"toolName,omitempty"`
+	Risk       string `json:"risk,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Arguments  string `json:"arguments,omitempty"`
+	// ServerID and Annotations are the approved tool's, from the run's
+	// pinned catalog.
+	ServerID    string               `json:"serverId,omitempty"`
+	Annotations *mcp.ToolAnnotations `json:"annotations,omitempty"`
+	Decision    string               `json:"decision,omitempty"`
+	Comment     string               `json:"comment,omitempty"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	ResolvedAt  *time.Time           `json:"resolvedAt,omitempty"`
+}
+
+// RunInput is an MCP server's request for user input and, once answered, the
+// answer.
+type RunInput struct {
+	InputID    string                 `json:"inputId"`
+	ToolCallID string                 `json:"toolCallId,omitempty"`
+	ToolName   string                 `json:"toolName,omitempty"`
+	ServerID   string                 `json:"serverId,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Schema     map[string]interface{} `json:"schema,omitempty"`
+	Action     string                 `json:"action,omitempty"`
+	Content    map[string]interface{} `json:"content,omitempty"`
+	Comment    string                 `json:"comment,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	ResolvedAt *time.Time             `json:"resolvedAt,omitempty"`
 }
 
 type RunStoreInterface interface {
@@ -182,7 +207,21 @@ func (s *RunStore) AppendEvent(runID string, event agent.SSEEvent) {
 	}
 	event.Sequence = run.NextSequence
 	run.NextSequence++
-	run.Events = append(run.Events, event)
+	if supersedesContentDeltas(event) {
+		run.Events = dropContentDeltas(run.Events)
+	}
+	if i := supersededProgressIndex(run.Events, event); i >= 0 {
+		run.Events = slices.Delete(run.Events, i, i+1)
+	}
+	if n := len(run.Events); n > 0 {
+		if merged, ok := coalesceContentDelta(run.Events[n-1], event); ok {
+			run.Events[n-1] = merged
+		} else if !isContentDeltaReset(event) {
+			run.Events = append(run.Events, event)
+		}
+	} else if !isContentDeltaReset(event) {
+		run.Events = append(run.Events, event)
+	}
 	if len(run.Events) > RunMaxEventsPerRun {
 		run.Events = run.Events[len(run.Events)-RunMaxEventsPerRun:]
 	}
@@ -293,6 +332,86 @@ func copyRun(run *AgentRun) *AgentRun {
 	return &copied
 }
 
+// supersedesContentDeltas reports whether event makes the stored content_delta
+// events obsolete: the assembled content event replaces their text, and a reset
+// retracts it. Stores drop those deltas from the replay log so a long streamed
+// answer can't push tool events out of the RunMaxEventsPerRun window.
+func supersedesContentDeltas(event agent.SSEEvent) bool {
+	return event.Type == "content" || isContentDeltaReset(event)
+}
+
+// isContentDeltaReset reports whether event is a content_delta reset. Resets are
+// broadcast to live subscribers but not stored: once the deltas they retract
+// are dropped, replay has nothing left to reset.
+func isContentDeltaReset(event agent.SSEEvent) bool {
+	if event.Type != "content_delta" {
+		return false
+	}
+	data, ok := decodeEventData[agent.ContentDeltaEvent](event.Data)
+	return ok && data.Reset
+}
+
+// coalesceContentDelta folds a content_delta into the stored one before it, so
+// a message that is still streaming occupies a single replay-log slot however
+// many flushes it takes. Replay always sends the whole log, so the merged
+// event only needs the latest sequence.
+func coalesceContentDelta(prev, event agent.SSEEvent) (agent.SSEEvent, bool) {
+	if prev.Type != "content_delta" || event.Type != "content_delta" {
+		return agent.SSEEvent{}, false
+	}
+	prevData, ok := decodeEventData[agent.ContentDeltaEvent](prev.Data)
+	if !ok {
+		return agent.SSEEvent{}, false
+	}
+	data, ok := decodeEventData[agent.ContentDeltaEvent](event.Data)
+	if !ok || data.Reset {
+		return agent.SSEEvent{}, false
+	}
+	event.Data = agent.ContentDeltaEvent{Delta: prevData.Delta + data.Delta}
+	return event, true
+}
+
+// progressUpdateCallID returns the tool call a tool_call_progress progress
+// update belongs to. Server log messages share the event type but are kept
+// individually, so they report false.
+func progressUpdateCallID(event agent.SSEEvent) (string, bool) {
+	if event.Type != "tool_call_progress" {
+		return "", false
+	}
+	data, ok := decodeEventData[agent.ToolCallProgressEvent](event.Data)
+	if !ok || data.Level != "" {
+		return "", false
+	}
+	return data.ID, true
+}
+
+// supersededProgressIndex returns the index of the stored progress update that
+// event replaces, or -1. Only the latest update of each tool call is kept, so a
+// long-running call occupies a single replay-log slot however often it
+// reports, like a streaming message does.
+func supersededProgressIndex(events []agent.SSEEvent, event agent.SSEEvent) int {
+	id, ok := progressUpdateCallID(event)
+	if !ok {
+		return -1
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if prevID, ok := progressUpdateCallID(events[i]); ok && prevID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func dropContentDeltas(events []agent.SSEEvent) []agent.SSEEvent {
+	kept := events[:0]
+	for _, e := range events {
+		if e.Type != "content_delta" {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
 func applyTraceEvent(run *AgentRun, event agent.SSEEvent) {
 	if run.Trace == nil {
 		run.Trace = &AgentRunTrace{}
@@ -305,19 +424,41 @@ func applyTraceEvent(run *AgentRun, event agent.SSEEvent) {
 	case "approval_request":
 		if data, ok := decodeEventData[agent.ApprovalRequestEvent](event.Data); ok {
 			upsertApproval(run.Trace, RunApproval{
-				ApprovalID: data.ApprovalID,
-				ToolCallID: data.ToolCallID,
-				ToolName:   data.ToolName,
-				Risk:       data.Risk,
-				Reason:     data.Reason,
-				Arguments:  data.Arguments,
-				CreatedAt:  time.Now().UTC(),
+				ApprovalID:  data.ApprovalID,
+				ToolCallID:  data.ToolCallID,
+				ToolName:    data.ToolName,
+				Risk:        data.Risk,
+				Reason:      data.Reason,
+				Arguments:   data.Arguments,
+				ServerID:    data.ServerID,
+				Annotations: data.Annotations,
+				CreatedAt:   time.Now().UTC(),
 			})
 		}
 	case "approval_resolved":
 		if data, ok := decodeEventData[agent.ApprovalResolvedEvent](event.Data); ok {
 			resolveTraceApproval(run.Trace, data)
 		}
+	case "input_request":
+		if data, ok := decodeEventData[agent.InputRequestEvent](event.Data); ok {
+			upsertInput(run.Trace, RunInput{
+				InputID:    data.InputID,
+				ToolCallID: data.ToolCallID,
+				ToolName:   data.ToolName,
+				ServerID:   data.ServerID,
+				Message:    data.Message,
+				Schema:     data.Schema,
+				CreatedAt:  time.Now().UTC(),
+			})
+		}
+	case "input_resolved":
+		if data, ok := decodeEventData[agent.InputResolvedEvent](event.Data); ok {
+			resolveTraceInput(run.Trace, data)
+		}
+	case "tool_catalog":
+		if data, ok := decodeEventData[agent.ToolCatalogEvent](event.Data); ok {
+			run.Trace.ToolCatalogVersion = data.Version
+		}
 	case "final_report":
 		if data, ok := decodeEventData[agent.FinalReportEvent](event.Data); ok {
 			report := data
@@ -390,6 +531,48 @@ func resolveTraceApproval(trace *AgentRunTrace, resolved agent.ApprovalResolvedE
 	})
 }
 
+func upsertInput(trace *AgentRunTrace, input RunInput) {
+	for i := range trace.Inputs {
+		if trace.Inputs[i].InputID == input.InputID {
+			if trace.Inputs[i].Action != "" {
+				input.Action = trace.Inputs[i].Action
+				input.Content = trace.Inputs[i].Content
+				input.Comment = trace.Inputs[i].Comment
+				input.ResolvedAt = trace.Inputs[i].ResolvedAt
+			}
+			trace.Inputs[i] = input
+			return
+		}
+	}
+	trace.Inputs = append(trace.Inputs, input)
+}
+
+func resolveTraceInput(trace *AgentRunTrace, resolved agent.InputResolvedEvent) {
+	resolvedAt := time.Now().UTC()
+	if resolved.ResolvedAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, resolved.ResolvedAt); err == nil {
+			resolvedAt = parsed
+		}
+	}
+	for i := range trace.Inputs {
+		if trace.Inputs[i].InputID == resolved.InputID {
+			trace.Inputs[i].Action = resolved.Action
+			trace.Inputs[i].Content = resolved.Content
+			trace.Inputs[i].Comment = resolved.Comment
+			trace.Inputs[i].ResolvedAt = &resolvedAt
+			return
+		}
+	}
+	trace.Inputs = append(trace.Inputs, RunInput{
+		InputID:    resolved.InputID,
+		Action:     resolved.Action,
+		Content:    resolved.Content,
+		Comment:    resolved.Comment,
+		CreatedAt:  resolvedAt,
+		ResolvedAt: &resolvedAt,
+	})
+}
+
 func copyTrace(trace *AgentRunTrace) *AgentRunTrace {
 	if trace == nil {
 		return nil
@@ -397,6 +580,7 @@ func copyTrace(trace *AgentRunTrace) *AgentRunTrace {
 	copied := *trace
 	copied.Evidence = append([]agent.EvidenceEvent(nil), trace.Evidence...)
 	copied.Approvals = append([]RunApproval(nil), trace.Approvals...)
+	copied.Inputs = append([]RunInput(nil), trace.Inputs...)
 	if trace.FinalReport != nil {
 		report := *trace.FinalReport
 		report.EvidenceIDs = append([]string(nil), trace.FinalReport.EvidenceIDs...)
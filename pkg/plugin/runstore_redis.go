@@ -94,20 +94,27 @@ func (s *RedisRunStore) AppendEvent(runID string, event agent.SSEEvent) {
 		return
 	}
 
-	ek := eventsKey(runID)
-	pushCtx, pushCancel := redisContext(s.ctx, RedisOpTimeout)
-	defer pushCancel()
-
-	listLen, err := s.client.RPush(pushCtx, ek, eventJSON).Result()
-	if err != nil {
-		s.logger.Error("Failed to append event to Redis", "error", err, "runId", runID)
-		return
+	if supersedesContentDeltas(event) {
+		s.dropContentDeltas(runID)
 	}
+	s.dropSupersededProgress(runID, event)
+
+	if !isContentDeltaReset(event) && !s.coalesceContentDelta(runID, event) {
+		ek := eventsKey(runID)
+		pushCtx, pushCancel := redisContext(s.ctx, RedisOpTimeout)
+		defer pushCancel()
+
+		listLen, err := s.client.RPush(pushCtx, ek, eventJSON).Result()
+		if err != nil {
+			s.logger.Error("Failed to append event to Redis", "error", err, "runId", runID)
+			return
+		}
 
-	if listLen > int64(RunMaxEventsPerRun) {
-		ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
-		defer cancel()
-		s.client.LTrim(ctx, ek, -int64(RunMaxEventsPerRun), -1)
+		if listLen > int64(RunMaxEventsPerRun) {
+			ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+			defer cancel()
+			s.client.LTrim(ctx, ek, -int64(RunMaxEventsPerRun), -1)
+		}
 	}
 
 	s.appendTraceEvent(runID, event)
@@ -386,6 +393,110 @@ func (s *RedisRunStore) CleanupOld() {
 	}
 }
 
+// coalesceContentDelta merges event into the content_delta at the tail of the
+// run's event list, reporting whether it did. Each run has a single writer, so
+// the read-then-set needs no transaction.
+func (s *RedisRunStore) coalesceContentDelta(runID string, event agent.SSEEvent) bool {
+	if event.Type != "content_delta" {
+		return false
+	}
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	ek := eventsKey(runID)
+	raw, err := s.client.LIndex(ctx, ek, -1).Result()
+	if err != nil {
+		return false
+	}
+	var prev agent.SSEEvent
+	if err := json.Unmarshal([]byte(raw), &prev); err != nil {
+		return false
+	}
+	merged, ok := coalesceContentDelta(prev, event)
+	if !ok {
+		return false
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return false
+	}
+	if err := s.client.LSet(ctx, ek, -1, mergedJSON).Err(); err != nil {
+		s.logger.Warn("Failed to coalesce content delta", "error", err, "runId", runID)
+		return false
+	}
+	return true
+}
+
+// dropSupersededProgress removes the stored progress update that event
+// replaces. Events for a run are appended by a single consumer goroutine, so
+// the stored entry can't change between the read and the removal.
+func (s *RedisRunStore) dropSupersededProgress(runID string, event agent.SSEEvent) {
+	if _, ok := progressUpdateCallID(event); !ok {
+		return
+	}
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	ek := eventsKey(runID)
+	stored, err := s.client.LRange(ctx, ek, 0, -1).Result()
+	if err != nil {
+		s.logger.Warn("Failed to load events for progress compaction", "error", err, "runId", runID)
+		return
+	}
+	events := make([]agent.SSEEvent, len(stored))
+	for i, raw := range stored {
+		// Entries that don't decode are left as zero events, which match nothing.
+		_ = json.Unmarshal([]byte(raw), &events[i])
+	}
+	i := supersededProgressIndex(events, event)
+	if i < 0 {
+		return
+	}
+	if err := s.client.LRem(ctx, ek, -1, stored[i]).Err(); err != nil {
+		s.logger.Warn("Failed to drop superseded progress", "error", err, "runId", runID)
+	}
+}
+
+// dropContentDeltas rewrites the run's event list without its content_delta
+// entries. Events for a run are appended by a single consumer goroutine, so the
+// read-then-replace cannot race with another append for the same run.
+func (s *RedisRunStore) dropContentDeltas(runID string) {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	ek := eventsKey(runID)
+	stored, err := s.client.LRange(ctx, ek, 0, -1).Result()
+	if err != nil {
+		s.logger.Warn("Failed to load events for delta compaction", "error", err, "runId", runID)
+		return
+	}
+
+	kept := make([]interface{}, 0, len(stored))
+	for _, raw := range stored {
+		var e struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(raw), &e); err == nil && e.Type == "content_delta" {
+			continue
+		}
+		kept = append(kept, raw)
+	}
+	if len(kept) == len(stored) {
+		return
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, ek)
+		if len(kept) > 0 {
+			pipe.RPush(ctx, ek, kept...)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to compact content deltas", "error", err, "runId", runID)
+	}
+}
+
 func (s *RedisRunStore) touchRun(runID string) {
 	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
 	defer cancel()
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestRedisRunStore_ContentSupersedesDeltas(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	store := NewRedisRunStore(context.Background(), client, log.DefaultLogger)
+	store.CreateRun("run-redis-deltas", 100, 1)
+
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_1"}})
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "draft"}})
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Reset: true}})
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "Hel"}})
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "lo"}})
+
+	run, err := store.GetRun("run-redis-deltas")
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if len(run.Events) != 2 || run.Events[1].Sequence != 4 {
+		t.Fatalf("expected pending deltas coalesced into one event, got %+v", run.Events)
+	}
+	if d, ok := decodeEventData[agent.ContentDeltaEvent](run.Events[1].Data); !ok || d.Delta != "Hello" {
+		t.Fatalf("expected coalesced delta %q, got %+v", "Hello", run.Events[1].Data)
+	}
+
+	store.AppendEvent("run-redis-deltas", agent.SSEEvent{Type: "content", Data: agent.ContentEvent{Content: "Hello"}})
+
+	run, err = store.GetRun("run-redis-deltas")
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	var got []string
+	for _, e := range run.Events {
+		got = append(got, fmt.Sprintf("%s#%d", e.Type, e.Sequence))
+	}
+	if fmt.Sprint(got) != "[tool_call_start#0 content#5]" {
+		t.Fatalf("expected only tool_call_start and content to remain, got %v", got)
+	}
+}
+
+func TestRedisRunStore_KeepsLatestProgressPerToolCall(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	store := NewRedisRunStore(context.Background(), client, log.DefaultLogger)
+	store.CreateRun("run-redis-progress", 100, 1)
+
+	store.AppendEvent("run-redis-progress", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_slow"}})
+	store.AppendEvent("run-redis-progress", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_slow", Message: "connected", Level: "info"}})
+	for i := 1; i <= RunMaxEventsPerRun+10; i++ {
+		store.AppendEvent("run-redis-progress", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_slow", Progress: float64(i)}})
+	}
+	store.AppendEvent("run-redis-progress", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_other", Progress: 1}})
+
+	run, err := store.GetRun("run-redis-progress")
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	var got []string
+	for _, e := range run.Events {
+		label := e.Type
+		if p, ok := decodeEventData[agent.ToolCallProgressEvent](e.Data); ok && e.Type == "tool_call_progress" {
+			label = fmt.Sprintf("%s:%s:%v%s", e.Type, p.ID, p.Progress, p.Level)
+		}
+		got = append(got, label)
+	}
+	want := fmt.Sprintf("[tool_call_start tool_call_progress:tc_slow:0info tool_call_progress:tc_slow:%d tool_call_progress:tc_other:1]", RunMaxEventsPerRun+10)
+	if fmt.Sprint(got) != want {
+		t.Fatalf("replay log = %v, want %s", got, want)
+	}
+}
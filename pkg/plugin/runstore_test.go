@@ -75,6 +75,10 @@ func TestRunStore_AppendEventBuildsTrace(t *testing.T) {
 		Title:   "Prometheus result",
 		Summary: "up is 1",
 	}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_catalog", Data: agent.ToolCatalogEvent{
+		Version:   "abc123",
+		ToolCount: 4,
+	}})
 
 	run, err := store.GetRun("run-1")
 	if err != nil {
@@ -89,6 +93,9 @@ func TestRunStore_AppendEventBuildsTrace(t *testing.T) {
 	if len(run.Trace.Evidence) != 1 || run.Trace.Evidence[0].Summary != "up is 1" {
 		t.Fatalf("unexpected evidence: %+v", run.Trace.Evidence)
 	}
+	if run.Trace.ToolCatalogVersion != "abc123" {
+		t.Fatalf("unexpected tool catalog version: %q", run.Trace.ToolCatalogVersion)
+	}
 }
 
 func TestRunStore_AppendEvent_TrimsOldest(t *testing.T) {
@@ -120,6 +127,72 @@ func TestRunStore_AppendEvent_TrimsOldest(t *testing.T) {
 	}
 }
 
+func TestRunStore_AppendEvent_StreamingDeltasDoNotEvictEvents(t *testing.T) {
+	store := NewRunStore(log.DefaultLogger)
+	store.CreateRun("run-1", 100, 1)
+
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "run_started", Data: agent.RunStartedEvent{RunID: "run-1"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_1"}})
+	for i := 0; i < RunMaxEventsPerRun+100; i++ {
+		store.AppendEvent("run-1", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "x"}})
+	}
+
+	run, _ := store.GetRun("run-1")
+	if len(run.Events) != 3 || run.Events[0].Type != "run_started" || run.Events[1].Type != "tool_call_start" {
+		t.Fatalf("expected earlier events to survive a long stream, got %d events", len(run.Events))
+	}
+	if d, _ := run.Events[2].Data.(agent.ContentDeltaEvent); len(d.Delta) != RunMaxEventsPerRun+100 {
+		t.Fatalf("expected every delta folded into one event, got %d bytes", len(d.Delta))
+	}
+}
+
+func TestRunStore_AppendEvent_ContentSupersedesDeltas(t *testing.T) {
+	store := NewRunStore(log.DefaultLogger)
+	store.CreateRun("run-1", 100, 1)
+	_, ch, unsub, err := store.SubscribeAndSnapshot("run-1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer unsub()
+
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "draft"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Reset: true}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_1"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "Hel"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "content_delta", Data: agent.ContentDeltaEvent{Delta: "lo"}})
+
+	run, _ := store.GetRun("run-1")
+	if len(run.Events) != 2 {
+		t.Fatalf("expected tool_call_start plus 1 coalesced delta stored, got %d: %+v", len(run.Events), run.Events)
+	}
+	if d, _ := run.Events[1].Data.(agent.ContentDeltaEvent); d.Delta != "Hello" || run.Events[1].Sequence != 4 {
+		t.Fatalf("expected pending deltas coalesced into %q at sequence 4, got %+v", "Hello", run.Events[1])
+	}
+
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "content", Data: agent.ContentEvent{Content: "Hello"}})
+
+	run, _ = store.GetRun("run-1")
+	var types []string
+	for _, e := range run.Events {
+		types = append(types, e.Type)
+	}
+	if fmt.Sprint(types) != "[tool_call_start content]" {
+		t.Fatalf("expected deltas dropped once content arrived, got %v", types)
+	}
+	if run.Events[1].Sequence != 5 {
+		t.Errorf("expected content sequence 5 (deltas keep their numbers), got %d", run.Events[1].Sequence)
+	}
+
+	// Live subscribers still see every event, including the unstored reset.
+	var live []int64
+	for i := 0; i < 6; i++ {
+		live = append(live, (<-ch).Sequence)
+	}
+	if fmt.Sprint(live) != "[0 1 2 3 4 5]" {
+		t.Errorf("expected all six events broadcast in sequence, got %v", live)
+	}
+}
+
 func TestRunStore_AppendEvent_NonExistent(t *testing.T) {
 	store := NewRunStore(log.DefaultLogger)
 	store.AppendEvent("nonexistent", agent.SSEEvent{Type: "content", Data: agent.ContentEvent{Content: "x"}})
@@ -337,3 +410,38 @@ func TestRunBroadcaster_ConcurrentAccess(t *testing.T) {
 	b.Close()
 	wg.Wait()
 }
+
+func TestRunStore_AppendEvent_KeepsLatestProgressPerToolCall(t *testing.T) {
+	store := NewRunStore(log.DefaultLogger)
+	store.CreateRun("run-1", 100, 1)
+
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "run_started", Data: agent.RunStartedEvent{RunID: "run-1"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_slow"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_start", Data: agent.ToolCallStartEvent{ID: "tc_other"}})
+	store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_slow", Message: "connected", Level: "info"}})
+	// A long-running call reports far more often than the log holds, with
+	// another call's progress interleaved.
+	for i := 1; i <= RunMaxEventsPerRun+100; i++ {
+		store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_slow", Progress: float64(i)}})
+		if i%100 == 0 {
+			store.AppendEvent("run-1", agent.SSEEvent{Type: "tool_call_progress", Data: agent.ToolCallProgressEvent{ID: "tc_other", Progress: float64(i)}})
+		}
+	}
+
+	run, _ := store.GetRun("run-1")
+	var got []string
+	for _, e := range run.Events {
+		label := e.Type
+		if p, ok := e.Data.(agent.ToolCallProgressEvent); ok {
+			label = fmt.Sprintf("%s:%s:%v%s", e.Type, p.ID, p.Progress, p.Level)
+		}
+		got = append(got, label)
+	}
+	want := fmt.Sprintf("[run_started tool_call_start tool_call_start tool_call_progress:tc_slow:0info tool_call_progress:tc_slow:%d tool_call_progress:tc_other:%d]", RunMaxEventsPerRun+100, RunMaxEventsPerRun+100)
+	if fmt.Sprint(got) != want {
+		t.Fatalf("replay log = %v, want %s", got, want)
+	}
+	if last := run.Events[len(run.Events)-1]; last.Sequence != run.NextSequence-1 {
+		t.Errorf("expected the latest event at sequence %d, got %d", run.NextSequence-1, last.Sequence)
+	}
+}
@@ -166,6 +166,7 @@ func (s *Scout) Scavenge() {
 		OrgID:              fmt.Sprintf("%d", orgID),
 		OrgName:            fmt.Sprintf("Org%d", orgID),
 		ExcludeToolNames:   graphitiWriteToolNames,
+		ToolPolicies:       s.settings.ToolPolicies,
 		ConversationType:   "discovery",
 		ApprovalPolicy:     "off",
 	}
@@ -183,6 +184,7 @@ func (s *Scout) Scavenge() {
 		return
 	}
 	if err := ingestGraphitiMemory(
+		s.ctx,
 		s.mcpProxy,
 		orgID,
 		"scout_synthesis",
@@ -108,3 +108,48 @@ func TestApplySecureHeaders_DottedHeaderName(t *testing.T) {
 		t.Errorf("Expected X-Custom.Dotted.Header, got %v", servers[0].Headers)
 	}
 }
+
+func TestApplySecureEnv_InjectsMatchingServer(t *testing.T) {
+	servers := []mcp.ServerConfig{
+		{ID: "local", Name: "Local", Type: "stdio", Env: map[string]string{"REGION": "eu"}},
+		{ID: "remote", Name: "Remote"},
+	}
+	secure := map[string]string{
+		"mcpServerEnv.local.API_TOKEN":      "tok",
+		"mcpServerHeader.local.X-API-Key":   "key",
+		"mcpServerEnv.unknown.API_TOKEN":    "other",
+	}
+
+	applySecureEnv(servers, secure)
+
+	if servers[0].Env["API_TOKEN"] != "tok" || servers[0].Env["REGION"] != "eu" {
+		t.Errorf("Expected secret and plain env on local, got %v", servers[0].Env)
+	}
+	if _, ok := servers[0].Env["X-API-Key"]; ok {
+		t.Errorf("Header secrets must not become env vars: %v", servers[0].Env)
+	}
+	if servers[1].Env != nil {
+		t.Errorf("Expected no env on remote, got %v", servers[1].Env)
+	}
+}
+
+func TestApplySecureAuth_SetsClientSecret(t *testing.T) {
+	servers := []mcp.ServerConfig{
+		{ID: "oauth", Name: "OAuth", Auth: &mcp.ServerAuth{Type: mcp.AuthTypeOAuth2ClientCredentials, ClientID: "asko11y"}},
+		{ID: "plain", Name: "Plain"},
+	}
+	secure := map[string]string{
+		"mcpServerAuth.oauth.clientSecret": "s3cret",
+		"mcpServerAuth.plain.clientSecret": "ignored",
+		"mcpServerAuth.oauth.other":        "ignored",
+	}
+
+	applySecureAuth(servers, secure)
+
+	if servers[0].Auth.ClientSecret != "s3cret" || servers[0].Auth.ClientID != "asko11y" {
+		t.Errorf("Expected client secret on oauth server, got %+v", servers[0].Auth)
+	}
+	if servers[1].Auth != nil {
+		t.Errorf("Servers without auth must stay without auth, got %+v", servers[1].Auth)
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"time"
+)
+
+// SessionSummaryTimeout bounds the background LLM call that folds dropped turns
+// into a session's rolling summary.
+const SessionSummaryTimeout = 60 * time.Second
+
+// summarizeSession keeps ChatSession.Summary covering every message that has
+// scrolled out of the agent's recent-message window, as the next run will
+// build it. Only turns not yet folded in (SummarizedCount..cutoff) are sent,
+// together with the existing summary, so each call costs roughly one window's
+// worth of tokens however long the session grows. Token usage is added to the
+// session stats without counting as a run, and charged to the user's token
+// budget.
+func (p *Plugin) summarizeSession(sessionID string, userID int64, userLogin string, orgID int64, grafanaURL, authToken, llmOrgID string) {
+	if sessionID == "" {
+		return
+	}
+	// A second run finishing on the same session would fold in the same turns.
+	if _, busy := p.summarizing.LoadOrStore(sessionID, struct{}{}); busy {
+		return
+	}
+	defer p.summarizing.Delete(sessionID)
+
+	session, err := p.sessionStore.GetSession(sessionID, userID, orgID)
+	if err != nil {
+		return
+	}
+
+	// The next run appends its user prompt before BuildContextWindow keeps the
+	// last RecentMessageCount messages, so the window will start one message
+	// later than it would today. Summarize up to that boundary, or the message
+	// just before it falls out of both the window and the summary.
+	cutoff := len(session.Messages) + 1 - p.settings.RecentMessageCount
+	if cutoff <= 0 {
+		return
+	}
+	start, previous := session.SummarizedCount, session.Summary
+	if start > cutoff {
+		// History was rewritten underneath the summary; rebuild it from scratch.
+		start, previous = 0, ""
+	}
+	if start == cutoff {
+		return
+	}
+
+	dropped := make([]agent.Message, 0, cutoff-start)
+	for _, msg := range session.Messages[start:cutoff] {
+		dropped = append(dropped, agent.Message{Role: msg.Role, Content: msg.Content})
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, SessionSummaryTimeout)
+	defer cancel()
+	summary, usage, err := p.agentLoop.SummarizeConversation(ctx, previous, dropped, grafanaURL, authToken, llmOrgID)
+	if usage != nil {
+		delta := SessionStatsDelta{
+			PromptTokens:     int64(usage.PromptTokens),
+			CompletionTokens: int64(usage.CompletionTokens),
+			TotalTokens:      int64(usage.TotalTokens),
+		}
+		if statsErr := p.sessionStore.IncrementStats(sessionID, userID, orgID, delta); statsErr != nil {
+			p.logger.Warn("Failed to record summary token usage", "error", statsErr, "sessionId", sessionID)
+		}
+		p.recordTokenUsage(orgID, userLogin, delta.TotalTokens)
+	}
+	if err != nil {
+		p.logger.Warn("Failed to summarize session", "error", err, "sessionId", sessionID)
+		return
+	}
+
+	if err := p.sessionStore.UpdateSession(sessionID, userID, orgID, SessionUpdate{
+		Summary:         &summary,
+		SummarizedCount: &cutoff,
+	}); err != nil {
+		p.logger.Warn("Failed to persist session summary", "error", err, "sessionId", sessionID)
+		return
+	}
+
+	p.logger.Debug("Session summary updated", "sessionId", sessionID, "summarizedCount", cutoff, "newMessages", len(dropped))
+}
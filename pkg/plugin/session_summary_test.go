@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newSummaryLLMServer(t *testing.T, summaries ...string) (*httptest.Server, <-chan agent.ChatCompletionRequest) {
+	t.Helper()
+
+	received := make(chan agent.ChatCompletionRequest, len(summaries))
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req agent.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode LLM request: %v", err)
+		}
+		received <- req
+
+		content, _ := json.Marshal(summaries[calls])
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"sum\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%s},\"finish_reason\":null}]}\n\n", content)
+		fmt.Fprint(w, "data: {\"id\":\"sum\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"sum\",\"choices\":[],\"usage\":{\"prompt_tokens\":50,\"completion_tokens\":10,\"total_tokens\":60}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+
+	return server, received
+}
+
+func numberedSessionMessages(from, to int) []SessionMessage {
+	var msgs []SessionMessage
+	for i := from; i < to; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msgs = append(msgs, SessionMessage{Role: role, Content: fmt.Sprintf("turn-%d", i)})
+	}
+	return msgs
+}
+
+func TestSummarizeSession_SkipsWithinRecentWindow(t *testing.T) {
+	llmServer, received := newSummaryLLMServer(t)
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	// Nine messages plus the next run's prompt still fit the 10-message window.
+	session, err := p.sessionStore.CreateSession(7, 2, "", numberedSessionMessages(0, 9))
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	p.summarizeSession(session.ID, 7, "alice", 2, llmServer.URL, "test-token", "2")
+
+	if len(received) != 0 {
+		t.Fatal("expected no summarization while the session fits the recent window")
+	}
+}
+
+func TestSummarizeSession_FoldsDroppedTurnsIncrementally(t *testing.T) {
+	llmServer, received := newSummaryLLMServer(t, "summary v1", "summary v2")
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	session, err := p.sessionStore.CreateSession(7, 2, "", numberedSessionMessages(0, 14))
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	p.summarizeSession(session.ID, 7, "alice", 2, llmServer.URL, "test-token", "2")
+
+	first := receiveAgentRunLLMRequest(t, received)
+	if first.Model != "base" {
+		t.Fatalf("expected summary on base model, got %q", first.Model)
+	}
+	input := first.Messages[len(first.Messages)-1].Content
+	if !strings.Contains(input, "turn-4") || strings.Contains(input, "turn-5") {
+		t.Fatalf("expected only turns 0-4 to be summarized, got %q", input)
+	}
+
+	got, err := p.sessionStore.GetSession(session.ID, 7, 2)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if got.Summary != "summary v1" || got.SummarizedCount != 5 {
+		t.Fatalf("unexpected summary state: %q (count %d)", got.Summary, got.SummarizedCount)
+	}
+	if got.RunCount != 0 || got.PromptTokens != 50 || got.CompletionTokens != 10 || got.TotalTokens != 60 {
+		t.Fatalf("expected summary tokens in stats without a run, got %+v", got)
+	}
+
+	if err := p.sessionStore.AppendMessages(session.ID, 7, 2, numberedSessionMessages(14, 16)); err != nil {
+		t.Fatalf("AppendMessages failed: %v", err)
+	}
+	p.summarizeSession(session.ID, 7, "alice", 2, llmServer.URL, "test-token", "2")
+
+	second := receiveAgentRunLLMRequest(t, received)
+	input = second.Messages[len(second.Messages)-1].Content
+	if !strings.Contains(input, "summary v1") {
+		t.Fatalf("expected previous summary to be folded in, got %q", input)
+	}
+	if strings.Contains(input, "turn-4") || !strings.Contains(input, "turn-5") || !strings.Contains(input, "turn-6") || strings.Contains(input, "turn-7") {
+		t.Fatalf("expected only newly dropped turns 5-6, got %q", input)
+	}
+
+	got, err = p.sessionStore.GetSession(session.ID, 7, 2)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if got.Summary != "summary v2" || got.SummarizedCount != 7 || got.TotalTokens != 120 {
+		t.Fatalf("unexpected summary state after second pass: %q (count %d, tokens %d)", got.Summary, got.SummarizedCount, got.TotalTokens)
+	}
+}
+
+func TestSummarizeSession_CoversEveryMessageOutsideNextWindow(t *testing.T) {
+	for size := 10; size < 14; size++ {
+		llmServer, _ := newSummaryLLMServer(t, "summary")
+		p := newAgentRunTestPlugin(t)
+		session, err := p.sessionStore.CreateSession(7, 2, "", numberedSessionMessages(0, size))
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+
+		p.summarizeSession(session.ID, 7, "alice", 2, llmServer.URL, "test-token", "2")
+		llmServer.Close()
+
+		got, err := p.sessionStore.GetSession(session.ID, 7, 2)
+		if err != nil {
+			t.Fatalf("GetSession failed: %v", err)
+		}
+
+		// Build the next run's context the way startAgentRun and the loop do.
+		next := make([]agent.Message, 0, size+1)
+		for _, msg := range got.Messages {
+			next = append(next, agent.Message{Role: msg.Role, Content: msg.Content})
+		}
+		next = append(next, agent.Message{Role: "user", Content: "next question"})
+		window := agent.BuildContextWindow("sys", next, got.Summary, p.settings.RecentMessageCount)
+
+		inWindow := map[string]bool{}
+		for _, msg := range window {
+			inWindow[msg.Content] = true
+		}
+		for i, msg := range got.Messages {
+			if i >= got.SummarizedCount && !inWindow[msg.Content] {
+				t.Fatalf("size %d: %s is neither summarized (count %d) nor in the next window", size, msg.Content, got.SummarizedCount)
+			}
+		}
+	}
+}
+
+func TestHandleAgentRunPassesSessionSummary(t *testing.T) {
+	llmServer, received := newAgentRunLLMServer(t)
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	session, err := p.sessionStore.CreateSession(7, 2, "existing", numberedSessionMessages(0, 12))
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	summary := "earlier: user asked about checkout latency"
+	if err := p.sessionStore.UpdateSession(session.ID, 7, 2, SessionUpdate{Summary: &summary}); err != nil {
+		t.Fatalf("UpdateSession failed: %v", err)
+	}
+
+	req := newAgentRunRequest(t, llmServer.URL, "/api/agent/run?model=base", fmt.Sprintf(`{"message":"follow up","sessionId":%q}`, session.ID))
+	rec := httptest.NewRecorder()
+
+	p.handleAgentRun(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	got := receiveAgentRunLLMRequest(t, received)
+	found := false
+	for _, m := range got.Messages {
+		if m.Role == "system" && strings.Contains(m.Content, summary) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected session summary in the context window, got %+v", got.Messages)
+	}
+}
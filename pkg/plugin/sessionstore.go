@@ -19,17 +19,20 @@ type SessionMessage struct {
 }
 
 type ChatSession struct {
-	ID           string           `json:"id"`
-	Title        string           `json:"title"`
-	Messages     []SessionMessage `json:"messages"`
-	Summary      string           `json:"summary,omitempty"`
-	CreatedAt    time.Time        `json:"createdAt"`
-	UpdatedAt    time.Time        `json:"updatedAt"`
-	MessageCount int              `json:"messageCount"`
-	ActiveRunID  string           `json:"activeRunId,omitempty"`
-	Model        string           `json:"model,omitempty"`
-	UserID       int64            `json:"-"`
-	OrgID        int64            `json:"-"`
+	ID       string           `json:"id"`
+	Title    string           `json:"title"`
+	Messages []SessionMessage `json:"messages"`
+	Summary  string           `json:"summary,omitempty"`
+	// SummarizedCount is how many leading Messages are already folded into
+	// Summary, so the summarizer only has to process newly dropped turns.
+	SummarizedCount int       `json:"summarizedCount,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	MessageCount    int       `json:"messageCount"`
+	ActiveRunID     string    `json:"activeRunId,omitempty"`
+	Model           string    `json:"model,omitempty"`
+	UserID          int64     `json:"-"`
+	OrgID           int64     `json:"-"`
 
 	// Usage stats, accumulated from each completed agent run's DoneEvent.
 	// Runs are TTL'd out of Redis after RunMaxAge, so these must be
@@ -69,6 +72,9 @@ type SessionUpdate struct {
 	Title    *string          `json:"title,omitempty"`
 	Summary  *string          `json:"summary,omitempty"`
 	Model    *string          `json:"model,omitempty"`
+	// SummarizedCount is only set by the server-side summarizer, never
+	// decoded from a client update.
+	SummarizedCount *int `json:"-"`
 }
 
 type SessionStoreInterface interface {
@@ -259,6 +265,9 @@ func (s *SessionStore) UpdateSession(sessionID string, userID, orgID int64, upda
 	if update.Summary != nil {
 		session.Summary = *update.Summary
 	}
+	if update.SummarizedCount != nil {
+		session.SummarizedCount = *update.SummarizedCount
+	}
 	if update.Model != nil {
 		session.Model = *update.Model
 	}
@@ -29,23 +29,25 @@ func sessionStatsKey(id string) string { return fmt.Sprintf("session:%s:stats",
 // Usage-stats fields live in a separate hash (sessionStatsKey) so they never
 // round-trip through this struct's read-modify-write cycle — see IncrementStats.
 type redisSession struct {
-	ID           string           `json:"id"`
-	Title        string           `json:"title"`
-	Messages     []SessionMessage `json:"messages"`
-	Summary      string           `json:"summary,omitempty"`
-	CreatedAt    time.Time        `json:"createdAt"`
-	UpdatedAt    time.Time        `json:"updatedAt"`
-	MessageCount int              `json:"messageCount"`
-	ActiveRunID  string           `json:"activeRunId,omitempty"`
-	Model        string           `json:"model,omitempty"`
-	UserID       int64            `json:"userId"`
-	OrgID        int64            `json:"orgId"`
+	ID              string           `json:"id"`
+	Title           string           `json:"title"`
+	Messages        []SessionMessage `json:"messages"`
+	Summary         string           `json:"summary,omitempty"`
+	SummarizedCount int              `json:"summarizedCount,omitempty"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+	MessageCount    int              `json:"messageCount"`
+	ActiveRunID     string           `json:"activeRunId,omitempty"`
+	Model           string           `json:"model,omitempty"`
+	UserID          int64            `json:"userId"`
+	OrgID           int64            `json:"orgId"`
 }
 
 func toRedis(s *ChatSession) *redisSession {
 	return &redisSession{
 		ID: s.ID, Title: s.Title, Messages: s.Messages,
-		Summary: s.Summary, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt,
+		Summary: s.Summary, SummarizedCount: s.SummarizedCount,
+		CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt,
 		MessageCount: s.MessageCount, ActiveRunID: s.ActiveRunID, Model: s.Model,
 		UserID: s.UserID, OrgID: s.OrgID,
 	}
@@ -54,7 +56,8 @@ func toRedis(s *ChatSession) *redisSession {
 func fromRedis(rs *redisSession) *ChatSession {
 	return &ChatSession{
 		ID: rs.ID, Title: rs.Title, Messages: rs.Messages,
-		Summary: rs.Summary, CreatedAt: rs.CreatedAt, UpdatedAt: rs.UpdatedAt,
+		Summary: rs.Summary, SummarizedCount: rs.SummarizedCount,
+		CreatedAt: rs.CreatedAt, UpdatedAt: rs.UpdatedAt,
 		MessageCount: rs.MessageCount, ActiveRunID: rs.ActiveRunID, Model: rs.Model,
 		UserID: rs.UserID, OrgID: rs.OrgID,
 	}
@@ -280,6 +283,9 @@ func (s *RedisSessionStore) UpdateSession(sessionID string, userID, orgID int64,
 	if update.Summary != nil {
 		session.Summary = *update.Summary
 	}
+	if update.SummarizedCount != nil {
+		session.SummarizedCount = *update.SummarizedCount
+	}
 	if update.Model != nil {
 		session.Model = *update.Model
 	}
@@ -40,6 +40,31 @@ func TestRedisSessionStore_ModelRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRedisSessionStore_SummaryRoundTrip(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	store := NewRedisSessionStore(context.Background(), client, log.DefaultLogger)
+	session, err := store.CreateSession(1, 1, "test", []SessionMessage{{Role: "user", Content: "hello"}})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	summary := "user is debugging checkout latency"
+	count := 4
+	if err := store.UpdateSession(session.ID, 1, 1, SessionUpdate{Summary: &summary, SummarizedCount: &count}); err != nil {
+		t.Fatalf("UpdateSession failed: %v", err)
+	}
+
+	got, err := store.GetSession(session.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if got.Summary != summary || got.SummarizedCount != 4 {
+		t.Fatalf("expected summary to round-trip, got %q (count %d)", got.Summary, got.SummarizedCount)
+	}
+}
+
 func TestRedisSessionStore_IncrementStats(t *testing.T) {
 	client := createTestRedisClient(t)
 	defer client.Close()
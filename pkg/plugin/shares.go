@@ -16,12 +16,15 @@ type ShareMetadata struct {
 	UserID     int64      `json:"userId"`
 	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
 	CreatedAt  time.Time  `json:"createdAt"`
+	// Teams, when set, limits the share to its creator and members of these
+	// Grafana teams; otherwise anyone in OrgID can open it.
+	Teams      []string   `json:"teams,omitempty"`
 	SessionData []byte    `json:"sessionData"` // JSON snapshot of session
 }
 
 // ShareStoreInterface defines the interface for share storage implementations
 type ShareStoreInterface interface {
-	CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int) (*ShareMetadata, error)
+	CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int, teams []string) (*ShareMetadata, error)
 	GetShare(shareID string) (*ShareMetadata, error)
 	DeleteShare(shareID string) error
 	GetSharesBySession(sessionID string) []*ShareMetadata
@@ -46,7 +49,7 @@ func NewShareStore(logger log.Logger, rateLimiter RateLimiter) *ShareStore {
 }
 
 // CreateShare creates a new share and returns the share metadata
-func (s *ShareStore) CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int) (*ShareMetadata, error) {
+func (s *ShareStore) CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int, teams []string) (*ShareMetadata, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -71,6 +74,7 @@ func (s *ShareStore) CreateShare(sessionID string, sessionData []byte, orgID, us
 		UserID:     userID,
 		ExpiresAt:  expiresAt,
 		CreatedAt:  time.Now(),
+		Teams:      teams,
 		SessionData: sessionData,
 	}
 
@@ -29,7 +29,7 @@ func NewRedisShareStore(ctx context.Context, client *redis.Client, logger log.Lo
 }
 
 // CreateShare creates a new share and returns the share metadata
-func (s *RedisShareStore) CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int) (*ShareMetadata, error) {
+func (s *RedisShareStore) CreateShare(sessionID string, sessionData []byte, orgID, userID int64, expiresInHours *int, teams []string) (*ShareMetadata, error) {
 	// Check rate limit
 	if !s.rateLimiter.CheckLimit(userID) {
 		return nil, fmt.Errorf("rate limit exceeded: too many share requests")
@@ -51,6 +51,7 @@ func (s *RedisShareStore) CreateShare(sessionID string, sessionData []byte, orgI
 		UserID:     userID,
 		ExpiresAt:  expiresAt,
 		CreatedAt:  time.Now(),
+		Teams:      teams,
 		SessionData: sessionData,
 	}
 
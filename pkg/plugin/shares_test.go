@@ -13,7 +13,7 @@ func TestShareStore_CreateShare(t *testing.T) {
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
 	expiresInHours := 7 * 24 // 7 days in hours
-	share, err := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours)
+	share, err := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours, nil)
 	if err != nil {
 		t.Fatalf("Failed to create share: %v", err)
 	}
@@ -42,7 +42,7 @@ func TestShareStore_CreateShare_NoExpiry(t *testing.T) {
 	store := NewShareStore(log.DefaultLogger, NewInMemoryRateLimiter(log.DefaultLogger))
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
-	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil)
+	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create share: %v", err)
 	}
@@ -56,7 +56,7 @@ func TestShareStore_GetShare(t *testing.T) {
 	store := NewShareStore(log.DefaultLogger, NewInMemoryRateLimiter(log.DefaultLogger))
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
-	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil)
+	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create share: %v", err)
 	}
@@ -88,7 +88,7 @@ func TestShareStore_GetShare_Expired(t *testing.T) {
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
 	expiresInHours := -1 // Expired (negative value)
-	share, err := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours)
+	share, err := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours, nil)
 	if err != nil {
 		t.Fatalf("Failed to create share: %v", err)
 	}
@@ -111,7 +111,7 @@ func TestShareStore_DeleteShare(t *testing.T) {
 	store := NewShareStore(log.DefaultLogger, NewInMemoryRateLimiter(log.DefaultLogger))
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
-	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil)
+	share, err := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create share: %v", err)
 	}
@@ -132,9 +132,9 @@ func TestShareStore_GetSharesBySession(t *testing.T) {
 	sessionData := []byte(`{"id":"session-123","messages":[{"role":"user","content":"test"}]}`)
 
 	// Create multiple shares for the same session
-	share1, _ := store.CreateShare("session-123", sessionData, 1, 100, nil)
-	share2, _ := store.CreateShare("session-123", sessionData, 1, 100, nil)
-	store.CreateShare("session-456", sessionData, 1, 100, nil) // Different session
+	share1, _ := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
+	share2, _ := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
+	store.CreateShare("session-456", sessionData, 1, 100, nil, nil) // Different session
 
 	shares := store.GetSharesBySession("session-123")
 	if len(shares) != 2 {
@@ -156,13 +156,13 @@ func TestShareStore_CleanupExpired(t *testing.T) {
 
 	// Create expired share
 	expiresInHours := -1 // Negative value (expired)
-	expiredShare, _ := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours)
+	expiredShare, _ := store.CreateShare("session-123", sessionData, 1, 100, &expiresInHours, nil)
 	expiredTime := time.Now().Add(-1 * time.Hour)
 	expiredShare.ExpiresAt = &expiredTime
 	store.shares[expiredShare.ShareID] = expiredShare
 
 	// Create non-expired share
-	store.CreateShare("session-456", sessionData, 1, 100, nil)
+	store.CreateShare("session-456", sessionData, 1, 100, nil, nil)
 
 	store.CleanupExpired()
 
@@ -185,14 +185,14 @@ func TestShareStore_RateLimit(t *testing.T) {
 
 	// Create 50 shares (should succeed)
 	for i := 0; i < 50; i++ {
-		_, err := store.CreateShare("session-123", sessionData, 1, 100, nil)
+		_, err := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to create share %d: %v", i, err)
 		}
 	}
 
 	// 51st share should fail due to rate limit
-	_, err := store.CreateShare("session-123", sessionData, 1, 100, nil)
+	_, err := store.CreateShare("session-123", sessionData, 1, 100, nil, nil)
 	if err == nil {
 		t.Error("Expected rate limit error")
 	}
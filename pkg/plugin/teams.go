@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/rbac"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	// teamCacheTTL bounds how long a team membership change takes to reach
+	// tool policies, approvals and shares.
+	teamCacheTTL = 5 * time.Minute
+
+	// teamCacheMaxUsers caps the cache; eviction is by oldest fetchedAt.
+	teamCacheMaxUsers = 1024
+
+	// teamLookupTimeout is the budget for both Grafana API calls. A slow
+	// Grafana costs at most this per uncached user.
+	teamLookupTimeout = 3 * time.Second
+)
+
+type teamCacheEntry struct {
+	teams     []string
+	fetchedAt time.Time
+}
+
+// userTeams returns the names of the Grafana teams login belongs to in orgID,
+// looked up with the plugin service account and cached per user for
+// teamCacheTTL. Failed lookups are not cached.
+func (p *Plugin) userTeams(ctx context.Context, orgID int64, login string) ([]string, error) {
+	if login == "" || login == "unknown" {
+		return nil, errors.New("user login is unknown")
+	}
+	cacheKey := strconv.FormatInt(orgID, 10) + "/" + login
+	if teams, ok := p.lookupTeamCache(cacheKey); ok {
+		return teams, nil
+	}
+
+	cfg := backend.GrafanaConfigFromContext(ctx)
+	if cfg == nil {
+		return nil, errors.New("grafana configuration not available")
+	}
+	saToken, err := cfg.PluginAppClientSecret()
+	if err != nil {
+		return nil, fmt.Errorf("service account token not available: %w", err)
+	}
+	p.settingsMu.RLock()
+	grafanaURL, _ := resolveGrafanaURL(p.settings, cfg)
+	p.settingsMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, teamLookupTimeout)
+	defer cancel()
+
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.getGrafanaJSON(ctx, grafanaURL+"/api/users/lookup?loginOrEmail="+url.QueryEscape(login), saToken, orgID, &user); err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	var memberships []struct {
+		Name string `json:"name"`
+	}
+	if err := p.getGrafanaJSON(ctx, fmt.Sprintf("%s/api/users/%d/teams", grafanaURL, user.ID), saToken, orgID, &memberships); err != nil {
+		return nil, fmt.Errorf("failed to list user teams: %w", err)
+	}
+
+	teams := make([]string, 0, len(memberships))
+	for _, team := range memberships {
+		teams = append(teams, team.Name)
+	}
+	p.storeTeamCache(cacheKey, teams)
+	return teams, nil
+}
+
+// policyTeams resolves login's teams when rules have team conditions. A
+// failed lookup returns the error, which callers must treat as unresolved
+// teams rather than as no team membership.
+func (p *Plugin) policyTeams(ctx context.Context, rules []rbac.Rule, orgID int64, login string) ([]string, error) {
+	if !slices.ContainsFunc(rules, func(rule rbac.Rule) bool { return len(rule.Teams) > 0 }) {
+		return nil, nil
+	}
+	return p.userTeams(ctx, orgID, login)
+}
+
+// policySubject is the user login, as rules see them. When their teams
+// can't be resolved the subject is marked TeamsUnresolved, so team deny
+// rules still match.
+func (p *Plugin) policySubject(ctx context.Context, rules []rbac.Rule, orgID int64, login, role string) rbac.Subject {
+	teams, err := p.policyTeams(ctx, rules, orgID, login)
+	if err != nil {
+		p.logger.Warn("Failed to resolve Grafana teams, applying team deny rules", "error", err, "orgID", orgID, "login", login)
+	}
+	return rbac.Subject{
+		Role:            role,
+		Teams:           teams,
+		TeamsUnresolved: err != nil,
+		OrgID:           strconv.FormatInt(orgID, 10),
+	}
+}
+
+// canOpenShare reports whether the user r was made by may open share. Shares
+// without teams are open to their whole org; team-scoped shares only to their
+// creator and the teams' members. Team lookup failures deny access.
+func (p *Plugin) canOpenShare(r *http.Request, share *ShareMetadata) bool {
+	if share.OrgID != getOrgID(r) {
+		return false
+	}
+	if len(share.Teams) == 0 || share.UserID == getUserID(r) {
+		return true
+	}
+	teams, err := p.userTeams(r.Context(), share.OrgID, getUserLogin(r))
+	if err != nil {
+		p.logger.Warn("Failed to resolve Grafana teams for shared session", "error", err, "shareId", share.ShareID)
+		return false
+	}
+	return slices.ContainsFunc(share.Teams, func(team string) bool { return slices.Contains(teams, team) })
+}
+
+// handleUserTeams serves GET /api/teams, listing the caller's Grafana teams
+// in the current org so the UI can offer them as share scopes.
+func (p *Plugin) handleUserTeams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	teams, err := p.userTeams(r.Context(), getOrgID(r), getUserLogin(r))
+	if err != nil {
+		p.logger.Warn("Failed to resolve Grafana teams", "error", err)
+		http.Error(w, "Failed to resolve teams", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"teams": teams})
+}
+
+func (p *Plugin) getGrafanaJSON(ctx context.Context, target, saToken string, orgID int64, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+saToken)
+	req.Header.Set("X-Grafana-Org-Id", strconv.FormatInt(orgID, 10))
+	resp, err := p.grafanaClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("grafana returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (p *Plugin) lookupTeamCache(key string) ([]string, bool) {
+	p.teamCacheMu.Lock()
+	defer p.teamCacheMu.Unlock()
+	entry, ok := p.teamCache[key]
+	if !ok || time.Since(entry.fetchedAt) > teamCacheTTL {
+		return nil, false
+	}
+	return entry.teams, true
+}
+
+func (p *Plugin) storeTeamCache(key string, teams []string) {
+	p.teamCacheMu.Lock()
+	defer p.teamCacheMu.Unlock()
+	if p.teamCache == nil {
+		p.teamCache = make(map[string]teamCacheEntry, teamCacheMaxUsers+1)
+	}
+	p.teamCache[key] = teamCacheEntry{teams: teams, fetchedAt: time.Now()}
+	if len(p.teamCache) > teamCacheMaxUsers {
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range p.teamCache {
+			if oldestKey == "" || entry.fetchedAt.Before(oldest) {
+				oldestKey, oldest = k, entry.fetchedAt
+			}
+		}
+		delete(p.teamCache, oldestKey)
+	}
+}
@@ -0,0 +1,270 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"consensys-asko11y-app/pkg/mcp"
+	"consensys-asko11y-app/pkg/rbac"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// newTeamsGrafana serves the user lookup and team APIs for alice (sre), bob
+// (platform) and carol (contractors), counting lookups.
+func newTeamsGrafana(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	users := map[string]int{"alice": 1, "bob": 2, "carol": 3}
+	teams := map[string]string{"1": "sre", "2": "platform", "3": "contractors"}
+	var lookups atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" || r.Header.Get("X-Grafana-Org-Id") != "2" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/api/users/lookup" {
+			lookups.Add(1)
+			id, ok := users[r.URL.Query().Get("loginOrEmail")]
+			if !ok {
+				http.Error(w, "user not found", http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"id":%d}`, id)
+			return
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/teams")
+		fmt.Fprintf(w, `[{"id":10,"name":%q}]`, teams[id])
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &lookups
+}
+
+func newTeamsRequest(method, target, body, grafanaURL, login, role string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Grafana-Org-Id", "2")
+	ctx := backend.WithPluginContext(req.Context(), backend.PluginContext{
+		User: &backend.User{Login: login, Role: role},
+	})
+	if grafanaURL != "" {
+		ctx = backend.WithGrafanaConfig(ctx, backend.NewGrafanaCfg(map[string]string{
+			"GF_APP_URL":                  grafanaURL,
+			"GF_PLUGIN_APP_CLIENT_SECRET": "test-token",
+		}))
+	}
+	return req.WithContext(ctx)
+}
+
+func TestUserTeamsCachesLookups(t *testing.T) {
+	grafana, lookups := newTeamsGrafana(t)
+	p := newAgentRunTestPlugin(t)
+	ctx := newTeamsRequest(http.MethodGet, "/api/teams", "", grafana.URL, "alice", "Viewer").Context()
+
+	for range 2 {
+		teams, err := p.userTeams(ctx, 2, "alice")
+		if err != nil || len(teams) != 1 || teams[0] != "sre" {
+			t.Fatalf("userTeams = %v, %v", teams, err)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Fatalf("expected one lookup, got %d", got)
+	}
+
+	for range 2 {
+		if _, err := p.userTeams(ctx, 2, "mallory"); err == nil {
+			t.Fatal("expected an error for an unknown user")
+		}
+	}
+	if got := lookups.Load(); got != 3 {
+		t.Fatalf("failed lookups should not be cached, got %d lookups", got)
+	}
+}
+
+func TestToolPoliciesMatchGrafanaTeams(t *testing.T) {
+	grafana, _ := newTeamsGrafana(t)
+	p := newToolPolicyTestPlugin(t, []rbac.Rule{
+		{Effect: rbac.EffectAllow, Tools: []string{"ops_restart_*"}, Teams: []string{"sre"}},
+	})
+
+	for login, want := range map[string]int{"alice": 2, "bob": 1} {
+		rec := httptest.NewRecorder()
+		p.handleMCPTools(rec, newTeamsRequest(http.MethodGet, "/api/mcp/tools", "", grafana.URL, login, "Viewer"))
+		var listed struct {
+			Tools []mcp.Tool `json:"tools"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(listed.Tools) != want {
+			t.Errorf("%s: expected %d tools, got %+v", login, want, listed.Tools)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	p.handleRBACExplain(rec, newTeamsRequest(http.MethodGet, "/api/rbac/explain?tool=ops_restart_service", "", grafana.URL, "alice", "Viewer"))
+	var got toolAccessExplanation
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !got.Allowed || got.Rule != 0 || len(got.Teams) != 1 || got.Teams[0] != "sre" {
+		t.Fatalf("unexpected explanation: %+v", got)
+	}
+}
+
+func TestToolPoliciesDenyWhenTeamsCannotBeResolved(t *testing.T) {
+	p := newToolPolicyTestPlugin(t, []rbac.Rule{
+		{Effect: rbac.EffectDeny, Tools: []string{"ops_restart_*"}, Teams: []string{"contractors"}},
+	})
+
+	// Without a Grafana config the team lookup fails.
+	rec := httptest.NewRecorder()
+	p.handleRBACExplain(rec, newTeamsRequest(http.MethodGet, "/api/rbac/explain?tool=ops_restart_service", "", "", "carol", "Editor"))
+	var got toolAccessExplanation
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Allowed || got.Rule != 0 {
+		t.Fatalf("expected the team deny rule to deny, got %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleMCPTools(rec, newTeamsRequest(http.MethodGet, "/api/mcp/tools", "", "", "carol", "Editor"))
+	var listed struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(listed.Tools) != 1 || listed.Tools[0].Name != "ops_get_status" {
+		t.Fatalf("expected only ops_get_status, got %+v", listed.Tools)
+	}
+}
+
+func TestHandleAgentApprovalAppliesApprovalPolicies(t *testing.T) {
+	grafana, _ := newTeamsGrafana(t)
+	p := newAgentRunTestPlugin(t)
+	p.settings.ApprovalPolicies = []rbac.Rule{
+		{Effect: rbac.EffectAllow, Description: "SRE approves restarts", Tools: []string{"k8s_restart_*"}, Teams: []string{"sre"}},
+		{Effect: rbac.EffectDeny, Description: "contractors cannot approve", Teams: []string{"contractors"}},
+	}
+
+	tests := []struct {
+		login, role string
+		want        int
+	}{
+		{"alice", "Viewer", http.StatusOK},
+		{"bob", "Viewer", http.StatusForbidden},
+		{"carol", "Editor", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		runID := "run-" + tt.login
+		wait, err := p.approvalBroker.Register(context.Background(), runID, agent.ApprovalRequestEvent{ApprovalID: "tc_1"})
+		if err != nil {
+			t.Fatalf("register approval failed: %v", err)
+		}
+		p.runStore.CreateRun(runID, userIDForLogin(tt.login), 2, "session-1")
+		p.runStore.AppendEvent(runID, agent.SSEEvent{Type: "approval_request", Data: agent.ApprovalRequestEvent{
+			ApprovalID: "tc_1",
+			ToolCallID: "tc_1",
+			ToolName:   "k8s_restart_deployment",
+		}})
+
+		rec := httptest.NewRecorder()
+		p.handleAgentApproval(rec, newTeamsRequest(http.MethodPost, "/api/agent/runs/"+runID+"/approvals/tc_1", `{"decision":"approved"}`, grafana.URL, tt.login, tt.role), runID, "tc_1")
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.login, tt.want, rec.Code, rec.Body.String())
+		}
+		if tt.want == http.StatusOK {
+			if _, err := wait(context.Background()); err != nil {
+				t.Fatalf("wait failed: %v", err)
+			}
+		}
+	}
+}
+
+func TestHandleGetSharedSessionTeamScope(t *testing.T) {
+	grafana, _ := newTeamsGrafana(t)
+	p := newAgentRunTestPlugin(t)
+	p.shareStore = NewShareStore(log.DefaultLogger, NewInMemoryRateLimiter(log.DefaultLogger))
+	sessionData := []byte(`{"id":"session-1","messages":[{"role":"user","content":"test"}]}`)
+	scoped, err := p.shareStore.CreateShare("session-1", sessionData, 2, userIDForLogin("dave"), nil, []string{"sre"})
+	if err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+	open, err := p.shareStore.CreateShare("session-1", sessionData, 2, userIDForLogin("dave"), nil, nil)
+	if err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+
+	tests := []struct {
+		name, shareID, grafanaURL, login string
+		want                             int
+	}{
+		// Runs before alice's teams are cached.
+		{"team lookup fails", scoped.ShareID, "", "alice", http.StatusForbidden},
+		{"team member", scoped.ShareID, grafana.URL, "alice", http.StatusOK},
+		{"other team", scoped.ShareID, grafana.URL, "bob", http.StatusForbidden},
+		{"creator", scoped.ShareID, grafana.URL, "dave", http.StatusOK},
+		{"share without teams", open.ShareID, grafana.URL, "bob", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			p.handleGetSharedSession(rec, newTeamsRequest(http.MethodGet, "/api/sessions/shared/"+tt.shareID, "", tt.grafanaURL, tt.login, "Viewer"))
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleAgentApprovalMatchesSamplingServer(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.settings.ApprovalPolicies = []rbac.Rule{
+		{Effect: rbac.EffectAllow, Description: "viewers approve summaries", Servers: []string{"summarizer"}, Roles: []string{"Viewer"}},
+	}
+
+	for _, tt := range []struct {
+		server string
+		want   int
+	}{
+		{"summarizer", http.StatusOK},
+		{"other", http.StatusForbidden},
+	} {
+		runID := "run-" + tt.server
+		approvalID := "tc_1-sampling-1"
+		wait, err := p.approvalBroker.Register(context.Background(), runID, agent.ApprovalRequestEvent{ApprovalID: approvalID})
+		if err != nil {
+			t.Fatalf("register approval failed: %v", err)
+		}
+		p.runStore.CreateRun(runID, 7, 2, "session-1")
+		p.runStore.AppendEvent(runID, agent.SSEEvent{Type: "approval_request", Data: agent.ApprovalRequestEvent{
+			ApprovalID: approvalID,
+			ToolCallID: "tc_1",
+			ToolName:   "sampling:" + tt.server,
+			Risk:       "sampling",
+			ServerID:   tt.server,
+		}})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/agent/runs/"+runID+"/approvals/"+approvalID, strings.NewReader(`{"decision":"approved"}`))
+		req.Header.Set("X-Grafana-Org-Id", "2")
+		req.Header.Set("X-Grafana-User-Id", "7")
+		req.Header.Set("X-Grafana-User-Role", "Viewer")
+		rec := httptest.NewRecorder()
+		p.handleAgentApproval(rec, req, runID, approvalID)
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.server, tt.want, rec.Code, rec.Body.String())
+		}
+		if tt.want == http.StatusOK {
+			if _, err := wait(context.Background()); err != nil {
+				t.Fatalf("wait failed: %v", err)
+			}
+		}
+	}
+}
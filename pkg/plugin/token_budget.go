@@ -0,0 +1,460 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// TokenLimits caps LLM tokens per UTC calendar day and month. Zero means
+// unlimited.
+type TokenLimits struct {
+	Daily   int64 `json:"daily,omitempty"`
+	Monthly int64 `json:"monthly,omitempty"`
+}
+
+// TokenBudgetSettings configures per-user and per-org token budgets. User and
+// Org are the defaults; Users (keyed by Grafana login) and Orgs (keyed by
+// numeric org ID) replace them for individual users and orgs.
+type TokenBudgetSettings struct {
+	User  TokenLimits            `json:"user"`
+	Org   TokenLimits            `json:"org"`
+	Users map[string]TokenLimits `json:"users,omitempty"`
+	Orgs  map[string]TokenLimits `json:"orgs,omitempty"`
+}
+
+func (s TokenBudgetSettings) userLimits(login string) TokenLimits {
+	if limits, ok := s.Users[login]; ok {
+		return limits
+	}
+	return s.User
+}
+
+func (s TokenBudgetSettings) orgLimits(orgID int64) TokenLimits {
+	if limits, ok := s.Orgs[strconv.FormatInt(orgID, 10)]; ok {
+		return limits
+	}
+	return s.Org
+}
+
+// TokenConsumption is what one user and their org have used in the current
+// day and month.
+type TokenConsumption struct {
+	UserDaily   int64
+	UserMonthly int64
+	OrgDaily    int64
+	OrgMonthly  int64
+}
+
+// TokenUsage is an org's consumption for one period, broken down by login.
+type TokenUsage struct {
+	Period string           `json:"period"`
+	Org    int64            `json:"org"`
+	Users  map[string]int64 `json:"users"`
+}
+
+const (
+	tokenBudgetPeriodDay   = "day"
+	tokenBudgetPeriodMonth = "month"
+	tokenBudgetOrgField    = "org"
+	tokenBudgetUserPrefix  = "user:"
+)
+
+// TokenBudgetStore tracks LLM token consumption per org and login in UTC
+// calendar days and months.
+type TokenBudgetStore interface {
+	// Add charges tokens to login and its org for the day and month of now.
+	Add(orgID int64, login string, tokens int64, now time.Time) error
+	Consumption(orgID int64, login string, now time.Time) (TokenConsumption, error)
+	// Usage returns the org's day and month consumption for the admin API.
+	Usage(orgID int64, now time.Time) (day, month TokenUsage, err error)
+	// Reset clears login's consumption, or the whole org's when login is
+	// empty, for period ("day", "month", or "" for both).
+	Reset(orgID int64, login, period string, now time.Time) error
+}
+
+func tokenBudgetPeriodID(period string, now time.Time) string {
+	now = now.UTC()
+	if period == tokenBudgetPeriodMonth {
+		return now.Format("2006-01")
+	}
+	return now.Format("2006-01-02")
+}
+
+func tokenBudgetKey(orgID int64, period string, now time.Time) string {
+	return fmt.Sprintf("tokenbudget:%d:%s:%s", orgID, period, tokenBudgetPeriodID(period, now))
+}
+
+func tokenBudgetRetention(period string) time.Duration {
+	if period == tokenBudgetPeriodMonth {
+		return TokenBudgetMonthRetention
+	}
+	return TokenBudgetDayRetention
+}
+
+func tokenBudgetPeriods(period string) ([]string, error) {
+	switch period {
+	case "":
+		return []string{tokenBudgetPeriodDay, tokenBudgetPeriodMonth}, nil
+	case tokenBudgetPeriodDay, tokenBudgetPeriodMonth:
+		return []string{period}, nil
+	default:
+		return nil, fmt.Errorf("unknown budget period %q", period)
+	}
+}
+
+func tokenUsageFromFields(period string, now time.Time, fields map[string]int64) TokenUsage {
+	usage := TokenUsage{Period: tokenBudgetPeriodID(period, now), Users: map[string]int64{}}
+	for field, tokens := range fields {
+		if field == tokenBudgetOrgField {
+			usage.Org = tokens
+		} else if login, ok := strings.CutPrefix(field, tokenBudgetUserPrefix); ok {
+			usage.Users[login] = tokens
+		}
+	}
+	return usage
+}
+
+type tokenBudgetBucket struct {
+	fields  map[string]int64
+	expires time.Time
+}
+
+type InMemoryTokenBudgetStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBudgetBucket
+}
+
+func NewInMemoryTokenBudgetStore() *InMemoryTokenBudgetStore {
+	return &InMemoryTokenBudgetStore{buckets: make(map[string]*tokenBudgetBucket)}
+}
+
+func (s *InMemoryTokenBudgetStore) Add(orgID int64, login string, tokens int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, key)
+		}
+	}
+	for _, period := range []string{tokenBudgetPeriodDay, tokenBudgetPeriodMonth} {
+		key := tokenBudgetKey(orgID, period, now)
+		b, ok := s.buckets[key]
+		if !ok {
+			b = &tokenBudgetBucket{fields: make(map[string]int64)}
+			s.buckets[key] = b
+		}
+		b.expires = now.Add(tokenBudgetRetention(period))
+		b.fields[tokenBudgetOrgField] += tokens
+		b.fields[tokenBudgetUserPrefix+login] += tokens
+	}
+	return nil
+}
+
+func (s *InMemoryTokenBudgetStore) field(key, field string) int64 {
+	if b, ok := s.buckets[key]; ok {
+		return b.fields[field]
+	}
+	return 0
+}
+
+func (s *InMemoryTokenBudgetStore) Consumption(orgID int64, login string, now time.Time) (TokenConsumption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := tokenBudgetKey(orgID, tokenBudgetPeriodDay, now)
+	month := tokenBudgetKey(orgID, tokenBudgetPeriodMonth, now)
+	return TokenConsumption{
+		UserDaily:   s.field(day, tokenBudgetUserPrefix+login),
+		UserMonthly: s.field(month, tokenBudgetUserPrefix+login),
+		OrgDaily:    s.field(day, tokenBudgetOrgField),
+		OrgMonthly:  s.field(month, tokenBudgetOrgField),
+	}, nil
+}
+
+func (s *InMemoryTokenBudgetStore) Usage(orgID int64, now time.Time) (TokenUsage, TokenUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var usage [2]TokenUsage
+	for i, period := range []string{tokenBudgetPeriodDay, tokenBudgetPeriodMonth} {
+		var fields map[string]int64
+		if b, ok := s.buckets[tokenBudgetKey(orgID, period, now)]; ok {
+			fields = b.fields
+		}
+		usage[i] = tokenUsageFromFields(period, now, fields)
+	}
+	return usage[0], usage[1], nil
+}
+
+func (s *InMemoryTokenBudgetStore) Reset(orgID int64, login, period string, now time.Time) error {
+	periods, err := tokenBudgetPeriods(period)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range periods {
+		key := tokenBudgetKey(orgID, p, now)
+		if login == "" {
+			delete(s.buckets, key)
+		} else if b, ok := s.buckets[key]; ok {
+			delete(b.fields, tokenBudgetUserPrefix+login)
+		}
+	}
+	return nil
+}
+
+// RedisTokenBudgetStore keeps one hash per org and period with an "org" total
+// and one "user:<login>" field per user, so every replica enforces the same
+// budgets.
+type RedisTokenBudgetStore struct {
+	client *redis.Client
+	logger log.Logger
+	ctx    context.Context
+}
+
+func NewRedisTokenBudgetStore(ctx context.Context, client *redis.Client, logger log.Logger) *RedisTokenBudgetStore {
+	return &RedisTokenBudgetStore{client: client, logger: logger, ctx: ctx}
+}
+
+func (s *RedisTokenBudgetStore) Add(orgID int64, login string, tokens int64, now time.Time) error {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	pipe := s.client.TxPipeline()
+	for _, period := range []string{tokenBudgetPeriodDay, tokenBudgetPeriodMonth} {
+		key := tokenBudgetKey(orgID, period, now)
+		pipe.HIncrBy(ctx, key, tokenBudgetOrgField, tokens)
+		pipe.HIncrBy(ctx, key, tokenBudgetUserPrefix+login, tokens)
+		pipe.Expire(ctx, key, tokenBudgetRetention(period))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record token usage: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisTokenBudgetStore) Consumption(orgID int64, login string, now time.Time) (TokenConsumption, error) {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+	day := pipe.HMGet(ctx, tokenBudgetKey(orgID, tokenBudgetPeriodDay, now), tokenBudgetUserPrefix+login, tokenBudgetOrgField)
+	month := pipe.HMGet(ctx, tokenBudgetKey(orgID, tokenBudgetPeriodMonth, now), tokenBudgetUserPrefix+login, tokenBudgetOrgField)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return TokenConsumption{}, fmt.Errorf("failed to load token usage: %w", err)
+	}
+
+	dayVals, monthVals := day.Val(), month.Val()
+	return TokenConsumption{
+		UserDaily:   redisInt64(dayVals, 0),
+		OrgDaily:    redisInt64(dayVals, 1),
+		UserMonthly: redisInt64(monthVals, 0),
+		OrgMonthly:  redisInt64(monthVals, 1),
+	}, nil
+}
+
+func redisInt64(vals []interface{}, i int) int64 {
+	if i >= len(vals) {
+		return 0
+	}
+	s, ok := vals[i].(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+func (s *RedisTokenBudgetStore) Usage(orgID int64, now time.Time) (TokenUsage, TokenUsage, error) {
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	var usage [2]TokenUsage
+	for i, period := range []string{tokenBudgetPeriodDay, tokenBudgetPeriodMonth} {
+		raw, err := s.client.HGetAll(ctx, tokenBudgetKey(orgID, period, now)).Result()
+		if err != nil {
+			return TokenUsage{}, TokenUsage{}, fmt.Errorf("failed to load token usage: %w", err)
+		}
+		fields := make(map[string]int64, len(raw))
+		for field, value := range raw {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			fields[field] = n
+		}
+		usage[i] = tokenUsageFromFields(period, now, fields)
+	}
+	return usage[0], usage[1], nil
+}
+
+func (s *RedisTokenBudgetStore) Reset(orgID int64, login, period string, now time.Time) error {
+	periods, err := tokenBudgetPeriods(period)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := redisContext(s.ctx, RedisOpTimeout)
+	defer cancel()
+
+	pipe := s.client.TxPipeline()
+	for _, p := range periods {
+		key := tokenBudgetKey(orgID, p, now)
+		if login == "" {
+			pipe.Del(ctx, key)
+		} else {
+			pipe.HDel(ctx, key, tokenBudgetUserPrefix+login)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to reset token usage: %w", err)
+	}
+	return nil
+}
+
+// checkTokenBudget returns an error wrapping agent.ErrTokenBudgetExhausted
+// when login or its org has used up a daily or monthly budget. Other errors
+// mean the store could not be read; callers fail open on those.
+func (p *Plugin) checkTokenBudget(orgID int64, login string) error {
+	p.settingsMu.RLock()
+	budgets := p.settings.TokenBudgets
+	p.settingsMu.RUnlock()
+
+	userLimits, orgLimits := budgets.userLimits(login), budgets.orgLimits(orgID)
+	if userLimits == (TokenLimits{}) && orgLimits == (TokenLimits{}) {
+		return nil
+	}
+	if p.tokenBudgets == nil {
+		return nil
+	}
+
+	used, err := p.tokenBudgets.Consumption(orgID, login, time.Now())
+	if err != nil {
+		return err
+	}
+
+	checks := []struct {
+		scope, period string
+		used, limit   int64
+	}{
+		{"user", "daily", used.UserDaily, userLimits.Daily},
+		{"user", "monthly", used.UserMonthly, userLimits.Monthly},
+		{"org", "daily", used.OrgDaily, orgLimits.Daily},
+		{"org", "monthly", used.OrgMonthly, orgLimits.Monthly},
+	}
+	for _, c := range checks {
+		if c.limit > 0 && c.used >= c.limit {
+			return fmt.Errorf("%w: %s %s budget of %d tokens used up (%d used); contact your Grafana admin", agent.ErrTokenBudgetExhausted, c.scope, c.period, c.limit, c.used)
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) recordTokenUsage(orgID int64, login string, tokens int64) {
+	if p.tokenBudgets == nil || tokens <= 0 {
+		return
+	}
+	if err := p.tokenBudgets.Add(orgID, login, tokens, time.Now()); err != nil {
+		p.logger.Warn("Failed to record token budget usage", "error", err, "orgID", orgID, "login", login)
+	}
+}
+
+func (p *Plugin) tokenUsageRecorder(orgID int64, login string) agent.TokenUsageRecorder {
+	return func(ctx context.Context, usage agent.ModelUsage) {
+		p.recordTokenUsage(orgID, login, int64(usage.TotalTokens))
+	}
+}
+
+func (p *Plugin) tokenBudgetChecker(orgID int64, login string) agent.TokenBudgetChecker {
+	return func(ctx context.Context) error {
+		return p.checkTokenBudget(orgID, login)
+	}
+}
+
+// handleTokenBudgets serves GET /api/token-budgets: the org's limits and its
+// consumption for the current UTC day and month, per user. Admin only.
+func (p *Plugin) handleTokenBudgets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if getUserRole(r) != "Admin" {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	orgID := getOrgID(r)
+	day, month, err := p.tokenBudgets.Usage(orgID, time.Now())
+	if err != nil {
+		p.logger.Error("Failed to load token budget usage", "error", err, "orgID", orgID)
+		http.Error(w, "Failed to load token usage", http.StatusInternalServerError)
+		return
+	}
+
+	p.settingsMu.RLock()
+	budgets := p.settings.TokenBudgets
+	p.settingsMu.RUnlock()
+	userOverrides := budgets.Users
+	if userOverrides == nil {
+		userOverrides = map[string]TokenLimits{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orgId":         orgID,
+		"orgLimits":     budgets.orgLimits(orgID),
+		"userLimits":    budgets.User,
+		"userOverrides": userOverrides,
+		"day":           day,
+		"month":         month,
+	})
+}
+
+// handleTokenBudgetReset serves POST /api/token-budgets/reset, clearing one
+// user's consumption, or the whole org's when login is omitted. Admin only.
+func (p *Plugin) handleTokenBudgetReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if getUserRole(r) != "Admin" {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Login  string `json:"login"`
+		Period string `json:"period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := tokenBudgetPeriods(req.Period); err != nil {
+		http.Error(w, "period must be 'day', 'month' or empty", http.StatusBadRequest)
+		return
+	}
+
+	orgID := getOrgID(r)
+	login := strings.TrimSpace(req.Login)
+	if err := p.tokenBudgets.Reset(orgID, login, req.Period, time.Now()); err != nil {
+		p.logger.Error("Failed to reset token budget usage", "error", err, "orgID", orgID)
+		http.Error(w, "Failed to reset token usage", http.StatusInternalServerError)
+		return
+	}
+
+	p.logger.Info("Token budget usage reset", "orgID", orgID, "login", login, "period", req.Period, "by", getUserLogin(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reset"})
+}
@@ -0,0 +1,237 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/agent"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func testTokenBudgetStore(t *testing.T, store TokenBudgetStore) {
+	t.Helper()
+
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	if err := store.Add(1, "alice", 100, now); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(1, "bob", 50, now); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(2, "alice", 7, now); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	used, err := store.Consumption(1, "alice", now)
+	if err != nil {
+		t.Fatalf("Consumption failed: %v", err)
+	}
+	want := TokenConsumption{UserDaily: 100, UserMonthly: 100, OrgDaily: 150, OrgMonthly: 150}
+	if used != want {
+		t.Fatalf("consumption = %+v, want %+v", used, want)
+	}
+
+	// The next day starts a fresh daily bucket in a fresh month.
+	tomorrow := now.Add(2 * time.Hour)
+	if err := store.Add(1, "alice", 10, tomorrow); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	used, err = store.Consumption(1, "alice", tomorrow)
+	if err != nil {
+		t.Fatalf("Consumption failed: %v", err)
+	}
+	want = TokenConsumption{UserDaily: 10, UserMonthly: 10, OrgDaily: 10, OrgMonthly: 10}
+	if used != want {
+		t.Fatalf("consumption next day = %+v, want %+v", used, want)
+	}
+
+	day, month, err := store.Usage(1, now)
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if day.Period != "2026-03-31" || month.Period != "2026-03" {
+		t.Fatalf("periods = %q/%q", day.Period, month.Period)
+	}
+	if day.Org != 150 || day.Users["alice"] != 100 || day.Users["bob"] != 50 {
+		t.Fatalf("day usage = %+v", day)
+	}
+
+	if err := store.Reset(1, "alice", "day", now); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	used, err = store.Consumption(1, "alice", now)
+	if err != nil {
+		t.Fatalf("Consumption failed: %v", err)
+	}
+	want = TokenConsumption{UserDaily: 0, UserMonthly: 100, OrgDaily: 150, OrgMonthly: 150}
+	if used != want {
+		t.Fatalf("consumption after user day reset = %+v, want %+v", used, want)
+	}
+
+	if err := store.Reset(1, "", "", now); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	used, err = store.Consumption(1, "bob", now)
+	if err != nil {
+		t.Fatalf("Consumption failed: %v", err)
+	}
+	if used != (TokenConsumption{}) {
+		t.Fatalf("consumption after org reset = %+v, want zero", used)
+	}
+	if used, _ := store.Consumption(2, "alice", now); used.UserDaily != 7 {
+		t.Fatalf("org 2 consumption = %+v, want untouched", used)
+	}
+
+	if err := store.Reset(1, "", "week", now); err == nil {
+		t.Fatal("expected error for unknown period")
+	}
+}
+
+func TestInMemoryTokenBudgetStore(t *testing.T) {
+	testTokenBudgetStore(t, NewInMemoryTokenBudgetStore())
+}
+
+func TestRedisTokenBudgetStore(t *testing.T) {
+	client := createTestRedisClient(t)
+	defer client.Close()
+
+	testTokenBudgetStore(t, NewRedisTokenBudgetStore(context.Background(), client, log.DefaultLogger))
+}
+
+func TestInMemoryTokenBudgetStore_ExpiresOldBuckets(t *testing.T) {
+	store := NewInMemoryTokenBudgetStore()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	if err := store.Add(1, "alice", 10, now); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(1, "alice", 10, now.Add(90*24*time.Hour)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if got := len(store.buckets); got != 2 {
+		t.Fatalf("buckets = %d, want only the current day and month", got)
+	}
+}
+
+func TestCheckTokenBudget(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.settings.TokenBudgets = TokenBudgetSettings{
+		User:  TokenLimits{Daily: 100},
+		Org:   TokenLimits{Monthly: 1000},
+		Users: map[string]TokenLimits{"power": {Daily: 5000}},
+		Orgs:  map[string]TokenLimits{"3": {}},
+	}
+	now := time.Now()
+
+	p.tokenBudgets.Add(2, "alice", 100, now) //nolint:errcheck
+	if err := p.checkTokenBudget(2, "alice"); !errors.Is(err, agent.ErrTokenBudgetExhausted) {
+		t.Fatalf("alice: err = %v, want ErrTokenBudgetExhausted", err)
+	}
+
+	p.tokenBudgets.Add(2, "power", 400, now) //nolint:errcheck
+	if err := p.checkTokenBudget(2, "power"); err != nil {
+		t.Fatalf("power user override: err = %v, want nil", err)
+	}
+
+	p.tokenBudgets.Add(2, "power", 600, now) //nolint:errcheck
+	err := p.checkTokenBudget(2, "power")
+	if !errors.Is(err, agent.ErrTokenBudgetExhausted) || !strings.Contains(err.Error(), "org monthly") {
+		t.Fatalf("org budget: err = %v, want org monthly exhaustion", err)
+	}
+
+	// Org 3 has no org limit, but the default user limit still applies.
+	p.tokenBudgets.Add(3, "carol", 99, now) //nolint:errcheck
+	if err := p.checkTokenBudget(3, "carol"); err != nil {
+		t.Fatalf("org 3: err = %v, want nil", err)
+	}
+}
+
+func TestHandleAgentRunRejectsExhaustedTokenBudget(t *testing.T) {
+	llmServer, received := newAgentRunLLMServer(t)
+	defer llmServer.Close()
+
+	p := newAgentRunTestPlugin(t)
+	p.settings.TokenBudgets = TokenBudgetSettings{User: TokenLimits{Daily: 10}}
+	p.tokenBudgets.Add(2, "unknown", 10, time.Now()) //nolint:errcheck
+
+	req := newAgentRunRequest(t, llmServer.URL, "/api/agent/run", `{"message":"hello","type":"chat"}`)
+	rec := httptest.NewRecorder()
+	p.handleAgentRun(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusTooManyRequests, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "user daily budget") {
+		t.Fatalf("body = %q, want budget explanation", rec.Body.String())
+	}
+	select {
+	case <-received:
+		t.Fatal("LLM was called despite exhausted budget")
+	default:
+	}
+}
+
+func TestHandleTokenBudgets(t *testing.T) {
+	p := newAgentRunTestPlugin(t)
+	p.settings.TokenBudgets = TokenBudgetSettings{
+		User: TokenLimits{Daily: 100},
+		Orgs: map[string]TokenLimits{"2": {Monthly: 5000}},
+	}
+	p.tokenBudgets.Add(2, "alice", 40, time.Now()) //nolint:errcheck
+
+	newReq := func(method, target, role, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Grafana-Org-Id", "2")
+		req.Header.Set("X-Grafana-User-Role", role)
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	p.handleTokenBudgets(rec, newReq(http.MethodGet, "/api/token-budgets", "Editor", ""))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("editor status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleTokenBudgets(rec, newReq(http.MethodGet, "/api/token-budgets", "Admin", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		OrgLimits  TokenLimits `json:"orgLimits"`
+		UserLimits TokenLimits `json:"userLimits"`
+		Day        TokenUsage  `json:"day"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.OrgLimits.Monthly != 5000 || body.UserLimits.Daily != 100 || body.Day.Users["alice"] != 40 {
+		t.Fatalf("unexpected response: %+v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleTokenBudgetReset(rec, newReq(http.MethodPost, "/api/token-budgets/reset", "Admin", `{"login":"alice","period":"week"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad period status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleTokenBudgetReset(rec, newReq(http.MethodPost, "/api/token-budgets/reset", "Viewer", `{"login":"alice"}`))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("viewer reset status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleTokenBudgetReset(rec, newReq(http.MethodPost, "/api/token-budgets/reset", "Admin", `{"login":"alice"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if used, _ := p.tokenBudgets.Consumption(2, "alice", time.Now()); used.UserDaily != 0 || used.UserMonthly != 0 {
+		t.Fatalf("consumption after reset = %+v, want zero", used)
+	}
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"consensys-asko11y-app/pkg/rbac"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// warnInvalidToolPolicies logs rules that can never match, so a typo in a
+// deny rule doesn't go unnoticed. Invalid rules are kept so rule numbers in
+// explanations match the configuration.
+func warnInvalidToolPolicies(rules []rbac.Rule, logger log.Logger) {
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			logger.Warn("Tool policy rule never matches", "rule", i, "error", err)
+		}
+	}
+}
+
+// toolPolicy returns the admin-defined tool access rules together with the
+// server configs their risk conditions are classified against.
+func (p *Plugin) toolPolicy() *rbac.Policy {
+	p.settingsMu.RLock()
+	rules := p.settings.ToolPolicies
+	p.settingsMu.RUnlock()
+	return &rbac.Policy{Rules: rules, Servers: p.settingsForFilter()}
+}
+
+// toolSubject is the user r was made by, as tool policies see them. Teams
+// are only looked up when a tool policy rule has team conditions.
+func (p *Plugin) toolSubject(r *http.Request) rbac.Subject {
+	p.settingsMu.RLock()
+	rules := p.settings.ToolPolicies
+	p.settingsMu.RUnlock()
+	return p.policySubject(r.Context(), rules, getOrgID(r), getUserLogin(r), getUserRole(r))
+}
+
+// approvalPolicy returns the rules deciding who may resolve approvals of a
+// tool's calls. Without a matching rule only Admin and Editor may.
+func (p *Plugin) approvalPolicy() *rbac.Policy {
+	p.settingsMu.RLock()
+	rules := p.settings.ApprovalPolicies
+	p.settingsMu.RUnlock()
+	return &rbac.Policy{
+		Rules:   rules,
+		Servers: p.settingsForFilter(),
+		Default: func(subject rbac.Subject, _ mcp.Tool) (bool, string) {
+			if subject.Role == "Admin" || subject.Role == "Editor" {
+				return true, fmt.Sprintf("No approval rule matches; the %s role can approve tool calls.", subject.Role)
+			}
+			return false, "No approval rule matches; only Admin and Editor can approve tool calls."
+		},
+	}
+}
+
+// approvalSubject is the user r was made by, as approval policies see them.
+func (p *Plugin) approvalSubject(r *http.Request) rbac.Subject {
+	p.settingsMu.RLock()
+	rules := p.settings.ApprovalPolicies
+	p.settingsMu.RUnlock()
+	return p.policySubject(r.Context(), rules, getOrgID(r), getUserLogin(r), getUserRole(r))
+}
+
+type toolAccessExplanation struct {
+	Tool  string   `json:"tool"`
+	Role  string   `json:"role"`
+	Teams []string `json:"teams,omitempty"`
+	OrgID string   `json:"orgId"`
+	rbac.Decision
+}
+
+// handleRBACExplain serves GET /api/rbac/explain?tool=..., telling the caller
+// whether they may use a tool and which policy rule or role default decided.
+func (p *Plugin) handleRBACExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("tool")
+	if name == "" {
+		http.Error(w, "'tool' is required", http.StatusBadRequest)
+		return
+	}
+
+	tool, found := p.mcpProxy.FindToolByName(name)
+	if !found {
+		if _, err := p.mcpProxy.ListTools(); err == nil {
+			tool, found = p.mcpProxy.FindToolByName(name)
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("Unknown tool: %s", name), http.StatusNotFound)
+			return
+		}
+	}
+
+	subject := p.toolSubject(r)
+	decision := p.toolPolicy().Decide(subject, tool)
+	if decision.Allowed && !mcp.IsToolEnabled(name, p.settingsForFilter()) {
+		decision.Allowed = false
+		decision.Rule = -1
+		decision.Reason = "The tool is disabled in the MCP server settings."
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toolAccessExplanation{
+		Tool:     name,
+		Role:     subject.Role,
+		Teams:    subject.Teams,
+		OrgID:    subject.OrgID,
+		Decision: decision,
+	})
+}
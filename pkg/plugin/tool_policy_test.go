@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"consensys-asko11y-app/pkg/rbac"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newToolPolicyTestPlugin registers an "ops" server with a read-only
+// get_status tool and a restart_service tool.
+func newToolPolicyTestPlugin(t *testing.T, rules []rbac.Rule) *Plugin {
+	t.Helper()
+	downstream := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "ops", Version: "1.0.0"}, nil)
+	mcpsdk.AddTool(downstream, &mcpsdk.Tool{Name: "get_status", Annotations: &mcpsdk.ToolAnnotations{ReadOnlyHint: true}}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "up"}}}, nil, nil
+	})
+	mcpsdk.AddTool(downstream, &mcpsdk.Tool{Name: "restart_service"}, func(ctx context.Context, req *mcpsdk.CallToolRequest, in struct{}) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "restarted"}}}, nil, nil
+	})
+	ts := httptest.NewServer(mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return downstream }, nil))
+	t.Cleanup(ts.Close)
+
+	p := newAgentRunTestPlugin(t)
+	p.settings.ToolPolicies = rules
+	p.settings.MCPServers = []mcp.ServerConfig{{ID: "ops", URL: ts.URL, Type: "streamable-http", Enabled: true}}
+	if err := p.mcpProxy.EnsureServer(p.settings.MCPServers[0]); err != nil {
+		t.Fatalf("EnsureServer: %v", err)
+	}
+	return p
+}
+
+func explainTool(t *testing.T, p *Plugin, tool, role, orgID string) (int, toolAccessExplanation) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/rbac/explain?tool="+tool, nil)
+	req.Header.Set("X-Grafana-User-Role", role)
+	req.Header.Set("X-Grafana-Org-Id", orgID)
+	rec := httptest.NewRecorder()
+	p.handleRBACExplain(rec, req)
+	var out toolAccessExplanation
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, out
+}
+
+func TestHandleRBACExplain(t *testing.T) {
+	p := newToolPolicyTestPlugin(t, []rbac.Rule{
+		{Effect: rbac.EffectAllow, Description: "on-call may restart services", Tools: []string{"ops_restart_*"}, Roles: []string{"Viewer"}, Orgs: []string{"2"}},
+	})
+
+	code, got := explainTool(t, p, "ops_restart_service", "Viewer", "2")
+	if code != http.StatusOK || !got.Allowed || got.Rule != 0 || !strings.Contains(got.Reason, "on-call may restart services") {
+		t.Fatalf("org 2 viewer: %d %+v", code, got)
+	}
+	code, got = explainTool(t, p, "ops_restart_service", "Viewer", "3")
+	if code != http.StatusOK || got.Allowed || got.Rule != -1 || got.Role != "Viewer" || got.OrgID != "3" {
+		t.Fatalf("org 3 viewer: %d %+v", code, got)
+	}
+	if code, _ := explainTool(t, p, "ops_unknown", "Viewer", "2"); code != http.StatusNotFound {
+		t.Fatalf("unknown tool: got %d, want 404", code)
+	}
+}
+
+func TestHandleMCPToolsAndCallToolApplyToolPolicies(t *testing.T) {
+	p := newToolPolicyTestPlugin(t, []rbac.Rule{
+		{Effect: rbac.EffectDeny, Servers: []string{"ops"}, Risks: []string{rbac.RiskWrite}, Roles: []string{"Editor"}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/mcp/tools", nil)
+	req.Header.Set("X-Grafana-User-Role", "Editor")
+	rec := httptest.NewRecorder()
+	p.handleMCPTools(rec, req)
+	var listed struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(listed.Tools) != 1 || listed.Tools[0].Name != "ops_get_status" {
+		t.Fatalf("expected only ops_get_status, got %+v", listed.Tools)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/mcp/call-tool", strings.NewReader(`{"name":"ops_restart_service","arguments":{}}`))
+	req.Header.Set("X-Grafana-User-Role", "Editor")
+	rec = httptest.NewRecorder()
+	p.handleMCPCallTool(rec, req)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "Denied by policy rule 0") {
+		t.Fatalf("expected the deny rule to block the call, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleMCPJSONRPCAppliesToolPolicies(t *testing.T) {
+	p := newToolPolicyTestPlugin(t, []rbac.Rule{
+		{Effect: rbac.EffectDeny, Servers: []string{"ops"}, Risks: []string{rbac.RiskWrite}, Roles: []string{"Editor"}},
+	})
+	rpc := func(body string) mcp.MCPResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Grafana-User-Role", "Editor")
+		rec := httptest.NewRecorder()
+		p.handleMCP(rec, req)
+		var resp mcp.MCPResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %q: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	listed := rpc(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	data, _ := json.Marshal(listed.Result)
+	var tools mcp.ListToolsResult
+	if err := json.Unmarshal(data, &tools); err != nil {
+		t.Fatalf("decode tools: %v", err)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "ops_get_status" {
+		t.Fatalf("expected only ops_get_status, got %+v", tools.Tools)
+	}
+
+	denied := rpc(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"ops_restart_service","arguments":{}}}`)
+	if denied.Error == nil || !strings.Contains(denied.Error.Message, "Denied by policy rule 0") {
+		t.Fatalf("expected the deny rule to block the call, got %+v", denied)
+	}
+
+	allowed := rpc(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"ops_get_status","arguments":{}}}`)
+	if allowed.Error != nil || !strings.Contains(fmt.Sprint(allowed.Result), "up") {
+		t.Fatalf("expected the allowed call to run, got %+v", allowed)
+	}
+}
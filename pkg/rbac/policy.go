@@ -0,0 +1,209 @@
+package rbac
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Rule effects.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Risk classes a rule can match, derived from mcp.ClassifyToolRisk.
+const (
+	RiskReadOnly         = "read-only"
+	RiskWrite            = "write"
+	RiskDestructive      = "destructive"
+	RiskOpenWorld        = "open-world"
+	RiskRequiresApproval = "requires-approval"
+)
+
+var riskClasses = []string{RiskReadOnly, RiskWrite, RiskDestructive, RiskOpenWorld, RiskRequiresApproval}
+
+// Rule allows or denies the tools it matches to the users it matches. Every
+// condition that is set must hold; within one condition any listed value
+// matches. A rule without conditions matches every tool for every user.
+type Rule struct {
+	Effect      string `json:"effect"`
+	Description string `json:"description,omitempty"`
+	// Servers lists MCP server IDs.
+	Servers []string `json:"servers,omitempty"`
+	// Tools lists globs matched against the prefixed tool name, such as
+	// "mcp-grafana_*" or "*_delete_*".
+	Tools []string `json:"tools,omitempty"`
+	// Risks lists risk classes: read-only, write, destructive, open-world
+	// and requires-approval.
+	Risks []string `json:"risks,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	Teams []string `json:"teams,omitempty"`
+	// Orgs lists Grafana org IDs.
+	Orgs []string `json:"orgs,omitempty"`
+}
+
+// Validate reports rules that could never be evaluated as written.
+func (r Rule) Validate() error {
+	if r.Effect != EffectAllow && r.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %q or %q", EffectAllow, EffectDeny)
+	}
+	for _, pattern := range r.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q", pattern)
+		}
+	}
+	for _, risk := range r.Risks {
+		if !slices.Contains(riskClasses, risk) {
+			return fmt.Errorf("unknown risk class %q", risk)
+		}
+	}
+	return nil
+}
+
+// Subject is the user a tool decision is made for.
+type Subject struct {
+	Role  string
+	Teams []string
+	// TeamsUnresolved is set when the subject's teams could not be looked
+	// up. Team conditions then fail closed: they match in deny rules and
+	// never in allow rules.
+	TeamsUnresolved bool
+	OrgID           string
+}
+
+// Policy is the admin-defined tool access rules, evaluated on top of the
+// role defaults of CanAccessTool. A nil Policy applies the role defaults
+// only.
+type Policy struct {
+	Rules []Rule
+	// Servers supplies the trust and risk overrides mcp.ClassifyToolRisk
+	// uses to classify tools for Risks conditions.
+	Servers []mcp.ServerConfig
+	// Default decides tools no rule matches. When nil the role defaults of
+	// CanAccessTool decide.
+	Default func(subject Subject, tool mcp.Tool) (allowed bool, reason string)
+}
+
+// Decision is the outcome of evaluating a tool for a subject.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+	// Rule is the index of the deciding rule, or -1 when the defaults
+	// decided.
+	Rule int          `json:"rule"`
+	Risk mcp.ToolRisk `json:"risk"`
+}
+
+// Decide evaluates tool for subject. A matching deny rule wins over any
+// allow rule; tools no rule matches fall back to the role defaults.
+func (p *Policy) Decide(subject Subject, tool mcp.Tool) Decision {
+	var servers []mcp.ServerConfig
+	if p != nil {
+		servers = p.Servers
+	}
+	decision := Decision{Rule: -1, Risk: mcp.ClassifyToolRisk(tool, servers)}
+
+	allowRule := -1
+	if p != nil {
+		for i, rule := range p.Rules {
+			if !rule.matches(subject, tool, decision.Risk) {
+				continue
+			}
+			if rule.Effect == EffectDeny {
+				decision.Rule = i
+				decision.Reason = ruleReason("Denied", i, rule)
+				if len(rule.Teams) > 0 && subject.TeamsUnresolved {
+					decision.Reason += " Grafana teams could not be resolved, so the rule's team condition is assumed to match."
+				}
+				return decision
+			}
+			if rule.Effect == EffectAllow && allowRule < 0 {
+				allowRule = i
+			}
+		}
+	}
+	if allowRule >= 0 {
+		decision.Allowed = true
+		decision.Rule = allowRule
+		decision.Reason = ruleReason("Allowed", allowRule, p.Rules[allowRule])
+		return decision
+	}
+
+	if p != nil && p.Default != nil {
+		decision.Allowed, decision.Reason = p.Default(subject, tool)
+		return decision
+	}
+	decision.Allowed = CanAccessTool(subject.Role, tool)
+	switch {
+	case subject.Role == "Admin" || subject.Role == "Editor":
+		decision.Reason = fmt.Sprintf("No policy rule matches; the %s role can use every tool.", subject.Role)
+	case decision.Allowed:
+		decision.Reason = "No policy rule matches; the tool is read-only, which every role can use."
+	default:
+		decision.Reason = "No policy rule matches; only Admin and Editor can use tools that are not read-only."
+	}
+	return decision
+}
+
+func ruleReason(verb string, index int, rule Rule) string {
+	if rule.Description != "" {
+		return fmt.Sprintf("%s by policy rule %d: %s", verb, index, rule.Description)
+	}
+	return fmt.Sprintf("%s by policy rule %d.", verb, index)
+}
+
+func (r Rule) matches(subject Subject, tool mcp.Tool, risk mcp.ToolRisk) bool {
+	if r.Effect != EffectAllow && r.Effect != EffectDeny {
+		return false
+	}
+	if len(r.Servers) > 0 && !slices.Contains(r.Servers, tool.ServerID) {
+		return false
+	}
+	if len(r.Tools) > 0 && !slices.ContainsFunc(r.Tools, func(pattern string) bool {
+		ok, _ := path.Match(pattern, tool.Name)
+		return ok
+	}) {
+		return false
+	}
+	if len(r.Risks) > 0 && !slices.ContainsFunc(r.Risks, func(class string) bool {
+		return riskHasClass(risk, class)
+	}) {
+		return false
+	}
+	if len(r.Roles) > 0 && !slices.Contains(r.Roles, subject.Role) {
+		return false
+	}
+	if len(r.Teams) > 0 {
+		if subject.TeamsUnresolved {
+			if r.Effect != EffectDeny {
+				return false
+			}
+		} else if !slices.ContainsFunc(r.Teams, func(team string) bool {
+			return slices.Contains(subject.Teams, team)
+		}) {
+			return false
+		}
+	}
+	if len(r.Orgs) > 0 && !slices.Contains(r.Orgs, subject.OrgID) {
+		return false
+	}
+	return true
+}
+
+func riskHasClass(risk mcp.ToolRisk, class string) bool {
+	switch class {
+	case RiskReadOnly:
+		return risk.ReadOnly
+	case RiskWrite:
+		return !risk.ReadOnly
+	case RiskDestructive:
+		return risk.Destructive
+	case RiskOpenWorld:
+		return risk.OpenWorld
+	case RiskRequiresApproval:
+		return risk.RequiresApproval
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"consensys-asko11y-app/pkg/mcp"
+	"strings"
+	"testing"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	policy := &Policy{
+		Rules: []Rule{
+			{Effect: EffectDeny, Description: "no deletes in prod", Risks: []string{RiskDestructive}, Orgs: []string{"1"}},
+			{Effect: EffectAllow, Servers: []string{"k8s"}, Roles: []string{"Viewer"}, Teams: []string{"sre"}},
+			{Effect: EffectDeny, Tools: []string{"grafana_*_datasource"}, Roles: []string{"Editor"}},
+		},
+		Servers: []mcp.ServerConfig{{ID: "k8s"}, {ID: "grafana"}},
+	}
+	tests := []struct {
+		name    string
+		subject Subject
+		tool    mcp.Tool
+		allowed bool
+		rule    int
+	}{
+		{"deny wins over allow", Subject{Role: "Viewer", Teams: []string{"sre"}, OrgID: "1"}, writeTool("k8s_delete_pod"), false, 0},
+		{"deny scoped to org", Subject{Role: "Admin", OrgID: "2"}, writeTool("k8s_delete_pod"), true, -1},
+		{"allow grants viewer write tool", Subject{Role: "Viewer", Teams: []string{"sre"}, OrgID: "2"}, writeTool("k8s_restart_deployment"), true, 1},
+		{"allow needs the team", Subject{Role: "Viewer", Teams: []string{"platform"}, OrgID: "2"}, writeTool("k8s_restart_deployment"), false, -1},
+		{"unresolved teams never match allow rules", Subject{Role: "Viewer", TeamsUnresolved: true, OrgID: "2"}, writeTool("k8s_restart_deployment"), false, -1},
+		{"glob deny", Subject{Role: "Editor", OrgID: "2"}, writeTool("grafana_update_datasource"), false, 2},
+		{"glob does not match other tools", Subject{Role: "Editor", OrgID: "2"}, writeTool("grafana_update_dashboard"), true, -1},
+		{"role defaults without a match", Subject{Role: "Viewer", OrgID: "2"}, readOnlyTool("grafana_get_dashboard"), true, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Decide(tt.subject, tt.tool)
+			if got.Allowed != tt.allowed || got.Rule != tt.rule {
+				t.Fatalf("Decide() = allowed %v by rule %d (%s), want allowed %v by rule %d", got.Allowed, got.Rule, got.Reason, tt.allowed, tt.rule)
+			}
+		})
+	}
+}
+
+func TestPolicyDecideExplainsTheDecision(t *testing.T) {
+	policy := &Policy{Rules: []Rule{{Effect: EffectDeny, Description: "no deletes in prod", Risks: []string{RiskDestructive}}}}
+
+	if got := policy.Decide(Subject{Role: "Admin"}, writeTool("k8s_delete_pod")); !strings.Contains(got.Reason, "no deletes in prod") || !got.Risk.Destructive {
+		t.Errorf("unexpected decision: %+v", got)
+	}
+	if got := (*Policy)(nil).Decide(Subject{Role: "Viewer"}, writeTool("k8s_restart")); got.Allowed || !strings.Contains(got.Reason, "only Admin and Editor") {
+		t.Errorf("unexpected role default decision: %+v", got)
+	}
+}
+
+func TestPolicyDecideFailsClosedOnUnresolvedTeams(t *testing.T) {
+	policy := &Policy{Rules: []Rule{{Effect: EffectDeny, Teams: []string{"contractors"}}}}
+
+	if got := policy.Decide(Subject{Role: "Editor", Teams: []string{"sre"}}, writeTool("k8s_restart")); !got.Allowed {
+		t.Errorf("expected members of other teams to be allowed: %+v", got)
+	}
+	got := policy.Decide(Subject{Role: "Editor", TeamsUnresolved: true}, writeTool("k8s_restart"))
+	if got.Allowed || got.Rule != 0 || !strings.Contains(got.Reason, "could not be resolved") {
+		t.Errorf("expected the team deny rule to match: %+v", got)
+	}
+}
+
+func TestFilterToolsByRoleAppliesPolicy(t *testing.T) {
+	tools := []mcp.Tool{readOnlyTool("grafana_get_dashboard"), writeTool("grafana_delete_dashboard"), writeTool("k8s_restart")}
+	policy := &Policy{Rules: []Rule{
+		{Effect: EffectDeny, Risks: []string{RiskDestructive}},
+		{Effect: EffectAllow, Servers: []string{"k8s"}},
+	}}
+
+	if got := toolNames(FilterToolsByRole(tools, Subject{Role: "Admin"}, policy)); got != "grafana_get_dashboard,k8s_restart" {
+		t.Errorf("Admin got %s", got)
+	}
+	if got := toolNames(FilterToolsByRole(tools, Subject{Role: "Viewer"}, policy)); got != "grafana_get_dashboard,k8s_restart" {
+		t.Errorf("Viewer got %s", got)
+	}
+}
+
+func TestPolicyDecideMatchesServerID(t *testing.T) {
+	policy := &Policy{Rules: []Rule{{Effect: EffectDeny, Servers: []string{"prod"}}}}
+	prodMetrics := writeTool("prod_metrics_query")
+	prodMetrics.ServerID = "prod_metrics"
+
+	if got := policy.Decide(Subject{Role: "Admin"}, prodMetrics); !got.Allowed {
+		t.Errorf("expected prod_metrics tools not to match server prod: %+v", got)
+	}
+	if got := policy.Decide(Subject{Role: "Admin"}, writeTool("prod_query")); got.Allowed {
+		t.Errorf("expected prod tools to match server prod: %+v", got)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		wantErr bool
+	}{
+		{Rule{Effect: EffectAllow, Tools: []string{"grafana_*"}, Risks: []string{RiskReadOnly}}, false},
+		{Rule{Effect: "permit"}, true},
+		{Rule{Effect: EffectDeny, Tools: []string{"grafana_["}}, true},
+		{Rule{Effect: EffectDeny, Risks: []string{"dangerous"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func toolNames(tools []mcp.Tool) string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestPolicyDecideUsesDefault(t *testing.T) {
+	policy := &Policy{
+		Rules: []Rule{{Effect: EffectAllow, Teams: []string{"sre"}}},
+		Default: func(subject Subject, tool mcp.Tool) (bool, string) {
+			return subject.Role == "Admin", "admins only"
+		},
+	}
+
+	if got := policy.Decide(Subject{Role: "Editor"}, readOnlyTool("grafana_get_dashboard")); got.Allowed || got.Reason != "admins only" || got.Rule != -1 {
+		t.Errorf("unexpected default decision: %+v", got)
+	}
+	if got := policy.Decide(Subject{Role: "Viewer", Teams: []string{"sre"}}, writeTool("k8s_restart")); !got.Allowed || got.Rule != 0 {
+		t.Errorf("unexpected team decision: %+v", got)
+	}
+}
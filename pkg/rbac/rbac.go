@@ -16,13 +16,13 @@ func CanAccessTool(role string, tool mcp.Tool) bool {
 	return IsReadOnlyTool(tool)
 }
 
-func FilterToolsByRole(tools []mcp.Tool, role string) []mcp.Tool {
-	if role == "Admin" || role == "Editor" {
+func FilterToolsByRole(tools []mcp.Tool, subject Subject, policy *Policy) []mcp.Tool {
+	if (policy == nil || len(policy.Rules) == 0) && (subject.Role == "Admin" || subject.Role == "Editor") {
 		return tools
 	}
 	filtered := make([]mcp.Tool, 0, len(tools))
 	for _, t := range tools {
-		if CanAccessTool(role, t) {
+		if policy.Decide(subject, t).Allowed {
 			filtered = append(filtered, t)
 		}
 	}
@@ -2,6 +2,7 @@ package rbac
 
 import (
 	"consensys-asko11y-app/pkg/mcp"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +11,7 @@ func boolPtr(b bool) *bool { return &b }
 func readOnlyTool(name string) mcp.Tool {
 	return mcp.Tool{
 		Name:        name,
+		ServerID:    serverID(name),
 		Annotations: &mcp.ToolAnnotations{ReadOnlyHint: boolPtr(true)},
 	}
 }
@@ -17,10 +19,16 @@ func readOnlyTool(name string) mcp.Tool {
 func writeTool(name string) mcp.Tool {
 	return mcp.Tool{
 		Name:        name,
+		ServerID:    serverID(name),
 		Annotations: &mcp.ToolAnnotations{ReadOnlyHint: boolPtr(false)},
 	}
 }
 
+func serverID(toolName string) string {
+	id, _, _ := strings.Cut(toolName, "_")
+	return id
+}
+
 func unannotatedTool(name string) mcp.Tool {
 	return mcp.Tool{Name: name}
 }
@@ -86,21 +94,21 @@ func TestFilterToolsByRole(t *testing.T) {
 	}
 
 	t.Run("admin gets all", func(t *testing.T) {
-		filtered := FilterToolsByRole(tools, "Admin")
+		filtered := FilterToolsByRole(tools, Subject{Role: "Admin"}, nil)
 		if len(filtered) != 5 {
 			t.Errorf("Admin got %d tools, want 5", len(filtered))
 		}
 	})
 
 	t.Run("editor gets all", func(t *testing.T) {
-		filtered := FilterToolsByRole(tools, "Editor")
+		filtered := FilterToolsByRole(tools, Subject{Role: "Editor"}, nil)
 		if len(filtered) != 5 {
 			t.Errorf("Editor got %d tools, want 5", len(filtered))
 		}
 	})
 
 	t.Run("viewer gets read-only only", func(t *testing.T) {
-		filtered := FilterToolsByRole(tools, "Viewer")
+		filtered := FilterToolsByRole(tools, Subject{Role: "Viewer"}, nil)
 		if len(filtered) != 2 {
 			t.Errorf("Viewer got %d tools, want 2", len(filtered))
 		}
@@ -112,7 +120,7 @@ func TestFilterToolsByRole(t *testing.T) {
 	})
 
 	t.Run("empty list", func(t *testing.T) {
-		filtered := FilterToolsByRole([]mcp.Tool{}, "Viewer")
+		filtered := FilterToolsByRole([]mcp.Tool{}, Subject{Role: "Viewer"}, nil)
 		if len(filtered) != 0 {
 			t.Errorf("Expected empty, got %d", len(filtered))
 		}
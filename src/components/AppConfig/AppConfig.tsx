@@ -106,7 +106,10 @@ type State = {
   serviceGraphMaxEdges: number;
   approvalPolicy: string;
   maxParallelToolCalls: number;
+  samplingTokenBudget: number;
   agentEvalCaptureEnabled: boolean;
+  alertWebhookUser: string;
+  alertWebhookOrgName: string;
 };
 
 type ValidationErrors = {
@@ -123,6 +126,7 @@ const MAX_TOTAL_TOKENS = 200000;
 const BUILTIN_MCP_SERVER_ID = 'mcp-grafana';
 const DEFAULT_SERVICE_GRAPH_MAX_NODES = 100;
 const DEFAULT_SERVICE_GRAPH_MAX_EDGES = 200;
+const DEFAULT_SAMPLING_TOKEN_BUDGET = 20000;
 const SERVICE_GRAPH_MAX_NODES_LIMIT = 500;
 const SERVICE_GRAPH_MAX_EDGES_LIMIT = 1000;
 const DEFAULT_TOPOLOGY_QUERY = 'service topology dependencies incidents upstream downstream';
@@ -193,6 +197,8 @@ function normalizeMCPServersForDirty(settings: AppPluginSettings): unknown[] {
     headers: cleanObject(server.headers),
     toolSelections: cleanObject(server.toolSelections),
     riskOverrides: cleanObject(server.riskOverrides),
+    toolTimeoutSeconds: server.toolTimeoutSeconds ?? 0,
+    toolTimeouts: cleanObject(server.toolTimeouts),
   }));
 }
 
@@ -280,7 +286,10 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
     serviceGraphMaxEdges: jsonData?.serviceGraphMaxEdges || DEFAULT_SERVICE_GRAPH_MAX_EDGES,
     approvalPolicy: jsonData?.approvalPolicy || 'approval-gated-writes',
     maxParallelToolCalls: jsonData?.maxParallelToolCalls || 4,
+    samplingTokenBudget: jsonData?.samplingTokenBudget || DEFAULT_SAMPLING_TOKEN_BUDGET,
     agentEvalCaptureEnabled: jsonData?.agentEvalCaptureEnabled ?? false,
+    alertWebhookUser: jsonData?.alertWebhookUser || '',
+    alertWebhookOrgName: jsonData?.alertWebhookOrgName || '',
   });
   const [validationErrors, setValidationErrors] = useState<ValidationErrors>({
     mcpServers: {},
@@ -350,7 +359,9 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
   const isLLMSettingsDisabled = Boolean(
     !state.maxTotalTokens || state.maxTotalTokens < MIN_TOTAL_TOKENS || state.maxTotalTokens > MAX_TOTAL_TOKENS
   );
-  const isAgentRuntimeDisabled = state.maxParallelToolCalls < 1 || state.maxParallelToolCalls > 16;
+  const isMaxParallelInvalid = state.maxParallelToolCalls < 1 || state.maxParallelToolCalls > 16;
+  const isSamplingBudgetInvalid = state.samplingTokenBudget < -1;
+  const isAgentRuntimeDisabled = isMaxParallelInvalid || isSamplingBudgetInvalid;
   const isServiceGraphSettingsDisabled =
     state.serviceGraphMaxNodes < 1 ||
     state.serviceGraphMaxNodes > SERVICE_GRAPH_MAX_NODES_LIMIT ||
@@ -398,7 +409,10 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
       'agent-runtime':
         state.approvalPolicy !== (savedJsonData.approvalPolicy || 'approval-gated-writes') ||
         state.maxParallelToolCalls !== (savedJsonData.maxParallelToolCalls || 4) ||
-        state.agentEvalCaptureEnabled !== (savedJsonData.agentEvalCaptureEnabled ?? false),
+        state.samplingTokenBudget !== (savedJsonData.samplingTokenBudget || DEFAULT_SAMPLING_TOKEN_BUDGET) ||
+        state.agentEvalCaptureEnabled !== (savedJsonData.agentEvalCaptureEnabled ?? false) ||
+        state.alertWebhookUser !== (savedJsonData.alertWebhookUser || '') ||
+        state.alertWebhookOrgName !== (savedJsonData.alertWebhookOrgName || ''),
       mcp: mcpDirty,
       'service-graph':
         state.graphitiScanInterval !== (savedJsonData.graphitiScanInterval || 'off') ||
@@ -883,7 +897,10 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
         ...savedJsonData,
         approvalPolicy: state.approvalPolicy,
         maxParallelToolCalls: state.maxParallelToolCalls,
+        samplingTokenBudget: state.samplingTokenBudget,
         agentEvalCaptureEnabled: state.agentEvalCaptureEnabled,
+        alertWebhookUser: state.alertWebhookUser,
+        alertWebhookOrgName: state.alertWebhookOrgName,
       },
     });
   }
@@ -1076,8 +1093,8 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
               label="Max parallel tool calls"
               description="Upper bound reserved for the agent scheduler. The current runtime keeps tool execution sequential when a provider serializes tool calls."
               className="mt-2"
-              invalid={isAgentRuntimeDisabled}
-              error={isAgentRuntimeDisabled ? 'Enter a value from 1 to 16' : undefined}
+              invalid={isMaxParallelInvalid}
+              error={isMaxParallelInvalid ? 'Enter a value from 1 to 16' : undefined}
             >
               <Input
                 width={20}
@@ -1087,7 +1104,25 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
                 max={16}
                 value={state.maxParallelToolCalls}
                 onChange={onChange}
-                invalid={isAgentRuntimeDisabled}
+                invalid={isMaxParallelInvalid}
+              />
+            </Field>
+
+            <Field
+              label="Sampling token budget"
+              description="LLM tokens MCP servers may spend per run through sampling requests, using the requesting user's credentials. Requests from untrusted servers need approval when the approval policy is on. Set to -1 to refuse sampling."
+              className="mt-2"
+              invalid={isSamplingBudgetInvalid}
+              error={isSamplingBudgetInvalid ? 'Enter -1 or a positive number of tokens' : undefined}
+            >
+              <Input
+                width={20}
+                name="samplingTokenBudget"
+                type="number"
+                min={-1}
+                value={state.samplingTokenBudget}
+                onChange={onChange}
+                invalid={isSamplingBudgetInvalid}
               />
             </Field>
 
@@ -1102,6 +1137,22 @@ const AppConfig = ({ plugin }: AppConfigProps) => {
               />
             </Field>
 
+            <Field
+              label="Alert webhook service user"
+              description="Grafana login that owns investigations started by /api/alerts/webhook. Leave empty to disable the webhook."
+              className="mt-2"
+            >
+              <Input width={40} name="alertWebhookUser" value={state.alertWebhookUser} onChange={onChange} />
+            </Field>
+
+            <Field
+              label="Alert webhook org name"
+              description="Grafana org name used for webhook investigations and forwarded to MCP servers as X-Scope-OrgID. The orgName and scopeOrgId query parameters on the webhook URL override it."
+              className="mt-2"
+            >
+              <Input width={40} name="alertWebhookOrgName" value={state.alertWebhookOrgName} onChange={onChange} />
+            </Field>
+
             <div className="mt-3">
               <Button onClick={onSubmitAgentRuntimeSettings} disabled={isAgentRuntimeDisabled}>
                 Save agent runtime
@@ -85,6 +85,7 @@ function ChatComponent({
     messageQueue,
     stopGeneration,
     resolveApproval,
+    resolveInput,
   } = useChat(
     pluginSettings,
     sessionIdFromUrl,
@@ -183,6 +184,7 @@ function ChatComponent({
       queuedMessageCount: messageQueue.length,
       onStopGeneration: stopGeneration,
       onResolveApproval: resolveApproval,
+      onResolveInput: resolveInput,
       onRetry: retryLastMessage,
     }),
     [
@@ -208,6 +210,7 @@ function ChatComponent({
       messageQueue.length,
       stopGeneration,
       resolveApproval,
+      resolveInput,
       retryLastMessage,
     ]
   );
@@ -1,7 +1,8 @@
 import React from 'react';
 
 import { ChatMessage } from '../ChatMessage/ChatMessage';
-import { AgentApprovalItem, ChatMessage as ChatMessageType } from '../../types';
+import { AgentApprovalItem, AgentInputItem, ChatMessage as ChatMessageType } from '../../types';
+import type { InputAction } from '../../../../services/agentClient';
 
 interface ChatHistoryProps {
   chatHistory: ChatMessageType[];
@@ -11,6 +12,7 @@ interface ChatHistoryProps {
     decision: 'approved' | 'rejected',
     approvalScope?: 'once' | 'always'
   ) => Promise<void>;
+  onResolveInput?: (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>) => Promise<void>;
   onRetry?: () => void;
 }
 
@@ -18,6 +20,7 @@ export const ChatHistory: React.FC<ChatHistoryProps> = ({
   chatHistory,
   isGenerating,
   onResolveApproval,
+  onResolveInput,
   onRetry,
 }) => (
   <div>
@@ -28,6 +31,7 @@ export const ChatHistory: React.FC<ChatHistoryProps> = ({
         isGenerating={isGenerating}
         isLastMessage={index === chatHistory.length - 1}
         onResolveApproval={onResolveApproval}
+        onResolveInput={onResolveInput}
         onRetry={onRetry}
       />
     ))}
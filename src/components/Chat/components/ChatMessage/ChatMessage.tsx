@@ -7,7 +7,9 @@ import { ToolCallsSection } from '../ToolCallsSection/ToolCallsSection';
 import { GraphRenderer } from '../GraphRenderer/GraphRenderer';
 import { LogsRenderer } from '../LogsRenderer/LogsRenderer';
 import { TracesRenderer } from '../TracesRenderer/TracesRenderer';
-import { AgentApprovalItem, ChatMessage as ChatMessageType, ContentSection } from '../../types';
+import { InputRequestForm } from '../InputRequestForm/InputRequestForm';
+import { AgentApprovalItem, AgentInputItem, ChatMessage as ChatMessageType, ContentSection } from '../../types';
+import type { InputAction } from '../../../../services/agentClient';
 import { splitContentByPromQL } from '../../utils/promqlParser';
 
 interface ChatMessageProps {
@@ -19,6 +21,7 @@ interface ChatMessageProps {
     decision: 'approved' | 'rejected',
     approvalScope?: 'once' | 'always'
   ) => Promise<void>;
+  onResolveInput?: (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>) => Promise<void>;
   onRetry?: () => void;
 }
 
@@ -74,6 +77,7 @@ function QuerySection({ section }: QuerySectionProps): React.ReactElement | null
 function AgentTraceSummary({
   message,
   onResolveApproval,
+  onResolveInput,
 }: {
   message: ChatMessageType;
   onResolveApproval?: (
@@ -81,13 +85,15 @@ function AgentTraceSummary({
     decision: 'approved' | 'rejected',
     approvalScope?: 'once' | 'always'
   ) => Promise<void>;
+  onResolveInput?: (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>) => Promise<void>;
 }): React.ReactElement | null {
   const theme = useTheme2();
   const hasEvidence = Boolean(message.evidence?.length);
   const hasApprovals = Boolean(message.approvals?.length);
+  const hasInputs = Boolean(message.inputs?.length);
   const [isEvidenceOpen, setIsEvidenceOpen] = React.useState(false);
 
-  if (!hasEvidence && !hasApprovals) {
+  if (!hasEvidence && !hasApprovals && !hasInputs) {
     return null;
   }
 
@@ -151,6 +157,14 @@ function AgentTraceSummary({
         </div>
       )}
 
+      {hasInputs && (
+        <div className="px-3 py-2 border-b border-weak">
+          {message.inputs?.map((input) => (
+            <InputRequestForm key={input.inputId} input={input} onResolveInput={onResolveInput} />
+          ))}
+        </div>
+      )}
+
       {hasEvidence && (
         <div className="px-3 py-2">
           <button
@@ -208,6 +222,7 @@ export const ChatMessage: React.FC<ChatMessageProps> = ({
   isGenerating = false,
   isLastMessage = false,
   onResolveApproval,
+  onResolveInput,
   onRetry,
 }) => {
   const theme = useTheme2();
@@ -247,7 +262,7 @@ export const ChatMessage: React.FC<ChatMessageProps> = ({
           </div>
         )}
 
-        <AgentTraceSummary message={message} onResolveApproval={onResolveApproval} />
+        <AgentTraceSummary message={message} onResolveApproval={onResolveApproval} onResolveInput={onResolveInput} />
 
         {showThinking && (
           <div className="flex items-center gap-3 px-4 py-3 rounded-lg animate-pulse bg-surface text-secondary">
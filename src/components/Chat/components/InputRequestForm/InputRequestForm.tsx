@@ -0,0 +1,132 @@
+import React from 'react';
+import { Button, Checkbox, Field, Input, Select, useTheme2 } from '@grafana/ui';
+import type { InputAction, InputSchemaProperty } from '../../../../services/agentClient';
+import { AgentInputItem } from '../../types';
+
+interface InputRequestFormProps {
+  input: AgentInputItem;
+  onResolveInput?: (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>) => Promise<void>;
+}
+
+type FormValues = Record<string, string | boolean>;
+
+function initialValues(properties: Record<string, InputSchemaProperty>): FormValues {
+  const values: FormValues = {};
+  for (const [name, property] of Object.entries(properties)) {
+    if (property.type === 'boolean') {
+      values[name] = property.default === true;
+    } else {
+      values[name] = property.default !== undefined ? String(property.default) : '';
+    }
+  }
+  return values;
+}
+
+// toContent converts the form's string values to the schema's types. Empty
+// optional fields are left out; the backend validates the rest.
+function toContent(properties: Record<string, InputSchemaProperty>, values: FormValues): Record<string, unknown> {
+  const content: Record<string, unknown> = {};
+  for (const [name, property] of Object.entries(properties)) {
+    const value = values[name];
+    if (typeof value === 'boolean') {
+      content[name] = value;
+      continue;
+    }
+    if (value === '') {
+      continue;
+    }
+    content[name] = property.type === 'number' || property.type === 'integer' ? Number(value) : value;
+  }
+  return content;
+}
+
+/** Form for the input an MCP server requests while one of its tools runs. */
+export function InputRequestForm({ input, onResolveInput }: InputRequestFormProps): React.ReactElement {
+  const theme = useTheme2();
+  const properties = React.useMemo(() => input.schema?.properties ?? {}, [input.schema]);
+  const required = new Set(input.schema?.required ?? []);
+  const [values, setValues] = React.useState<FormValues>(() => initialValues(properties));
+  const disabled = input.resolving || !onResolveInput;
+
+  const setValue = (name: string, value: string | boolean) => setValues((prev) => ({ ...prev, [name]: value }));
+
+  const handleSubmit = (event: React.FormEvent) => {
+    event.preventDefault();
+    onResolveInput?.(input, 'accept', toContent(properties, values));
+  };
+
+  return (
+    <div className="flex flex-col gap-2 py-2">
+      <div>
+        <div className="text-sm font-medium" style={{ color: theme.colors.text.primary }}>
+          {input.action ? 'Input provided' : 'Input requested'}: {input.serverId || input.toolName}
+        </div>
+        <div className="text-xs mt-1 whitespace-pre-wrap" style={{ color: theme.colors.text.secondary }}>
+          {input.message}
+        </div>
+        {input.error && <div className="text-xs text-error mt-1">{input.error}</div>}
+      </div>
+      {input.action ? (
+        <span className="text-xs font-medium text-secondary">
+          {input.action}
+          {input.comment ? ` · ${input.comment}` : ''}
+        </span>
+      ) : (
+        <form onSubmit={handleSubmit}>
+          {Object.entries(properties).map(([name, property]) => {
+            const label = property.title || name;
+            if (property.type === 'boolean') {
+              return (
+                <Field key={name} description={property.description}>
+                  <Checkbox
+                    label={label}
+                    value={values[name] === true}
+                    disabled={disabled}
+                    onChange={(e) => setValue(name, e.currentTarget.checked)}
+                  />
+                </Field>
+              );
+            }
+            if (property.enum) {
+              return (
+                <Field key={name} label={label} description={property.description} required={required.has(name)}>
+                  <Select
+                    options={property.enum.map((option) => ({ label: String(option), value: String(option) }))}
+                    value={values[name] === '' ? null : String(values[name])}
+                    disabled={disabled}
+                    onChange={(option) => setValue(name, option?.value ?? '')}
+                  />
+                </Field>
+              );
+            }
+            const numeric = property.type === 'number' || property.type === 'integer';
+            return (
+              <Field key={name} label={label} description={property.description} required={required.has(name)}>
+                <Input
+                  type={numeric ? 'number' : 'text'}
+                  value={String(values[name] ?? '')}
+                  disabled={disabled}
+                  onChange={(e) => setValue(name, e.currentTarget.value)}
+                />
+              </Field>
+            );
+          })}
+          <div className="flex flex-wrap justify-end gap-2">
+            <Button size="sm" type="submit" icon="check" disabled={disabled}>
+              Submit
+            </Button>
+            <Button
+              size="sm"
+              variant="secondary"
+              icon="times"
+              disabled={disabled}
+              onClick={() => onResolveInput?.(input, 'decline')}
+            >
+              Decline
+            </Button>
+          </div>
+        </form>
+      )}
+    </div>
+  );
+}
@@ -3,7 +3,7 @@ import { UseSessionManagerReturn, SessionMetadata } from '../../hooks/useSession
 import { LoadingButton, InlineLoading } from '../../../LoadingOverlay';
 import { ShareDialog } from '../ShareDialog/ShareDialog';
 import { sessionShareService, CreateShareResponse } from '../../../../services/sessionShare';
-import { getSession, getSessionStats, SessionStats } from '../../../../services/backendSessionClient';
+import { getSessionStats, SessionStats } from '../../../../services/backendSessionClient';
 import { Icon, useTheme2 } from '@grafana/ui';
 
 interface SessionSidebarProps {
@@ -248,7 +248,7 @@ export function SessionSidebar({ sessionManager, currentSessionId, isOpen, onClo
 
         {/* Share dialog */}
         {shareDialogSessionId && (
-          <ShareDialogWrapper
+          <ShareDialog
             sessionId={shareDialogSessionId}
             onClose={() => setShareDialogSessionId(null)}
             existingShares={sessionShares.get(shareDialogSessionId) || []}
@@ -266,50 +266,6 @@ export function SessionSidebar({ sessionManager, currentSessionId, isOpen, onClo
   );
 }
 
-// Wrapper component to load session data for ShareDialog
-function ShareDialogWrapper({
-  sessionId,
-  onClose,
-  existingShares,
-  onSharesChanged,
-}: {
-  sessionId: string;
-  onClose: () => void;
-  existingShares: CreateShareResponse[];
-  onSharesChanged: (shares: CreateShareResponse[]) => void;
-}) {
-  const [session, setSession] = React.useState<any>(null);
-  const [loading, setLoading] = React.useState(true);
-
-  useEffect(() => {
-    const loadSession = async () => {
-      try {
-        const loadedSession = await getSession(sessionId);
-        setSession(loadedSession);
-      } catch {
-        // Failed to load session for share dialog; loading state handles UI
-      } finally {
-        setLoading(false);
-      }
-    };
-    loadSession();
-  }, [sessionId]);
-
-  if (loading || !session) {
-    return null;
-  }
-
-  return (
-    <ShareDialog
-      sessionId={sessionId}
-      session={session}
-      onClose={onClose}
-      existingShares={existingShares}
-      onSharesChanged={onSharesChanged}
-    />
-  );
-}
-
 function formatTokenCount(tokens: number): string {
   if (tokens < 1000) {
     return tokens.toString();
@@ -1,7 +1,6 @@
 import React, { useState, useEffect } from 'react';
-import { Modal, Button, Select, Input, ClipboardButton, Alert } from '@grafana/ui';
+import { Modal, Button, Select, MultiSelect, Input, ClipboardButton, Alert } from '@grafana/ui';
 import { sessionShareService, CreateShareResponse } from '../../../../services/sessionShare';
-import { ChatMessage } from '../../types';
 import {
   EXPIRY_OPTIONS,
   expiryConfigToApiParams,
@@ -10,25 +9,14 @@ import {
   findExpiryOptionByKey,
 } from '../../../../utils/shareUtils';
 
-interface SessionData {
-  id: string;
-  title: string;
-  messages: ChatMessage[];
-  createdAt: string;
-  updatedAt: string;
-  messageCount: number;
-  summary?: string;
-}
-
 interface ShareDialogProps {
   sessionId: string;
-  session: SessionData;
   onClose: () => void;
   existingShares?: CreateShareResponse[];
   onSharesChanged?: (shares: CreateShareResponse[]) => void;
 }
 
-export function ShareDialog({ sessionId, session, onClose, existingShares = [], onSharesChanged }: ShareDialogProps) {
+export function ShareDialog({ sessionId, onClose, existingShares = [], onSharesChanged }: ShareDialogProps) {
   // Default to 7 days
   const [selectedExpiryKey, setSelectedExpiryKey] = useState<string>(expiryConfigToKey({ type: 'days', value: 7 }));
   const [isCreating, setIsCreating] = useState(false);
@@ -36,6 +24,12 @@ export function ShareDialog({ sessionId, session, onClose, existingShares = [],
   const [shares, setShares] = useState<CreateShareResponse[]>(existingShares);
   const [revokingShareId, setRevokingShareId] = useState<string | null>(null);
   const [errorMessage, setErrorMessage] = useState<string | null>(null);
+  const [userTeams, setUserTeams] = useState<string[]>([]);
+  const [selectedTeams, setSelectedTeams] = useState<string[]>([]);
+
+  useEffect(() => {
+    sessionShareService.getUserTeams().then(setUserTeams);
+  }, []);
 
   useEffect(() => {
     // Load existing shares if not provided
@@ -67,12 +61,7 @@ export function ShareDialog({ sessionId, session, onClose, existingShares = [],
 
       const { expiresInDays, expiresInHours } = expiryConfigToApiParams(expiryOption.config);
 
-      const share = await sessionShareService.createShare(
-        sessionId,
-        session,
-        expiresInDays,
-        expiresInHours
-      );
+      const share = await sessionShareService.createShare(sessionId, expiresInDays, expiresInHours, selectedTeams);
       setCreatedShare(share);
 
       // Update shares list locally and notify parent
@@ -136,6 +125,12 @@ export function ShareDialog({ sessionId, session, onClose, existingShares = [],
             <div className="text-xs text-secondary">
               <strong>Expires:</strong> {formatExpiryDate(createdShare.expiresAt)}
             </div>
+            {createdShare.redactions && createdShare.redactions.length > 0 && (
+              <div className="text-xs text-secondary">
+                <strong>Redacted:</strong>{' '}
+                {createdShare.redactions.map((r) => `${r.rule} (${r.count})`).join(', ')}
+              </div>
+            )}
             <div className="flex gap-1.5 justify-end pt-2">
               <Button variant="secondary" size="sm" onClick={() => setCreatedShare(null)}>
                 Create Another Share
@@ -167,6 +162,21 @@ export function ShareDialog({ sessionId, session, onClose, existingShares = [],
               />
             </div>
 
+            {userTeams.length > 0 && (
+              <div>
+                <label className="block text-xs font-medium mb-1 text-primary">
+                  Limit to teams (optional):
+                </label>
+                <MultiSelect
+                  data-testid="share-teams-select"
+                  options={userTeams.map((team) => ({ label: team, value: team }))}
+                  value={selectedTeams}
+                  onChange={(options) => setSelectedTeams(options.map((option) => option.value as string))}
+                  placeholder="Anyone in this organization"
+                />
+              </div>
+            )}
+
             <div className="p-2 bg-secondary rounded text-xs text-secondary">
               <p className="m-0">
                 Shared sessions can be viewed in read-only mode. Recipients can import the session to their account.
@@ -167,6 +167,13 @@ export const ToolCallDisplay: React.FC<ToolCallDisplayProps> = ({ toolCall }) =>
         )}
       </div>
 
+      {/* Progress while running */}
+      {toolCall.running && toolCall.progress && (
+        <div className="mx-3 mb-2 text-xs truncate" style={{ color: theme.colors.text.secondary }} title={toolCall.progress}>
+          {toolCall.progress}
+        </div>
+      )}
+
       {/* Expanded arguments */}
       {isExpanded && hasArgs && (
         <div
@@ -7,6 +7,7 @@ interface ToolCallsSectionProps {
     name: string;
     arguments: string;
     running: boolean;
+    progress?: string;
     error?: string;
     response?: any;
   }>;
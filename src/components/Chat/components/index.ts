@@ -3,6 +3,7 @@ export { ChatHistory } from './ChatHistory/ChatHistory';
 export { ChatInput } from './ChatInput/ChatInput';
 export { ChatMessage } from './ChatMessage/ChatMessage';
 export { GraphRenderer } from './GraphRenderer/GraphRenderer';
+export { InputRequestForm } from './InputRequestForm/InputRequestForm';
 export { HistoryButton } from './HistoryButton/HistoryButton';
 export { SaveToMemoryButton } from './SaveToMemoryButton/SaveToMemoryButton';
 export { LogsRenderer } from './LogsRenderer/LogsRenderer';
@@ -1,6 +1,6 @@
 import { useState, useRef, useEffect, useCallback, useMemo } from 'react';
 import { config } from '@grafana/runtime';
-import { AgentApprovalItem, ChatMessage, GrafanaPageRef, RenderedToolCall } from '../types';
+import { AgentApprovalItem, AgentInputItem, ChatMessage, GrafanaPageRef, RenderedToolCall } from '../types';
 import { useSessionManager } from './useSessionManager';
 import { ValidationService } from '../../../services/validation';
 import { parseGrafanaLinks } from '../utils/grafanaLinkParser';
@@ -9,16 +9,22 @@ import {
   reconnectToAgentRun,
   cancelAgentRun,
   resolveAgentApproval,
+  resolveAgentInput,
   getAgentRunStatus,
   type AgentCallbacks,
   type ApprovalRequestEvent,
   type ApprovalResolvedEvent,
+  type ContentDeltaEvent,
   type ContentEvent,
   type EvidenceEvent,
   type FinalReportEvent,
+  type InputAction,
+  type InputRequestEvent,
+  type InputResolvedEvent,
   type MCPUnavailableEvent,
   type ToolCallStartEvent,
   type ToolCallResultEvent,
+  type ToolCallProgressEvent,
 } from '../../../services/agentClient';
 import { getSession } from '../../../services/backendSessionClient';
 import type { AppPluginSettings } from '../../../types/plugin';
@@ -33,6 +39,40 @@ function updateLastAssistantMessage(history: ChatMessage[], updater: (msg: ChatM
   return history.map((msg, idx) => (idx === history.length - 1 && msg.role === 'assistant' ? updater(msg) : msg));
 }
 
+// updateInputItem applies fields to one input request of the last assistant message.
+function updateInputItem(history: ChatMessage[], inputId: string, fields: Partial<AgentInputItem>): ChatMessage[] {
+  return updateLastAssistantMessage(history, (msg) => ({
+    ...msg,
+    inputs: (msg.inputs || []).map((item) => (item.inputId === inputId ? { ...item, ...fields } : item)),
+  }));
+}
+
+function resolvedInputFields(event: InputResolvedEvent): Partial<AgentInputItem> {
+  return {
+    action: event.action,
+    content: event.content,
+    comment: event.comment,
+    resolvedAt: event.resolvedAt,
+    resolving: false,
+    error: undefined,
+  };
+}
+
+// withoutStreamedDraft removes text previewed via content_delta events from the
+// end of a message, so the assembled content (or a reset) can replace it.
+function withoutStreamedDraft(content: string, draft: string): string {
+  return draft && content.endsWith(draft) ? content.slice(0, content.length - draft.length) : content;
+}
+
+// formatToolProgress renders a tool_call_progress event as one status line.
+function formatToolProgress(event: ToolCallProgressEvent): string {
+  if (event.level) {
+    return event.logger ? `[${event.logger}] ${event.message ?? ''}` : event.message ?? '';
+  }
+  const pct = event.total ? ` (${Math.round(((event.progress ?? 0) / event.total) * 100)}%)` : '';
+  return `${event.message || 'Working'}${pct}`;
+}
+
 export function useChat(
   pluginSettings: AppPluginSettings,
   sessionIdFromUrl: string | null,
@@ -164,6 +204,9 @@ export function useChat(
   const activeSessionIdRef = useRef<string | null>(null);
   const pendingRunSessionIdRef = useRef<string | null>(null);
   const approvalInFlightRef = useRef<Set<string>>(new Set());
+  const inputInFlightRef = useRef<Set<string>>(new Set());
+  // Text shown from content_delta events since the last content event.
+  const streamedDraftRef = useRef('');
 
   const sessionManager = useSessionManager(
     orgId,
@@ -205,9 +248,11 @@ export function useChat(
         if (abortController.signal.aborted) {
           return;
         }
+        const draft = streamedDraftRef.current;
+        streamedDraftRef.current = '';
         setChatHistory((prev) =>
           updateLastAssistantMessage(prev, (msg) => {
-            const accumulated = msg.content + event.content;
+            const accumulated = withoutStreamedDraft(msg.content, draft) + event.content;
             return {
               ...msg,
               content: accumulated,
@@ -216,6 +261,22 @@ export function useChat(
           })
         );
       },
+      onContentDelta: (event: ContentDeltaEvent) => {
+        if (abortController.signal.aborted) {
+          return;
+        }
+        if (event.reset) {
+          const draft = streamedDraftRef.current;
+          streamedDraftRef.current = '';
+          setChatHistory((prev) =>
+            updateLastAssistantMessage(prev, (msg) => ({ ...msg, content: withoutStreamedDraft(msg.content, draft) }))
+          );
+          return;
+        }
+        const delta = event.delta ?? '';
+        streamedDraftRef.current += delta;
+        setChatHistory((prev) => updateLastAssistantMessage(prev, (msg) => ({ ...msg, content: msg.content + delta })));
+      },
       onToolCallStart: (event: ToolCallStartEvent) => {
         if (abortController.signal.aborted) {
           return;
@@ -243,10 +304,25 @@ export function useChat(
           return next;
         });
       },
+      onToolCallProgress: (event: ToolCallProgressEvent) => {
+        if (abortController.signal.aborted) {
+          return;
+        }
+        setToolCalls((prev) => {
+          const existing = prev.get(event.id);
+          if (!existing?.running) {
+            return prev;
+          }
+          const next = new Map(prev);
+          next.set(event.id, { ...existing, progress: formatToolProgress(event) });
+          return next;
+        });
+      },
       onDone: () => {
         // Terminal event; stream completion is handled by the reconnect loop.
       },
       onReconnect: () => {
+        streamedDraftRef.current = '';
         setChatHistory((prev) =>
           updateLastAssistantMessage(prev, (msg) => ({
             ...msg,
@@ -321,6 +397,27 @@ export function useChat(
           }))
         );
       },
+      onInputRequest: (event: InputRequestEvent) => {
+        if (abortController.signal.aborted) {
+          return;
+        }
+        const runId = activeRunIdRef.current || undefined;
+        setChatHistory((prev) =>
+          updateLastAssistantMessage(prev, (msg) => {
+            const inputs = msg.inputs || [];
+            if (inputs.some((input) => input.inputId === event.inputId)) {
+              return msg;
+            }
+            return { ...msg, inputs: [...inputs, { ...event, runId }] };
+          })
+        );
+      },
+      onInputResolved: (event: InputResolvedEvent) => {
+        if (abortController.signal.aborted) {
+          return;
+        }
+        setChatHistory((prev) => updateInputItem(prev, event.inputId, resolvedInputFields(event)));
+      },
       onFinalReport: (event: FinalReportEvent) => {
         if (abortController.signal.aborted) {
           return;
@@ -437,6 +534,38 @@ export function useChat(
     [orgId]
   );
 
+  const resolveInput = useCallback(
+    async (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>): Promise<void> => {
+      const runId = input.runId || activeRunIdRef.current;
+      if (!runId || input.action) {
+        return;
+      }
+      const inFlightKey = `${runId}:${input.inputId}`;
+      if (inputInFlightRef.current.has(inFlightKey)) {
+        return;
+      }
+      inputInFlightRef.current.add(inFlightKey);
+
+      setChatHistory((prev) => updateInputItem(prev, input.inputId, { resolving: true, error: undefined }));
+
+      try {
+        const resolved = await resolveAgentInput(runId, input.inputId, action, content, orgId);
+        setChatHistory((prev) => updateInputItem(prev, input.inputId, resolvedInputFields(resolved)));
+      } catch (error) {
+        // Answers are validated server-side, so keep the form open to fix them.
+        setChatHistory((prev) =>
+          updateInputItem(prev, input.inputId, {
+            resolving: false,
+            error: error instanceof Error ? error.message : 'Failed to answer input request',
+          })
+        );
+      } finally {
+        inputInFlightRef.current.delete(inFlightKey);
+      }
+    },
+    [orgId]
+  );
+
   const sendMessage = async (explicitInput?: string): Promise<void> => {
     const inputToSend = explicitInput ?? currentInput;
     if (!inputToSend.trim()) {
@@ -509,6 +638,7 @@ export function useChat(
         onSessionIdChange(result.sessionId);
       }
 
+      streamedDraftRef.current = '';
       const callbacks = makeCallbacks(abortController, hadErrorRef);
       await reconnectToAgentRun(result.runId, callbacks, orgId, abortController.signal);
 
@@ -625,6 +755,7 @@ export function useChat(
         activeRunIdRef.current = activeRunId;
 
         const hadErrorRef = { current: false };
+        streamedDraftRef.current = '';
         const callbacks = makeCallbacks(abortController, hadErrorRef);
         await reconnectToAgentRun(activeRunId, callbacks, orgId, abortController.signal);
       } catch (err) {
@@ -790,5 +921,6 @@ export function useChat(
     messageQueue,
     stopGeneration,
     resolveApproval,
+    resolveInput,
   };
 }
@@ -32,6 +32,7 @@ function useChatInterface(model: ChatInterfaceScene): ChatInterfaceProps {
     queuedMessageCount: state.queuedMessageCount,
     onStopGeneration: state.onStopGeneration,
     onResolveApproval: state.onResolveApproval,
+    onResolveInput: state.onResolveInput,
     onRetry: state.onRetry,
   };
 }
@@ -67,6 +68,7 @@ function ChatInterfaceRenderer({ model }: SceneComponentProps<ChatInterfaceScene
     queuedMessageCount,
     onStopGeneration,
     onResolveApproval,
+    onResolveInput,
     onRetry,
   } = props;
 
@@ -104,6 +106,7 @@ function ChatInterfaceRenderer({ model }: SceneComponentProps<ChatInterfaceScene
                   chatHistory={chatHistory}
                   isGenerating={isGenerating}
                   onResolveApproval={onResolveApproval}
+                  onResolveInput={onResolveInput}
                   onRetry={onRetry}
                 />
                 <div ref={bottomSpacerRef} className="h-16" style={{ scrollMarginBottom: '100px' }} />
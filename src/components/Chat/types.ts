@@ -1,4 +1,5 @@
 import type { Query } from './utils/promqlParser';
+import type { InputAction, InputSchema } from '../../services/agentClient';
 
 /** Content item from MCP tool response */
 export interface ToolResponseContent {
@@ -19,6 +20,8 @@ export interface RenderedToolCall {
   name: string;
   arguments: string;
   running: boolean;
+  /** Latest progress or server log line while the call is running. */
+  progress?: string;
   error?: string;
   response?: ToolCallResponse;
 }
@@ -49,6 +52,22 @@ export interface AgentApprovalItem {
   error?: string;
 }
 
+export interface AgentInputItem {
+  inputId: string;
+  runId?: string;
+  toolCallId: string;
+  toolName: string;
+  serverId?: string;
+  message: string;
+  schema?: InputSchema;
+  action?: string;
+  content?: Record<string, unknown>;
+  comment?: string;
+  resolvedAt?: string;
+  resolving?: boolean;
+  error?: string;
+}
+
 export interface AgentFinalReport {
   verdict?: string;
   confidence?: string;
@@ -73,6 +92,7 @@ export interface ChatMessage {
   toolCalls?: RenderedToolCall[];
   evidence?: AgentEvidenceItem[];
   approvals?: AgentApprovalItem[];
+  inputs?: AgentInputItem[];
   finalReport?: AgentFinalReport;
   pageRefs?: GrafanaPageRef[];
   timestamp?: Date;
@@ -111,6 +131,7 @@ export interface ChatInterfaceProps {
     decision: 'approved' | 'rejected',
     approvalScope?: 'once' | 'always'
   ) => Promise<void>;
+  onResolveInput?: (input: AgentInputItem, action: InputAction, content?: Record<string, unknown>) => Promise<void>;
   /** Re-send the most recent user prompt after a failed run. */
   onRetry?: () => void;
 }
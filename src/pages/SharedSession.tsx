@@ -27,7 +27,7 @@ function getErrorMessage(err: unknown): string {
     return 'This share link is not found or has expired.';
   }
   if (status === 403 || message.includes('access') || message.includes('permission')) {
-    return "You don't have access to this shared session. It may be from a different organization.";
+    return "You don't have access to this shared session. It may be from a different organization or limited to teams you're not in.";
   }
   return 'Failed to load shared session. Please try again later.';
 }
@@ -141,6 +141,12 @@ export function SharedSession(): React.ReactElement {
             <p className="text-xs text-secondary mt-0.5 truncate">
               This is a shared session. You can view it or import it to your account.
             </p>
+            {sharedSession && sharedSession.redactions.length > 0 && (
+              <p className="text-xs text-secondary mt-0.5 truncate">
+                Sensitive values were redacted:{' '}
+                {sharedSession.redactions.map((r) => `${r.rule} (${r.count})`).join(', ')}
+              </p>
+            )}
           </div>
           <div className="flex-shrink-0">
             <Button variant="primary" size="sm" onClick={handleImport} disabled={importing}>
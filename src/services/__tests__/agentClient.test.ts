@@ -21,6 +21,7 @@ function createMockBody(lines: string[]) {
 function createMockCallbacks(): jest.Mocked<AgentCallbacks> {
   return {
     onContent: jest.fn(),
+    onContentDelta: jest.fn(),
     onToolCallStart: jest.fn(),
     onToolCallResult: jest.fn(),
     onDone: jest.fn(),
@@ -143,6 +144,31 @@ describe('reconnectToAgentRun', () => {
     expect(callbacks.onDone).toHaveBeenCalledWith({ totalIterations: 1 });
   });
 
+  it('should dispatch content_delta events to callback', async () => {
+    const callbacks = createMockCallbacks();
+    const sseLines = [
+      'data: {"type":"content_delta","data":{"delta":"Hel"},"sequence":0}',
+      '',
+      'data: {"type":"content_delta","data":{"reset":true},"sequence":1}',
+      '',
+      'data: {"type":"content","data":{"content":"Hello"},"sequence":2}',
+      '',
+      'data: {"type":"done","data":{"totalIterations":1},"sequence":3}',
+      '',
+    ];
+
+    global.fetch = jest.fn().mockResolvedValue({
+      ok: true,
+      body: createMockBody(sseLines),
+    });
+
+    await reconnectToAgentRun('run-1', callbacks);
+
+    expect(callbacks.onContentDelta).toHaveBeenNthCalledWith(1, { delta: 'Hel' });
+    expect(callbacks.onContentDelta).toHaveBeenNthCalledWith(2, { reset: true });
+    expect(callbacks.onContent).toHaveBeenCalledWith({ content: 'Hello' });
+  });
+
   it('should parse tool call events from SSE stream', async () => {
     const callbacks = createMockCallbacks();
     const sseLines = [
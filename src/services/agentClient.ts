@@ -15,13 +15,23 @@ export interface ContentEvent {
   content: string;
 }
 
+/**
+ * Assistant text streamed ahead of the assembled content event. `reset` means
+ * discard the deltas shown since the last content event (the stream was
+ * retried, or the turn ended in tool calls and its text was dropped).
+ */
+export interface ContentDeltaEvent {
+  delta?: string;
+  reset?: boolean;
+}
+
 export interface ToolCallStartEvent {
   id: string;
   name: string;
   arguments: string;
 }
 
-export type ToolErrorKind = 'transport' | 'tool' | 'protocol' | '';
+export type ToolErrorKind = 'transport' | 'tool' | 'protocol' | 'schema' | '';
 export type AgentToolErrorKind = ToolErrorKind | 'approval_required' | 'approval_denied';
 
 export interface ToolCallResultEvent {
@@ -37,8 +47,25 @@ export interface ToolCallResultEvent {
   errorKind?: AgentToolErrorKind;
 }
 
+export interface ToolCallProgressEvent {
+  id: string;
+  name: string;
+  progress?: number;
+  total?: number;
+  message?: string;
+  /** Set for MCP server log messages; empty for progress updates. */
+  level?: string;
+  logger?: string;
+}
+
 export interface DoneEvent {
   totalIterations: number;
+  promptTokens?: number;
+  completionTokens?: number;
+  totalTokens?: number;
+  /** Prompt tokens the LLM provider served from its prompt cache. */
+  cachedPromptTokens?: number;
+  toolCallCount?: number;
 }
 
 export interface ErrorEvent {
@@ -54,6 +81,11 @@ export interface RunStartedEvent {
   sessionId?: string;
 }
 
+export interface ToolCatalogEvent {
+  version: string;
+  toolCount: number;
+}
+
 export interface MCPUnavailableEvent {
   message: string;
 }
@@ -76,6 +108,7 @@ export interface ApprovalRequestEvent {
   risk: string;
   reason: string;
   arguments: string;
+  serverId?: string;
 }
 
 export interface ApprovalResolvedEvent {
@@ -85,6 +118,39 @@ export interface ApprovalResolvedEvent {
   resolvedAt?: string;
 }
 
+export type InputAction = 'accept' | 'decline' | 'cancel';
+
+export interface InputRequestEvent {
+  inputId: string;
+  toolCallId: string;
+  toolName: string;
+  serverId?: string;
+  message: string;
+  schema?: InputSchema;
+}
+
+export interface InputSchemaProperty {
+  type?: 'string' | 'number' | 'integer' | 'boolean';
+  title?: string;
+  description?: string;
+  enum?: Array<string | number>;
+  default?: string | number | boolean;
+}
+
+export interface InputSchema {
+  type?: 'object';
+  properties?: Record<string, InputSchemaProperty>;
+  required?: string[];
+}
+
+export interface InputResolvedEvent {
+  inputId: string;
+  action: InputAction | string;
+  content?: Record<string, unknown>;
+  comment?: string;
+  resolvedAt?: string;
+}
+
 export interface FinalReportEvent {
   verdict?: string;
   confidence?: string;
@@ -96,8 +162,10 @@ export interface FinalReportEvent {
 
 export type SSEEvent =
   | { type: 'content'; data: ContentEvent; sequence: number }
+  | { type: 'content_delta'; data: ContentDeltaEvent; sequence: number }
   | { type: 'tool_call_start'; data: ToolCallStartEvent; sequence: number }
   | { type: 'tool_call_result'; data: ToolCallResultEvent; sequence: number }
+  | { type: 'tool_call_progress'; data: ToolCallProgressEvent; sequence: number }
   | { type: 'done'; data: DoneEvent; sequence: number }
   | { type: 'error'; data: ErrorEvent; sequence: number }
   | { type: 'run_started'; data: RunStartedEvent; sequence: number }
@@ -105,12 +173,17 @@ export type SSEEvent =
   | { type: 'evidence'; data: EvidenceEvent; sequence: number }
   | { type: 'approval_request'; data: ApprovalRequestEvent; sequence: number }
   | { type: 'approval_resolved'; data: ApprovalResolvedEvent; sequence: number }
-  | { type: 'final_report'; data: FinalReportEvent; sequence: number };
+  | { type: 'input_request'; data: InputRequestEvent; sequence: number }
+  | { type: 'input_resolved'; data: InputResolvedEvent; sequence: number }
+  | { type: 'final_report'; data: FinalReportEvent; sequence: number }
+  | { type: 'tool_catalog'; data: ToolCatalogEvent; sequence: number };
 
 export interface AgentCallbacks {
   onContent: (event: ContentEvent) => void;
+  onContentDelta?: (event: ContentDeltaEvent) => void;
   onToolCallStart: (event: ToolCallStartEvent) => void;
   onToolCallResult: (event: ToolCallResultEvent) => void;
+  onToolCallProgress?: (event: ToolCallProgressEvent) => void;
   onDone: (event: DoneEvent) => void;
   onError: (message: string) => void;
   onRunStarted?: (event: RunStartedEvent) => void;
@@ -119,6 +192,8 @@ export interface AgentCallbacks {
   onEvidence?: (event: EvidenceEvent) => void;
   onApprovalRequest?: (event: ApprovalRequestEvent) => void;
   onApprovalResolved?: (event: ApprovalResolvedEvent) => void;
+  onInputRequest?: (event: InputRequestEvent) => void;
+  onInputResolved?: (event: InputResolvedEvent) => void;
   onFinalReport?: (event: FinalReportEvent) => void;
 }
 
@@ -136,7 +211,17 @@ export interface AgentRunStatus {
     approvals?: Array<
       ApprovalRequestEvent & { decision?: string; comment?: string; createdAt?: string; resolvedAt?: string }
     >;
+    inputs?: Array<
+      InputRequestEvent & {
+        action?: string;
+        content?: Record<string, unknown>;
+        comment?: string;
+        createdAt?: string;
+        resolvedAt?: string;
+      }
+    >;
     finalReport?: FinalReportEvent;
+    toolCatalogVersion?: string;
   };
   error?: string;
 }
@@ -255,12 +340,18 @@ function dispatchEvent(event: SSEEvent, callbacks: AgentCallbacks): void {
     case 'content':
       callbacks.onContent(event.data);
       break;
+    case 'content_delta':
+      callbacks.onContentDelta?.(event.data);
+      break;
     case 'tool_call_start':
       callbacks.onToolCallStart(event.data);
       break;
     case 'tool_call_result':
       callbacks.onToolCallResult(event.data);
       break;
+    case 'tool_call_progress':
+      callbacks.onToolCallProgress?.(event.data);
+      break;
     case 'done':
       callbacks.onDone(event.data);
       break;
@@ -282,6 +373,12 @@ function dispatchEvent(event: SSEEvent, callbacks: AgentCallbacks): void {
     case 'approval_resolved':
       callbacks.onApprovalResolved?.(event.data);
       break;
+    case 'input_request':
+      callbacks.onInputRequest?.(event.data);
+      break;
+    case 'input_resolved':
+      callbacks.onInputResolved?.(event.data);
+      break;
     case 'final_report':
       callbacks.onFinalReport?.(event.data);
       break;
@@ -315,6 +412,30 @@ export async function resolveAgentApproval(
   return resp.json();
 }
 
+export async function resolveAgentInput(
+  runId: string,
+  inputId: string,
+  action: InputAction,
+  content?: Record<string, unknown>,
+  orgId?: string
+): Promise<InputResolvedEvent> {
+  const resp = await fetch(`${AGENT_RUNS_URL}/${runId}/inputs/${inputId}`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...orgIdHeaders(orgId),
+    },
+    body: JSON.stringify({ action, content: action === 'accept' ? content : undefined }),
+  });
+
+  if (!resp.ok) {
+    const text = await resp.text();
+    throw new Error(`Failed to answer input request (${resp.status}): ${text}`);
+  }
+
+  return resp.json();
+}
+
 export interface DetachedRunResult {
   runId: string;
   sessionId: string;
@@ -23,6 +23,7 @@ export interface SessionMetadata {
 export interface BackendChatSession extends SessionMetadata {
   messages: ChatMessage[];
   summary?: string;
+  summarizedCount?: number;
 }
 
 export interface SessionUpdate {
@@ -18,6 +18,7 @@ export interface MCPServerStatus {
   errorCount: number;
   consecutiveFailures: number;
   lastError?: string;
+  breaker?: 'closed' | 'open' | 'half-open';
   tools: MCPTool[];
   toolCount: number;
 }
@@ -4,11 +4,18 @@ import { ChatMessage } from '../components/Chat/types';
 import pluginJson from '../plugin.json';
 import { withSubpath } from '../utils/subpath';
 
+/** A redaction rule that fired while the backend built a share snapshot */
+export interface RedactionHit {
+  rule: string;
+  count: number;
+}
+
 /** Response from creating a share link */
 export interface CreateShareResponse {
   shareId: string;
   shareUrl: string;
   expiresAt: string | null;
+  redactions?: RedactionHit[];
 }
 
 /** Session data retrieved from a share link */
@@ -20,6 +27,7 @@ export interface SharedSession {
   updatedAt: string;
   isShared: true;
   sharedBy?: string;
+  redactions: RedactionHit[];
 }
 
 /** Raw session data from backend response */
@@ -30,6 +38,7 @@ interface SharedSessionResponse {
   createdAt?: string;
   updatedAt?: string;
   sharedBy?: string;
+  redactions?: RedactionHit[];
 }
 
 /** Error thrown by session share operations */
@@ -47,17 +56,18 @@ export class SessionShareService {
   private baseUrl = '/api/plugins/consensys-asko11y-app/resources';
 
   /**
-   * Create a shareable link for a session
+   * Create a shareable link for a session. The backend snapshots and redacts
+   * the stored session itself. When teams are given, only the creator and
+   * members of those Grafana teams can open the link.
    */
   async createShare(
     sessionId: string,
-    sessionData: { id: string; title: string; messages: ChatMessage[]; createdAt: string; updatedAt: string; messageCount: number; summary?: string },
     expiresInDays?: number,
-    expiresInHours?: number
+    expiresInHours?: number,
+    teams?: string[]
   ): Promise<CreateShareResponse> {
     const requestData: Record<string, unknown> = {
       sessionId,
-      sessionData,
     };
 
     // Send expiresInHours if provided, otherwise expiresInDays
@@ -66,6 +76,9 @@ export class SessionShareService {
     } else if (expiresInDays !== undefined) {
       requestData.expiresInDays = expiresInDays;
     }
+    if (teams && teams.length > 0) {
+      requestData.teams = teams;
+    }
 
     const response = await firstValueFrom(
       getBackendSrv().fetch<CreateShareResponse>({
@@ -83,6 +96,26 @@ export class SessionShareService {
     return response.data;
   }
 
+  /**
+   * Get the current user's Grafana teams, offered as share scopes
+   * Returns empty array on error to allow graceful degradation
+   */
+  async getUserTeams(): Promise<string[]> {
+    try {
+      const response = await firstValueFrom(
+        getBackendSrv().fetch<{ teams: string[] }>({
+          url: `${this.baseUrl}/api/teams`,
+          method: 'GET',
+          showErrorAlert: false,
+        })
+      );
+
+      return response?.data?.teams || [];
+    } catch {
+      return [];
+    }
+  }
+
   /**
    * Get a shared session by share ID
    */
@@ -116,6 +149,7 @@ export class SessionShareService {
       updatedAt: data.updatedAt ?? new Date().toISOString(),
       isShared: true,
       sharedBy: data.sharedBy,
+      redactions: data.redactions ?? [],
     };
 
     return sharedSession;
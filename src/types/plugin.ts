@@ -3,11 +3,25 @@ export interface MCPServerConfig {
   name: string;
   url: string;
   enabled: boolean;
-  type?: 'openapi' | 'standard' | 'sse' | 'streamable-http';
+  type?: 'openapi' | 'standard' | 'sse' | 'streamable-http' | 'stdio';
   trusted?: boolean;
   headers?: Record<string, string>;
   toolSelections?: Record<string, boolean>;
   riskOverrides?: Record<string, ToolRiskOverride>;
+  toolTimeoutSeconds?: number;
+  toolTimeouts?: Record<string, number>;
+  command?: string;
+  args?: string[];
+  env?: Record<string, string>;
+  auth?: MCPServerAuth;
+}
+
+export interface MCPServerAuth {
+  type: 'oauth2-client-credentials';
+  tokenUrl?: string;
+  clientId: string;
+  scopes?: string[];
+  resource?: string;
 }
 
 export interface ToolRiskOverride {
@@ -18,6 +32,41 @@ export interface ToolRiskOverride {
   reason?: string;
 }
 
+export type ToolRiskClass = 'read-only' | 'write' | 'destructive' | 'open-world' | 'requires-approval';
+
+export interface ToolPolicyRule {
+  effect: 'allow' | 'deny';
+  description?: string;
+  servers?: string[];
+  tools?: string[];
+  risks?: ToolRiskClass[];
+  roles?: string[];
+  teams?: string[];
+  orgs?: string[];
+}
+
+export interface TokenLimits {
+  daily?: number;
+  monthly?: number;
+}
+
+export interface TokenBudgetSettings {
+  user?: TokenLimits;
+  org?: TokenLimits;
+  users?: Record<string, TokenLimits>;
+  orgs?: Record<string, TokenLimits>;
+}
+
+export interface RedactionRule {
+  name: string;
+  pattern: string;
+}
+
+export interface ShareRedactionSettings {
+  disabledRules?: string[];
+  customRules?: RedactionRule[];
+}
+
 export type AppPluginSettings = {
   mcpServers?: MCPServerConfig[];
   useBuiltInMCP?: boolean;
@@ -26,6 +75,8 @@ export type AppPluginSettings = {
   localGrafanaPort?: number;
   trustedMCPServers?: Record<string, boolean>;
   riskOverrides?: Record<string, ToolRiskOverride>;
+  toolPolicies?: ToolPolicyRule[];
+  approvalPolicies?: ToolPolicyRule[];
 
   defaultSystemPrompt?: string;
   investigationPrompt?: string;
@@ -41,7 +92,14 @@ export type AppPluginSettings = {
   serviceGraphMaxNodes?: number;
   serviceGraphMaxEdges?: number;
 
+  toolCatalogTTL?: string;
+
   approvalPolicy?: string;
   maxParallelToolCalls?: number;
   agentEvalCaptureEnabled?: boolean;
+  samplingTokenBudget?: number;
+  alertWebhookUser?: string;
+  alertWebhookOrgName?: string;
+  shareRedaction?: ShareRedactionSettings;
+  tokenBudgets?: TokenBudgetSettings;
 };